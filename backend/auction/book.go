@@ -0,0 +1,137 @@
+package auction
+
+import (
+	"math/big"
+	"sync"
+)
+
+// BidStatus is where a bid stands if the auction cleared now
+type BidStatus string
+
+const (
+	// BidStatusWinning bids receive every option they bid for
+	BidStatusWinning BidStatus = "winning"
+	// BidStatusPartial is the clearing bid when it only receives part of its options
+	BidStatusPartial BidStatus = "partial"
+	// BidStatusLosing bids receive no options
+	BidStatusLosing BidStatus = "losing"
+)
+
+// StatusChange reports a bid whose status changed after a book update.
+// Previous is empty for a bid that was not in the book.
+type StatusChange struct {
+	RoundAddress  string    `json:"roundAddress"`
+	BidID         string    `json:"bidId"`
+	Buyer         string    `json:"buyerAddress"`
+	Amount        string    `json:"amount"`
+	Price         string    `json:"price"`
+	Previous      BidStatus `json:"previousStatus"`
+	Status        BidStatus `json:"status"`
+	Options       string    `json:"options"`
+	ClearingPrice string    `json:"clearingPrice"`
+}
+
+// Book keeps the bids of auctioning rounds in memory together with the status
+// each bid had at the last clearing, so that updates can be diffed
+type Book struct {
+	mu     sync.Mutex
+	rounds map[string]*roundBook
+}
+
+type roundBook struct {
+	available *big.Int
+	bids      map[string]Bid
+	statuses  map[string]BidStatus
+}
+
+// NewBook creates an empty book
+func NewBook() *Book {
+	return &Book{rounds: make(map[string]*roundBook)}
+}
+
+// Load replaces a round's bids without reporting any change
+func (b *Book) Load(round string, availableOptions *big.Int, bids []Bid) {
+	rb := &roundBook{
+		available: new(big.Int).Set(orZero(availableOptions)),
+		bids:      make(map[string]Bid, len(bids)),
+	}
+	for _, bid := range bids {
+		rb.bids[bid.ID] = bid
+	}
+	rb.statuses, _ = rb.clear()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rounds[round] = rb
+}
+
+// Drop forgets a round, once its auction is over
+func (b *Book) Drop(round string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.rounds, round)
+}
+
+// Apply inserts or replaces a bid in a loaded round and returns every bid,
+// including the applied one, whose status changed. Changes are ordered the
+// way the bids are in the tree. Applying to a round that is not loaded is a
+// no-op.
+func (b *Book) Apply(round string, bid Bid) []StatusChange {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	rb, ok := b.rounds[round]
+	if !ok {
+		return nil
+	}
+	rb.bids[bid.ID] = bid
+
+	statuses, result := rb.clear()
+	sorted := make([]Bid, 0, len(rb.bids))
+	for _, bid := range rb.bids {
+		sorted = append(sorted, bid)
+	}
+	Sort(sorted)
+
+	var changes []StatusChange
+	for _, bid := range sorted {
+		previous, status := rb.statuses[bid.ID], statuses[bid.ID]
+		if previous == status {
+			continue
+		}
+		changes = append(changes, StatusChange{
+			RoundAddress:  round,
+			BidID:         bid.ID,
+			Buyer:         bid.Buyer,
+			Amount:        bid.Amount.String(),
+			Price:         bid.Price.String(),
+			Previous:      previous,
+			Status:        status,
+			Options:       result.Fills[bid.ID].Options.String(),
+			ClearingPrice: result.ClearingPrice.String(),
+		})
+	}
+	rb.statuses = statuses
+	return changes
+}
+
+func (rb *roundBook) clear() (map[string]BidStatus, Result) {
+	bids := make([]Bid, 0, len(rb.bids))
+	for _, bid := range rb.bids {
+		bids = append(bids, bid)
+	}
+	result := Clear(bids, rb.available)
+
+	statuses := make(map[string]BidStatus, len(bids))
+	for _, bid := range bids {
+		options := result.Fills[bid.ID].Options
+		switch {
+		case options.Sign() == 0:
+			statuses[bid.ID] = BidStatusLosing
+		case options.Cmp(bid.Amount) < 0:
+			statuses[bid.ID] = BidStatusPartial
+		default:
+			statuses[bid.ID] = BidStatusWinning
+		}
+	}
+	return statuses, result
+}
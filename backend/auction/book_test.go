@@ -0,0 +1,138 @@
+package auction
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"pitchlake-backend/models"
+)
+
+func statuses(changes []StatusChange) map[string][2]BidStatus {
+	got := make(map[string][2]BidStatus, len(changes))
+	for _, c := range changes {
+		got[c.BidID] = [2]BidStatus{c.Previous, c.Status}
+	}
+	return got
+}
+
+func TestBookApply(t *testing.T) {
+	book := NewBook()
+	book.Load("round", big.NewInt(8), []Bid{bid("a", 0, 4, 10), bid("b", 1, 4, 20)})
+
+	// c outbids a entirely and is winning itself
+	changes := book.Apply("round", bid("c", 2, 4, 30))
+	want := map[string][2]BidStatus{
+		"c": {"", BidStatusWinning},
+		"a": {BidStatusWinning, BidStatusLosing},
+	}
+	if got := statuses(changes); len(got) != len(want) || got["a"] != want["a"] || got["c"] != want["c"] {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+	if changes[0].BidID != "c" || changes[0].ClearingPrice != "20" || changes[0].Options != "4" {
+		t.Errorf("expected tree order and clearing data, got %+v", changes)
+	}
+
+	// a raises its price above b, b is now the one losing
+	changes = book.Apply("round", bid("a", 0, 4, 25))
+	want = map[string][2]BidStatus{
+		"a": {BidStatusLosing, BidStatusWinning},
+		"b": {BidStatusWinning, BidStatusLosing},
+	}
+	if got := statuses(changes); len(got) != len(want) || got["a"] != want["a"] || got["b"] != want["b"] {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+
+	// d takes half of a's options, a becomes partial
+	changes = book.Apply("round", bid("d", 3, 2, 40))
+	if got := statuses(changes); len(got) != 2 || got["a"] != [2]BidStatus{BidStatusWinning, BidStatusPartial} {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+
+	// A losing bid that stays losing reports only itself
+	changes = book.Apply("round", bid("e", 4, 1, 1))
+	if len(changes) != 1 || changes[0].BidID != "e" || changes[0].Status != BidStatusLosing {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+
+	if changes := book.Apply("unknown", bid("x", 0, 1, 1)); changes != nil {
+		t.Errorf("expected no changes for an unknown round, got %+v", changes)
+	}
+	book.Drop("round")
+	if changes := book.Apply("round", bid("x", 9, 1, 100)); changes != nil {
+		t.Errorf("expected no changes for a dropped round, got %+v", changes)
+	}
+}
+
+type fakeRounds map[string]*models.OptionRound
+
+func (f fakeRounds) GetOptionRoundByAddress(ctx context.Context, address string) (*models.OptionRound, error) {
+	if r, ok := f[address]; ok {
+		return r, nil
+	}
+	return nil, errors.New("not found")
+}
+
+type fakeBids struct {
+	bids  []*models.Bid
+	loads int
+}
+
+func (f *fakeBids) GetBidsByRoundAddress(ctx context.Context, roundAddress string) ([]*models.Bid, error) {
+	f.loads++
+	return f.bids, nil
+}
+
+func modelBid(id, buyer, nonce string, amount, price int64) *models.Bid {
+	return &models.Bid{
+		BuyerAddress: buyer,
+		RoundAddress: "0xRound",
+		BidID:        id,
+		TreeNonce:    nonce,
+		Amount:       models.BigInt{Int: big.NewInt(amount)},
+		Price:        models.BigInt{Int: big.NewInt(price)},
+	}
+}
+
+func TestTracker(t *testing.T) {
+	round := &models.OptionRound{
+		VaultAddress:     "0xVault",
+		Address:          "0xRound",
+		AvailableOptions: models.BigInt{Int: big.NewInt(8)},
+		RoundState:       RoundAuctioning,
+	}
+	placed := modelBid("c", "0xCarol", "2", 4, 30)
+	bids := &fakeBids{bids: []*models.Bid{
+		modelBid("a", "0xAlice", "0", 4, 10),
+		modelBid("b", "0xBob", "1", 4, 20),
+		// The database already holds the bid being applied
+		placed,
+	}}
+	tracker := NewTracker(fakeRounds{"0xRound": round}, bids)
+
+	got, changes, err := tracker.ApplyBid(context.Background(), placed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.VaultAddress != "0xVault" {
+		t.Errorf("unexpected round %+v", got)
+	}
+	if s := statuses(changes); len(s) != 2 || s["a"] != [2]BidStatus{BidStatusWinning, BidStatusLosing} {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+
+	// The loaded book is reused
+	if _, _, err := tracker.ApplyBid(context.Background(), modelBid("d", "0xDan", "3", 1, 5)); err != nil || bids.loads != 1 {
+		t.Errorf("expected a single load, got %d (%v)", bids.loads, err)
+	}
+
+	// Once the auction ends the round is dropped and later bids are ignored
+	ended := *round
+	ended.RoundState = "Running"
+	tracker.UpdateRound(&ended)
+	tracker.rounds = fakeRounds{"0xRound": &ended}
+	if _, changes, err := tracker.ApplyBid(context.Background(), modelBid("e", "0xEve", "4", 1, 50)); err != nil || changes != nil {
+		t.Errorf("expected no changes after the auction, got %+v (%v)", changes, err)
+	}
+}
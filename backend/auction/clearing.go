@@ -0,0 +1,204 @@
+package auction
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"pitchlake-backend/models"
+)
+
+var (
+	// ErrBelowReservePrice is returned for bids the option round would reject
+	ErrBelowReservePrice = errors.New("bid price is below the reserve price")
+	// ErrZeroAmount is returned for bids without any options
+	ErrZeroAmount = errors.New("bid amount must be greater than 0")
+	// ErrBidNotFound is returned when updating a bid that is not in the book
+	ErrBidNotFound = errors.New("bid not found")
+	// ErrPriceNotIncreased is returned when an update does not raise the bid price
+	ErrPriceNotIncreased = errors.New("updated bid price must be higher than the current price")
+)
+
+// Bid is a bid in the auction book. Amount is a number of options and
+// Price is the price per option in wei.
+type Bid struct {
+	ID     string
+	Buyer  string
+	Nonce  uint64
+	Amount *big.Int
+	Price  *big.Int
+}
+
+// BidFromModel converts an indexed bid into a book entry
+func BidFromModel(b *models.Bid) (Bid, error) {
+	var nonce uint64
+	if b.TreeNonce != "" {
+		n, ok := new(big.Int).SetString(strings.TrimSpace(b.TreeNonce), 10)
+		if !ok || !n.IsUint64() {
+			return Bid{}, fmt.Errorf("invalid tree nonce %q for bid %s", b.TreeNonce, b.BidID)
+		}
+		nonce = n.Uint64()
+	}
+	return Bid{
+		ID:     b.BidID,
+		Buyer:  b.BuyerAddress,
+		Nonce:  nonce,
+		Amount: orZero(b.Amount.Int),
+		Price:  orZero(b.Price.Int),
+	}, nil
+}
+
+// Fill is the outcome of a single bid once the auction clears
+type Fill struct {
+	// Options is the number of options the bid receives
+	Options *big.Int
+	// Refund is the part of the bid's escrow (amount * price) that is not spent
+	Refund *big.Int
+}
+
+// Result is the outcome of clearing an auction
+type Result struct {
+	ClearingPrice *big.Int
+	OptionsSold   *big.Int
+	// ClearingBidID is the last bid that receives options, empty when none do
+	ClearingBidID string
+	Fills         map[string]Fill
+}
+
+// Sort orders bids the way the option round's bid tree does: highest price
+// first and, for equal prices, the earliest bid (lowest tree nonce) first
+func Sort(bids []Bid) {
+	sort.SliceStable(bids, func(i, j int) bool {
+		if c := bids[i].Price.Cmp(bids[j].Price); c != 0 {
+			return c > 0
+		}
+		return bids[i].Nonce < bids[j].Nonce
+	})
+}
+
+// Clear runs the option round's clearing logic over the book.
+//
+// Bids are filled in tree order until the available options run out. The
+// bid that exhausts the supply sets the clearing price and is partially
+// filled, and every bid after it receives nothing. When the book does not
+// cover the supply every bid is filled and the lowest price clears. Each bid
+// is refunded whatever it escrowed beyond its fill at the clearing price,
+// which is what the event-processor credits in UpdateBiddersAuctionEnd.
+func Clear(bids []Bid, availableOptions *big.Int) Result {
+	sorted := append([]Bid(nil), bids...)
+	Sort(sorted)
+
+	result := Result{
+		ClearingPrice: new(big.Int),
+		OptionsSold:   new(big.Int),
+		Fills:         make(map[string]Fill, len(sorted)),
+	}
+	remaining := new(big.Int).Set(orZero(availableOptions))
+	for _, bid := range sorted {
+		if remaining.Sign() <= 0 {
+			break
+		}
+		filled := new(big.Int).Set(bid.Amount)
+		if filled.Cmp(remaining) > 0 {
+			filled.Set(remaining)
+		}
+		remaining.Sub(remaining, filled)
+		result.OptionsSold.Add(result.OptionsSold, filled)
+		result.ClearingPrice.Set(bid.Price)
+		result.ClearingBidID = bid.ID
+		result.Fills[bid.ID] = Fill{Options: filled}
+	}
+
+	for _, bid := range sorted {
+		fill, ok := result.Fills[bid.ID]
+		if !ok {
+			fill = Fill{Options: new(big.Int)}
+		}
+		refund := new(big.Int).Mul(bid.Amount, bid.Price)
+		refund.Sub(refund, new(big.Int).Mul(fill.Options, result.ClearingPrice))
+		fill.Refund = refund
+		result.Fills[bid.ID] = fill
+	}
+	return result
+}
+
+// Simulation is the projected outcome of a hypothetical bid
+type Simulation struct {
+	ClearingPrice string `json:"clearingPrice"`
+	OptionsSold   string `json:"optionsSold"`
+	Options       string `json:"options"`
+	Refund        string `json:"refund"`
+	Premium       string `json:"premium"`
+	Clears        bool   `json:"clears"`
+	IsClearingBid bool   `json:"isClearingBid"`
+}
+
+// simulatedBidID is the book key of the hypothetical bid
+const simulatedBidID = "simulated"
+
+// Simulate clears the book with a hypothetical bid added to it. When
+// updateBidID is set the bid replaces that existing bid instead, keeping its
+// amount and taking the new price, the way update_bid re-inserts it in the
+// tree. The hypothetical bid always gets the newest tree nonce.
+func Simulate(book []Bid, availableOptions, reservePrice *big.Int, amount, price *big.Int, updateBidID string) (*Simulation, error) {
+	if price.Cmp(orZero(reservePrice)) < 0 {
+		return nil, ErrBelowReservePrice
+	}
+
+	var maxNonce uint64
+	bids := make([]Bid, 0, len(book)+1)
+	var updated *Bid
+	for i := range book {
+		if book[i].Nonce > maxNonce {
+			maxNonce = book[i].Nonce
+		}
+		if updateBidID != "" && book[i].ID == updateBidID {
+			updated = &book[i]
+			continue
+		}
+		bids = append(bids, book[i])
+	}
+
+	if updateBidID != "" {
+		if updated == nil {
+			return nil, ErrBidNotFound
+		}
+		if price.Cmp(updated.Price) <= 0 {
+			return nil, ErrPriceNotIncreased
+		}
+		amount = updated.Amount
+	}
+	if amount == nil || amount.Sign() <= 0 {
+		return nil, ErrZeroAmount
+	}
+
+	bids = append(bids, Bid{
+		ID:     simulatedBidID,
+		Nonce:  maxNonce + 1,
+		Amount: amount,
+		Price:  price,
+	})
+
+	result := Clear(bids, availableOptions)
+	fill := result.Fills[simulatedBidID]
+	premium := new(big.Int).Mul(fill.Options, result.ClearingPrice)
+
+	return &Simulation{
+		ClearingPrice: result.ClearingPrice.String(),
+		OptionsSold:   result.OptionsSold.String(),
+		Options:       fill.Options.String(),
+		Refund:        fill.Refund.String(),
+		Premium:       premium.String(),
+		Clears:        fill.Options.Sign() > 0,
+		IsClearingBid: result.ClearingBidID == simulatedBidID,
+	}, nil
+}
+
+func orZero(v *big.Int) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return v
+}
@@ -0,0 +1,160 @@
+package auction
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func bid(id string, nonce uint64, amount, price int64) Bid {
+	return Bid{ID: id, Nonce: nonce, Amount: big.NewInt(amount), Price: big.NewInt(price)}
+}
+
+func TestClear(t *testing.T) {
+	tests := []struct {
+		name          string
+		bids          []Bid
+		available     int64
+		clearingPrice int64
+		sold          int64
+		clearingBid   string
+		fills         map[string][2]int64 // options, refund
+	}{
+		{
+			name:          "no bids",
+			available:     10,
+			clearingPrice: 0,
+			sold:          0,
+			fills:         map[string][2]int64{},
+		},
+		{
+			name:          "undersubscribed clears at the lowest price",
+			bids:          []Bid{bid("a", 0, 3, 20), bid("b", 1, 2, 10)},
+			available:     10,
+			clearingPrice: 10,
+			sold:          5,
+			clearingBid:   "b",
+			fills:         map[string][2]int64{"a": {3, 30}, "b": {2, 0}},
+		},
+		{
+			name:          "oversubscribed partially fills the clearing bid",
+			bids:          []Bid{bid("a", 0, 4, 10), bid("b", 1, 5, 30), bid("c", 2, 5, 20)},
+			available:     8,
+			clearingPrice: 20,
+			sold:          8,
+			clearingBid:   "c",
+			// b wins at 20 instead of 30, c gets 3 of 5, a is fully refunded
+			fills: map[string][2]int64{"a": {0, 40}, "b": {5, 50}, "c": {3, 40}},
+		},
+		{
+			name:          "equal prices fill by tree nonce",
+			bids:          []Bid{bid("late", 5, 4, 10), bid("early", 1, 4, 10)},
+			available:     5,
+			clearingPrice: 10,
+			sold:          5,
+			clearingBid:   "late",
+			fills:         map[string][2]int64{"early": {4, 0}, "late": {1, 30}},
+		},
+		{
+			name:          "exact fill stops at the bid that exhausts supply",
+			bids:          []Bid{bid("a", 0, 5, 30), bid("b", 1, 5, 20), bid("c", 2, 5, 10)},
+			available:     10,
+			clearingPrice: 20,
+			sold:          10,
+			clearingBid:   "b",
+			fills:         map[string][2]int64{"a": {5, 50}, "b": {5, 0}, "c": {0, 50}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Clear(tt.bids, big.NewInt(tt.available))
+			if result.ClearingPrice.Int64() != tt.clearingPrice {
+				t.Errorf("ClearingPrice = %s, want %d", result.ClearingPrice, tt.clearingPrice)
+			}
+			if result.OptionsSold.Int64() != tt.sold {
+				t.Errorf("OptionsSold = %s, want %d", result.OptionsSold, tt.sold)
+			}
+			if result.ClearingBidID != tt.clearingBid {
+				t.Errorf("ClearingBidID = %q, want %q", result.ClearingBidID, tt.clearingBid)
+			}
+			for id, want := range tt.fills {
+				fill := result.Fills[id]
+				if fill.Options.Int64() != want[0] || fill.Refund.Int64() != want[1] {
+					t.Errorf("bid %s: got options %s refund %s, want %d and %d",
+						id, fill.Options, fill.Refund, want[0], want[1])
+				}
+			}
+		})
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	book := []Bid{bid("a", 0, 4, 10), bid("b", 1, 5, 30)}
+	available := big.NewInt(8)
+	reserve := big.NewInt(5)
+
+	// A bid at 20 sits between a and b and clears for the remaining 3 options
+	sim, err := Simulate(book, available, reserve, big.NewInt(5), big.NewInt(20), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sim.ClearingPrice != "20" || sim.Options != "3" || sim.Refund != "40" || sim.Premium != "60" {
+		t.Errorf("unexpected simulation %+v", sim)
+	}
+	if !sim.Clears || !sim.IsClearingBid {
+		t.Errorf("expected the bid to be the clearing bid %+v", sim)
+	}
+
+	// Equal to an existing price, the hypothetical bid is the newest and loses the tie
+	sim, err = Simulate(book, available, reserve, big.NewInt(5), big.NewInt(30), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sim.Options != "3" || sim.ClearingPrice != "30" {
+		t.Errorf("unexpected simulation %+v", sim)
+	}
+
+	// A low bid does not clear and is fully refunded
+	sim, err = Simulate(book, available, reserve, big.NewInt(2), big.NewInt(6), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sim.Clears || sim.Options != "0" || sim.Refund != "12" || sim.ClearingPrice != "10" {
+		t.Errorf("unexpected simulation %+v", sim)
+	}
+
+	// Updating a keeps its amount and moves it ahead of b's tie
+	sim, err = Simulate(book, available, reserve, nil, big.NewInt(40), "a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sim.Options != "4" || sim.ClearingPrice != "30" || sim.Refund != "40" {
+		t.Errorf("unexpected simulation %+v", sim)
+	}
+}
+
+func TestSimulateErrors(t *testing.T) {
+	book := []Bid{bid("a", 0, 4, 10)}
+	tests := []struct {
+		name   string
+		amount *big.Int
+		price  int64
+		update string
+		err    error
+	}{
+		{name: "below reserve", amount: big.NewInt(1), price: 1, err: ErrBelowReservePrice},
+		{name: "zero amount", amount: big.NewInt(0), price: 10, err: ErrZeroAmount},
+		{name: "unknown bid", price: 20, update: "x", err: ErrBidNotFound},
+		{name: "price not increased", price: 10, update: "a", err: ErrPriceNotIncreased},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Simulate(book, big.NewInt(5), big.NewInt(5), tt.amount, big.NewInt(tt.price), tt.update)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Simulate() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package auction
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"pitchlake-backend/models"
+)
+
+// RoundAuctioning is the state of an option round while bids are accepted
+const RoundAuctioning = "Auctioning"
+
+// RoundLoader loads option rounds, it is implemented by repositories.OptionRepository
+type RoundLoader interface {
+	GetOptionRoundByAddress(ctx context.Context, address string) (*models.OptionRound, error)
+}
+
+// BidLoader loads a round's bids, it is implemented by repositories.BidRepository
+type BidLoader interface {
+	GetBidsByRoundAddress(ctx context.Context, roundAddress string) ([]*models.Bid, error)
+}
+
+// Tracker keeps a Book of the auctioning rounds in sync with the bids_update
+// and or_update notifications. A round's book is loaded from the database the
+// first time one of its bids is seen, and dropped once its auction ends.
+type Tracker struct {
+	book   *Book
+	rounds RoundLoader
+	bids   BidLoader
+
+	mu sync.Mutex
+	// loaded holds the round model of every round in the book
+	loaded map[string]*models.OptionRound
+}
+
+// NewTracker creates a tracker with an empty book
+func NewTracker(rounds RoundLoader, bids BidLoader) *Tracker {
+	return &Tracker{
+		book:   NewBook(),
+		rounds: rounds,
+		bids:   bids,
+		loaded: make(map[string]*models.OptionRound),
+	}
+}
+
+// ApplyBid applies a new or updated bid to its round's book and returns the
+// round with every bid whose status changed. Bids of rounds that are not
+// auctioning produce no change.
+//
+// When the round is not loaded yet, its book is loaded from the database
+// without the applied bid, so for an update the previous statuses are
+// computed as if the bid had not been placed before.
+func (t *Tracker) ApplyBid(ctx context.Context, bid *models.Bid) (*models.OptionRound, []StatusChange, error) {
+	entry, err := BidFromModel(bid)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := strings.ToLower(bid.RoundAddress)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	round, ok := t.loaded[key]
+	if !ok {
+		round, err = t.rounds.GetOptionRoundByAddress(ctx, bid.RoundAddress)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to load round %s: %w", bid.RoundAddress, err)
+		}
+		if round.RoundState != RoundAuctioning {
+			return round, nil, nil
+		}
+		if err := t.load(ctx, key, round, entry.ID); err != nil {
+			return nil, nil, err
+		}
+	}
+	return round, t.book.Apply(key, entry), nil
+}
+
+// load fills the book of a round from the database, leaving out one bid
+func (t *Tracker) load(ctx context.Context, key string, round *models.OptionRound, skipBidID string) error {
+	bids, err := t.bids.GetBidsByRoundAddress(ctx, round.Address)
+	if err != nil {
+		return fmt.Errorf("unable to load bids of round %s: %w", round.Address, err)
+	}
+	entries := make([]Bid, 0, len(bids))
+	for _, b := range bids {
+		if b.BidID == skipBidID {
+			continue
+		}
+		entry, err := BidFromModel(b)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+	}
+	t.book.Load(key, round.AvailableOptions.Int, entries)
+	t.loaded[key] = round
+	return nil
+}
+
+// UpdateRound handles an or_update notification. A round leaves the book when
+// its auction is over, or when its available options change so that the next
+// bid reloads it against the new supply.
+func (t *Tracker) UpdateRound(round *models.OptionRound) {
+	key := strings.ToLower(round.Address)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	loaded, ok := t.loaded[key]
+	if !ok {
+		return
+	}
+	if round.RoundState != RoundAuctioning || orZero(round.AvailableOptions.Int).Cmp(orZero(loaded.AvailableOptions.Int)) != 0 {
+		t.book.Drop(key)
+		delete(t.loaded, key)
+		return
+	}
+	copied := *round
+	t.loaded[key] = &copied
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS public.webhook_dead_letters;
+DROP TABLE IF EXISTS public.webhook_deliveries;
+DROP TABLE IF EXISTS public.webhook_subscriptions;
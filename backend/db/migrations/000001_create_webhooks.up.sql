@@ -0,0 +1,55 @@
+-- Webhook subscriptions registered by integrators
+CREATE TABLE IF NOT EXISTS public.webhook_subscriptions
+(
+    id bigserial PRIMARY KEY,
+    url text NOT NULL,
+    secret text NOT NULL,
+    -- An empty list subscribes to every event type
+    event_types text[] NOT NULL DEFAULT '{}',
+    -- NULL matches every vault / account
+    vault_address character varying,
+    account_address character varying,
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_subscriptions_vault_address_idx
+    ON public.webhook_subscriptions (vault_address);
+
+-- Delivery log, one row per event and subscription
+CREATE TABLE IF NOT EXISTS public.webhook_deliveries
+(
+    id bigserial PRIMARY KEY,
+    subscription_id bigint NOT NULL REFERENCES public.webhook_subscriptions (id) ON DELETE CASCADE,
+    event_id text NOT NULL,
+    event_type text NOT NULL,
+    payload jsonb NOT NULL,
+    -- pending, delivered or dead
+    status character varying(16) NOT NULL DEFAULT 'pending',
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL DEFAULT now(),
+    last_error text,
+    response_status integer,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    delivered_at timestamptz,
+    CONSTRAINT webhook_deliveries_subscription_event_key UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_pending_idx
+    ON public.webhook_deliveries (next_attempt_at)
+    WHERE status = 'pending';
+
+-- Deliveries that exhausted their retries
+CREATE TABLE IF NOT EXISTS public.webhook_dead_letters
+(
+    id bigserial PRIMARY KEY,
+    delivery_id bigint NOT NULL,
+    subscription_id bigint NOT NULL,
+    url text NOT NULL,
+    event_id text NOT NULL,
+    event_type text NOT NULL,
+    payload jsonb NOT NULL,
+    attempts integer NOT NULL,
+    last_error text,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+	"pitchlake-backend/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// BidRepository handles bid-related database operations
+type BidRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewBidRepository creates a new bid repository
+func NewBidRepository(pool *pgxpool.Pool) *BidRepository {
+	return &BidRepository{pool: pool}
+}
+
+// GetBidsByRoundAddress retrieves every bid placed in an option round
+func (r *BidRepository) GetBidsByRoundAddress(ctx context.Context, roundAddress string) ([]*models.Bid, error) {
+	query := `SELECT buyer_address, round_address, bid_id, tree_nonce, amount, price
+	FROM public."Bids"
+	WHERE round_address = $1
+	ORDER BY tree_nonce ASC`
+
+	rows, err := r.pool.Query(ctx, query, roundAddress)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bids []*models.Bid
+	for rows.Next() {
+		var bid models.Bid
+		err := rows.Scan(
+			&bid.BuyerAddress,
+			&bid.RoundAddress,
+			&bid.BidID,
+			&bid.TreeNonce,
+			&bid.Amount,
+			&bid.Price,
+		)
+		if err != nil {
+			return nil, err
+		}
+		bids = append(bids, &bid)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return bids, nil
+}
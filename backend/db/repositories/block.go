@@ -67,3 +67,53 @@ func (r *BlockRepository) GetBlocks(ctx context.Context, startTimestamp, endTime
 
 	return blocks, nil
 }
+
+// GetTwap retrieves the time weighted average basefee between two timestamps
+func (r *BlockRepository) GetTwap(ctx context.Context, startTimestamp, endTimestamp uint64) (string, error) {
+	query := `WITH windowed AS (
+		SELECT basefee, timestamp,
+			LEAD(timestamp) OVER (ORDER BY block_number ASC) AS next_timestamp
+		FROM public."blocks"
+		WHERE timestamp BETWEEN $1 AND $2
+	)
+	SELECT COALESCE(SUM(basefee * (next_timestamp - timestamp)) / NULLIF(SUM(next_timestamp - timestamp), 0), 0)::text
+	FROM windowed
+	WHERE next_timestamp IS NOT NULL`
+
+	var twap string
+	if err := r.pool.QueryRow(ctx, query, startTimestamp, endTimestamp).Scan(&twap); err != nil {
+		return "", err
+	}
+	return twap, nil
+}
+
+// GetBaseFeeBuckets retrieves the average basefee of consecutive buckets of
+// bucketSeconds between two timestamps, oldest first
+func (r *BlockRepository) GetBaseFeeBuckets(ctx context.Context, startTimestamp, endTimestamp, bucketSeconds uint64) ([]string, error) {
+	query := `SELECT AVG(basefee)::text
+	FROM public."blocks"
+	WHERE timestamp BETWEEN $1 AND $2
+	GROUP BY FLOOR((timestamp - $1) / $3)
+	ORDER BY FLOOR((timestamp - $1) / $3) ASC`
+
+	rows, err := r.pool.Query(ctx, query, startTimestamp, endTimestamp, bucketSeconds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buckets []string
+	for rows.Next() {
+		var avg string
+		if err := rows.Scan(&avg); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, avg)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return buckets, nil
+}
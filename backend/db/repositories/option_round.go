@@ -80,10 +80,20 @@ func (r *OptionRepository) GetOptionRoundsByVaultAddress(ctx context.Context, va
 // GetOptionRoundByAddress retrieves an option round by its address
 func (r *OptionRepository) GetOptionRoundByAddress(ctx context.Context, address string) (*models.OptionRound, error) {
 	var optionRound models.OptionRound
-	query := `SELECT address, round_id, bids, cap_level, starting_block, ending_block, settlement_date, starting_liquidity, queued_liquidity, remaining_liquidity, unsold_liquidity, available_options, settlement_price, strike_price, sold_options, clearing_price, state, premiums, payout_per_option, deployment_date FROM public."Option_Rounds" WHERE address=$1`
+	query := `
+	SELECT 
+		address, vault_address, round_id, cap_level, start_date, end_date, settlement_date, 
+		starting_liquidity, queued_liquidity, remaining_liquidity, unsold_liquidity, available_options, reserve_price, 
+		settlement_price, strike_price, sold_options, clearing_price, state, 
+		premiums, payout_per_option, deployment_date
+	FROM 
+		public."Option_Rounds" 
+	WHERE 
+		address = $1;`
 
 	err := r.pool.QueryRow(ctx, query, address).Scan(
 		&optionRound.Address,
+		&optionRound.VaultAddress,
 		&optionRound.RoundID,
 		&optionRound.CapLevel,
 		&optionRound.AuctionStartDate,
@@ -94,6 +104,7 @@ func (r *OptionRepository) GetOptionRoundByAddress(ctx context.Context, address
 		&optionRound.RemainingLiquidity,
 		&optionRound.UnsoldLiquidity,
 		&optionRound.AvailableOptions,
+		&optionRound.ReservePrice,
 		&optionRound.SettlementPrice,
 		&optionRound.StrikePrice,
 		&optionRound.OptionsSold,
@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"context"
+	"pitchlake-backend/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TwapRepository handles TWAP-related database operations
+type TwapRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewTwapRepository creates a new TWAP repository
+func NewTwapRepository(pool *pgxpool.Pool) *TwapRepository {
+	return &TwapRepository{pool: pool}
+}
+
+// BlockTwaps holds the TWAP columns of a single block
+type BlockTwaps struct {
+	BlockNumber   uint64
+	TwelveMinTwap string
+	ThreeHourTwap string
+	ThirtyDayTwap string
+}
+
+// GetLatestBlock retrieves the block with the highest number
+func (r *TwapRepository) GetLatestBlock(ctx context.Context) (*models.Block, error) {
+	query := `SELECT block_number, timestamp, basefee, is_confirmed
+	FROM public."blocks"
+	ORDER BY block_number DESC
+	LIMIT 1`
+
+	var block models.Block
+	err := r.pool.QueryRow(ctx, query).Scan(
+		&block.BlockNumber,
+		&block.Timestamp,
+		&block.BaseFee,
+		&block.IsConfirmed,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// GetBlocksSince retrieves all blocks with a timestamp at or after the given one, without sampling
+func (r *TwapRepository) GetBlocksSince(ctx context.Context, timestamp uint64) ([]models.Block, error) {
+	query := `SELECT block_number, timestamp, basefee, is_confirmed
+	FROM public."blocks"
+	WHERE timestamp >= $1
+	ORDER BY block_number ASC`
+
+	return r.queryBlocks(ctx, query, timestamp)
+}
+
+// GetBlocksInRange retrieves all blocks within a time range, without sampling
+func (r *TwapRepository) GetBlocksInRange(ctx context.Context, startTimestamp, endTimestamp uint64) ([]models.Block, error) {
+	query := `SELECT block_number, timestamp, basefee, is_confirmed
+	FROM public."blocks"
+	WHERE timestamp BETWEEN $1 AND $2
+	ORDER BY block_number ASC`
+
+	return r.queryBlocks(ctx, query, startTimestamp, endTimestamp)
+}
+
+// HasBlocksBefore reports whether any block is older than the given timestamp
+func (r *TwapRepository) HasBlocksBefore(ctx context.Context, timestamp uint64) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM public."blocks" WHERE timestamp < $1)`
+
+	var exists bool
+	if err := r.pool.QueryRow(ctx, query, timestamp).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+func (r *TwapRepository) queryBlocks(ctx context.Context, query string, args ...any) ([]models.Block, error) {
+	var blocks []models.Block
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var block models.Block
+		err := rows.Scan(
+			&block.BlockNumber,
+			&block.Timestamp,
+			&block.BaseFee,
+			&block.IsConfirmed,
+		)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return blocks, nil
+}
+
+// SaveTwaps writes the TWAP columns of the given blocks and the latest window
+// states in a single transaction
+func (r *TwapRepository) SaveTwaps(ctx context.Context, blocks []BlockTwaps, states []models.TwapState) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for _, b := range blocks {
+		batch.Queue(`UPDATE public."blocks"
+		SET twelve_min_twap = $2, three_hour_twap = $3, thirty_day_twap = $4
+		WHERE block_number = $1`,
+			b.BlockNumber, b.TwelveMinTwap, b.ThreeHourTwap, b.ThirtyDayTwap)
+	}
+	for _, s := range states {
+		batch.Queue(`INSERT INTO public."twap_state" (
+			window_type, weighted_sum, total_seconds, twap_value,
+			last_block_number, last_block_timestamp, is_confirmed
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT ON CONSTRAINT twap_state_window_type_is_confirmed_key
+		DO UPDATE SET
+			weighted_sum = EXCLUDED.weighted_sum,
+			total_seconds = EXCLUDED.total_seconds,
+			twap_value = EXCLUDED.twap_value,
+			last_block_number = EXCLUDED.last_block_number,
+			last_block_timestamp = EXCLUDED.last_block_timestamp`,
+			string(s.WindowType), s.WeightedSum, s.TotalSeconds.String(), s.TwapValue,
+			s.LastBlockNumber, s.LastBlockTimestamp, s.IsConfirmed)
+	}
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,244 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"pitchlake-backend/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead"
+)
+
+// WebhookRepository handles webhook subscriptions and their delivery log
+type WebhookRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(pool *pgxpool.Pool) *WebhookRepository {
+	return &WebhookRepository{pool: pool}
+}
+
+// CreateSubscription registers a webhook and fills in its id and creation time
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	query := `INSERT INTO public.webhook_subscriptions
+		(url, secret, event_types, vault_address, account_address, active)
+	VALUES ($1, $2, $3, NULLIF(lower($4), ''), NULLIF(lower($5), ''), true)
+	RETURNING id, active, created_at`
+
+	eventTypes := sub.EventTypes
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	return r.pool.QueryRow(ctx, query,
+		sub.URL,
+		sub.Secret,
+		eventTypes,
+		sub.VaultAddress,
+		sub.AccountAddress,
+	).Scan(&sub.ID, &sub.Active, &sub.CreatedAt)
+}
+
+// GetSubscription retrieves a webhook subscription by id, nil when it does not exist
+func (r *WebhookRepository) GetSubscription(ctx context.Context, id int64) (*models.WebhookSubscription, error) {
+	query := `SELECT id, url, secret, event_types, COALESCE(vault_address, ''),
+		COALESCE(account_address, ''), active, created_at
+	FROM public.webhook_subscriptions
+	WHERE id = $1`
+
+	var sub models.WebhookSubscription
+	err := r.pool.QueryRow(ctx, query, id).Scan(
+		&sub.ID,
+		&sub.URL,
+		&sub.Secret,
+		&sub.EventTypes,
+		&sub.VaultAddress,
+		&sub.AccountAddress,
+		&sub.Active,
+		&sub.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// DeleteSubscription removes a webhook subscription and its delivery log,
+// reporting whether it existed
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id int64) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM public.webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// EnqueueEvent creates a pending delivery of the event for every active
+// subscription whose filters match it. An empty event type list matches every
+// event, and the account filter only applies to events about an account.
+// Events already enqueued for a subscription are skipped.
+func (r *WebhookRepository) EnqueueEvent(ctx context.Context, event models.WebhookEvent, payload []byte) (int64, error) {
+	query := `INSERT INTO public.webhook_deliveries (subscription_id, event_id, event_type, payload)
+	SELECT id, $1, $2, $3
+	FROM public.webhook_subscriptions
+	WHERE active
+		AND (cardinality(event_types) = 0 OR $2 = ANY(event_types))
+		AND (vault_address IS NULL OR vault_address = lower($4))
+		AND (account_address IS NULL OR $5 = '' OR account_address = lower($5))
+	ON CONFLICT ON CONSTRAINT webhook_deliveries_subscription_event_key DO NOTHING`
+
+	tag, err := r.pool.Exec(ctx, query,
+		event.ID,
+		event.Type,
+		payload,
+		event.VaultAddress,
+		event.AccountAddress,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ClaimDueDeliveries returns pending deliveries whose next attempt is due and
+// pushes their next attempt back by lease, so that concurrent dispatchers do
+// not send them twice while they are in flight
+func (r *WebhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	query := `WITH due AS (
+		SELECT id FROM public.webhook_deliveries
+		WHERE status = 'pending' AND next_attempt_at <= now()
+		ORDER BY next_attempt_at ASC
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	UPDATE public.webhook_deliveries d
+	SET next_attempt_at = now() + $2::bigint * interval '1 millisecond'
+	FROM due, public.webhook_subscriptions s
+	WHERE d.id = due.id AND s.id = d.subscription_id
+	RETURNING d.id, d.subscription_id, s.url, s.secret, d.event_id, d.event_type,
+		d.payload, d.status, d.attempts, d.next_attempt_at, COALESCE(d.last_error, ''),
+		COALESCE(d.response_status, 0), d.created_at, d.delivered_at`
+
+	rows, err := r.pool.Query(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows, true)
+}
+
+// GetDeliveries retrieves the latest deliveries of a subscription, newest first
+func (r *WebhookRepository) GetDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]*models.WebhookDelivery, error) {
+	query := `SELECT d.id, d.subscription_id, s.url, d.event_id, d.event_type,
+		d.payload, d.status, d.attempts, d.next_attempt_at, COALESCE(d.last_error, ''),
+		COALESCE(d.response_status, 0), d.created_at, d.delivered_at
+	FROM public.webhook_deliveries d
+	JOIN public.webhook_subscriptions s ON s.id = d.subscription_id
+	WHERE d.subscription_id = $1
+	ORDER BY d.id DESC
+	LIMIT $2`
+
+	rows, err := r.pool.Query(ctx, query, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows, false)
+}
+
+// MarkDelivered records a successful attempt
+func (r *WebhookRepository) MarkDelivered(ctx context.Context, id int64, responseStatus int) error {
+	query := `UPDATE public.webhook_deliveries
+	SET status = 'delivered', attempts = attempts + 1, response_status = $2,
+		last_error = NULL, delivered_at = now()
+	WHERE id = $1`
+
+	_, err := r.pool.Exec(ctx, query, id, responseStatus)
+	return err
+}
+
+// MarkFailed records a failed attempt and schedules the next one
+func (r *WebhookRepository) MarkFailed(ctx context.Context, id int64, responseStatus int, lastError string, nextAttemptAt time.Time) error {
+	query := `UPDATE public.webhook_deliveries
+	SET attempts = attempts + 1, response_status = NULLIF($2, 0), last_error = $3,
+		next_attempt_at = $4
+	WHERE id = $1`
+
+	_, err := r.pool.Exec(ctx, query, id, responseStatus, lastError, nextAttemptAt)
+	return err
+}
+
+// DeadLetter records the final failed attempt of a delivery and copies it to
+// the dead letter table
+func (r *WebhookRepository) DeadLetter(ctx context.Context, id int64, responseStatus int, lastError string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `UPDATE public.webhook_deliveries
+	SET status = 'dead', attempts = attempts + 1, response_status = NULLIF($2, 0),
+		last_error = $3
+	WHERE id = $1`, id, responseStatus, lastError)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO public.webhook_dead_letters
+		(delivery_id, subscription_id, url, event_id, event_type, payload, attempts, last_error)
+	SELECT d.id, d.subscription_id, s.url, d.event_id, d.event_type, d.payload, d.attempts, d.last_error
+	FROM public.webhook_deliveries d
+	JOIN public.webhook_subscriptions s ON s.id = d.subscription_id
+	WHERE d.id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func scanDeliveries(rows pgx.Rows, withSecret bool) ([]*models.WebhookDelivery, error) {
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		dest := []any{&d.ID, &d.SubscriptionID, &d.URL}
+		if withSecret {
+			dest = append(dest, &d.Secret)
+		}
+		dest = append(dest,
+			&d.EventID,
+			&d.EventType,
+			&d.Payload,
+			&d.Status,
+			&d.Attempts,
+			&d.NextAttemptAt,
+			&d.LastError,
+			&d.ResponseStatus,
+			&d.CreatedAt,
+			&d.DeliveredAt,
+		)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, &d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
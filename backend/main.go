@@ -33,9 +33,7 @@ func main() {
 // OR Trigger:or_update
 func run() error {
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
-	defer cancel()
-	dbs := server.NewDBServer(ctx)
+	dbs := server.NewDBServer(context.Background())
 	s := &http.Server{
 		Addr:         ":8080",
 		Handler:      dbs,
@@ -57,5 +55,7 @@ func run() error {
 		log.Printf("terminating: %v", sig)
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
 	return s.Shutdown(ctx)
 }
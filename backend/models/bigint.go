@@ -39,6 +39,17 @@ func (b *BigInt) Scan(value interface{}) error {
 
 func (b *BigInt) scanString(s string) error {
 	s = strings.TrimSpace(s)
+	if s == "" {
+		b.Int.SetInt64(0)
+		return nil
+	}
+	base := 10
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s, base = s[2:], 16
+	}
+	if _, ok := b.Int.SetString(s, base); !ok {
+		return fmt.Errorf("invalid BigInt value %q", s)
+	}
 	return b.validateUint256()
 }
 
@@ -1,5 +1,10 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type AllowedPayload interface {
 	IsAllowedPayload() // Dummy method
 }
@@ -132,3 +137,40 @@ func (OptionBuyer) IsAllowedPayload()            {}
 func (QueuedLiquidity) IsAllowedPayload()        {}
 func (Block) IsAllowedPayload()                  {}
 func (TwapState) IsAllowedPayload()              {}
+
+type WebhookSubscription struct {
+	ID             int64     `json:"id"`
+	URL            string    `json:"url"`
+	Secret         string    `json:"secret,omitempty"`
+	EventTypes     []string  `json:"eventTypes"`
+	VaultAddress   string    `json:"vaultAddress,omitempty"`
+	AccountAddress string    `json:"accountAddress,omitempty"`
+	Active         bool      `json:"active"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+type WebhookEvent struct {
+	ID             string          `json:"id"`
+	Type           string          `json:"type"`
+	VaultAddress   string          `json:"vaultAddress,omitempty"`
+	AccountAddress string          `json:"accountAddress,omitempty"`
+	Data           json.RawMessage `json:"data"`
+	CreatedAt      time.Time       `json:"createdAt"`
+}
+
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	SubscriptionID int64           `json:"subscriptionId"`
+	URL            string          `json:"url"`
+	Secret         string          `json:"-"`
+	EventID        string          `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"nextAttemptAt"`
+	LastError      string          `json:"lastError,omitempty"`
+	ResponseStatus int             `json:"responseStatus,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+}
@@ -81,12 +81,12 @@ func (vs *VaultState) UnmarshalJSON(data []byte) error {
 func (b *Bid) UnmarshalJSON(data []byte) error {
 	// Auxiliary struct to map JSON keys
 	aux := struct {
-		BuyerAddress string `json:"address"`
-		RoundAddress string `json:"round_address"`
-		BidID        string `json:"bid_id"`
-		TreeNonce    string `json:"tree_nonce"`
-		Amount       BigInt `json:"amount"`
-		Price        BigInt `json:"price"`
+		BuyerAddress string      `json:"buyer_address"`
+		RoundAddress string      `json:"round_address"`
+		BidID        string      `json:"bid_id"`
+		TreeNonce    json.Number `json:"tree_nonce"`
+		Amount       BigInt      `json:"amount"`
+		Price        BigInt      `json:"price"`
 	}{}
 
 	// Unmarshal into the auxiliary struct
@@ -98,7 +98,7 @@ func (b *Bid) UnmarshalJSON(data []byte) error {
 	b.BuyerAddress = aux.BuyerAddress
 	b.RoundAddress = aux.RoundAddress
 	b.BidID = aux.BidID
-	b.TreeNonce = aux.TreeNonce
+	b.TreeNonce = aux.TreeNonce.String()
 	b.Amount = aux.Amount
 	b.Price = aux.Price
 
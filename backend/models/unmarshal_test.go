@@ -0,0 +1,39 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBidUnmarshalRowToJSON(t *testing.T) {
+	// The payload of the bids_update notification, row_to_json of a "Bids" row
+	payload := `{
+		"buyer_address": "0x3f5b2a1c",
+		"round_address": "0x7a1d",
+		"bid_id": "0x1c9e",
+		"tree_nonce": 3,
+		"amount": 1000,
+		"price": 2500000000000000000000
+	}`
+
+	var bid Bid
+	if err := json.Unmarshal([]byte(payload), &bid); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if bid.BuyerAddress != "0x3f5b2a1c" || bid.RoundAddress != "0x7a1d" || bid.BidID != "0x1c9e" {
+		t.Errorf("Unexpected addresses in %+v", bid)
+	}
+	if bid.TreeNonce != "3" {
+		t.Errorf("Expected tree nonce 3, got %q", bid.TreeNonce)
+	}
+	if bid.Amount.String() != "1000" || bid.Price.String() != "2500000000000000000000" {
+		t.Errorf("Unexpected amount %s or price %s", bid.Amount, bid.Price)
+	}
+
+	if err := json.Unmarshal([]byte(`{"bid_id": "0x1", "tree_nonce": null}`), &bid); err != nil {
+		t.Fatalf("Unexpected error for a null tree nonce: %v", err)
+	}
+	if bid.TreeNonce != "" {
+		t.Errorf("Expected no tree nonce, got %q", bid.TreeNonce)
+	}
+}
@@ -0,0 +1,175 @@
+package pricing
+
+import (
+	"errors"
+	"math"
+	"math/big"
+)
+
+// BPS is the basis point scale used by the vault contracts
+const BPS = 10000
+
+// GweiToWei converts the gwei basefees stored in the blocks table into wei
+const GweiToWei = 1e9
+
+// BucketsPerRound is the number of basefee samples taken per round duration
+// when measuring volatility
+const BucketsPerRound = 8
+
+// LookbackRounds is the number of round durations of history used for volatility
+const LookbackRounds = 3
+
+// maxReturnsZ is the standard normal quantile used to turn volatility into
+// the max returns fed to the cap level (99th percentile)
+const maxReturnsZ = 2.326
+
+// ErrNotEnoughData is returned when the basefee history is too short to estimate
+var ErrNotEnoughData = errors.New("not enough basefee history to estimate pricing data")
+
+// starkPrime is the field prime, strike levels below zero are stored as felts
+var starkPrime, _ = new(big.Int).SetString("800000000000011000000000000000000000000000000000000000000000001", 16)
+
+// Input holds everything needed to estimate the pricing data of a round
+type Input struct {
+	// Alpha is the vault's alpha in BPS
+	Alpha int64
+	// StrikeLevel is the vault's strike level in BPS, may be negative
+	StrikeLevel int64
+	// Twap is the basefee TWAP over the last round duration in gwei
+	Twap float64
+	// BucketFees are the average basefees of consecutive equal time buckets,
+	// BucketsPerRound per round duration, oldest first
+	BucketFees []float64
+}
+
+// Preview is the projected pricing data of a round. Values are estimates
+// computed from the indexed basefee history, the on-chain values are set by
+// the Fossil request when the round is deployed.
+type Preview struct {
+	IsEstimate   bool   `json:"isEstimate"`
+	Twap         string `json:"twap"`
+	Volatility   uint64 `json:"volatility"`
+	MaxReturns   uint64 `json:"maxReturns"`
+	StrikePrice  string `json:"strikePrice"`
+	CapLevel     string `json:"capLevel"`
+	ReservePrice string `json:"reservePrice"`
+}
+
+// SignedFromFelt reads a signed BPS value that may have been stored as a felt
+func SignedFromFelt(v *big.Int) int64 {
+	if v == nil {
+		return 0
+	}
+	if v.BitLen() > 127 {
+		return new(big.Int).Sub(v, starkPrime).Int64()
+	}
+	return v.Int64()
+}
+
+// Volatility returns the volatility over one round duration, as a fraction,
+// from the log returns of consecutive bucket fees
+func Volatility(bucketFees []float64) (float64, error) {
+	var returns []float64
+	for i := 1; i < len(bucketFees); i++ {
+		if bucketFees[i-1] <= 0 || bucketFees[i] <= 0 {
+			continue
+		}
+		returns = append(returns, math.Log(bucketFees[i]/bucketFees[i-1]))
+	}
+	if len(returns) < 2 {
+		return 0, ErrNotEnoughData
+	}
+
+	var mean float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(returns) - 1)
+
+	// Scale the per bucket deviation to a full round
+	return math.Sqrt(variance * BucketsPerRound), nil
+}
+
+// CapLevel mirrors calculate_cap_level in the vault contracts:
+// cl = (max_returns - k) / (alpha * (1 + k)), clamped to a minimum of 1
+func CapLevel(alpha, k int64, maxReturns uint64) int64 {
+	maxReturnsMinusK := int64(maxReturns) - k
+	kPlusOne := BPS + k
+	if alpha == 0 || kPlusOne <= 0 || maxReturnsMinusK <= 0 {
+		return 1
+	}
+	cl := new(big.Int).Mul(big.NewInt(maxReturnsMinusK), big.NewInt(BPS*BPS))
+	cl.Quo(cl, new(big.Int).Mul(big.NewInt(alpha), big.NewInt(kPlusOne)))
+	if cl.Sign() <= 0 {
+		return 1
+	}
+	return cl.Int64()
+}
+
+// StrikePrice mirrors calculate_strike_price in the vault contracts:
+// K = (1 + k) * twap, with the twap in wei
+func StrikePrice(k int64, twapWei *big.Int) *big.Int {
+	kPlusOne := BPS + k
+	if kPlusOne <= 0 {
+		return new(big.Int)
+	}
+	strike := new(big.Int).Mul(big.NewInt(kPlusOne), twapWei)
+	return strike.Quo(strike, big.NewInt(BPS))
+}
+
+// ReservePrice approximates the premium of a capped call with a lognormal
+// basefee at settlement, priced as a call spread between the strike and the
+// capped payout with no discounting
+func ReservePrice(twap, strike, capLevelBPS, volatility float64) float64 {
+	if twap <= 0 || strike <= 0 || volatility <= 0 {
+		return 0
+	}
+	capStrike := strike * (1 + capLevelBPS/BPS)
+	return blackCall(twap, strike, volatility) - blackCall(twap, capStrike, volatility)
+}
+
+// blackCall prices a call with forward f, strike k and total volatility v
+func blackCall(f, k, v float64) float64 {
+	d1 := (math.Log(f/k) + v*v/2) / v
+	d2 := d1 - v
+	return f*normCDF(d1) - k*normCDF(d2)
+}
+
+func normCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+// Estimate projects the strike, cap level and reserve price of a round
+func Estimate(in Input) (*Preview, error) {
+	if in.Twap <= 0 {
+		return nil, ErrNotEnoughData
+	}
+	volatility, err := Volatility(in.BucketFees)
+	if err != nil {
+		return nil, err
+	}
+	maxReturns := uint64(math.Round((math.Exp(maxReturnsZ*volatility) - 1) * BPS))
+
+	twapWei, _ := new(big.Float).Mul(big.NewFloat(in.Twap), big.NewFloat(GweiToWei)).Int(nil)
+	strike := StrikePrice(in.StrikeLevel, twapWei)
+	capLevel := CapLevel(in.Alpha, in.StrikeLevel, maxReturns)
+
+	strikeWei, _ := new(big.Float).SetInt(strike).Float64()
+	reserve := ReservePrice(in.Twap*GweiToWei, strikeWei, float64(capLevel), volatility)
+	reserveWei, _ := big.NewFloat(math.Floor(reserve)).Int(nil)
+
+	return &Preview{
+		IsEstimate:   true,
+		Twap:         big.NewFloat(in.Twap).Text('f', 9),
+		Volatility:   uint64(math.Round(volatility * BPS)),
+		MaxReturns:   maxReturns,
+		StrikePrice:  strike.String(),
+		CapLevel:     big.NewInt(capLevel).String(),
+		ReservePrice: reserveWei.String(),
+	}, nil
+}
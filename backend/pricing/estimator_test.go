@@ -0,0 +1,131 @@
+package pricing
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestCapLevel(t *testing.T) {
+	tests := []struct {
+		name       string
+		alpha      int64
+		k          int64
+		maxReturns uint64
+		want       int64
+	}{
+		{name: "at the money", alpha: 2500, k: 0, maxReturns: 10000, want: 40000},
+		{name: "out of the money", alpha: 5000, k: -3333, maxReturns: 5000, want: 24997},
+		{name: "zero alpha clamps", alpha: 0, k: 0, maxReturns: 10000, want: 1},
+		{name: "returns below strike level clamp", alpha: 2500, k: 5000, maxReturns: 4000, want: 1},
+		{name: "strike level at -100% clamps", alpha: 2500, k: -10000, maxReturns: 4000, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CapLevel(tt.alpha, tt.k, tt.maxReturns); got != tt.want {
+				t.Errorf("CapLevel() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStrikePrice(t *testing.T) {
+	twap := big.NewInt(10_000_000_000)
+	tests := []struct {
+		name string
+		k    int64
+		want string
+	}{
+		{name: "at the money", k: 0, want: "10000000000"},
+		{name: "in the money", k: -2500, want: "7500000000"},
+		{name: "out of the money", k: 5000, want: "15000000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StrikePrice(tt.k, twap).String(); got != tt.want {
+				t.Errorf("StrikePrice() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignedFromFelt(t *testing.T) {
+	negative := new(big.Int).Sub(starkPrime, big.NewInt(3333))
+	if got := SignedFromFelt(negative); got != -3333 {
+		t.Errorf("SignedFromFelt(P-3333) = %d, want -3333", got)
+	}
+	if got := SignedFromFelt(big.NewInt(2500)); got != 2500 {
+		t.Errorf("SignedFromFelt(2500) = %d, want 2500", got)
+	}
+	if got := SignedFromFelt(nil); got != 0 {
+		t.Errorf("SignedFromFelt(nil) = %d, want 0", got)
+	}
+}
+
+func TestVolatility(t *testing.T) {
+	if _, err := Volatility([]float64{10, 11}); !errors.Is(err, ErrNotEnoughData) {
+		t.Errorf("expected ErrNotEnoughData, got %v", err)
+	}
+
+	flat, err := Volatility([]float64{10, 10, 10, 10})
+	if err != nil || flat != 0 {
+		t.Errorf("expected zero volatility for a flat series, got %f (%v)", flat, err)
+	}
+
+	// Alternating returns of +-ln(2) have a sample deviation of ln(2)*sqrt(4/3)
+	got, err := Volatility([]float64{10, 20, 10, 20, 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := math.Ln2 * math.Sqrt(4.0/3.0) * math.Sqrt(BucketsPerRound)
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("Volatility() = %f, want %f", got, want)
+	}
+}
+
+func TestReservePrice(t *testing.T) {
+	// A capped call is worth less than the uncapped call and the cap
+	twap, strike, vol := 100.0, 100.0, 0.5
+	capped := ReservePrice(twap, strike, 5000, vol)
+	uncapped := blackCall(twap, strike, vol)
+	if capped <= 0 || capped >= uncapped || capped >= strike*0.5 {
+		t.Errorf("unexpected capped premium %f (uncapped %f)", capped, uncapped)
+	}
+	if ReservePrice(twap, strike, 5000, 0) != 0 {
+		t.Error("expected zero premium without volatility")
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	est, err := Estimate(Input{
+		Alpha:       5000,
+		StrikeLevel: 0,
+		Twap:        12.5,
+		BucketFees:  []float64{10, 12, 11, 14, 13, 15, 12, 16, 14},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !est.IsEstimate {
+		t.Error("estimate must be labelled as such")
+	}
+	if est.StrikePrice != "12500000000" {
+		t.Errorf("unexpected strike price %s", est.StrikePrice)
+	}
+	if est.Twap != "12.500000000" {
+		t.Errorf("unexpected twap %s", est.Twap)
+	}
+	if est.Volatility == 0 || est.MaxReturns == 0 {
+		t.Errorf("expected a non zero volatility and max returns, got %+v", est)
+	}
+	if est.ReservePrice == "0" || est.CapLevel == "1" {
+		t.Errorf("unexpected reserve price or cap level %+v", est)
+	}
+
+	if _, err := Estimate(Input{Alpha: 5000, Twap: 0}); !errors.Is(err, ErrNotEnoughData) {
+		t.Errorf("expected ErrNotEnoughData without a twap, got %v", err)
+	}
+}
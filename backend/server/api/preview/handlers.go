@@ -0,0 +1,56 @@
+package preview
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"pitchlake-backend/pricing"
+	"pitchlake-backend/server/validations"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func (router *PreviewRouter) pricingPreviewHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	vaultAddress := r.URL.Query().Get("vaultAddress")
+	if err := validations.ValidatePricingPreviewRequest(vaultAddress); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	preview, err := router.getPricingPreview(r.Context(), vaultAddress)
+	if errors.Is(err, pricing.ErrNotEnoughData) {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	if err != nil {
+		router.log.Printf("Error estimating pricing data: %v", err)
+		writeError(w, http.StatusInternalServerError, errors.New("unable to estimate pricing data"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preview)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error": err.Error(),
+	})
+}
+
+func NewPreviewRouter(serveMux *http.ServeMux, logger *log.Logger, pool *pgxpool.Pool) *PreviewRouter {
+	router := &PreviewRouter{
+		log:  logger,
+		pool: pool,
+	}
+	serveMux.HandleFunc("/pricingPreview", router.pricingPreviewHandler)
+	return router
+}
@@ -0,0 +1,54 @@
+package preview
+
+import (
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewPreviewRouter(t *testing.T) {
+	serveMux := http.NewServeMux()
+	logger := log.Default()
+	router := NewPreviewRouter(serveMux, logger, nil)
+
+	if router == nil {
+		t.Error("Expected router to be created")
+	}
+}
+
+func TestPricingPreviewHandlerValidation(t *testing.T) {
+	logger := log.Default()
+	router := &PreviewRouter{log: logger}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		status int
+	}{
+		{name: "wrong method", method: "POST", target: "/pricingPreview", status: http.StatusMethodNotAllowed},
+		{name: "missing vault address", method: "GET", target: "/pricingPreview", status: http.StatusBadRequest},
+		{name: "invalid vault address", method: "GET", target: "/pricingPreview?vaultAddress=1234", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.target, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router.pricingPreviewHandler(rr, req)
+
+			if rr.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, rr.Code)
+			}
+			if tt.status == http.StatusBadRequest && !strings.Contains(rr.Body.String(), "error") {
+				t.Errorf("Expected an error body, got '%s'", rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package preview
+
+import (
+	"log"
+
+	"pitchlake-backend/pricing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PreviewRouter struct {
+	log  *log.Logger
+	pool *pgxpool.Pool
+}
+
+// PricingPreview is the estimated pricing data of a vault's next round
+type PricingPreview struct {
+	VaultAddress  string `json:"vaultAddress"`
+	RoundID       string `json:"roundId"`
+	AsOfTimestamp uint64 `json:"asOfTimestamp"`
+	RoundDuration uint64 `json:"roundDuration"`
+	pricing.Preview
+}
@@ -0,0 +1,85 @@
+package preview
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"pitchlake-backend/db/repositories"
+	"pitchlake-backend/pricing"
+)
+
+// getPricingPreview estimates the pricing data of the vault's next round from
+// the basefee history up to the latest indexed block
+func (router *PreviewRouter) getPricingPreview(ctx context.Context, vaultAddress string) (*PricingPreview, error) {
+	vaultRepo := repositories.NewVaultRepository(router.pool)
+	vaultState, err := vaultRepo.GetVaultStateByID(ctx, vaultAddress)
+	if err != nil {
+		return nil, err
+	}
+	roundDuration := vaultState.OptionRunTime
+	if roundDuration == 0 {
+		return nil, fmt.Errorf("vault %s has no round duration", vaultAddress)
+	}
+
+	twapRepo := repositories.NewTwapRepository(router.pool)
+	latest, err := twapRepo.GetLatestBlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if latest == nil || latest.Timestamp < roundDuration {
+		return nil, pricing.ErrNotEnoughData
+	}
+	now := latest.Timestamp
+
+	blockRepo := repositories.NewBlockRepository(router.pool)
+	twapValue, err := blockRepo.GetTwap(ctx, now-roundDuration, now)
+	if err != nil {
+		return nil, err
+	}
+	twap, err := strconv.ParseFloat(twapValue, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid twap %q: %w", twapValue, err)
+	}
+
+	lookback := roundDuration * pricing.LookbackRounds
+	if lookback > now {
+		lookback = now
+	}
+	bucketSeconds := max(roundDuration/pricing.BucketsPerRound, 1)
+	buckets, err := blockRepo.GetBaseFeeBuckets(ctx, now-lookback, now, bucketSeconds)
+	if err != nil {
+		return nil, err
+	}
+	bucketFees := make([]float64, 0, len(buckets))
+	for _, b := range buckets {
+		fee, err := strconv.ParseFloat(b, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid basefee bucket %q: %w", b, err)
+		}
+		bucketFees = append(bucketFees, fee)
+	}
+
+	estimate, err := pricing.Estimate(pricing.Input{
+		Alpha:       pricing.SignedFromFelt(vaultState.Alpha.Int),
+		StrikeLevel: pricing.SignedFromFelt(vaultState.StrikeLevel.Int),
+		Twap:        twap,
+		BucketFees:  bucketFees,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nextRound := big.NewInt(1)
+	if vaultState.CurrentRound.Int != nil {
+		nextRound.Add(nextRound, vaultState.CurrentRound.Int)
+	}
+	return &PricingPreview{
+		VaultAddress:  vaultAddress,
+		RoundID:       nextRound.String(),
+		AsOfTimestamp: now,
+		RoundDuration: roundDuration,
+		Preview:       *estimate,
+	}, nil
+}
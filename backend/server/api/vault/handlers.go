@@ -2,15 +2,22 @@ package vault
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log"
 	"net/http"
 
+	"pitchlake-backend/auction"
 	"pitchlake-backend/server/types"
+	"pitchlake-backend/server/validations"
 
 	"github.com/coder/websocket"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrAuctionNotRunning is returned when simulating a bid on a round that is not auctioning
+var ErrAuctionNotRunning = errors.New("auction is not running")
+
 func (router *VaultRouter) subscribeVaultHandler(w http.ResponseWriter, r *http.Request) {
 	err := router.subscribeVault(r.Context(), w, r)
 	if errors.Is(err, context.Canceled) {
@@ -26,13 +33,57 @@ func (router *VaultRouter) subscribeVaultHandler(w http.ResponseWriter, r *http.
 	}
 }
 
-func NewVaultRouter(serveMux *http.ServeMux, logger *log.Logger) *VaultRouter {
+func (router *VaultRouter) simulateBidHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var request types.SimulateBidRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := validations.ValidateSimulateBidRequest(request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	simulation, err := router.simulateBid(r.Context(), request)
+	switch {
+	case errors.Is(err, ErrAuctionNotRunning),
+		errors.Is(err, auction.ErrBelowReservePrice),
+		errors.Is(err, auction.ErrZeroAmount),
+		errors.Is(err, auction.ErrBidNotFound),
+		errors.Is(err, auction.ErrPriceNotIncreased):
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	case err != nil:
+		router.log.Printf("Error simulating bid: %v", err)
+		writeError(w, http.StatusInternalServerError, errors.New("unable to simulate bid"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(simulation)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error": err.Error(),
+	})
+}
+
+func NewVaultRouter(serveMux *http.ServeMux, logger *log.Logger, pool *pgxpool.Pool) *VaultRouter {
 	router := &VaultRouter{
 		Subscribers: SubscribersWithLock{
 			List: make(map[string][]*types.SubscriberVault),
 		},
-		log: logger,
+		log:  logger,
+		pool: pool,
 	}
 	serveMux.HandleFunc("/subscribeVault", router.subscribeVaultHandler)
+	serveMux.HandleFunc("/simulateBid", router.simulateBidHandler)
 	return router
 }
@@ -4,13 +4,14 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
 func TestNewVaultRouter(t *testing.T) {
 	serveMux := http.NewServeMux()
 	logger := log.Default()
-	router := NewVaultRouter(serveMux, logger)
+	router := NewVaultRouter(serveMux, logger, nil)
 
 	if router == nil {
 		t.Error("Expected router to be created")
@@ -43,7 +44,7 @@ func TestSubscribeVaultHandler(t *testing.T) {
 func TestVaultRouterInitialization(t *testing.T) {
 	serveMux := http.NewServeMux()
 	logger := log.Default()
-	router := NewVaultRouter(serveMux, logger)
+	router := NewVaultRouter(serveMux, logger, nil)
 
 	// Check that the endpoint is registered
 	req, err := http.NewRequest("GET", "/subscribeVault", nil)
@@ -59,3 +60,36 @@ func TestVaultRouterInitialization(t *testing.T) {
 		t.Error("Expected non-OK status for non-WebSocket request")
 	}
 }
+
+func TestSimulateBidHandlerValidation(t *testing.T) {
+	logger := log.Default()
+	router := &VaultRouter{log: logger}
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+	}{
+		{name: "wrong method", method: "GET", body: "", status: http.StatusMethodNotAllowed},
+		{name: "malformed body", method: "POST", body: "{", status: http.StatusBadRequest},
+		{name: "missing round address", method: "POST", body: `{"amount":"1","price":"2"}`, status: http.StatusBadRequest},
+		{name: "invalid price", method: "POST", body: `{"roundAddress":"0x1234567890123456789012345678901234567890","amount":"1","price":"-2"}`, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, "/simulateBid", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router.simulateBidHandler(rr, req)
+
+			if rr.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, rr.Code)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package vault
 
 import (
 	"log"
+	"pitchlake-backend/auction"
 	"pitchlake-backend/models"
 	"pitchlake-backend/server/types"
 	"sync"
@@ -17,7 +18,7 @@ type VaultRouter struct {
 	subscriberMessageBuffer int
 	Subscribers             SubscribersWithLock
 	log                     *log.Logger
-	pool                    pgxpool.Pool
+	pool                    *pgxpool.Pool
 }
 
 type InitialPayloadVault struct {
@@ -27,3 +28,9 @@ type InitialPayloadVault struct {
 	VaultState             models.VaultState             `json:"vaultState"`
 	OptionRoundStates      []*models.OptionRound         `json:"optionRoundStates"`
 }
+
+type BidSimulationPayload struct {
+	PayloadType string                   `json:"payloadType"`
+	Request     types.SimulateBidRequest `json:"request"`
+	Simulation  *auction.Simulation      `json:"simulation"`
+}
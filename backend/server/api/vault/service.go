@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math/big"
 	"net"
 	"net/http"
+	"pitchlake-backend/auction"
 	"pitchlake-backend/db/repositories"
 	"pitchlake-backend/server/api/utils"
 	"pitchlake-backend/server/types"
@@ -91,10 +93,10 @@ func (router *VaultRouter) subscribeVault(ctx context.Context, w http.ResponseWr
 	payload.PayloadType = "initial"
 
 	//Create repositories
-	vaultRepo := repositories.NewVaultRepository(&router.pool)
-	optionRoundRepo := repositories.NewOptionRepository(&router.pool)
-	optionBuyerRepo := repositories.NewOptionBuyerRepository(&router.pool)
-	lpRepo := repositories.NewLiquidityRepository(&router.pool)
+	vaultRepo := repositories.NewVaultRepository(router.pool)
+	optionRoundRepo := repositories.NewOptionRepository(router.pool)
+	optionBuyerRepo := repositories.NewOptionBuyerRepository(router.pool)
+	lpRepo := repositories.NewLiquidityRepository(router.pool)
 
 	vaultState, err := vaultRepo.GetVaultStateByID(ctx, s.VaultAddress)
 
@@ -162,6 +164,31 @@ func (router *VaultRouter) subscribeVault(ctx context.Context, w http.ResponseWr
 				break
 			}
 
+			if request.RequestType == types.VaultRequestSimulateBid {
+				response := BidSimulationPayload{
+					PayloadType: "bidSimulation",
+					Request:     *request.Bid,
+				}
+				simulation, err := router.simulateBid(ctx, *request.Bid)
+				if err != nil {
+					errorResponse := map[string]string{
+						"error":   "Bid simulation failed",
+						"details": err.Error(),
+					}
+					errorJson, _ := json.Marshal(errorResponse)
+					s.Msgs <- errorJson
+					continue
+				}
+				response.Simulation = simulation
+				jsonPayload, err := json.Marshal(response)
+				if err != nil {
+					log.Printf("Incorrect response generated: %v", err)
+					continue
+				}
+				s.Msgs <- jsonPayload
+				continue
+			}
+
 			var payload InitialPayloadVault
 			if request.UpdatedField == "address" {
 				s.Address = request.UpdatedValue
@@ -203,6 +230,39 @@ func (router *VaultRouter) subscribeVault(ctx context.Context, w http.ResponseWr
 	}
 }
 
+// simulateBid clears the round's current book with a hypothetical bid added
+func (router *VaultRouter) simulateBid(ctx context.Context, req types.SimulateBidRequest) (*auction.Simulation, error) {
+	optionRoundRepo := repositories.NewOptionRepository(router.pool)
+	optionRound, err := optionRoundRepo.GetOptionRoundByAddress(ctx, req.RoundAddress)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching option round: %w", err)
+	}
+	if optionRound.RoundState != "Auctioning" {
+		return nil, fmt.Errorf("%w: round is %s", ErrAuctionNotRunning, optionRound.RoundState)
+	}
+
+	bidRepo := repositories.NewBidRepository(router.pool)
+	bids, err := bidRepo.GetBidsByRoundAddress(ctx, req.RoundAddress)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching bids: %w", err)
+	}
+	book := make([]auction.Bid, 0, len(bids))
+	for _, b := range bids {
+		bid, err := auction.BidFromModel(b)
+		if err != nil {
+			return nil, err
+		}
+		book = append(book, bid)
+	}
+
+	price, _ := new(big.Int).SetString(req.Price, 10)
+	var amount *big.Int
+	if req.Amount != "" {
+		amount, _ = new(big.Int).SetString(req.Amount, 10)
+	}
+	return auction.Simulate(book, optionRound.AvailableOptions.Int, optionRound.ReservePrice.Int, amount, price, req.BidID)
+}
+
 func (router *VaultRouter) addSubscriberVault(s *types.SubscriberVault) {
 
 	router.Subscribers.mux.Lock()
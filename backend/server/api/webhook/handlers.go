@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"pitchlake-backend/db/repositories"
+	"pitchlake-backend/server/types"
+	"pitchlake-backend/server/validations"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// authorized checks the admin bearer token, writing the error response when
+// the request is rejected
+func (router *WebhookRouter) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if router.adminToken == "" {
+		writeError(w, http.StatusServiceUnavailable, errors.New("webhook management is not configured"))
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(router.adminToken)) != 1 {
+		writeError(w, http.StatusUnauthorized, errors.New("invalid or missing token"))
+		return false
+	}
+	return true
+}
+
+func (router *WebhookRouter) webhooksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !router.authorized(w, r) {
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		var req types.WebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("invalid request body"))
+			return
+		}
+		if err := validations.ValidateWebhookRequest(req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		sub, err := router.createWebhook(r.Context(), req)
+		if err != nil {
+			router.log.Printf("Error creating webhook: %v", err)
+			writeError(w, http.StatusInternalServerError, errors.New("unable to create webhook"))
+			return
+		}
+		// The secret is only ever returned on creation
+		writeJSON(w, http.StatusCreated, sub)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid webhook id"))
+		return
+	}
+	webhookRepo := repositories.NewWebhookRepository(router.pool)
+
+	if r.Method == http.MethodDelete {
+		deleted, err := webhookRepo.DeleteSubscription(r.Context(), id)
+		if err != nil {
+			router.log.Printf("Error deleting webhook %d: %v", id, err)
+			writeError(w, http.StatusInternalServerError, errors.New("unable to delete webhook"))
+			return
+		}
+		if !deleted {
+			writeError(w, http.StatusNotFound, errors.New("webhook not found"))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	sub, err := webhookRepo.GetSubscription(r.Context(), id)
+	if err != nil {
+		router.log.Printf("Error loading webhook %d: %v", id, err)
+		writeError(w, http.StatusInternalServerError, errors.New("unable to load webhook"))
+		return
+	}
+	if sub == nil {
+		writeError(w, http.StatusNotFound, errors.New("webhook not found"))
+		return
+	}
+	sub.Secret = ""
+	writeJSON(w, http.StatusOK, sub)
+}
+
+func (router *WebhookRouter) deliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !router.authorized(w, r) {
+		return
+	}
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid webhook id"))
+		return
+	}
+
+	webhookRepo := repositories.NewWebhookRepository(router.pool)
+	deliveries, err := webhookRepo.GetDeliveries(r.Context(), id, defaultDeliveryLimit)
+	if err != nil {
+		router.log.Printf("Error loading deliveries of webhook %d: %v", id, err)
+		writeError(w, http.StatusInternalServerError, errors.New("unable to load deliveries"))
+		return
+	}
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{
+		"error": err.Error(),
+	})
+}
+
+func NewWebhookRouter(serveMux *http.ServeMux, logger *log.Logger, pool *pgxpool.Pool, adminToken string) *WebhookRouter {
+	router := &WebhookRouter{
+		log:        logger,
+		pool:       pool,
+		adminToken: adminToken,
+	}
+	serveMux.HandleFunc("/webhooks", router.webhooksHandler)
+	serveMux.HandleFunc("/webhooks/deliveries", router.deliveriesHandler)
+	return router
+}
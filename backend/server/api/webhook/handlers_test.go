@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewWebhookRouter(t *testing.T) {
+	serveMux := http.NewServeMux()
+	logger := log.Default()
+	router := NewWebhookRouter(serveMux, logger, nil, "token")
+
+	if router == nil {
+		t.Error("Expected router to be created")
+	}
+}
+
+func TestWebhooksHandlerValidation(t *testing.T) {
+	logger := log.Default()
+
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		target string
+		auth   string
+		body   string
+		status int
+	}{
+		{name: "wrong method", token: "token", method: "PUT", target: "/webhooks", status: http.StatusMethodNotAllowed},
+		{name: "management disabled", method: "GET", target: "/webhooks?id=1", auth: "Bearer token", status: http.StatusServiceUnavailable},
+		{name: "missing token", token: "token", method: "GET", target: "/webhooks?id=1", status: http.StatusUnauthorized},
+		{name: "wrong token", token: "token", method: "GET", target: "/webhooks?id=1", auth: "Bearer nope", status: http.StatusUnauthorized},
+		{name: "invalid id", token: "token", method: "GET", target: "/webhooks?id=abc", auth: "Bearer token", status: http.StatusBadRequest},
+		{name: "invalid body", token: "token", method: "POST", target: "/webhooks", auth: "Bearer token", body: "{", status: http.StatusBadRequest},
+		{name: "invalid url", token: "token", method: "POST", target: "/webhooks", auth: "Bearer token", body: `{"url":"nope"}`, status: http.StatusBadRequest},
+		{name: "invalid event type", token: "token", method: "POST", target: "/webhooks", auth: "Bearer token", body: `{"url":"https://example.com","eventTypes":["x"]}`, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := &WebhookRouter{log: logger, adminToken: tt.token}
+			req, err := http.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+
+			rr := httptest.NewRecorder()
+			router.webhooksHandler(rr, req)
+
+			if rr.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, rr.Code)
+			}
+			if tt.status != http.StatusMethodNotAllowed && !strings.Contains(rr.Body.String(), "error") {
+				t.Errorf("Expected an error body, got '%s'", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestDeliveriesHandlerValidation(t *testing.T) {
+	router := &WebhookRouter{log: log.Default(), adminToken: "token"}
+
+	req, _ := http.NewRequest("GET", "/webhooks/deliveries", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	rr := httptest.NewRecorder()
+	router.deliveriesHandler(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
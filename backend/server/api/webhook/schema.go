@@ -0,0 +1,15 @@
+package webhook
+
+import (
+	"log"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WebhookRouter struct {
+	log  *log.Logger
+	pool *pgxpool.Pool
+	// adminToken is the bearer token required to manage webhooks, management
+	// is disabled when it is empty
+	adminToken string
+}
@@ -0,0 +1,36 @@
+package webhook
+
+import (
+	"context"
+
+	"pitchlake-backend/db/repositories"
+	"pitchlake-backend/models"
+	"pitchlake-backend/server/types"
+	"pitchlake-backend/webhooks"
+)
+
+// defaultDeliveryLimit is the number of deliveries returned by the delivery log
+const defaultDeliveryLimit = 50
+
+// createWebhook registers a webhook with a freshly generated signing secret
+func (router *WebhookRouter) createWebhook(ctx context.Context, req types.WebhookRequest) (*models.WebhookSubscription, error) {
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+	sub := &models.WebhookSubscription{
+		URL:            req.URL,
+		Secret:         secret,
+		EventTypes:     req.EventTypes,
+		VaultAddress:   req.VaultAddress,
+		AccountAddress: req.AccountAddress,
+	}
+	if sub.EventTypes == nil {
+		sub.EventTypes = []string{}
+	}
+	webhookRepo := repositories.NewWebhookRepository(router.pool)
+	if err := webhookRepo.CreateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"pitchlake-backend/auction"
 	"pitchlake-backend/db/repositories"
 	"pitchlake-backend/models"
 	"pitchlake-backend/server/types"
+	"strings"
 )
 
 type confirmedUpdate struct {
@@ -33,6 +35,14 @@ type InitialPayloadVault struct {
 	OptionRoundStates      []*models.OptionRound         `json:"optionRoundStates"`
 }
 
+// BidStatusPayload tells a bidder that one of their bids moved between
+// winning, partially filled and losing at the current clearing price
+type BidStatusPayload struct {
+	PayloadType  string `json:"payloadType"`
+	VaultAddress string `json:"vaultAddress"`
+	auction.StatusChange
+}
+
 type InitialPayloadGas struct {
 	UnconfirmedBlocks []models.Block `json:"unconfirmedBlocks"`
 	ConfirmedBlocks   []models.Block `json:"confirmedBlocks"`
@@ -251,6 +261,7 @@ func (dbs *dbServer) listener(ctx context.Context, sv map[string][]*types.Subscr
 				}
 
 			}
+			dbs.sendBidStatuses(ctx, sv, &updatedData.Payload)
 		case "lp_update":
 			var updatedData NotificationPayloadVault[models.LiquidityProviderState]
 			err := json.Unmarshal([]byte(notification.Payload), &updatedData)
@@ -326,6 +337,7 @@ func (dbs *dbServer) listener(ctx context.Context, sv map[string][]*types.Subscr
 			}
 			// Print the updated row
 			fmt.Printf("Updated OptionRound: %+v\n", updatedData.Payload.Address)
+			dbs.bidTracker.UpdateRound(&updatedData.Payload)
 			if sv[updatedData.Payload.VaultAddress] != nil {
 
 				for _, s := range sv[updatedData.Payload.VaultAddress] {
@@ -335,3 +347,29 @@ func (dbs *dbServer) listener(ctx context.Context, sv map[string][]*types.Subscr
 		}
 	}
 }
+
+// sendBidStatuses applies a bid to the auction book and pushes a bidStatus
+// message to the vault subscriptions of every bidder whose bid changed status
+func (dbs *dbServer) sendBidStatuses(ctx context.Context, sv map[string][]*types.SubscriberVault, bid *models.Bid) {
+	round, changes, err := dbs.bidTracker.ApplyBid(ctx, bid)
+	if err != nil {
+		log.Printf("Error updating the bid book: %v", err)
+		return
+	}
+	for _, change := range changes {
+		response, err := json.Marshal(BidStatusPayload{
+			PayloadType:  "bidStatus",
+			VaultAddress: round.VaultAddress,
+			StatusChange: change,
+		})
+		if err != nil {
+			log.Printf("Error marshalling bid status: %v", err)
+			continue
+		}
+		for _, s := range sv[round.VaultAddress] {
+			if strings.EqualFold(s.Address, change.Buyer) {
+				s.Msgs <- response
+			}
+		}
+	}
+}
@@ -4,10 +4,17 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"os"
+	"pitchlake-backend/auction"
 	"pitchlake-backend/db"
+	"pitchlake-backend/db/repositories"
 	"pitchlake-backend/server/api/general"
 	"pitchlake-backend/server/api/home"
+	"pitchlake-backend/server/api/preview"
 	"pitchlake-backend/server/api/vault"
+	"pitchlake-backend/server/api/webhook"
+	"pitchlake-backend/twap"
+	"pitchlake-backend/webhooks"
 )
 
 // dbServer enables broadcasting to a set of subscribers.
@@ -15,6 +22,7 @@ import (
 type dbServer struct {
 	subscriberMessageBuffer int
 	db                      *db.DB
+	bidTracker              *auction.Tracker
 	log                     log.Logger
 	serveMux                http.ServeMux
 	ctx                     context.Context
@@ -35,11 +43,33 @@ func NewDBServer(ctx context.Context) *dbServer {
 		db:     db,
 		ctx:    ctx,
 		cancel: cancel,
+		bidTracker: auction.NewTracker(
+			repositories.NewOptionRepository(db.Pool),
+			repositories.NewBidRepository(db.Pool),
+		),
 	}
 	homeRouter := home.NewHomeRouter(&dbs.serveMux, &dbs.log)
-	vaultRouter := vault.NewVaultRouter(&dbs.serveMux, &dbs.log)
+	vaultRouter := vault.NewVaultRouter(&dbs.serveMux, &dbs.log, db.Pool)
 	generalRouter := general.NewGeneralRouter(&dbs.serveMux, &dbs.log)
+	preview.NewPreviewRouter(&dbs.serveMux, &dbs.log, db.Pool)
 	go dbs.listener(ctx, vaultRouter.Subscribers.List, homeRouter.Subscribers.List, generalRouter.Subscribers.List)
+	if os.Getenv("TWAP_ENGINE_ENABLED") == "true" {
+		twapService := twap.NewService(db.Pool, &dbs.log)
+		go func() {
+			if err := twapService.Run(ctx); err != nil {
+				dbs.log.Printf("TWAP engine stopped: %v", err)
+			}
+		}()
+	}
+	if os.Getenv("WEBHOOKS_ENABLED") == "true" {
+		webhook.NewWebhookRouter(&dbs.serveMux, &dbs.log, db.Pool, os.Getenv("WEBHOOKS_ADMIN_TOKEN"))
+		webhookService := webhooks.NewService(db.Pool, &dbs.log)
+		go func() {
+			if err := webhookService.Run(ctx); err != nil {
+				dbs.log.Printf("Webhook service stopped: %v", err)
+			}
+		}()
+	}
 	return dbs
 }
 
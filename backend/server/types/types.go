@@ -42,8 +42,25 @@ type SubscriberMessage struct {
 }
 
 type SubscriberVaultRequest struct {
-	UpdatedField string `json:"updatedField"`
-	UpdatedValue string `json:"updatedValue"`
+	RequestType  string              `json:"requestType"`
+	UpdatedField string              `json:"updatedField"`
+	UpdatedValue string              `json:"updatedValue"`
+	Bid          *SimulateBidRequest `json:"bid,omitempty"`
+}
+
+// Vault request types, an empty request type is treated as a field update
+const (
+	VaultRequestUpdateField = "updateField"
+	VaultRequestSimulateBid = "simulateBid"
+)
+
+// SimulateBidRequest is a hypothetical bid to clear against a round's current book.
+// Setting BidID simulates updating that bid's price instead of placing a new bid.
+type SimulateBidRequest struct {
+	RoundAddress string `json:"roundAddress"`
+	Amount       string `json:"amount"`
+	Price        string `json:"price"`
+	BidID        string `json:"bidId,omitempty"`
 }
 
 type BidData struct {
@@ -66,3 +83,12 @@ type SubscriberGasRequest struct {
 	EndTimestamp   uint64 `json:"endTimestamp"`
 	RoundDuration  uint64 `json:"roundDuration"`
 }
+
+// WebhookRequest registers a webhook. Empty filters match every event type,
+// vault or account.
+type WebhookRequest struct {
+	URL            string   `json:"url"`
+	EventTypes     []string `json:"eventTypes"`
+	VaultAddress   string   `json:"vaultAddress"`
+	AccountAddress string   `json:"accountAddress"`
+}
@@ -2,7 +2,10 @@ package validations
 
 import (
 	"fmt"
+	"math/big"
+	"net/url"
 	"pitchlake-backend/server/types"
+	"pitchlake-backend/webhooks"
 	"strings"
 )
 
@@ -59,6 +62,17 @@ func ValidateGasRequest(req types.SubscriberGasRequest) error {
 
 // ValidateVaultRequest validates vault update requests
 func ValidateVaultRequest(req types.SubscriberVaultRequest) error {
+	switch req.RequestType {
+	case "", types.VaultRequestUpdateField:
+	case types.VaultRequestSimulateBid:
+		if req.Bid == nil {
+			return fmt.Errorf("bid is required")
+		}
+		return ValidateSimulateBidRequest(*req.Bid)
+	default:
+		return fmt.Errorf("invalid request type: %s, must be '%s' or '%s'", req.RequestType, types.VaultRequestUpdateField, types.VaultRequestSimulateBid)
+	}
+
 	// Validate required fields
 	if req.UpdatedField == "" {
 		return fmt.Errorf("updated field is required")
@@ -82,3 +96,68 @@ func ValidateVaultRequest(req types.SubscriberVaultRequest) error {
 
 	return nil
 }
+
+// ValidatePricingPreviewRequest validates the vault address of a pricing preview request
+func ValidatePricingPreviewRequest(vaultAddress string) error {
+	if vaultAddress == "" {
+		return fmt.Errorf("vault address is required")
+	}
+	if !strings.HasPrefix(vaultAddress, "0x") || len(vaultAddress) != 42 {
+		return fmt.Errorf("invalid vault address format: %s", vaultAddress)
+	}
+	return nil
+}
+
+// ValidateSimulateBidRequest validates a hypothetical bid
+func ValidateSimulateBidRequest(req types.SimulateBidRequest) error {
+	if req.RoundAddress == "" {
+		return fmt.Errorf("round address is required")
+	}
+	if !strings.HasPrefix(req.RoundAddress, "0x") || len(req.RoundAddress) != 42 {
+		return fmt.Errorf("invalid round address format: %s", req.RoundAddress)
+	}
+	if req.Price == "" {
+		return fmt.Errorf("price is required")
+	}
+	if !isPositiveInteger(req.Price) {
+		return fmt.Errorf("invalid price: %s, must be a positive integer", req.Price)
+	}
+	// Updates keep the amount of the existing bid
+	if req.BidID == "" {
+		if req.Amount == "" {
+			return fmt.Errorf("amount is required")
+		}
+		if !isPositiveInteger(req.Amount) {
+			return fmt.Errorf("invalid amount: %s, must be a positive integer", req.Amount)
+		}
+	}
+	return nil
+}
+
+// ValidateWebhookRequest validates a webhook registration
+func ValidateWebhookRequest(req types.WebhookRequest) error {
+	if req.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url: %s, must be an absolute http or https url", req.URL)
+	}
+	for _, eventType := range req.EventTypes {
+		if !webhooks.IsEventType(eventType) {
+			return fmt.Errorf("invalid event type: %s, must be one of %s", eventType, strings.Join(webhooks.EventTypes, ", "))
+		}
+	}
+	if req.VaultAddress != "" && (!strings.HasPrefix(req.VaultAddress, "0x") || len(req.VaultAddress) != 42) {
+		return fmt.Errorf("invalid vault address format: %s", req.VaultAddress)
+	}
+	if req.AccountAddress != "" && (!strings.HasPrefix(req.AccountAddress, "0x") || len(req.AccountAddress) != 42) {
+		return fmt.Errorf("invalid account address format: %s", req.AccountAddress)
+	}
+	return nil
+}
+
+func isPositiveInteger(value string) bool {
+	v, ok := new(big.Int).SetString(value, 10)
+	return ok && v.Sign() > 0
+}
@@ -271,6 +271,34 @@ func TestValidateVaultRequest(t *testing.T) {
 			wantErr: true,
 			errMsg:  "invalid address format: 1234567890123456789012345678901234567890",
 		},
+		{
+			name: "valid simulate bid request",
+			request: types.SubscriberVaultRequest{
+				RequestType: types.VaultRequestSimulateBid,
+				Bid: &types.SimulateBidRequest{
+					RoundAddress: "0x1234567890123456789012345678901234567890",
+					Amount:       "10",
+					Price:        "1000000000",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "simulate bid request without bid",
+			request: types.SubscriberVaultRequest{
+				RequestType: types.VaultRequestSimulateBid,
+			},
+			wantErr: true,
+			errMsg:  "bid is required",
+		},
+		{
+			name: "invalid request type",
+			request: types.SubscriberVaultRequest{
+				RequestType: "unknown",
+			},
+			wantErr: true,
+			errMsg:  "invalid request type: unknown, must be 'updateField' or 'simulateBid'",
+		},
 	}
 
 	for _, tt := range tests {
@@ -292,3 +320,223 @@ func TestValidateVaultRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestValidatePricingPreviewRequest(t *testing.T) {
+	tests := []struct {
+		name         string
+		vaultAddress string
+		wantErr      bool
+		errMsg       string
+	}{
+		{
+			name:         "valid vault address",
+			vaultAddress: "0xabcdefabcdefabcdefabcdefabcdefabcdefabcd",
+			wantErr:      false,
+		},
+		{
+			name:         "missing vault address",
+			vaultAddress: "",
+			wantErr:      true,
+			errMsg:       "vault address is required",
+		},
+		{
+			name:         "invalid vault address format",
+			vaultAddress: "0x123",
+			wantErr:      true,
+			errMsg:       "invalid vault address format: 0x123",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePricingPreviewRequest(tt.vaultAddress)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ValidatePricingPreviewRequest() expected error but got none")
+					return
+				}
+				if err.Error() != tt.errMsg {
+					t.Errorf("ValidatePricingPreviewRequest() error = %v, want %v", err.Error(), tt.errMsg)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("ValidatePricingPreviewRequest() unexpected error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateSimulateBidRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		request types.SimulateBidRequest
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "valid new bid",
+			request: types.SimulateBidRequest{
+				RoundAddress: "0x1234567890123456789012345678901234567890",
+				Amount:       "10",
+				Price:        "1000",
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid bid update without amount",
+			request: types.SimulateBidRequest{
+				RoundAddress: "0x1234567890123456789012345678901234567890",
+				Price:        "1000",
+				BidID:        "0xabc",
+			},
+			wantErr: false,
+		},
+		{
+			name: "missing round address",
+			request: types.SimulateBidRequest{
+				Amount: "10",
+				Price:  "1000",
+			},
+			wantErr: true,
+			errMsg:  "round address is required",
+		},
+		{
+			name: "invalid round address",
+			request: types.SimulateBidRequest{
+				RoundAddress: "0x123",
+				Amount:       "10",
+				Price:        "1000",
+			},
+			wantErr: true,
+			errMsg:  "invalid round address format: 0x123",
+		},
+		{
+			name: "missing price",
+			request: types.SimulateBidRequest{
+				RoundAddress: "0x1234567890123456789012345678901234567890",
+				Amount:       "10",
+			},
+			wantErr: true,
+			errMsg:  "price is required",
+		},
+		{
+			name: "zero amount",
+			request: types.SimulateBidRequest{
+				RoundAddress: "0x1234567890123456789012345678901234567890",
+				Amount:       "0",
+				Price:        "1000",
+			},
+			wantErr: true,
+			errMsg:  "invalid amount: 0, must be a positive integer",
+		},
+		{
+			name: "missing amount for a new bid",
+			request: types.SimulateBidRequest{
+				RoundAddress: "0x1234567890123456789012345678901234567890",
+				Price:        "1000",
+			},
+			wantErr: true,
+			errMsg:  "amount is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSimulateBidRequest(tt.request)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ValidateSimulateBidRequest() expected error but got none")
+					return
+				}
+				if err.Error() != tt.errMsg {
+					t.Errorf("ValidateSimulateBidRequest() error = %v, want %v", err.Error(), tt.errMsg)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("ValidateSimulateBidRequest() unexpected error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateWebhookRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		request types.WebhookRequest
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "valid request with filters",
+			request: types.WebhookRequest{
+				URL:            "https://example.com/hooks",
+				EventTypes:     []string{"round.deployed", "bid.outbid"},
+				VaultAddress:   "0x1234567890123456789012345678901234567890",
+				AccountAddress: "0x1234567890123456789012345678901234567890",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "valid request without filters",
+			request: types.WebhookRequest{URL: "http://localhost:9000"},
+			wantErr: false,
+		},
+		{
+			name:    "missing url",
+			request: types.WebhookRequest{},
+			wantErr: true,
+			errMsg:  "url is required",
+		},
+		{
+			name:    "relative url",
+			request: types.WebhookRequest{URL: "/hooks"},
+			wantErr: true,
+			errMsg:  "invalid url: /hooks, must be an absolute http or https url",
+		},
+		{
+			name:    "unsupported scheme",
+			request: types.WebhookRequest{URL: "ftp://example.com"},
+			wantErr: true,
+			errMsg:  "invalid url: ftp://example.com, must be an absolute http or https url",
+		},
+		{
+			name:    "unknown event type",
+			request: types.WebhookRequest{URL: "https://example.com", EventTypes: []string{"round.exploded"}},
+			wantErr: true,
+			errMsg:  "invalid event type: round.exploded, must be one of round.deployed, auction.started, auction.ended, round.settled, bid.outbid",
+		},
+		{
+			name:    "invalid vault address",
+			request: types.WebhookRequest{URL: "https://example.com", VaultAddress: "1234"},
+			wantErr: true,
+			errMsg:  "invalid vault address format: 1234",
+		},
+		{
+			name:    "invalid account address",
+			request: types.WebhookRequest{URL: "https://example.com", AccountAddress: "0x12"},
+			wantErr: true,
+			errMsg:  "invalid account address format: 0x12",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateWebhookRequest(tt.request)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ValidateWebhookRequest() expected error but got none")
+					return
+				}
+				if err.Error() != tt.errMsg {
+					t.Errorf("ValidateWebhookRequest() error = %v, want %v", err.Error(), tt.errMsg)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("ValidateWebhookRequest() unexpected error = %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,393 @@
+package twap
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"pitchlake-backend/models"
+)
+
+// Window describes a single TWAP window maintained by the engine
+type Window struct {
+	Type     models.TwapWindowType
+	Duration uint64
+}
+
+// DefaultWindows are the windows written back to the blocks table
+var DefaultWindows = []Window{
+	{Type: models.TwapWindowTwelveMin, Duration: 720},
+	{Type: models.TwapWindowThreeHour, Duration: 10800},
+	{Type: models.TwapWindowThirtyDay, Duration: 2592000},
+}
+
+// Precision is the number of decimals stored in the numeric(30,9) TWAP columns
+const Precision = 9
+
+var (
+	// ErrBeyondHorizon is returned when a change lands before the oldest retained
+	// block, the caller is expected to reseed the engine from the database
+	ErrBeyondHorizon = errors.New("block is older than the retained TWAP horizon")
+	// ErrNonMonotonicTimestamp is returned when a block's timestamp is lower than its parent's
+	ErrNonMonotonicTimestamp = errors.New("block timestamp is lower than the previous block")
+)
+
+// Sample is the subset of a blocks row the engine needs
+type Sample struct {
+	BlockNumber uint64
+	Timestamp   uint64
+	BaseFee     *big.Rat
+	IsConfirmed bool
+}
+
+// SampleFromBlock converts a blocks row into a Sample
+func SampleFromBlock(block models.Block) (Sample, error) {
+	baseFee, ok := new(big.Rat).SetString(block.BaseFee)
+	if !ok {
+		return Sample{}, fmt.Errorf("invalid basefee %q for block %d", block.BaseFee, block.BlockNumber)
+	}
+	return Sample{
+		BlockNumber: block.BlockNumber,
+		Timestamp:   block.Timestamp,
+		BaseFee:     baseFee,
+		IsConfirmed: block.IsConfirmed,
+	}, nil
+}
+
+func (s Sample) equal(o Sample) bool {
+	return s.BlockNumber == o.BlockNumber &&
+		s.Timestamp == o.Timestamp &&
+		s.IsConfirmed == o.IsConfirmed &&
+		s.BaseFee.Cmp(o.BaseFee) == 0
+}
+
+// WindowValue is the TWAP of one window at a given block
+type WindowValue struct {
+	WeightedSum  *big.Rat
+	TotalSeconds uint64
+	Twap         *big.Rat
+}
+
+// Result holds the TWAPs of a block, one per engine window in window order
+type Result struct {
+	BlockNumber uint64
+	Timestamp   uint64
+	IsConfirmed bool
+	Values      []WindowValue
+}
+
+// Engine maintains the TWAPs of a contiguous run of blocks.
+//
+// A block's TWAP over a window D covers the blocks whose timestamp lies in
+// (T-D, T], where T is the block's own timestamp. Each block's basefee is
+// weighted by the time until the next block and the window starts at the
+// first block inside it, matching the support server's calculation.
+//
+// Blocks are kept in block number order together with a running prefix sum
+// of basefee*duration, so appending a block is O(log n) per window and a
+// replaced block only recomputes the blocks that follow it.
+type Engine struct {
+	windows []Window
+	// widest is the longest window duration
+	widest uint64
+	// retention is how long (in seconds) blocks are kept behind the head
+	retention uint64
+	blocks    []Sample
+	// prefix[i] is the weighted sum of the segments of blocks[0..i-1]
+	prefix []*big.Rat
+	// pending holds blocks that arrived ahead of a gap
+	pending map[uint64]Sample
+	// truncated is set once old blocks have been pruned
+	truncated bool
+}
+
+// NewEngine creates an engine for the given windows
+func NewEngine(windows []Window) *Engine {
+	var retention uint64
+	for _, w := range windows {
+		if w.Duration > retention {
+			retention = w.Duration
+		}
+	}
+	return &Engine{
+		windows: windows,
+		widest:  retention,
+		// Keep twice the widest window so replaced blocks near the head can
+		// still be recomputed without going back to the database
+		retention: retention * 2,
+		pending:   make(map[uint64]Sample),
+	}
+}
+
+// Windows returns the windows maintained by the engine
+func (e *Engine) Windows() []Window {
+	return e.windows
+}
+
+// Reset drops all state held by the engine
+func (e *Engine) Reset() {
+	e.blocks = nil
+	e.prefix = nil
+	e.pending = make(map[uint64]Sample)
+	e.truncated = false
+}
+
+// Len returns the number of contiguous blocks held by the engine
+func (e *Engine) Len() int {
+	return len(e.blocks)
+}
+
+// Head returns the latest contiguous block, if any
+func (e *Engine) Head() (Sample, bool) {
+	if len(e.blocks) == 0 {
+		return Sample{}, false
+	}
+	return e.blocks[len(e.blocks)-1], true
+}
+
+// Seed loads a contiguous, ordered run of blocks into an empty engine
+// without producing results. When truncated is set, older blocks than the
+// seeded run exist and changes that would need them are out of reach.
+func (e *Engine) Seed(samples []Sample, truncated bool) error {
+	e.Reset()
+	if _, err := e.Apply(samples...); err != nil {
+		return err
+	}
+	e.truncated = e.truncated || truncated
+	return nil
+}
+
+// Apply inserts or replaces the given blocks and returns the TWAPs of every
+// block whose value may have changed, in block number order.
+//
+// A block with a number above the head+1 is buffered until the gap is
+// filled. A block that replaces an existing block with different data (a
+// reorg or a confirmation) triggers a recompute from that block onwards.
+func (e *Engine) Apply(samples ...Sample) ([]Result, error) {
+	changed := -1
+	for _, s := range samples {
+		if s.BaseFee == nil {
+			return nil, fmt.Errorf("missing basefee for block %d", s.BlockNumber)
+		}
+		idx, err := e.insert(s)
+		if err != nil {
+			return nil, err
+		}
+		if idx >= 0 && (changed < 0 || idx < changed) {
+			changed = idx
+		}
+	}
+	if changed < 0 {
+		return nil, nil
+	}
+	if e.truncated && changed > 0 && e.blocks[changed].Timestamp < e.blocks[0].Timestamp+e.widest {
+		// The recomputed windows would reach past the oldest retained block
+		return nil, ErrBeyondHorizon
+	}
+
+	e.rebuildPrefix(changed)
+
+	results := make([]Result, 0, len(e.blocks)-changed)
+	for i := changed; i < len(e.blocks); i++ {
+		results = append(results, e.resultAt(i))
+	}
+	e.prune()
+	return results, nil
+}
+
+// Recompute returns the TWAPs of every held block from the given block number
+// onwards, without changing the engine state
+func (e *Engine) Recompute(from uint64) []Result {
+	if len(e.blocks) == 0 {
+		return nil
+	}
+	start := 0
+	if from > e.blocks[0].BlockNumber {
+		start = int(from - e.blocks[0].BlockNumber)
+	}
+	var results []Result
+	for i := start; i < len(e.blocks); i++ {
+		results = append(results, e.resultAt(i))
+	}
+	return results
+}
+
+// insert places s in the block run and returns the index of the first
+// modified block, or -1 when nothing changed
+func (e *Engine) insert(s Sample) (int, error) {
+	if len(e.blocks) == 0 {
+		e.blocks = append(e.blocks, s)
+		return e.drainPending(0)
+	}
+
+	first := e.blocks[0].BlockNumber
+	head := e.blocks[len(e.blocks)-1].BlockNumber
+
+	switch {
+	case s.BlockNumber < first:
+		if e.truncated {
+			return -1, ErrBeyondHorizon
+		}
+		if s.BlockNumber+1 != first {
+			return -1, fmt.Errorf("block %d does not connect to the first block %d", s.BlockNumber, first)
+		}
+		if s.Timestamp > e.blocks[0].Timestamp {
+			return -1, ErrNonMonotonicTimestamp
+		}
+		e.blocks = append([]Sample{s}, e.blocks...)
+		e.prefix = nil
+		return 0, nil
+	case s.BlockNumber > head+1:
+		e.pending[s.BlockNumber] = s
+		return -1, nil
+	case s.BlockNumber == head+1:
+		if s.Timestamp < e.blocks[len(e.blocks)-1].Timestamp {
+			return -1, ErrNonMonotonicTimestamp
+		}
+		e.blocks = append(e.blocks, s)
+		return e.drainPending(len(e.blocks) - 1)
+	}
+
+	idx := int(s.BlockNumber - first)
+	if e.blocks[idx].equal(s) {
+		return -1, nil
+	}
+	if idx > 0 && s.Timestamp < e.blocks[idx-1].Timestamp {
+		return -1, ErrNonMonotonicTimestamp
+	}
+	if idx+1 < len(e.blocks) && s.Timestamp > e.blocks[idx+1].Timestamp {
+		// The replacement does not fit the following blocks, they belong to
+		// the abandoned branch and will be delivered again
+		e.blocks = e.blocks[:idx+1]
+	}
+	e.blocks[idx] = s
+	return idx, nil
+}
+
+// drainPending appends buffered blocks that now connect to the head
+func (e *Engine) drainPending(changed int) (int, error) {
+	for {
+		head := e.blocks[len(e.blocks)-1]
+		next, ok := e.pending[head.BlockNumber+1]
+		if !ok {
+			break
+		}
+		delete(e.pending, next.BlockNumber)
+		if next.Timestamp < head.Timestamp {
+			return -1, ErrNonMonotonicTimestamp
+		}
+		e.blocks = append(e.blocks, next)
+	}
+	for n := range e.pending {
+		if n <= e.blocks[len(e.blocks)-1].BlockNumber {
+			delete(e.pending, n)
+		}
+	}
+	return changed, nil
+}
+
+// rebuildPrefix recomputes the prefix sums affected by a change to
+// blocks[from], which starts at the segment of the block before it
+func (e *Engine) rebuildPrefix(from int) {
+	if from > len(e.prefix) {
+		from = len(e.prefix)
+	}
+	if from < 1 {
+		e.prefix = []*big.Rat{new(big.Rat)}
+		from = 1
+	}
+	e.prefix = e.prefix[:from]
+	for i := from; i < len(e.blocks); i++ {
+		e.prefix = append(e.prefix, new(big.Rat).Add(e.prefix[i-1], e.segment(i-1)))
+	}
+}
+
+// segment is the weighted contribution of blocks[i] up to the next block
+func (e *Engine) segment(i int) *big.Rat {
+	duration := e.blocks[i+1].Timestamp - e.blocks[i].Timestamp
+	return new(big.Rat).Mul(e.blocks[i].BaseFee, new(big.Rat).SetInt(new(big.Int).SetUint64(duration)))
+}
+
+// resultAt computes the TWAPs of blocks[i] for every window
+func (e *Engine) resultAt(i int) Result {
+	block := e.blocks[i]
+	result := Result{
+		BlockNumber: block.BlockNumber,
+		Timestamp:   block.Timestamp,
+		IsConfirmed: block.IsConfirmed,
+		Values:      make([]WindowValue, len(e.windows)),
+	}
+	for w, window := range e.windows {
+		result.Values[w] = e.valueAt(i, window.Duration)
+	}
+	return result
+}
+
+func (e *Engine) valueAt(i int, duration uint64) WindowValue {
+	end := e.blocks[i].Timestamp
+	var start uint64
+	if end > duration {
+		start = end - duration
+	}
+	// First block strictly inside the window
+	first := sort.Search(i+1, func(j int) bool {
+		return e.blocks[j].Timestamp > start
+	})
+	if first >= i {
+		return WindowValue{WeightedSum: new(big.Rat), TotalSeconds: 0, Twap: new(big.Rat)}
+	}
+
+	sum := new(big.Rat).Sub(e.prefix[i], e.prefix[first])
+	total := end - e.blocks[first].Timestamp
+	twap := new(big.Rat)
+	if total > 0 {
+		twap.Quo(sum, new(big.Rat).SetInt(new(big.Int).SetUint64(total)))
+	}
+	return WindowValue{WeightedSum: sum, TotalSeconds: total, Twap: twap}
+}
+
+// prune drops blocks that are further behind the head than the retention
+func (e *Engine) prune() {
+	if len(e.blocks) == 0 {
+		return
+	}
+	head := e.blocks[len(e.blocks)-1].Timestamp
+	if head <= e.retention {
+		return
+	}
+	cutoff := head - e.retention
+	drop := sort.Search(len(e.blocks), func(j int) bool {
+		return e.blocks[j].Timestamp > cutoff
+	})
+	if drop == 0 {
+		return
+	}
+	e.blocks = append([]Sample(nil), e.blocks[drop:]...)
+	e.prefix = append([]*big.Rat(nil), e.prefix[drop:]...)
+	e.truncated = true
+}
+
+// State returns the TWAP state of a window at the latest block, or at the
+// latest confirmed block when confirmed is set
+func (e *Engine) State(window int, confirmed bool) (models.TwapState, bool) {
+	i := len(e.blocks) - 1
+	if confirmed {
+		for i >= 0 && !e.blocks[i].IsConfirmed {
+			i--
+		}
+	}
+	if i < 0 || window < 0 || window >= len(e.windows) {
+		return models.TwapState{}, false
+	}
+	value := e.valueAt(i, e.windows[window].Duration)
+	return models.TwapState{
+		WindowType:         e.windows[window].Type,
+		WeightedSum:        value.WeightedSum.FloatString(Precision),
+		TotalSeconds:       models.BigInt{Int: new(big.Int).SetUint64(value.TotalSeconds)},
+		IsConfirmed:        confirmed,
+		TwapValue:          value.Twap.FloatString(Precision),
+		LastBlockNumber:    e.blocks[i].BlockNumber,
+		LastBlockTimestamp: e.blocks[i].Timestamp,
+	}, true
+}
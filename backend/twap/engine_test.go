@@ -0,0 +1,279 @@
+package twap
+
+import (
+	"errors"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"pitchlake-backend/models"
+)
+
+var testWindows = []Window{
+	{Type: models.TwapWindowTwelveMin, Duration: 30},
+	{Type: models.TwapWindowThreeHour, Duration: 120},
+	{Type: models.TwapWindowThirtyDay, Duration: 600},
+}
+
+// referenceTwap is a direct transcription of the support server's calculation
+func referenceTwap(chain []Sample, i int, duration uint64) *big.Rat {
+	current := chain[i]
+	var windowStart uint64
+	if current.Timestamp > duration {
+		windowStart = current.Timestamp - duration
+	}
+
+	var inWindow []int
+	for j := 0; j <= i; j++ {
+		// Only blocks with a known next block have a duration
+		if chain[j].Timestamp <= current.Timestamp && chain[j].Timestamp > windowStart && j+1 < len(chain) {
+			inWindow = append(inWindow, j)
+		}
+	}
+	if len(inWindow) == 0 {
+		return new(big.Rat)
+	}
+
+	effectiveStart := windowStart
+	if chain[inWindow[0]].Timestamp > effectiveStart {
+		effectiveStart = chain[inWindow[0]].Timestamp
+	}
+	effectiveEnd := current.Timestamp
+	total := effectiveEnd - effectiveStart
+
+	sum := new(big.Rat)
+	for k, j := range inWindow {
+		next := current
+		if k < len(inWindow)-1 {
+			next = chain[inWindow[k+1]]
+		}
+		blockStart := max(chain[j].Timestamp, effectiveStart)
+		blockEnd := min(next.Timestamp, effectiveEnd)
+		if blockEnd > blockStart {
+			d := new(big.Rat).SetInt64(int64(blockEnd - blockStart))
+			sum.Add(sum, new(big.Rat).Mul(chain[j].BaseFee, d))
+		}
+	}
+	if total == 0 {
+		return new(big.Rat)
+	}
+	return sum.Quo(sum, new(big.Rat).SetInt64(int64(total)))
+}
+
+func randomChain(r *rand.Rand, from uint64, n int, startTs uint64) []Sample {
+	chain := make([]Sample, n)
+	ts := startTs
+	for i := range chain {
+		// Mix of zero, short and long gaps to exercise window edges
+		switch r.Intn(4) {
+		case 0:
+			ts += 0
+		case 1:
+			ts += uint64(r.Intn(5) + 1)
+		case 2:
+			ts += uint64(r.Intn(40) + 1)
+		default:
+			ts += 6
+		}
+		chain[i] = Sample{
+			BlockNumber: from + uint64(i),
+			Timestamp:   ts,
+			BaseFee:     big.NewRat(int64(r.Intn(1_000_000_000)+1), 1000),
+		}
+	}
+	return chain
+}
+
+// collect keeps the latest result reported for each block
+func collect(t *testing.T, dst map[uint64]Result, results []Result, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, r := range results {
+		dst[r.BlockNumber] = r
+	}
+}
+
+func assertMatchesReference(t *testing.T, chain []Sample, got map[uint64]Result) {
+	t.Helper()
+	for i, block := range chain {
+		result, ok := got[block.BlockNumber]
+		if !ok {
+			t.Fatalf("no result for block %d", block.BlockNumber)
+		}
+		for w, window := range testWindows {
+			want := referenceTwap(chain, i, window.Duration)
+			if result.Values[w].Twap.Cmp(want) != 0 {
+				t.Fatalf("block %d window %s: got %s, want %s",
+					block.BlockNumber, window.Type,
+					result.Values[w].Twap.FloatString(Precision), want.FloatString(Precision))
+			}
+		}
+	}
+}
+
+func TestEngineSequentialMatchesReference(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	chain := randomChain(r, 100, 400, 1_000)
+
+	engine := NewEngine(testWindows)
+	got := make(map[uint64]Result)
+	for _, block := range chain {
+		results, err := engine.Apply(block)
+		collect(t, got, results, err)
+		if len(results) != 1 || results[0].BlockNumber != block.BlockNumber {
+			t.Fatalf("expected a single result for block %d, got %d", block.BlockNumber, len(results))
+		}
+	}
+	assertMatchesReference(t, chain, got)
+}
+
+func TestEngineOutOfOrderMatchesReference(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	chain := randomChain(r, 0, 300, 50)
+
+	// Deliver the first block, then the rest shuffled within small batches
+	engine := NewEngine(testWindows)
+	got := make(map[uint64]Result)
+	results, err := engine.Apply(chain[0])
+	collect(t, got, results, err)
+
+	rest := append([]Sample(nil), chain[1:]...)
+	for start := 0; start < len(rest); start += 10 {
+		end := min(start+10, len(rest))
+		batch := rest[start:end]
+		r.Shuffle(len(batch), func(i, j int) { batch[i], batch[j] = batch[j], batch[i] })
+		for _, block := range batch {
+			results, err := engine.Apply(block)
+			collect(t, got, results, err)
+		}
+	}
+	if head, ok := engine.Head(); !ok || head.BlockNumber != chain[len(chain)-1].BlockNumber {
+		t.Fatalf("expected the head to be block %d, got %+v", chain[len(chain)-1].BlockNumber, head)
+	}
+	assertMatchesReference(t, chain, got)
+}
+
+func TestEngineReorgRecomputesFollowingBlocks(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	chain := randomChain(r, 10, 200, 0)
+
+	engine := NewEngine(testWindows)
+	got := make(map[uint64]Result)
+	results, err := engine.Apply(chain...)
+	collect(t, got, results, err)
+
+	// Replace a block in the middle with a different basefee, as a reorg or a
+	// confirmation with a corrected value would
+	reorged := append([]Sample(nil), chain...)
+	replaced := reorged[120]
+	replaced.BaseFee = new(big.Rat).Add(replaced.BaseFee, big.NewRat(7, 3))
+	replaced.IsConfirmed = true
+	reorged[120] = replaced
+
+	results, err = engine.Apply(replaced)
+	collect(t, got, results, err)
+	if len(results) != len(chain)-120 {
+		t.Fatalf("expected %d recomputed blocks, got %d", len(chain)-120, len(results))
+	}
+	assertMatchesReference(t, reorged, got)
+
+	// Re-applying the same block is a no-op
+	results, err = engine.Apply(replaced)
+	if err != nil || len(results) != 0 {
+		t.Fatalf("expected no results for an unchanged block, got %d (%v)", len(results), err)
+	}
+}
+
+func TestEngineReorgDropsAbandonedBranch(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	chain := randomChain(r, 0, 100, 0)
+
+	engine := NewEngine(testWindows)
+	got := make(map[uint64]Result)
+	results, err := engine.Apply(chain...)
+	collect(t, got, results, err)
+
+	// The new block 60 is later than the old block 61, so blocks after it
+	// belong to the abandoned branch
+	fork := randomChain(r, 60, 50, chain[len(chain)-1].Timestamp+1)
+	results, err = engine.Apply(fork[0])
+	collect(t, got, results, err)
+	if engine.Len() != 61 {
+		t.Fatalf("expected the branch to be cut at block 60, have %d blocks", engine.Len())
+	}
+	for _, block := range fork[1:] {
+		results, err := engine.Apply(block)
+		collect(t, got, results, err)
+	}
+
+	newChain := append(append([]Sample(nil), chain[:60]...), fork...)
+	assertMatchesReference(t, newChain, got)
+}
+
+func TestEngineHorizon(t *testing.T) {
+	engine := NewEngine([]Window{{Type: models.TwapWindowTwelveMin, Duration: 10}})
+	for i := uint64(0); i < 100; i++ {
+		if _, err := engine.Apply(Sample{BlockNumber: i, Timestamp: i * 2, BaseFee: big.NewRat(1, 1)}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if engine.Len() >= 100 {
+		t.Fatalf("expected old blocks to be pruned, have %d", engine.Len())
+	}
+	_, err := engine.Apply(Sample{BlockNumber: 1, Timestamp: 2, BaseFee: big.NewRat(2, 1)})
+	if !errors.Is(err, ErrBeyondHorizon) {
+		t.Fatalf("expected ErrBeyondHorizon, got %v", err)
+	}
+
+	_, err = engine.Apply(Sample{BlockNumber: 100, Timestamp: 1, BaseFee: big.NewRat(2, 1)})
+	if !errors.Is(err, ErrNonMonotonicTimestamp) {
+		t.Fatalf("expected ErrNonMonotonicTimestamp, got %v", err)
+	}
+}
+
+func TestEngineState(t *testing.T) {
+	engine := NewEngine(testWindows)
+	blocks := []Sample{
+		{BlockNumber: 1, Timestamp: 100, BaseFee: big.NewRat(10, 1), IsConfirmed: true},
+		{BlockNumber: 2, Timestamp: 110, BaseFee: big.NewRat(20, 1), IsConfirmed: true},
+		{BlockNumber: 3, Timestamp: 120, BaseFee: big.NewRat(30, 1)},
+	}
+	if _, err := engine.Apply(blocks...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	state, ok := engine.State(0, false)
+	if !ok {
+		t.Fatal("expected a state")
+	}
+	// (10*10 + 20*10) / 20
+	if state.TwapValue != "15.000000000" || state.WeightedSum != "300.000000000" || state.TotalSeconds.Uint64() != 20 {
+		t.Errorf("unexpected unconfirmed state %+v", state)
+	}
+	if state.LastBlockNumber != 3 || state.IsConfirmed {
+		t.Errorf("unexpected unconfirmed state %+v", state)
+	}
+
+	state, ok = engine.State(0, true)
+	if !ok {
+		t.Fatal("expected a confirmed state")
+	}
+	if state.TwapValue != "10.000000000" || state.LastBlockNumber != 2 || !state.IsConfirmed {
+		t.Errorf("unexpected confirmed state %+v", state)
+	}
+}
+
+func TestSampleFromBlock(t *testing.T) {
+	sample, err := SampleFromBlock(models.Block{BlockNumber: 5, Timestamp: 9, BaseFee: "12.500000000"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sample.BaseFee.Cmp(big.NewRat(25, 2)) != 0 {
+		t.Errorf("unexpected basefee %s", sample.BaseFee)
+	}
+	if _, err := SampleFromBlock(models.Block{BaseFee: "abc"}); err == nil {
+		t.Error("expected an error for an invalid basefee")
+	}
+}
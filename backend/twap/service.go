@@ -0,0 +1,223 @@
+package twap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"pitchlake-backend/db/repositories"
+	"pitchlake-backend/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type confirmedUpdate struct {
+	StartTimestamp uint64 `json:"start_timestamp"`
+	EndTimestamp   uint64 `json:"end_timestamp"`
+}
+
+// Service keeps the TWAP columns of the blocks table and the twap_state rows
+// up to date as blocks are inserted and confirmed
+type Service struct {
+	pool   *pgxpool.Pool
+	repo   *repositories.TwapRepository
+	engine *Engine
+	log    *log.Logger
+}
+
+// NewService creates a TWAP service using the default windows
+func NewService(pool *pgxpool.Pool, logger *log.Logger) *Service {
+	return &Service{
+		pool:   pool,
+		repo:   repositories.NewTwapRepository(pool),
+		engine: NewEngine(DefaultWindows),
+		log:    logger,
+	}
+}
+
+// Run seeds the engine from the database and processes block notifications
+// until the context is cancelled
+func (s *Service) Run(ctx context.Context) error {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to acquire listener connection: %w", err)
+	}
+	defer conn.Release()
+
+	// Listen before seeding so no block inserted in between is missed
+	for _, channel := range []string{"unconfirmed_insert", "confirmed_insert"} {
+		if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+			return fmt.Errorf("unable to listen on %s: %w", channel, err)
+		}
+	}
+
+	if err := s.seed(ctx, nil); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		var samples []Sample
+		switch notification.Channel {
+		case "unconfirmed_insert":
+			var block models.Block
+			if err := json.Unmarshal([]byte(notification.Payload), &block); err != nil {
+				s.log.Printf("Error parsing unconfirmed_insert payload: %v", err)
+				continue
+			}
+			sample, err := SampleFromBlock(block)
+			if err != nil {
+				s.log.Printf("Error parsing unconfirmed_insert block: %v", err)
+				continue
+			}
+			samples = []Sample{sample}
+		case "confirmed_insert":
+			var update confirmedUpdate
+			if err := json.Unmarshal([]byte(notification.Payload), &update); err != nil {
+				s.log.Printf("Error parsing confirmed_insert payload: %v", err)
+				continue
+			}
+			blocks, err := s.repo.GetBlocksInRange(ctx, update.StartTimestamp, update.EndTimestamp)
+			if err != nil {
+				s.log.Printf("Error loading confirmed blocks: %v", err)
+				continue
+			}
+			samples, err = samplesFromBlocks(blocks)
+			if err != nil {
+				s.log.Printf("Error parsing confirmed blocks: %v", err)
+				continue
+			}
+		default:
+			continue
+		}
+
+		if err := s.apply(ctx, samples); err != nil {
+			s.log.Printf("Error updating TWAPs: %v", err)
+		}
+	}
+}
+
+// apply feeds samples to the engine and persists the results, reseeding the
+// engine when the change is out of its reach
+func (s *Service) apply(ctx context.Context, samples []Sample) error {
+	results, err := s.engine.Apply(samples...)
+	if errors.Is(err, ErrBeyondHorizon) || errors.Is(err, ErrNonMonotonicTimestamp) {
+		s.log.Printf("Reseeding TWAP engine: %v", err)
+		return s.seed(ctx, samples)
+	}
+	if err != nil {
+		return err
+	}
+	return s.save(ctx, results)
+}
+
+// seed reloads the engine from the database. Without changed blocks it loads
+// enough history to extend the chain head, otherwise it loads enough to
+// recompute every block from the earliest changed one and saves the result.
+func (s *Service) seed(ctx context.Context, changed []Sample) error {
+	latest, err := s.repo.GetLatestBlock(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to load latest block: %w", err)
+	}
+	s.engine.Reset()
+	if latest == nil {
+		return nil
+	}
+
+	from := latest.BlockNumber
+	horizon := latest.Timestamp
+	for _, c := range changed {
+		if c.BlockNumber < from {
+			from = c.BlockNumber
+		}
+		if c.Timestamp < horizon {
+			horizon = c.Timestamp
+		}
+	}
+	var since uint64
+	if horizon > s.engine.retention {
+		since = horizon - s.engine.retention
+	}
+	blocks, err := s.repo.GetBlocksSince(ctx, since)
+	if err != nil {
+		return fmt.Errorf("unable to load blocks: %w", err)
+	}
+	samples, err := samplesFromBlocks(blocks)
+	if err != nil {
+		return err
+	}
+	truncated, err := s.repo.HasBlocksBefore(ctx, since)
+	if err != nil {
+		return fmt.Errorf("unable to check block history: %w", err)
+	}
+	if err := s.engine.Seed(samples, truncated); err != nil {
+		return err
+	}
+	s.log.Printf("TWAP engine seeded with %d blocks", s.engine.Len())
+	if len(changed) == 0 {
+		return s.saveStates(ctx, nil)
+	}
+	return s.save(ctx, s.engine.Recompute(from))
+}
+
+func (s *Service) save(ctx context.Context, results []Result) error {
+	if len(results) == 0 {
+		return nil
+	}
+	blocks := make([]repositories.BlockTwaps, 0, len(results))
+	for _, r := range results {
+		blocks = append(blocks, blockTwaps(s.engine.Windows(), r))
+	}
+	return s.saveStates(ctx, blocks)
+}
+
+func (s *Service) saveStates(ctx context.Context, blocks []repositories.BlockTwaps) error {
+	var states []models.TwapState
+	for w := range s.engine.Windows() {
+		for _, confirmed := range []bool{false, true} {
+			if state, ok := s.engine.State(w, confirmed); ok {
+				states = append(states, state)
+			}
+		}
+	}
+	if len(blocks) == 0 && len(states) == 0 {
+		return nil
+	}
+	return s.repo.SaveTwaps(ctx, blocks, states)
+}
+
+func blockTwaps(windows []Window, r Result) repositories.BlockTwaps {
+	b := repositories.BlockTwaps{BlockNumber: r.BlockNumber}
+	for w, window := range windows {
+		value := r.Values[w].Twap.FloatString(Precision)
+		switch window.Type {
+		case models.TwapWindowTwelveMin:
+			b.TwelveMinTwap = value
+		case models.TwapWindowThreeHour:
+			b.ThreeHourTwap = value
+		case models.TwapWindowThirtyDay:
+			b.ThirtyDayTwap = value
+		}
+	}
+	return b
+}
+
+func samplesFromBlocks(blocks []models.Block) ([]Sample, error) {
+	samples := make([]Sample, 0, len(blocks))
+	for _, b := range blocks {
+		sample, err := SampleFromBlock(b)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, sample)
+	}
+	return samples, nil
+}
@@ -0,0 +1,177 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pitchlake-backend/models"
+)
+
+// Store persists the delivery log, it is implemented by
+// repositories.WebhookRepository
+type Store interface {
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, id int64, responseStatus int) error
+	MarkFailed(ctx context.Context, id int64, responseStatus int, lastError string, nextAttemptAt time.Time) error
+	DeadLetter(ctx context.Context, id int64, responseStatus int, lastError string) error
+}
+
+// Dispatcher defaults
+const (
+	DefaultMaxAttempts  = 8
+	DefaultBaseDelay    = 10 * time.Second
+	DefaultMaxDelay     = time.Hour
+	DefaultPollInterval = 5 * time.Second
+	DefaultBatchSize    = 50
+	DefaultTimeout      = 10 * time.Second
+)
+
+// maxErrorBody bounds how much of a failed response is kept in the delivery log
+const maxErrorBody = 512
+
+// Dispatcher sends pending deliveries to their subscriptions, retrying failed
+// attempts with exponential backoff until MaxAttempts is reached, after which
+// the delivery is dead lettered
+type Dispatcher struct {
+	store        Store
+	client       *http.Client
+	log          *log.Logger
+	wake         chan struct{}
+	now          func() time.Time
+	MaxAttempts  int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	PollInterval time.Duration
+	BatchSize    int
+}
+
+// NewDispatcher creates a dispatcher with the default retry policy
+func NewDispatcher(store Store, logger *log.Logger) *Dispatcher {
+	return &Dispatcher{
+		store:        store,
+		client:       &http.Client{Timeout: DefaultTimeout},
+		log:          logger,
+		wake:         make(chan struct{}, 1),
+		now:          time.Now,
+		MaxAttempts:  DefaultMaxAttempts,
+		BaseDelay:    DefaultBaseDelay,
+		MaxDelay:     DefaultMaxDelay,
+		PollInterval: DefaultPollInterval,
+		BatchSize:    DefaultBatchSize,
+	}
+}
+
+// Wake makes the dispatcher check for due deliveries without waiting for the
+// next poll
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run dispatches due deliveries until the context is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := d.DispatchDue(ctx)
+			if err != nil {
+				d.log.Printf("Error dispatching webhooks: %v", err)
+				break
+			}
+			// A full batch may mean more deliveries are due
+			if n < d.BatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// DispatchDue attempts every due delivery once and returns how many were attempted
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	// Claimed deliveries are hidden from other dispatchers until every attempt
+	// of the batch could have timed out
+	lease := time.Duration(d.BatchSize)*d.client.Timeout + time.Minute
+	deliveries, err := d.store.ClaimDueDeliveries(ctx, d.BatchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+	for _, delivery := range deliveries {
+		if err := d.deliver(ctx, delivery); err != nil {
+			return 0, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// deliver makes a single attempt and records its outcome
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) error {
+	status, attemptErr := d.send(ctx, delivery)
+	if attemptErr == nil {
+		return d.store.MarkDelivered(ctx, delivery.ID, status)
+	}
+
+	attempts := delivery.Attempts + 1
+	if attempts >= d.MaxAttempts {
+		d.log.Printf("Webhook delivery %d to %s dead lettered after %d attempts: %v",
+			delivery.ID, delivery.URL, attempts, attemptErr)
+		return d.store.DeadLetter(ctx, delivery.ID, status, attemptErr.Error())
+	}
+	next := d.now().Add(d.Backoff(attempts))
+	return d.store.MarkFailed(ctx, delivery.ID, status, attemptErr.Error(), next)
+}
+
+// send posts the delivery payload, returning the response status code and an
+// error when the attempt did not succeed
+func (d *Dispatcher) send(ctx context.Context, delivery *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode, nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+}
+
+// Backoff returns the delay before the attempt following the given number of
+// failed attempts: BaseDelay doubled for every earlier failure, up to MaxDelay
+func (d *Dispatcher) Backoff(failedAttempts int) time.Duration {
+	delay := d.BaseDelay
+	for i := 1; i < failedAttempts; i++ {
+		delay *= 2
+		if delay >= d.MaxDelay {
+			return d.MaxDelay
+		}
+	}
+	return delay
+}
@@ -0,0 +1,142 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"pitchlake-backend/models"
+)
+
+// memoryStore is an in memory Store that hands out every pending delivery
+type memoryStore struct {
+	deliveries map[int64]*models.WebhookDelivery
+	dead       []int64
+}
+
+func (s *memoryStore) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	var due []*models.WebhookDelivery
+	for _, d := range s.deliveries {
+		if d.Status == "pending" && len(due) < limit {
+			copied := *d
+			due = append(due, &copied)
+		}
+	}
+	return due, nil
+}
+
+func (s *memoryStore) MarkDelivered(ctx context.Context, id int64, responseStatus int) error {
+	d := s.deliveries[id]
+	d.Status, d.Attempts, d.ResponseStatus = "delivered", d.Attempts+1, responseStatus
+	return nil
+}
+
+func (s *memoryStore) MarkFailed(ctx context.Context, id int64, responseStatus int, lastError string, nextAttemptAt time.Time) error {
+	d := s.deliveries[id]
+	d.Attempts, d.ResponseStatus, d.LastError, d.NextAttemptAt = d.Attempts+1, responseStatus, lastError, nextAttemptAt
+	return nil
+}
+
+func (s *memoryStore) DeadLetter(ctx context.Context, id int64, responseStatus int, lastError string) error {
+	d := s.deliveries[id]
+	d.Status, d.Attempts, d.ResponseStatus, d.LastError = "dead", d.Attempts+1, responseStatus, lastError
+	s.dead = append(s.dead, id)
+	return nil
+}
+
+func newTestDispatcher(store Store, now time.Time) *Dispatcher {
+	d := NewDispatcher(store, log.New(io.Discard, "", 0))
+	d.now = func() time.Time { return now }
+	return d
+}
+
+func TestDispatcherDeliversSignedPayload(t *testing.T) {
+	payload := []byte(`{"id":"round.deployed:0x1","type":"round.deployed"}`)
+	now := time.Unix(1700000000, 0)
+
+	var got *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	store := &memoryStore{deliveries: map[int64]*models.WebhookDelivery{
+		1: {ID: 1, URL: server.URL, Secret: "secret", EventType: EventRoundDeployed, Payload: payload, Status: "pending"},
+	}}
+	n, err := newTestDispatcher(store, now).DispatchDue(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("DispatchDue() = %d, %v", n, err)
+	}
+
+	if d := store.deliveries[1]; d.Status != "delivered" || d.Attempts != 1 || d.ResponseStatus != http.StatusNoContent {
+		t.Errorf("unexpected delivery %+v", d)
+	}
+	if string(body) != string(payload) {
+		t.Errorf("unexpected body %s", body)
+	}
+	timestamp, _ := strconv.ParseInt(got.Header.Get(TimestampHeader), 10, 64)
+	if timestamp != now.Unix() || !Verify("secret", got.Header.Get(SignatureHeader), timestamp, body) {
+		t.Errorf("invalid signature headers %v", got.Header)
+	}
+	if got.Header.Get(EventHeader) != EventRoundDeployed || got.Header.Get(DeliveryHeader) != "1" {
+		t.Errorf("unexpected event headers %v", got.Header)
+	}
+}
+
+func TestDispatcherRetriesThenDeadLetters(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	now := time.Unix(1700000000, 0)
+	store := &memoryStore{deliveries: map[int64]*models.WebhookDelivery{
+		1: {ID: 1, URL: server.URL, Secret: "secret", Payload: []byte(`{}`), Status: "pending"},
+	}}
+	d := newTestDispatcher(store, now)
+	d.MaxAttempts = 3
+
+	for attempt := 1; attempt < d.MaxAttempts; attempt++ {
+		if _, err := d.DispatchDue(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		delivery := store.deliveries[1]
+		if delivery.Status != "pending" || delivery.Attempts != attempt || delivery.ResponseStatus != http.StatusServiceUnavailable {
+			t.Fatalf("attempt %d: unexpected delivery %+v", attempt, delivery)
+		}
+		if want := now.Add(d.Backoff(attempt)); !delivery.NextAttemptAt.Equal(want) {
+			t.Errorf("attempt %d: next attempt at %v, want %v", attempt, delivery.NextAttemptAt, want)
+		}
+	}
+
+	if _, err := d.DispatchDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if delivery := store.deliveries[1]; delivery.Status != "dead" || delivery.Attempts != 3 || len(store.dead) != 1 {
+		t.Errorf("expected the delivery to be dead lettered, got %+v", delivery)
+	}
+	if store.deliveries[1].LastError == "" {
+		t.Error("expected the last error to be recorded")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(nil, log.Default())
+	d.BaseDelay = 10 * time.Second
+	d.MaxDelay = time.Minute
+
+	want := []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute}
+	for i, w := range want {
+		if got := d.Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
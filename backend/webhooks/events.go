@@ -0,0 +1,137 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"pitchlake-backend/auction"
+	"pitchlake-backend/models"
+)
+
+// Event types integrators can subscribe to
+const (
+	EventRoundDeployed  = "round.deployed"
+	EventAuctionStarted = "auction.started"
+	EventAuctionEnded   = "auction.ended"
+	EventRoundSettled   = "round.settled"
+	EventBidOutbid      = "bid.outbid"
+)
+
+// EventTypes lists every event type
+var EventTypes = []string{
+	EventRoundDeployed,
+	EventAuctionStarted,
+	EventAuctionEnded,
+	EventRoundSettled,
+	EventBidOutbid,
+}
+
+// IsEventType reports whether t is a known event type
+func IsEventType(t string) bool {
+	for _, eventType := range EventTypes {
+		if eventType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// roundStateEvents maps option round states to the event sent when a round
+// enters them
+var roundStateEvents = map[string]string{
+	"Auctioning": EventAuctionStarted,
+	"Running":    EventAuctionEnded,
+	"Settled":    EventRoundSettled,
+}
+
+// OutbidData is the data of a bid.outbid event
+type OutbidData struct {
+	RoundAddress  string `json:"roundAddress"`
+	RoundID       string `json:"roundId"`
+	BidID         string `json:"bidId"`
+	Amount        string `json:"amount"`
+	Price         string `json:"price"`
+	OutbidByBidID string `json:"outbidByBidId"`
+	OutbidByPrice string `json:"outbidByPrice"`
+	ClearingPrice string `json:"clearingPrice"`
+}
+
+// RoundEvents derives the lifecycle events of an or_update notification.
+// previousState is the last state seen for the round, empty when unknown. Event
+// ids are stable per round so that a notification seen twice, for instance
+// after a restart, is only delivered once.
+func RoundEvents(operation string, round models.OptionRound, previousState string, now time.Time) ([]models.WebhookEvent, error) {
+	var types []string
+	if operation == "insert" {
+		types = append(types, EventRoundDeployed)
+	}
+	if eventType, ok := roundStateEvents[round.RoundState]; ok && round.RoundState != previousState {
+		types = append(types, eventType)
+	}
+	if len(types) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(round)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]models.WebhookEvent, 0, len(types))
+	for _, eventType := range types {
+		events = append(events, models.WebhookEvent{
+			ID:           eventType + ":" + strings.ToLower(round.Address),
+			Type:         eventType,
+			VaultAddress: strings.ToLower(round.VaultAddress),
+			Data:         data,
+			CreatedAt:    now,
+		})
+	}
+	return events, nil
+}
+
+// OutbidEvents derives the bid.outbid events of the status changes caused by
+// a new or updated bid: every other bidder's bid that went from receiving
+// options to receiving none.
+func OutbidEvents(round models.OptionRound, changed auction.Bid, changes []auction.StatusChange, now time.Time) ([]models.WebhookEvent, error) {
+	var events []models.WebhookEvent
+	for _, c := range changes {
+		if c.Status != auction.BidStatusLosing || c.Previous == "" || c.Previous == auction.BidStatusLosing {
+			continue
+		}
+		if c.BidID == changed.ID || strings.EqualFold(c.Buyer, changed.Buyer) {
+			continue
+		}
+		data, err := json.Marshal(OutbidData{
+			RoundAddress:  round.Address,
+			RoundID:       orZero(round.RoundID.Int).String(),
+			BidID:         c.BidID,
+			Amount:        c.Amount,
+			Price:         c.Price,
+			OutbidByBidID: changed.ID,
+			OutbidByPrice: changed.Price.String(),
+			ClearingPrice: c.ClearingPrice,
+		})
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, models.WebhookEvent{
+			ID:             fmt.Sprintf("%s:%s:%s:%s", EventBidOutbid, c.BidID, changed.ID, changed.Price),
+			Type:           EventBidOutbid,
+			VaultAddress:   strings.ToLower(round.VaultAddress),
+			AccountAddress: strings.ToLower(c.Buyer),
+			Data:           data,
+			CreatedAt:      now,
+		})
+	}
+	return events, nil
+}
+
+func orZero(v *big.Int) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return v
+}
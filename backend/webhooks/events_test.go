@@ -0,0 +1,130 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"pitchlake-backend/auction"
+	"pitchlake-backend/models"
+)
+
+func testRound(state string) models.OptionRound {
+	return models.OptionRound{
+		VaultAddress:     "0xVAULT",
+		Address:          "0xROUND",
+		RoundID:          models.BigInt{Int: big.NewInt(3)},
+		AvailableOptions: models.BigInt{Int: big.NewInt(8)},
+		RoundState:       state,
+	}
+}
+
+func TestRoundEvents(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		state     string
+		previous  string
+		want      []string
+	}{
+		{name: "deployed", operation: "insert", state: "Open", want: []string{EventRoundDeployed}},
+		{name: "auction started", operation: "update", state: "Auctioning", previous: "Open", want: []string{EventAuctionStarted}},
+		{name: "auction ended", operation: "update", state: "Running", previous: "Auctioning", want: []string{EventAuctionEnded}},
+		{name: "settled", operation: "update", state: "Settled", previous: "Running", want: []string{EventRoundSettled}},
+		{name: "unknown previous state", operation: "update", state: "Running", want: []string{EventAuctionEnded}},
+		{name: "unchanged state", operation: "update", state: "Auctioning", previous: "Auctioning"},
+		{name: "open update", operation: "update", state: "Open", previous: "Open"},
+	}
+
+	now := time.Unix(1700000000, 0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := RoundEvents(tt.operation, testRound(tt.state), tt.previous, now)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(events) != len(tt.want) {
+				t.Fatalf("got %d events, want %d", len(events), len(tt.want))
+			}
+			for i, event := range events {
+				if event.Type != tt.want[i] {
+					t.Errorf("event %d type = %s, want %s", i, event.Type, tt.want[i])
+				}
+				if event.ID != tt.want[i]+":0xround" || event.VaultAddress != "0xvault" {
+					t.Errorf("unexpected event id %q or vault %q", event.ID, event.VaultAddress)
+				}
+				var round map[string]any
+				if err := json.Unmarshal(event.Data, &round); err != nil || round["roundState"] != tt.state {
+					t.Errorf("unexpected event data %s", event.Data)
+				}
+			}
+		})
+	}
+}
+
+func TestOutbidEvents(t *testing.T) {
+	round := testRound("Auctioning")
+	now := time.Unix(1700000000, 0)
+
+	// a and b fill the 8 options, c at a higher price pushes a out entirely
+	book := auction.NewBook()
+	book.Load("0xround", round.AvailableOptions.Int, []auction.Bid{
+		{ID: "a", Buyer: "0xALICE", Nonce: 0, Amount: big.NewInt(4), Price: big.NewInt(10)},
+		{ID: "b", Buyer: "0xBOB", Nonce: 1, Amount: big.NewInt(4), Price: big.NewInt(20)},
+	})
+	c := auction.Bid{ID: "c", Buyer: "0xCAROL", Nonce: 2, Amount: big.NewInt(4), Price: big.NewInt(30)}
+	events, err := OutbidEvents(round, c, book.Apply("0xround", c), now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	event := events[0]
+	if event.Type != EventBidOutbid || event.AccountAddress != "0xalice" || event.VaultAddress != "0xvault" {
+		t.Errorf("unexpected event %+v", event)
+	}
+	var data OutbidData
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.BidID != "a" || data.OutbidByBidID != "c" || data.ClearingPrice != "20" || data.RoundID != "3" {
+		t.Errorf("unexpected outbid data %+v", data)
+	}
+
+	tests := []struct {
+		name    string
+		changed auction.Bid
+		changes []auction.StatusChange
+	}{
+		{
+			name:    "partial displacement",
+			changed: c,
+			changes: []auction.StatusChange{{BidID: "a", Buyer: "0xALICE", Previous: auction.BidStatusWinning, Status: auction.BidStatusPartial}},
+		},
+		{
+			name:    "outbidding yourself",
+			changed: auction.Bid{ID: "c", Buyer: "0xalice", Price: big.NewInt(30)},
+			changes: []auction.StatusChange{{BidID: "a", Buyer: "0xALICE", Previous: auction.BidStatusWinning, Status: auction.BidStatusLosing}},
+		},
+		{
+			name:    "new losing bid",
+			changed: c,
+			changes: []auction.StatusChange{{BidID: "c", Buyer: "0xCAROL", Status: auction.BidStatusLosing}},
+		},
+		{
+			name:    "bid back to winning",
+			changed: c,
+			changes: []auction.StatusChange{{BidID: "a", Buyer: "0xALICE", Previous: auction.BidStatusLosing, Status: auction.BidStatusWinning}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := OutbidEvents(round, tt.changed, tt.changes, now)
+			if err != nil || len(events) != 0 {
+				t.Errorf("expected no events, got %+v (%v)", events, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"pitchlake-backend/auction"
+	"pitchlake-backend/db/repositories"
+	"pitchlake-backend/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// notification mirrors the payload of the or_update and bids_update channels
+type notification[T any] struct {
+	Operation string `json:"operation"`
+	Payload   T      `json:"payload"`
+}
+
+// Service turns the option round and bid notifications into webhook events,
+// stores a delivery for every matching subscription and dispatches them
+type Service struct {
+	pool       *pgxpool.Pool
+	repo       *repositories.WebhookRepository
+	tracker    *auction.Tracker
+	dispatcher *Dispatcher
+	// roundStates is the last state seen for each round, so that lifecycle
+	// events are only derived when the state changes
+	roundStates map[string]string
+	log         *log.Logger
+}
+
+// NewService creates a webhook service with the default retry policy
+func NewService(pool *pgxpool.Pool, logger *log.Logger) *Service {
+	repo := repositories.NewWebhookRepository(pool)
+	return &Service{
+		pool: pool,
+		repo: repo,
+		tracker: auction.NewTracker(
+			repositories.NewOptionRepository(pool),
+			repositories.NewBidRepository(pool),
+		),
+		dispatcher:  NewDispatcher(repo, logger),
+		roundStates: make(map[string]string),
+		log:         logger,
+	}
+}
+
+// Run dispatches deliveries and processes notifications until the context is
+// cancelled
+func (s *Service) Run(ctx context.Context) error {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to acquire listener connection: %w", err)
+	}
+	defer conn.Release()
+
+	for _, channel := range []string{"or_update", "bids_update"} {
+		if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+			return fmt.Errorf("unable to listen on %s: %w", channel, err)
+		}
+	}
+
+	go s.dispatcher.Run(ctx)
+
+	for {
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		events, err := s.events(ctx, n.Channel, []byte(n.Payload))
+		if err != nil {
+			s.log.Printf("Error deriving webhook events from %s: %v", n.Channel, err)
+			continue
+		}
+		if s.enqueue(ctx, events) > 0 {
+			s.dispatcher.Wake()
+		}
+	}
+}
+
+func (s *Service) events(ctx context.Context, channel string, payload []byte) ([]models.WebhookEvent, error) {
+	switch channel {
+	case "or_update":
+		var n notification[models.OptionRound]
+		if err := json.Unmarshal(payload, &n); err != nil {
+			return nil, err
+		}
+		address := strings.ToLower(n.Payload.Address)
+		previous := s.roundStates[address]
+		s.roundStates[address] = n.Payload.RoundState
+		s.tracker.UpdateRound(&n.Payload)
+		return RoundEvents(n.Operation, n.Payload, previous, time.Now())
+	case "bids_update":
+		var n notification[models.Bid]
+		if err := json.Unmarshal(payload, &n); err != nil {
+			return nil, err
+		}
+		return s.outbidEvents(ctx, &n.Payload)
+	}
+	return nil, nil
+}
+
+func (s *Service) outbidEvents(ctx context.Context, changed *models.Bid) ([]models.WebhookEvent, error) {
+	round, changes, err := s.tracker.ApplyBid(ctx, changed)
+	if err != nil || len(changes) == 0 {
+		return nil, err
+	}
+	bid, err := auction.BidFromModel(changed)
+	if err != nil {
+		return nil, err
+	}
+	return OutbidEvents(*round, bid, changes, time.Now())
+}
+
+// enqueue stores the deliveries of each event and returns how many were created
+func (s *Service) enqueue(ctx context.Context, events []models.WebhookEvent) int64 {
+	var total int64
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			s.log.Printf("Error encoding webhook event %s: %v", event.ID, err)
+			continue
+		}
+		n, err := s.repo.EnqueueEvent(ctx, event, payload)
+		if err != nil {
+			s.log.Printf("Error enqueuing webhook event %s: %v", event.ID, err)
+			continue
+		}
+		total += n
+	}
+	return total
+}
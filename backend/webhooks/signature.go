@@ -0,0 +1,44 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Headers sent with every delivery
+const (
+	SignatureHeader = "X-Pitchlake-Signature"
+	TimestampHeader = "X-Pitchlake-Timestamp"
+	EventHeader     = "X-Pitchlake-Event"
+	DeliveryHeader  = "X-Pitchlake-Delivery"
+)
+
+const signaturePrefix = "sha256="
+
+// Sign returns the signature header value of a delivery body: the hex encoded
+// HMAC-SHA256 of "<timestamp>.<body>" keyed by the subscription secret.
+// Including the timestamp lets receivers reject replayed deliveries.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature header value in constant time
+func Verify(secret, signature string, timestamp int64, body []byte) bool {
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
+
+// NewSecret generates a random signing secret for a subscription
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,36 @@
+package webhooks
+
+import "testing"
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"id":"round.deployed:0x1"}`)
+	signature := Sign("secret", 1700000000, body)
+
+	if !Verify("secret", signature, 1700000000, body) {
+		t.Error("expected the signature to verify")
+	}
+	if Verify("other", signature, 1700000000, body) {
+		t.Error("expected a different secret to fail")
+	}
+	if Verify("secret", signature, 1700000001, body) {
+		t.Error("expected a different timestamp to fail")
+	}
+	if Verify("secret", signature, 1700000000, []byte(`{}`)) {
+		t.Error("expected a different body to fail")
+	}
+	// Signatures are a prefixed hex encoded SHA-256 digest
+	if got := Sign("secret", 1, []byte("{}")); got[:len(signaturePrefix)] != signaturePrefix || len(got) != len(signaturePrefix)+64 {
+		t.Errorf("unexpected signature format %q", got)
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	a, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewSecret()
+	if len(a) != 64 || a == b {
+		t.Errorf("expected distinct 32 byte hex secrets, got %q and %q", a, b)
+	}
+}
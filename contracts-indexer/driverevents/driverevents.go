@@ -0,0 +1,163 @@
+// Package driverevents is the contract between the event-logger, which writes
+// rows to the driver_events table, and the event-processor, which applies
+// them. Both sides use the constants and validation in this package so that a
+// type or payload change cannot drift silently between them.
+package driverevents
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Version is the payload version written by this package. It is stored in the
+// driver_events.version column and sent in every NOTIFY payload, and must be
+// bumped whenever the meaning or the fields of an event change.
+const Version = 1
+
+// Channel is the PostgreSQL NOTIFY channel driver events are sent on
+const Channel = "driver_events"
+
+// Type is the kind of a driver event
+type Type string
+
+const (
+	// TypeStartBlock is emitted once the events of a new block are stored
+	TypeStartBlock Type = "StartBlock"
+	// TypeRevertBlock is emitted for every block undone by a reorg
+	TypeRevertBlock Type = "RevertBlock"
+	// TypeCatchupVault is emitted once the past events of a vault are stored
+	TypeCatchupVault Type = "CatchupVault"
+)
+
+// Types lists every known driver event type
+var Types = []Type{TypeStartBlock, TypeRevertBlock, TypeCatchupVault}
+
+var (
+	// ErrUnknownType is returned for a driver event type this version does not know
+	ErrUnknownType = errors.New("unknown driver event type")
+	// ErrUnsupportedVersion is returned for a payload written by another version
+	ErrUnsupportedVersion = errors.New("unsupported driver event version")
+	// ErrMissingField is returned when a field required by the event type is empty
+	ErrMissingField = errors.New("missing driver event field")
+)
+
+// Valid reports whether t is a known driver event type
+func (t Type) Valid() bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is a row of the driver_events table and the NOTIFY payload sent when
+// it is inserted. Block events carry BlockHash, vault catchups carry
+// VaultAddress and the StartBlockHash..EndBlockHash range.
+type Event struct {
+	ID             int       `json:"id"`
+	Version        int       `json:"version"`
+	SequenceIndex  int64     `json:"sequence_index"`
+	Type           Type      `json:"type"`
+	Timestamp      time.Time `json:"timestamp"`
+	IsProcessed    bool      `json:"is_processed"`
+	BlockHash      string    `json:"block_hash,omitempty"`
+	StartBlockHash string    `json:"start_block_hash,omitempty"`
+	EndBlockHash   string    `json:"end_block_hash,omitempty"`
+	VaultAddress   string    `json:"vault_address,omitempty"`
+}
+
+// UnmarshalJSON decodes a NOTIFY payload, where the columns that do not apply
+// to the event type are null
+func (e *Event) UnmarshalJSON(data []byte) error {
+	aux := struct {
+		ID             int       `json:"id"`
+		Version        int       `json:"version"`
+		SequenceIndex  int64     `json:"sequence_index"`
+		Type           Type      `json:"type"`
+		Timestamp      time.Time `json:"timestamp"`
+		IsProcessed    *bool     `json:"is_processed"`
+		BlockHash      *string   `json:"block_hash"`
+		StartBlockHash *string   `json:"start_block_hash"`
+		EndBlockHash   *string   `json:"end_block_hash"`
+		VaultAddress   *string   `json:"vault_address"`
+	}{}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	e.ID = aux.ID
+	e.Version = aux.Version
+	e.SequenceIndex = aux.SequenceIndex
+	e.Type = aux.Type
+	e.Timestamp = aux.Timestamp
+	e.IsProcessed = aux.IsProcessed != nil && *aux.IsProcessed
+	e.BlockHash = deref(aux.BlockHash)
+	e.StartBlockHash = deref(aux.StartBlockHash)
+	e.EndBlockHash = deref(aux.EndBlockHash)
+	e.VaultAddress = deref(aux.VaultAddress)
+	return nil
+}
+
+// Validate checks that the event was written with this contract version, has a
+// known type and carries the fields its type requires
+func (e *Event) Validate() error {
+	if e.Version != Version {
+		return fmt.Errorf("%w: %d (driver event %d), expected %d", ErrUnsupportedVersion, e.Version, e.ID, Version)
+	}
+	switch e.Type {
+	case TypeStartBlock, TypeRevertBlock:
+		if e.BlockHash == "" {
+			return fmt.Errorf("%w: %s event %d has no block_hash", ErrMissingField, e.Type, e.ID)
+		}
+	case TypeCatchupVault:
+		if e.VaultAddress == "" || e.StartBlockHash == "" || e.EndBlockHash == "" {
+			return fmt.Errorf("%w: %s event %d needs vault_address, start_block_hash and end_block_hash", ErrMissingField, e.Type, e.ID)
+		}
+	default:
+		return fmt.Errorf("%w: %q (driver event %d)", ErrUnknownType, e.Type, e.ID)
+	}
+	return nil
+}
+
+// Parse decodes and validates a NOTIFY payload
+func Parse(payload []byte) (Event, error) {
+	var e Event
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return Event{}, fmt.Errorf("unable to decode driver event: %w", err)
+	}
+	if err := e.Validate(); err != nil {
+		return Event{}, err
+	}
+	return e, nil
+}
+
+// NewBlockEvent builds a StartBlock or RevertBlock event, rejecting any other type
+func NewBlockEvent(t Type, blockHash string) (Event, error) {
+	if t != TypeStartBlock && t != TypeRevertBlock {
+		return Event{}, fmt.Errorf("%w: %q is not a block event", ErrUnknownType, t)
+	}
+	e := Event{Version: Version, Type: t, BlockHash: blockHash}
+	return e, e.Validate()
+}
+
+// NewCatchupVaultEvent builds a CatchupVault event
+func NewCatchupVaultEvent(vaultAddress, startBlockHash, endBlockHash string) (Event, error) {
+	e := Event{
+		Version:        Version,
+		Type:           TypeCatchupVault,
+		VaultAddress:   vaultAddress,
+		StartBlockHash: startBlockHash,
+		EndBlockHash:   endBlockHash,
+	}
+	return e, e.Validate()
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,104 @@
+package driverevents
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    Event
+		err     error
+	}{
+		{
+			name:    "start block",
+			payload: `{"id":1,"version":1,"sequence_index":7,"type":"StartBlock","timestamp":"2025-01-02T03:04:05.123456+00:00","is_processed":false,"block_hash":"0xabc","start_block_hash":null,"end_block_hash":null,"vault_address":null}`,
+			want:    Event{ID: 1, Version: 1, SequenceIndex: 7, Type: TypeStartBlock, BlockHash: "0xabc"},
+		},
+		{
+			name:    "catchup vault",
+			payload: `{"id":2,"version":1,"sequence_index":8,"type":"CatchupVault","timestamp":"2025-01-02T03:04:05+00:00","is_processed":null,"block_hash":null,"start_block_hash":"0x1","end_block_hash":"0x2","vault_address":"0x7a"}`,
+			want:    Event{ID: 2, Version: 1, SequenceIndex: 8, Type: TypeCatchupVault, StartBlockHash: "0x1", EndBlockHash: "0x2", VaultAddress: "0x7a"},
+		},
+		{
+			name:    "legacy new block type",
+			payload: `{"id":3,"version":1,"type":"NewBlock","block_hash":"0xabc"}`,
+			err:     ErrUnknownType,
+		},
+		{
+			name:    "payload without version",
+			payload: `{"id":4,"type":"StartBlock","block_hash":"0xabc"}`,
+			err:     ErrUnsupportedVersion,
+		},
+		{
+			name:    "newer version",
+			payload: `{"id":5,"version":2,"type":"StartBlock","block_hash":"0xabc"}`,
+			err:     ErrUnsupportedVersion,
+		},
+		{
+			name:    "revert without block hash",
+			payload: `{"id":6,"version":1,"type":"RevertBlock","block_hash":null}`,
+			err:     ErrMissingField,
+		},
+		{
+			name:    "catchup without range",
+			payload: `{"id":7,"version":1,"type":"CatchupVault","vault_address":"0x7a"}`,
+			err:     ErrMissingField,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.payload))
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Parse() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			got.Timestamp = tt.want.Timestamp
+			if got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewBlockEvent(t *testing.T) {
+	for _, typ := range []Type{TypeStartBlock, TypeRevertBlock} {
+		e, err := NewBlockEvent(typ, "0xabc")
+		if err != nil {
+			t.Fatalf("NewBlockEvent(%s) unexpected error: %v", typ, err)
+		}
+		if e.Version != Version || e.Type != typ {
+			t.Errorf("NewBlockEvent(%s) = %+v", typ, e)
+		}
+	}
+	if _, err := NewBlockEvent(TypeCatchupVault, "0xabc"); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("NewBlockEvent(CatchupVault) error = %v, want %v", err, ErrUnknownType)
+	}
+	if _, err := NewBlockEvent("NewBlock", "0xabc"); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("NewBlockEvent(NewBlock) error = %v, want %v", err, ErrUnknownType)
+	}
+	if _, err := NewBlockEvent(TypeStartBlock, ""); !errors.Is(err, ErrMissingField) {
+		t.Errorf("NewBlockEvent without hash error = %v, want %v", err, ErrMissingField)
+	}
+}
+
+func TestNewCatchupVaultEvent(t *testing.T) {
+	e, err := NewCatchupVaultEvent("0x7a", "0x1", "0x2")
+	if err != nil {
+		t.Fatalf("NewCatchupVaultEvent unexpected error: %v", err)
+	}
+	if e.Version != Version || e.Type != TypeCatchupVault {
+		t.Errorf("NewCatchupVaultEvent = %+v", e)
+	}
+	if _, err := NewCatchupVaultEvent("0x7a", "", "0x2"); !errors.Is(err, ErrMissingField) {
+		t.Errorf("NewCatchupVaultEvent without start error = %v, want %v", err, ErrMissingField)
+	}
+}
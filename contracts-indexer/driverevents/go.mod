@@ -0,0 +1,3 @@
+module driverevents
+
+go 1.23
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"driverevents"
+	"fmt"
+	"junoplugin/db"
+	"junoplugin/plugin/block"
+	"junoplugin/plugin/vault"
+	"junoplugin/utils"
+	"text/tabwriter"
+	"time"
+)
+
+// reindex reverts the stored blocks from a block up, with their events and a
+// RevertBlock driver event each, and moves the vault cursors back so that the
+// indexer refills the range from RPC when it starts again. The vaults deployed
+// in the range are initialized again. It must not run along the indexer,
+// which would keep indexing on top of the reverted blocks.
+func (a *admin) reindex(args []string) error {
+	fs := newFlagSet("reindex")
+	from := fs.Uint64("from", 0, "first block to reindex, above 0")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *from == 0 {
+		return fmt.Errorf("-from must be above 0")
+	}
+
+	if err := a.connectNetwork(); err != nil {
+		return err
+	}
+	blocks, err := a.network.GetBlocks(*from, *from)
+	if err != nil {
+		return fmt.Errorf("failed to get block %d: %w", *from, err)
+	}
+	if len(blocks) == 0 {
+		return fmt.Errorf("block %d not found", *from)
+	}
+	parentHash := blocks[0].ParentHash
+
+	// The new cursor of each vault to move, nil for the vaults to initialize
+	// again
+	vaults, err := a.db.GetVaultRegistry()
+	if err != nil {
+		return fmt.Errorf("failed to get vault registry: %w", err)
+	}
+	cursors := make(map[string]*string)
+	for _, v := range vaults {
+		deployed, err := a.blockNumber(v.DeployedAt)
+		if err != nil {
+			return fmt.Errorf("failed to resolve the deploy block of vault %s: %w", v.Address, err)
+		}
+		if deployed >= *from {
+			cursors[v.Address] = nil
+			continue
+		}
+		if v.LastBlockIndexed == nil {
+			continue
+		}
+		cursor, err := a.blockNumber(*v.LastBlockIndexed)
+		if err != nil {
+			return fmt.Errorf("failed to resolve the cursor of vault %s: %w", v.Address, err)
+		}
+		if cursor >= *from {
+			cursors[v.Address] = &parentHash
+		}
+	}
+
+	var reverted int
+	if err := a.db.InTx(func(tx db.Tx) error {
+		reverted, err = revertFrom(tx, *from, cursors)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "Reverted %d blocks from block %d\n", reverted, *from)
+	for address, cursor := range cursors {
+		if cursor == nil {
+			fmt.Fprintf(a.out, "Vault %s will be initialized again\n", address)
+		} else {
+			fmt.Fprintf(a.out, "Vault %s rewound to %s\n", address, *cursor)
+		}
+	}
+	fmt.Fprintln(a.out, "Start the indexer to index the blocks again")
+	return nil
+}
+
+// revertFrom reverts the stored blocks from a block up, highest first, and
+// sets the vault cursors in tx
+func revertFrom(tx db.Tx, from uint64, cursors map[string]*string) (int, error) {
+	stored, err := tx.GetBlocksFrom(from)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get blocks from %d: %w", from, err)
+	}
+	for _, b := range stored {
+		if err := tx.RevertBlock(b.BlockNumber, b.BlockHash); err != nil {
+			return 0, fmt.Errorf("failed to revert block %d: %w", b.BlockNumber, err)
+		}
+		if _, err := tx.RevertEvents(b.BlockHash); err != nil {
+			return 0, fmt.Errorf("failed to revert the events of block %d: %w", b.BlockNumber, err)
+		}
+		if err := tx.StoreDriverEvent(driverevents.TypeRevertBlock, b.BlockHash); err != nil {
+			return 0, fmt.Errorf("failed to store the revert of block %d: %w", b.BlockNumber, err)
+		}
+	}
+	for address, cursor := range cursors {
+		if err := tx.SetVaultCursor(address, cursor); err != nil {
+			return 0, fmt.Errorf("failed to move the cursor of vault %s: %w", address, err)
+		}
+	}
+	return len(stored), nil
+}
+
+// blockNumber returns the number of a block from the stored blocks, or from
+// RPC when it is not stored
+func (a *admin) blockNumber(hash string) (uint64, error) {
+	stored, err := a.db.GetBlock(hash)
+	if err != nil {
+		return 0, err
+	}
+	if stored != nil {
+		return stored.BlockNumber, nil
+	}
+	b, err := a.network.GetBlockByHash(hash)
+	if err != nil {
+		return 0, err
+	}
+	return b.Number, nil
+}
+
+// events prints the first driver events the event-processor has not applied
+func (a *admin) events(args []string) error {
+	fs := newFlagSet("events")
+	limit := fs.Int("limit", 50, "number of events to show")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *limit <= 0 {
+		return fmt.Errorf("-limit must be above 0")
+	}
+	count, err := a.db.CountUnprocessedDriverEvents()
+	if err != nil {
+		return fmt.Errorf("failed to count driver events: %w", err)
+	}
+	pending, err := a.db.GetUnprocessedDriverEvents(*limit)
+	if err != nil {
+		return fmt.Errorf("failed to get driver events: %w", err)
+	}
+
+	fmt.Fprintf(a.out, "%d unprocessed driver events\n", count)
+	if len(pending) == 0 {
+		return nil
+	}
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SEQUENCE\tTYPE\tTIMESTAMP\tBLOCK\tVAULT")
+	for _, event := range pending {
+		blocks := event.BlockHash
+		if event.Type == driverevents.TypeCatchupVault {
+			blocks = event.StartBlockHash + ".." + event.EndBlockHash
+		}
+		vaultAddress := event.VaultAddress
+		if vaultAddress == "" {
+			vaultAddress = "-"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n",
+			event.SequenceIndex, event.Type, event.Timestamp.Format(time.RFC3339), blocks, vaultAddress)
+	}
+	return w.Flush()
+}
+
+// verify checks that the stored blocks form a chain. With -repair the breaks
+// are repaired from RPC like at startup, which must not run along the indexer.
+func (a *admin) verify(args []string) error {
+	fs := newFlagSet("verify")
+	from := fs.Uint64("from", 0, "first block to verify")
+	to := fs.Uint64("to", 0, "last block to verify, 0 for the last stored block")
+	repair := fs.Bool("repair", false, "repair the breaks from RPC")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	lastBlock, err := a.db.GetLastBlock()
+	if err != nil {
+		return fmt.Errorf("failed to get last block: %w", err)
+	}
+	var report *block.VerifyReport
+	if *repair {
+		if err := a.connectNetwork(); err != nil {
+			return err
+		}
+		// The repaired blocks index the events of the tracked vaults
+		if err := utils.LoadABIs(a.cfg.VaultABIPath, a.cfg.OptionRoundABIPath); err != nil {
+			return err
+		}
+		vaultManager := vault.NewManager(a.db, a.network, a.cfg.UDCAddress, a.cfg.VaultClassHashes, a.cfg.EventNames)
+		if err := vaultManager.TrackRegistry(); err != nil {
+			return err
+		}
+		processor := block.NewProcessor(a.db, a.network, vaultManager, lastBlock, a.cfg.Cursor)
+		report, err = processor.VerifyChain(*from, *to)
+	} else {
+		processor := block.NewProcessor(a.db, nil, nil, lastBlock, a.cfg.Cursor)
+		report, err = processor.CheckChain(*from, *to)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to verify blocks: %w", err)
+	}
+
+	fmt.Fprintf(a.out, "Verified blocks %d to %d: %d issues\n", report.FromBlock, report.ToBlock, len(report.Issues))
+	for _, issue := range report.Issues {
+		fmt.Fprintf(a.out, "  block %d: %s %s\n", issue.BlockNumber, issue.Kind, issue.BlockHash)
+	}
+	if *repair {
+		fmt.Fprintf(a.out, "Repaired blocks %v, reverted %v\n", report.Repaired, report.Reverted)
+	}
+	return nil
+}
+
+// backfill reads from RPC the position in their receipt of the events stored
+// before it was recorded. The blocks reorged out since are left as they are,
+// their events are reverted.
+func (a *admin) backfill(args []string) error {
+	fs := newFlagSet("backfill")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	blocks, err := a.db.GetLegacyEventBlocks()
+	if err != nil {
+		return fmt.Errorf("failed to get the blocks to backfill: %w", err)
+	}
+	if len(blocks) == 0 {
+		fmt.Fprintln(a.out, "No events to backfill")
+		return nil
+	}
+	if err := a.connectNetwork(); err != nil {
+		return err
+	}
+
+	var backfilled int
+	for _, stored := range blocks {
+		b, err := a.network.GetBlockWithEvents(stored.BlockNumber)
+		if err != nil {
+			return fmt.Errorf("failed to get block %d: %w", stored.BlockNumber, err)
+		}
+		if b.Hash.String() != stored.BlockHash {
+			fmt.Fprintf(a.out, "Block %d %s is no longer on the chain, skipped\n", stored.BlockNumber, stored.BlockHash)
+			continue
+		}
+		for _, receipt := range b.Receipts {
+			for _, event := range receipt.Events {
+				ok, err := a.db.SetLegacyEventIndex(stored.BlockHash, receipt.TransactionHash.String(), event.From.String(),
+					event.Index, utils.FeltArrayToStringArrays(event.Keys), utils.FeltArrayToStringArrays(event.Data))
+				if err != nil {
+					return err
+				}
+				if ok {
+					backfilled++
+				}
+			}
+		}
+	}
+	fmt.Fprintf(a.out, "Backfilled %d events of %d blocks\n", backfilled, len(blocks))
+
+	left, err := a.db.GetLegacyEventBlocks()
+	if err != nil {
+		return fmt.Errorf("failed to get the blocks left to backfill: %w", err)
+	}
+	for _, stored := range left {
+		fmt.Fprintf(a.out, "  block %d %s still has events without an index\n", stored.BlockNumber, stored.BlockHash)
+	}
+	return nil
+}
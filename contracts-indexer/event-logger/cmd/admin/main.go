@@ -0,0 +1,161 @@
+// Command admin operates the Pitchlake event logger from the command line. It
+// registers, pauses and resumes vaults, reindexes the chain from a block and
+// inspects the tables of the logger. It reads the configuration of the plugin.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"junoplugin/db"
+	"junoplugin/network"
+	"junoplugin/plugin/config"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/NethermindEth/juno/core/felt"
+)
+
+// command is an admin subcommand, run with the arguments that follow its name
+type command struct {
+	name    string
+	summary string
+	run     func(a *admin, args []string) error
+}
+
+var commands = []command{
+	{"register", "register a vault deployed at a block, the running indexer initializes it", (*admin).register},
+	{"list", "list the registered vaults with their cursor, lag and errors", (*admin).list},
+	{"pause", "stop indexing a vault", (*admin).pause},
+	{"resume", "index a paused vault again from its cursor", (*admin).resume},
+	{"reindex", "revert the stored blocks from a block so that they are indexed again, the indexer must be stopped", (*admin).reindex},
+	{"events", "show the driver events the event-processor has not applied", (*admin).events},
+	{"verify", "check the stored blocks against the chain and optionally repair them", (*admin).verify},
+	{"backfill", "read from RPC the receipt position of the events stored without it", (*admin).backfill},
+}
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	cmd, ok := findCommand(os.Args[1])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	a, err := newAdmin()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer a.db.Shutdown()
+	if err := cmd.run(a, os.Args[2:]); err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(2)
+		}
+		log.Fatalf("%s: %v", cmd.name, err)
+	}
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: admin <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run admin <command> -h for the flags of a command.")
+}
+
+// admin holds the clients of the commands. The network is only connected by
+// the commands that read the chain.
+type admin struct {
+	cfg     *config.Config
+	db      *db.DB
+	network *network.Network
+	out     io.Writer
+}
+
+func newAdmin() (*admin, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	dbClient, err := db.Init(cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	return &admin{cfg: cfg, db: dbClient, out: os.Stdout}, nil
+}
+
+// connectNetwork connects to the RPC node of the configuration
+func (a *admin) connectNetwork() error {
+	if a.network != nil {
+		return nil
+	}
+	networkClient, err := network.NewNetwork(a.cfg.RPCURL, network.Config{
+		Events: network.EventsConfig{
+			ChunkSize:  a.cfg.EventsChunkSize,
+			MaxRetries: a.cfg.EventsMaxRetries,
+		},
+		RateLimit: a.cfg.RPCRateLimit,
+		Timeout:   a.cfg.RPCTimeout,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize network: %w", err)
+	}
+	a.network = networkClient
+	return nil
+}
+
+// newFlagSet returns the flag set of a command, reporting its errors to the
+// caller
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+// isSet reports whether the flag name was given on the command line
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// parseAddress validates a 0x prefixed address and returns it without leading
+// zeros, the form the indexer registers vaults with
+func parseAddress(value string) (string, error) {
+	if value == "" {
+		return "", fmt.Errorf("-vault is required")
+	}
+	if !strings.HasPrefix(value, "0x") {
+		return "", fmt.Errorf("%s is not a 0x prefixed address", value)
+	}
+	f, err := new(felt.Felt).SetString(value)
+	if err != nil {
+		return "", fmt.Errorf("%s is not an address: %w", value, err)
+	}
+	return f.String(), nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"junoplugin/db"
+	"junoplugin/models"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// register registers a vault with its deploy block and no cursor. The vault
+// registry listener of the running indexer initializes it from that block.
+func (a *admin) register(args []string) error {
+	fs := newFlagSet("register")
+	vault := fs.String("vault", "", "address of the vault")
+	deployBlock := fs.Uint64("block", 0, "number of the block the vault was deployed in")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	address, err := parseAddress(*vault)
+	if err != nil {
+		return err
+	}
+	if !isSet(fs, "block") {
+		return fmt.Errorf("-block is required")
+	}
+
+	if err := a.connectNetwork(); err != nil {
+		return err
+	}
+	blocks, err := a.network.GetBlocks(*deployBlock, *deployBlock)
+	if err != nil {
+		return fmt.Errorf("failed to get block %d: %w", *deployBlock, err)
+	}
+	if len(blocks) == 0 {
+		return fmt.Errorf("block %d not found", *deployBlock)
+	}
+
+	registered := &models.VaultRegistry{Address: address, DeployedAt: blocks[0].BlockHash}
+	if err := a.db.InTx(func(tx db.Tx) error {
+		return tx.InsertVault(registered)
+	}); err != nil {
+		return fmt.Errorf("failed to register vault %s: %w", address, err)
+	}
+	fmt.Fprintf(a.out, "Registered vault %s deployed at block %d %s\n", address, *deployBlock, blocks[0].BlockHash)
+	return nil
+}
+
+// list prints the registered vaults. The lag of a vault is the number of
+// stored blocks above its cursor.
+func (a *admin) list(args []string) error {
+	if err := newFlagSet("list").Parse(args); err != nil {
+		return err
+	}
+	statuses, err := a.db.GetVaultStatuses()
+	if err != nil {
+		return fmt.Errorf("failed to get vault registry: %w", err)
+	}
+	head, err := a.db.GetLastBlock()
+	if err != nil {
+		return fmt.Errorf("failed to get last block: %w", err)
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ADDRESS\tDEPLOYED\tCURSOR\tLAG\tPAUSED\tERRORS\tLAST ERROR")
+	for _, status := range statuses {
+		cursor, lag := "-", "-"
+		if status.LastBlockIndexed != nil {
+			cursor = *status.LastBlockIndexed
+			block, err := a.db.GetBlock(cursor)
+			if err != nil {
+				return fmt.Errorf("failed to get block %s: %w", cursor, err)
+			}
+			if block != nil && head != nil && head.BlockNumber >= block.BlockNumber {
+				lag = strconv.FormatUint(head.BlockNumber-block.BlockNumber, 10)
+			}
+		}
+		lastError := "-"
+		if status.LastError != nil {
+			lastError = *status.LastError
+			if status.LastErrorAt != nil {
+				lastError = status.LastErrorAt.Format(time.RFC3339) + " " + lastError
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%d\t%s\n",
+			status.Address, status.DeployedAt, cursor, lag, status.Paused, status.ErrorCount, lastError)
+	}
+	return w.Flush()
+}
+
+// pause stops the indexing of a vault, the running indexer untracks it
+func (a *admin) pause(args []string) error {
+	return a.setPaused("pause", args, true)
+}
+
+// resume has the running indexer track a paused vault again and catch it up
+// from its cursor
+func (a *admin) resume(args []string) error {
+	return a.setPaused("resume", args, false)
+}
+
+func (a *admin) setPaused(name string, args []string, paused bool) error {
+	fs := newFlagSet(name)
+	vault := fs.String("vault", "", "address of the vault")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	address, err := parseAddress(*vault)
+	if err != nil {
+		return err
+	}
+	registered, err := a.db.SetVaultPaused(address, paused)
+	if err != nil {
+		return fmt.Errorf("failed to update vault %s: %w", address, err)
+	}
+	if !registered {
+		return fmt.Errorf("vault %s is not registered", address)
+	}
+	if paused {
+		fmt.Fprintf(a.out, "Paused vault %s\n", address)
+	} else {
+		fmt.Fprintf(a.out, "Resumed vault %s\n", address)
+	}
+	return nil
+}
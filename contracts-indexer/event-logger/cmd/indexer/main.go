@@ -0,0 +1,49 @@
+// Command indexer runs the Pitchlake event logger without Juno. It polls the
+// RPC node at RPC_URL for new blocks and indexes them with the same code as
+// the Juno plugin, so that it can index against a hosted RPC or a local devnet
+// such as katana. It reads the configuration of the plugin.
+package main
+
+import (
+	"context"
+	pluginCore "junoplugin/plugin/core"
+	"junoplugin/plugin/listener"
+	"junoplugin/plugin/source"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	slog.Info("Starting Pitchlake indexer")
+
+	pc, err := pluginCore.NewPluginCore()
+	if err != nil {
+		return err
+	}
+	defer pc.Shutdown()
+	if err := pc.Initialize(); err != nil {
+		return err
+	}
+
+	vaultListener := listener.NewListenerService(pc.GetVaultManager(), pc.GetConfig().DatabaseURL)
+	if err := vaultListener.Start(); err != nil {
+		return err
+	}
+	defer vaultListener.Stop()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg := pc.GetConfig()
+	slog.Info("Polling the RPC node", "interval", cfg.PollInterval)
+	return source.NewPoller(pc.GetNetwork(), cfg.PollInterval, cfg.ReorgDepth).Run(ctx, pc)
+}
@@ -2,9 +2,12 @@ package db
 
 import (
 	"context"
-	"errors"
+	"driverevents"
+	"fmt"
+	"junoplugin/logging"
+	"junoplugin/metrics"
 	"junoplugin/models"
-	"log"
+	"junoplugin/utils"
 
 	"github.com/jackc/pgx/v5"
 )
@@ -16,7 +19,8 @@ func (db *DB) GetVaultRegistry() ([]*models.VaultRegistry, error) {
 		vault_address,
 		deployed_at,
 		last_block_indexed,
-		last_block_processed
+		last_block_processed,
+		paused
 	FROM vault_registry`
 	rows, err := db.Pool.Query(context.Background(), query)
 	if err != nil {
@@ -29,7 +33,7 @@ func (db *DB) GetVaultRegistry() ([]*models.VaultRegistry, error) {
 
 	for rows.Next() {
 		var vault models.VaultRegistry
-		if err := rows.Scan(&vault.Address, &vault.DeployedAt, &vault.LastBlockIndexed, &vault.LastBlockProcessed); err != nil {
+		if err := rows.Scan(&vault.Address, &vault.DeployedAt, &vault.LastBlockIndexed, &vault.LastBlockProcessed, &vault.Paused); err != nil {
 			return nil, err
 		}
 		vaultRegistry = append(vaultRegistry, &vault)
@@ -40,9 +44,12 @@ func (db *DB) GetVaultRegistry() ([]*models.VaultRegistry, error) {
 	return vaultRegistry, nil
 }
 
-func (db *DB) InsertBlock(block *models.StarknetBlocks) error {
+func (t *tx) InsertBlock(block *models.StarknetBlocks) error {
 	hash := block.BlockHash
 	parentHash := block.ParentHash
+	// A reverted row is replaced by the block of the new branch, and inserting
+	// the same block twice is a no-op. A different mined block at the same
+	// height means a reorg was missed.
 	query := `
 	INSERT INTO starknet_blocks
 	(block_number,
@@ -51,22 +58,176 @@ func (db *DB) InsertBlock(block *models.StarknetBlocks) error {
 	timestamp,
 	status)
 	VALUES ($1, $2, $3, $4, 'MINED')
+	ON CONFLICT (block_number) DO UPDATE
+	SET block_hash = EXCLUDED.block_hash,
+		parent_hash = EXCLUDED.parent_hash,
+		timestamp = EXCLUDED.timestamp,
+		status = 'MINED'
+	WHERE starknet_blocks.status = 'REVERTED'
+		OR starknet_blocks.block_hash = EXCLUDED.block_hash
 	`
-	res, err := db.tx.Exec(context.Background(), query, block.BlockNumber, hash, parentHash, block.Timestamp)
-
-	log.Printf("STORAGE RESULT %v %v", res, err)
+	res, err := t.tx.Exec(context.Background(), query, block.BlockNumber, hash, parentHash, block.Timestamp)
+	if err == nil && res.RowsAffected() == 0 {
+		return fmt.Errorf("block %d is already indexed with a different hash", block.BlockNumber)
+	}
 	return err
 }
 
-func (db *DB) RevertBlock(blockNumber uint64, blockHash string) error {
+func (t *tx) RevertBlock(blockNumber uint64, blockHash string) error {
 	query := `
 	UPDATE starknet_blocks
 	SET status = 'REVERTED'
 	WHERE block_number = $1 and block_hash = $2`
-	_, err := db.tx.Exec(context.Background(), query, blockNumber, blockHash)
+	_, err := t.tx.Exec(context.Background(), query, blockNumber, blockHash)
 	return err
 }
 
+// GetBlocksFrom returns the mined blocks at or above a height, highest first
+func (t *tx) GetBlocksFrom(blockNumber uint64) ([]*models.StarknetBlocks, error) {
+	query := `
+	SELECT block_number, block_hash, parent_hash, timestamp, status FROM starknet_blocks
+	WHERE block_number >= $1 AND status = 'MINED'
+	ORDER BY block_number DESC`
+	rows, err := t.tx.Query(context.Background(), query, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blocks []*models.StarknetBlocks
+	for rows.Next() {
+		var block models.StarknetBlocks
+		if err := rows.Scan(&block.BlockNumber, &block.BlockHash, &block.ParentHash, &block.Timestamp, &block.Status); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, &block)
+	}
+	return blocks, rows.Err()
+}
+
+// GetBlockRange returns the stored blocks between two heights, mined or
+// reverted, lowest first
+func (db *DB) GetBlockRange(fromBlock, toBlock uint64) ([]*models.StarknetBlocks, error) {
+	return getBlockRange(db.Pool, fromBlock, toBlock)
+}
+
+// GetBlockRange returns the blocks between two heights as seen by the
+// transaction, see DB.GetBlockRange
+func (t *tx) GetBlockRange(fromBlock, toBlock uint64) ([]*models.StarknetBlocks, error) {
+	return getBlockRange(t.tx, fromBlock, toBlock)
+}
+
+func getBlockRange(q querier, fromBlock, toBlock uint64) ([]*models.StarknetBlocks, error) {
+	query := `
+	SELECT block_number, block_hash, parent_hash, timestamp, status FROM starknet_blocks
+	WHERE block_number BETWEEN $1 AND $2
+	ORDER BY block_number ASC`
+	rows, err := q.Query(context.Background(), query, fromBlock, toBlock)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blocks []*models.StarknetBlocks
+	for rows.Next() {
+		var block models.StarknetBlocks
+		if err := rows.Scan(&block.BlockNumber, &block.BlockHash, &block.ParentHash, &block.Timestamp, &block.Status); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, &block)
+	}
+	return blocks, rows.Err()
+}
+
+// GetFirstBlock returns the lowest mined block, nil when none is stored
+func (db *DB) GetFirstBlock() (*models.StarknetBlocks, error) {
+	var block models.StarknetBlocks
+	query := `
+	SELECT block_number, block_hash, parent_hash, timestamp, status FROM starknet_blocks
+	WHERE status = 'MINED'
+	ORDER BY block_number ASC
+	LIMIT 1`
+	err := db.Pool.QueryRow(context.Background(), query).Scan(&block.BlockNumber, &block.BlockHash, &block.ParentHash, &block.Timestamp, &block.Status)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &block, nil
+}
+
+// CountEvents returns the number of events of a block that are not reverted
+func (t *tx) CountEvents(blockHash string) (int64, error) {
+	query := `SELECT COUNT(*) FROM events WHERE block_hash = $1 AND NOT is_reverted`
+	var count int64
+	err := t.tx.QueryRow(context.Background(), query, blockHash).Scan(&count)
+	return count, err
+}
+
+// RevertEvents flags the events of a reverted block. The rows are kept so
+// that the event-processor can read them back to undo their effects.
+func (t *tx) RevertEvents(blockHash string) (int64, error) {
+	query := `
+	UPDATE events
+	SET is_reverted = TRUE
+	WHERE block_hash = $1 AND NOT is_reverted`
+	res, err := t.tx.Exec(context.Background(), query, blockHash)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+// RewindVaultCursors moves the cursor of every vault indexed up to one of the
+// reverted blocks back to the parent of the reorg and returns their addresses
+func (t *tx) RewindVaultCursors(revertedHashes []string, parentHash string) ([]string, error) {
+	query := `
+	UPDATE vault_registry
+	SET last_block_indexed = $1
+	WHERE last_block_indexed = ANY($2)
+	RETURNING vault_address`
+	rows, err := t.tx.Query(context.Background(), query, parentHash, revertedHashes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addresses []string
+	for rows.Next() {
+		var address string
+		if err := rows.Scan(&address); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, rows.Err()
+}
+
+// DeleteVaultsDeployedAt removes the vaults deployed in one of the reverted
+// blocks, they do not exist on the new branch, and returns their addresses
+func (t *tx) DeleteVaultsDeployedAt(revertedHashes []string) ([]string, error) {
+	query := `
+	DELETE FROM vault_registry
+	WHERE deployed_at = ANY($1)
+	RETURNING vault_address`
+	rows, err := t.tx.Query(context.Background(), query, revertedHashes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addresses []string
+	for rows.Next() {
+		var address string
+		if err := rows.Scan(&address); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, rows.Err()
+}
+
 func (db *DB) GetVaultRegistryByAddress(address string) (models.VaultRegistry, error) {
 	var vaultRegistry models.VaultRegistry
 	query := `
@@ -74,7 +235,8 @@ func (db *DB) GetVaultRegistryByAddress(address string) (models.VaultRegistry, e
 		vault_address,
 		deployed_at,
 		last_block_indexed,
-		last_block_processed
+		last_block_processed,
+		paused
 	FROM vault_registry
 	WHERE vault_address = $1`
 
@@ -83,32 +245,17 @@ func (db *DB) GetVaultRegistryByAddress(address string) (models.VaultRegistry, e
 		&vaultRegistry.DeployedAt,
 		&vaultRegistry.LastBlockIndexed,
 		&vaultRegistry.LastBlockProcessed,
+		&vaultRegistry.Paused,
 	)
 	return vaultRegistry, err
 }
 
-func (db *DB) GetNextBlock(hash string) (*models.StarknetBlocks, error) {
-	var block models.StarknetBlocks
-
-	log.Printf("Getting next block: %v", hash)
-	query := `
-	SELECT block_number, block_hash, parent_hash, timestamp, status FROM starknet_blocks
-	WHERE parent_hash = $1`
-	err := db.Pool.QueryRow(context.Background(), query, hash).Scan(&block.BlockNumber, &block.BlockHash, &block.ParentHash, &block.Timestamp, &block.Status)
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, nil
-		}
-		return nil, err
-	}
-	return &block, nil
-}
 func (db *DB) GetBlock(hash string) (*models.StarknetBlocks, error) {
 	var block models.StarknetBlocks
 	query := `
-	SELECT * FROM starknet_blocks
+	SELECT block_number, block_hash, parent_hash, timestamp, status FROM starknet_blocks
 	WHERE block_hash = $1`
-	err := db.Pool.QueryRow(context.Background(), query, hash).Scan(&block)
+	err := db.Pool.QueryRow(context.Background(), query, hash).Scan(&block.BlockNumber, &block.BlockHash, &block.ParentHash, &block.Timestamp, &block.Status)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -132,91 +279,428 @@ func (db *DB) GetLastBlock() (*models.StarknetBlocks, error) {
 	WHERE STATUS = 'MINED'
 	ORDER BY block_number DESC
 	LIMIT 1`
-	if db.tx == nil {
-		err := db.Pool.QueryRow(context.Background(), query).Scan(&lastBlock.BlockNumber, &lastBlock.BlockHash, &lastBlock.ParentHash, &lastBlock.Timestamp)
-		if err != nil {
-			if err == pgx.ErrNoRows {
-				return nil, nil
-			}
-			return nil, err
-		}
-	} else {
-		err := db.Pool.QueryRow(context.Background(), query).Scan(&lastBlock.BlockNumber, &lastBlock.BlockHash, &lastBlock.ParentHash, &lastBlock.Timestamp)
-		if err != nil {
-			if err == pgx.ErrNoRows {
-				return nil, nil
-			}
-			return nil, err
+	err := db.Pool.QueryRow(context.Background(), query).Scan(&lastBlock.BlockNumber, &lastBlock.BlockHash, &lastBlock.ParentHash, &lastBlock.Timestamp)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
 		}
+		return nil, err
 	}
 	return &lastBlock, nil
 }
 
-func (db *DB) StoreEvent(txHash, vaultAddress string, blockNumber uint64, blockHash string, eventName string, eventKeys []string, eventData []string) error {
+// StoreEvent stores an event of a vault in the transaction.
+// fromAddress is the contract that emitted it, the vault itself or one of its
+// option rounds. An event is identified by its block, transaction and
+// eventIndex, its position in the transaction receipt: storing it again is a
+// no-op, apart from undoing its revert when its block comes back, and storing
+// another event at the same position is an error. An event stored before its
+// position was known is claimed by content instead. A new event takes the
+// next event_nonce of its vault, allocated from vault_registry under the row
+// lock of the vault.
+func (t *tx) StoreEvent(txHash, vaultAddress, fromAddress string, blockNumber uint64, blockHash string, eventIndex uint64, eventName string, eventKeys []string, eventData []string) error {
+	t.log.Debug("Storing event",
+		logging.Block(blockNumber),
+		logging.Vault(vaultAddress),
+		logging.Event(eventName),
+		"tx", txHash,
+		"from", fromAddress,
+		"index", eventIndex,
+	)
+	query := `
+	WITH matched AS (
+		SELECT ctid, from_address, event_keys, event_data
+		FROM events
+		WHERE block_hash = $5 AND transaction_hash = $1 AND (
+			event_index = $6
+			OR (event_index IS NULL AND COALESCE(from_address, $3) = $3
+				AND event_keys::text[] = $8::text[] AND event_data::text[] = $9::text[])
+		)
+		ORDER BY event_index NULLS LAST, event_nonce
+		LIMIT 1
+	), existing AS (
+		UPDATE events e SET is_reverted = FALSE, event_index = $6, from_address = $3
+		FROM matched m
+		WHERE e.ctid = m.ctid AND COALESCE(m.from_address, $3) = $3
+			AND m.event_keys::text[] = $8::text[] AND m.event_data::text[] = $9::text[]
+		RETURNING e.event_nonce
+	), nonce AS (
+		UPDATE vault_registry SET last_event_nonce = last_event_nonce + 1
+		WHERE normalize_hex_address(vault_address) = normalize_hex_address($2::varchar) AND NOT EXISTS (SELECT 1 FROM matched)
+		RETURNING last_event_nonce
+	), inserted AS (
+		INSERT INTO events
+		(transaction_hash, vault_address, from_address, block_number, block_hash, event_index, event_name, event_keys, event_data, event_nonce)
+		SELECT $1, $2::varchar, $3, $4, $5, $6, $7, $8, $9, last_event_nonce
+		FROM nonce
+		ON CONFLICT (block_hash, transaction_hash, event_index) DO NOTHING
+		RETURNING 1
+	)
+	SELECT (SELECT COUNT(*) FROM matched), (SELECT COUNT(*) FROM existing), (SELECT COUNT(*) FROM inserted)`
+	var matched, existing, inserted int
+	err := t.tx.QueryRow(context.Background(), query, txHash, vaultAddress, fromAddress, blockNumber, blockHash, eventIndex, eventName, eventKeys, eventData).Scan(&matched, &existing, &inserted)
+	if err != nil {
+		return fmt.Errorf("failed to store event %s of tx %s: %w", eventName, txHash, err)
+	}
+	if matched > 0 && existing == 0 {
+		return fmt.Errorf("failed to store event %s of tx %s: another event is stored at index %d of block %s", eventName, txHash, eventIndex, blockHash)
+	}
+	if matched == 0 && inserted == 0 {
+		return fmt.Errorf("failed to store event %s of tx %s: vault %s is not in vault_registry", eventName, txHash, vaultAddress)
+	}
+	if inserted > 0 {
+		metrics.EventsStored.WithLabelValues(eventName).Inc()
+	}
+	return nil
+}
 
-	if db.tx == nil {
-		return errors.New("No transaction found")
+// GetLegacyEventBlocks returns the blocks, lowest first, holding events stored
+// before the position of events in their receipt was recorded
+func (db *DB) GetLegacyEventBlocks() ([]*models.StarknetBlocks, error) {
+	query := `
+	SELECT DISTINCT block_number, block_hash
+	FROM events
+	WHERE event_index IS NULL
+	ORDER BY block_number`
+	rows, err := db.Pool.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
 	}
-	log.Printf("Storing event %s %s %d %s %v %v", txHash, vaultAddress, blockNumber, eventName, eventKeys, eventData)
+	defer rows.Close()
+
+	var blocks []*models.StarknetBlocks
+	for rows.Next() {
+		var block models.StarknetBlocks
+		if err := rows.Scan(&block.BlockNumber, &block.BlockHash); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, &block)
+	}
+	return blocks, rows.Err()
+}
+
+// SetLegacyEventIndex records the position in its receipt of an event stored
+// without it, matched by content. Among identical events of a transaction the
+// first stored is matched first. It returns false when no such event is left.
+func (db *DB) SetLegacyEventIndex(blockHash, txHash, fromAddress string, eventIndex uint64, eventKeys, eventData []string) (bool, error) {
 	query := `
-	INSERT INTO events
-	(transaction_hash, vault_address, block_number, block_hash, event_name, event_keys, event_data, event_nonce)
-	VALUES ($1, $2::varchar, $3, $4::varchar, $5, $6, $7,
-		(SELECT COUNT(*) + 1
-		 FROM events
-		 WHERE vault_address = $2::varchar))`
-	_, err := db.tx.Exec(context.Background(), query, txHash, vaultAddress, blockNumber, blockHash, eventName, eventKeys, eventData)
+	UPDATE events SET event_index = $4, from_address = $3
+	WHERE ctid = (
+		SELECT ctid FROM events
+		WHERE block_hash = $1 AND transaction_hash = $2 AND event_index IS NULL
+			AND COALESCE(from_address, $3) = $3
+			AND event_keys::text[] = $5::text[] AND event_data::text[] = $6::text[]
+		ORDER BY event_nonce
+		LIMIT 1
+	)`
+	tag, err := db.Pool.Exec(context.Background(), query, blockHash, txHash, fromAddress, eventIndex, eventKeys, eventData)
 	if err != nil {
-		log.Printf("WTHELLY")
-		log.Printf("%v", err)
+		return false, fmt.Errorf("failed to set the index of an event of tx %s: %w", txHash, err)
 	}
-	return err
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetOptionRounds returns the option rounds deployed by the vaults, decoded
+// from the OptionRoundDeployed events of the blocks still on the chain
+func (db *DB) GetOptionRounds() ([]*models.OptionRound, error) {
+	query := `
+	SELECT event_keys, event_data, vault_address, block_hash, transaction_hash
+	FROM events
+	WHERE event_name = 'OptionRoundDeployed' AND NOT is_reverted`
+	rows, err := db.Pool.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rounds []*models.OptionRound
+	for rows.Next() {
+		var round models.OptionRound
+		var keys, data []string
+		var txHash string
+		if err := rows.Scan(&keys, &data, &round.VaultAddress, &round.DeployedAt, &txHash); err != nil {
+			return nil, err
+		}
+		round.Address, err = utils.OptionRoundDeployedAddress(keys, data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OptionRoundDeployed event of tx %s: %w", txHash, err)
+		}
+		rounds = append(rounds, &round)
+	}
+	return rounds, rows.Err()
 }
 
-func (db *DB) InsertVault(vault *models.VaultRegistry) error {
+// InsertVault registers a vault. A vault that is already registered keeps its
+// row and cursors, so that discovering it again is a no-op.
+func (t *tx) InsertVault(vault *models.VaultRegistry) error {
 	query := `
 	INSERT INTO vault_registry
 	(vault_address, deployed_at, last_block_indexed, last_block_processed)
-	VALUES ($1, $2, $3, $4)`
-	_, err := db.tx.Exec(context.Background(), query, vault.Address, vault.DeployedAt, vault.LastBlockIndexed, vault.LastBlockProcessed)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT ((normalize_hex_address(vault_address))) DO NOTHING`
+	_, err := t.tx.Exec(context.Background(), query, vault.Address, vault.DeployedAt, vault.LastBlockIndexed, vault.LastBlockProcessed)
+	return err
+}
+
+func (t *tx) UpdateVaultRegistry(address string, blockHash string) error {
+	query := `
+	UPDATE vault_registry
+	SET last_block_indexed = $1
+	WHERE vault_address = $2`
+	_, err := t.tx.Exec(context.Background(), query, blockHash, address)
+	return err
+}
+
+// GetUninitializedVaults returns the registered vaults whose deployment was
+// never indexed, apart from the paused ones
+func (db *DB) GetUninitializedVaults() ([]*models.VaultRegistry, error) {
+	query := `
+	SELECT
+		vault_address,
+		deployed_at,
+		last_block_indexed,
+		last_block_processed
+	FROM vault_registry
+	WHERE last_block_indexed IS NULL AND NOT paused`
+	rows, err := db.Pool.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var vaults []*models.VaultRegistry
+	for rows.Next() {
+		var vault models.VaultRegistry
+		if err := rows.Scan(&vault.Address, &vault.DeployedAt, &vault.LastBlockIndexed, &vault.LastBlockProcessed); err != nil {
+			return nil, err
+		}
+		vaults = append(vaults, &vault)
+	}
+	return vaults, rows.Err()
+}
+
+// RecordVaultError records a failed initialization of a vault. It is written
+// outside of the transaction of the initialization, which is rolled back.
+func (db *DB) RecordVaultError(address string, cause error) error {
+	query := `
+	UPDATE vault_registry
+	SET last_error = $1, error_count = error_count + 1, last_error_at = NOW()
+	WHERE normalize_hex_address(vault_address) = normalize_hex_address($2)`
+	_, err := db.Pool.Exec(context.Background(), query, cause.Error(), address)
+	return err
+}
+
+// ClearVaultError clears the error state of a vault once it is initialized
+func (db *DB) ClearVaultError(address string) error {
+	query := `
+	UPDATE vault_registry
+	SET last_error = NULL, error_count = 0, last_error_at = NULL
+	WHERE normalize_hex_address(vault_address) = normalize_hex_address($1)`
+	_, err := db.Pool.Exec(context.Background(), query, address)
 	return err
 }
 
-func (db *DB) UpdateVaultRegistry(address string, blockHash string) error {
+// GetVaultStatuses returns every registered vault with its error state, by
+// registration order
+func (db *DB) GetVaultStatuses() ([]*models.VaultStatus, error) {
+	query := `
+	SELECT
+		id,
+		vault_address,
+		deployed_at,
+		last_block_indexed,
+		last_block_processed,
+		paused,
+		last_error,
+		error_count,
+		last_error_at
+	FROM vault_registry
+	ORDER BY id`
+	rows, err := db.Pool.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statuses []*models.VaultStatus
+	for rows.Next() {
+		var status models.VaultStatus
+		if err := rows.Scan(
+			&status.ID,
+			&status.Address,
+			&status.DeployedAt,
+			&status.LastBlockIndexed,
+			&status.LastBlockProcessed,
+			&status.Paused,
+			&status.LastError,
+			&status.ErrorCount,
+			&status.LastErrorAt,
+		); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, &status)
+	}
+	return statuses, rows.Err()
+}
+
+// SetVaultPaused pauses or resumes a vault and reports whether it is
+// registered. The vault_update trigger notifies the listener of the change.
+func (db *DB) SetVaultPaused(address string, paused bool) (bool, error) {
+	query := `
+	UPDATE vault_registry
+	SET paused = $1
+	WHERE normalize_hex_address(vault_address) = normalize_hex_address($2)`
+	res, err := db.Pool.Exec(context.Background(), query, paused, address)
+	if err != nil {
+		return false, err
+	}
+	return res.RowsAffected() > 0, nil
+}
+
+// SetVaultCursor moves the cursor of a vault in the transaction. A nil
+// blockHash has the vault initialized again from its deploy block.
+func (t *tx) SetVaultCursor(address string, blockHash *string) error {
 	query := `
 	UPDATE vault_registry
 	SET last_block_indexed = $1
 	WHERE vault_address = $2`
-	_, err := db.tx.Exec(context.Background(), query, blockHash, address)
+	_, err := t.tx.Exec(context.Background(), query, blockHash, address)
 	return err
 }
 
-// StoreDriverEvent stores a basic driver event (StartBlock/RevertBlock) and triggers PostgreSQL NOTIFY
-func (db *DB) StoreDriverEvent(eventType string, blockHash string) error {
-	if db.tx == nil {
-		return errors.New("No transaction found")
+// GetUnprocessedDriverEvents returns the first driver events the
+// event-processor has not applied yet, by sequence
+func (db *DB) GetUnprocessedDriverEvents(limit int) ([]*models.DriverEvent, error) {
+	query := `
+	SELECT
+		id,
+		version,
+		sequence_index,
+		type,
+		timestamp,
+		COALESCE(block_hash, ''),
+		COALESCE(start_block_hash, ''),
+		COALESCE(end_block_hash, ''),
+		COALESCE(vault_address, '')
+	FROM driver_events
+	WHERE NOT COALESCE(is_processed, false)
+	ORDER BY sequence_index
+	LIMIT $1`
+	rows, err := db.Pool.Query(context.Background(), query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*models.DriverEvent
+	for rows.Next() {
+		var event models.DriverEvent
+		if err := rows.Scan(
+			&event.ID,
+			&event.Version,
+			&event.SequenceIndex,
+			&event.Type,
+			&event.Timestamp,
+			&event.BlockHash,
+			&event.StartBlockHash,
+			&event.EndBlockHash,
+			&event.VaultAddress,
+		); err != nil {
+			return nil, err
+		}
+		events = append(events, &event)
+	}
+	return events, rows.Err()
+}
+
+// CountUnprocessedDriverEvents returns how many driver events the
+// event-processor has not applied yet
+func (db *DB) CountUnprocessedDriverEvents() (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM driver_events WHERE NOT COALESCE(is_processed, false)`
+	err := db.Pool.QueryRow(context.Background(), query).Scan(&count)
+	return count, err
+}
+
+// GetVaultCatchupProgress returns the catchup progress of a vault, nil when it
+// was never caught up
+func (db *DB) GetVaultCatchupProgress(address string) (*models.VaultCatchupProgress, error) {
+	var progress models.VaultCatchupProgress
+	query := `
+	SELECT vault_address, start_block, target_block, last_block_number, last_block_hash
+	FROM vault_catchup_progress
+	WHERE vault_address = $1`
+	err := db.Pool.QueryRow(context.Background(), query, address).Scan(
+		&progress.VaultAddress,
+		&progress.StartBlock,
+		&progress.TargetBlock,
+		&progress.LastBlockNumber,
+		&progress.LastBlockHash,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// SaveVaultCatchupProgress records the catchup progress of a vault in the
+// transaction, with the events and cursor of the window it covers
+func (t *tx) SaveVaultCatchupProgress(progress *models.VaultCatchupProgress) error {
+	query := `
+	INSERT INTO vault_catchup_progress
+	(vault_address, start_block, target_block, last_block_number, last_block_hash, updated_at)
+	VALUES ($1, $2, $3, $4, $5, NOW())
+	ON CONFLICT (vault_address) DO UPDATE
+	SET start_block = EXCLUDED.start_block,
+		target_block = EXCLUDED.target_block,
+		last_block_number = EXCLUDED.last_block_number,
+		last_block_hash = EXCLUDED.last_block_hash,
+		updated_at = EXCLUDED.updated_at`
+	_, err := t.tx.Exec(context.Background(), query, progress.VaultAddress, progress.StartBlock, progress.TargetBlock, progress.LastBlockNumber, progress.LastBlockHash)
+	return err
+}
+
+// StoreDriverEvent stores a block driver event (StartBlock/RevertBlock) and triggers PostgreSQL NOTIFY.
+// An event that repeats the last block event of its block is skipped, so that
+// processing a block again does not notify it twice.
+func (t *tx) StoreDriverEvent(eventType driverevents.Type, blockHash string) error {
+	event, err := driverevents.NewBlockEvent(eventType, blockHash)
+	if err != nil {
+		return err
 	}
 
 	// Store event in database with sequence index (triggers NOTIFY automatically)
 	query := `
 	INSERT INTO driver_events
-	(sequence_index, type, block_hash, timestamp)
-	VALUES (nextval('driver_events_sequence'), $1, $2, NOW())`
-	_, err := db.tx.Exec(context.Background(), query, eventType, blockHash)
+	(sequence_index, version, type, block_hash, timestamp)
+	SELECT nextval('driver_events_sequence'), $1, $2::varchar, $3::varchar, NOW()
+	WHERE (
+		SELECT type FROM driver_events
+		WHERE block_hash = $3::varchar AND type IN ('StartBlock', 'RevertBlock')
+		ORDER BY sequence_index DESC
+		LIMIT 1
+	) IS DISTINCT FROM $2::varchar`
+	_, err = t.tx.Exec(context.Background(), query, event.Version, event.Type, event.BlockHash)
 	return err
 }
 
-// StoreVaultCatchupEvent stores a vault catchup event and triggers PostgreSQL NOTIFY
-func (db *DB) StoreVaultCatchupEvent(vaultAddress string, startBlockHash, endBlockHash string) error {
-	if db.tx == nil {
-		return errors.New("No transaction found")
+// StoreVaultCatchupEvent stores a vault catchup event and triggers PostgreSQL NOTIFY.
+// A range that was already notified for the vault is skipped.
+func (t *tx) StoreVaultCatchupEvent(vaultAddress string, startBlockHash, endBlockHash string) error {
+	event, err := driverevents.NewCatchupVaultEvent(vaultAddress, startBlockHash, endBlockHash)
+	if err != nil {
+		return err
 	}
 
 	// Store event in database with sequence index (triggers NOTIFY automatically)
 	query := `
 	INSERT INTO driver_events
-	(sequence_index, type, vault_address, start_block_hash, end_block_hash, timestamp)
-	VALUES (nextval('driver_events_sequence'), $1, $2, $3, $4, NOW())`
-	_, err := db.tx.Exec(context.Background(), query, "CatchupVault", vaultAddress, startBlockHash, endBlockHash)
+	(sequence_index, version, type, vault_address, start_block_hash, end_block_hash, timestamp)
+	VALUES (nextval('driver_events_sequence'), $1, $2, $3, $4, $5, NOW())
+	ON CONFLICT (vault_address, start_block_hash, end_block_hash) WHERE type = 'CatchupVault' DO NOTHING`
+	_, err = t.tx.Exec(context.Background(), query, event.Version, event.Type, event.VaultAddress, event.StartBlockHash, event.EndBlockHash)
 	return err
 }
@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"junoplugin/models"
+)
+
+const testVault = "0x7a417"
+
+// testDB resets the database at TEST_DB_URL to the migrations of the logger,
+// registers testVault and connects to it
+func testDB(t *testing.T) *DB {
+	t.Helper()
+	url := os.Getenv("TEST_DB_URL")
+	if url == "" {
+		t.Skip("TEST_DB_URL is not set")
+	}
+	db, err := Init(url)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(db.Shutdown)
+
+	ctx := context.Background()
+	if _, err := db.Pool.Exec(ctx, `DROP SCHEMA public CASCADE; CREATE SCHEMA public;`); err != nil {
+		t.Fatalf("Failed to reset the database: %v", err)
+	}
+	files, err := filepath.Glob("migrations/*.up.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("No migrations: %v", err)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		migration, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Pool.Exec(ctx, string(migration)); err != nil {
+			t.Fatalf("Failed to apply %s: %v", file, err)
+		}
+	}
+
+	if err := db.InTx(func(tx Tx) error {
+		return tx.InsertVault(&models.VaultRegistry{Address: testVault, DeployedAt: "0x1"})
+	}); err != nil {
+		t.Fatalf("Failed to register the vault: %v", err)
+	}
+	return db
+}
+
+func mustExec(t *testing.T, db *DB, query string, args ...any) {
+	t.Helper()
+	if _, err := db.Pool.Exec(context.Background(), query, args...); err != nil {
+		t.Fatalf("Failed to execute %q: %v", query, err)
+	}
+}
+
+// storeEvent stores an event of testVault in tx 0xa of block 0xb1
+func storeEvent(db *DB, index uint64, name string, data ...string) error {
+	return db.InTx(func(tx Tx) error {
+		return tx.StoreEvent("0xa", testVault, testVault, 1, "0xb1", index, name, []string{"0x" + name}, data)
+	})
+}
+
+// eventIndexes returns the event_index of the events of tx 0xa by nonce, -1
+// for the ones without
+func eventIndexes(t *testing.T, db *DB) []int64 {
+	t.Helper()
+	rows, err := db.Pool.Query(context.Background(),
+		`SELECT COALESCE(event_index, -1) FROM events WHERE transaction_hash = '0xa' ORDER BY event_nonce`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var indexes []int64
+	for rows.Next() {
+		var index int64
+		if err := rows.Scan(&index); err != nil {
+			t.Fatal(err)
+		}
+		indexes = append(indexes, index)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return indexes
+}
+
+func equalIndexes(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestStoreEventRejectsAnotherEventAtItsIndex(t *testing.T) {
+	db := testDB(t)
+	if err := storeEvent(db, 3, "Deposit", "0x1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := storeEvent(db, 3, "Deposit", "0x1"); err != nil {
+		t.Errorf("storing the event again: %v", err)
+	}
+	for _, name := range []string{"Deposit", "Withdrawal"} {
+		err := storeEvent(db, 3, name, "0x2")
+		if err == nil || !strings.Contains(err.Error(), "another event is stored at index 3") {
+			t.Errorf("storing %s at the index of another event = %v, want an error", name, err)
+		}
+	}
+	if got := eventIndexes(t, db); !equalIndexes(got, []int64{3}) {
+		t.Errorf("event indexes = %v, want [3]", got)
+	}
+}
+
+func TestStoreEventClaimsLegacyEvents(t *testing.T) {
+	db := testDB(t)
+	// Two identical events and another one stored before their index was
+	// recorded, the first without its emitter
+	mustExec(t, db, `
+		INSERT INTO events (transaction_hash, vault_address, from_address, block_number, block_hash, event_name, event_keys, event_data, event_nonce)
+		VALUES ('0xa', $1, NULL, 1, '0xb1', 'Deposit', '{0xDeposit}', '{0x1}', 1),
+			('0xa', $1, $1, 1, '0xb1', 'Deposit', '{0xDeposit}', '{0x1}', 2),
+			('0xa', $1, $1, 1, '0xb1', 'Withdrawal', '{0xWithdrawal}', '{0x1}', 3)`, testVault)
+	mustExec(t, db, `UPDATE vault_registry SET last_event_nonce = 3`)
+
+	// The block is stored again with an event the legacy rows do not have
+	for i, name := range []string{"Deposit", "Transfer", "Deposit"} {
+		if err := storeEvent(db, uint64(i), name, "0x1"); err != nil {
+			t.Fatalf("storing %s: %v", name, err)
+		}
+	}
+	if got, want := eventIndexes(t, db), []int64{0, 2, -1, 1}; !equalIndexes(got, want) {
+		t.Errorf("event indexes = %v, want %v", got, want)
+	}
+
+	blocks, err := db.GetLegacyEventBlocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 1 || blocks[0].BlockHash != "0xb1" {
+		t.Fatalf("legacy blocks = %v, want 0xb1", blocks)
+	}
+	ok, err := db.SetLegacyEventIndex("0xb1", "0xa", testVault, 3, []string{"0xWithdrawal"}, []string{"0x1"})
+	if err != nil || !ok {
+		t.Fatalf("SetLegacyEventIndex = %t, %v", ok, err)
+	}
+	ok, err = db.SetLegacyEventIndex("0xb1", "0xa", testVault, 4, []string{"0xWithdrawal"}, []string{"0x1"})
+	if err != nil || ok {
+		t.Errorf("SetLegacyEventIndex of no event left = %t, %v", ok, err)
+	}
+	if got, want := eventIndexes(t, db), []int64{0, 2, 3, 1}; !equalIndexes(got, want) {
+		t.Errorf("event indexes = %v, want %v", got, want)
+	}
+	if blocks, err := db.GetLegacyEventBlocks(); err != nil || len(blocks) != 0 {
+		t.Errorf("legacy blocks = %v, %v, want none", blocks, err)
+	}
+}
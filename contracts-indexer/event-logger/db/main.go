@@ -2,8 +2,13 @@ package db
 
 import (
 	"context"
+	"driverevents"
 	"fmt"
-	"log"
+	"junoplugin/logging"
+	"junoplugin/metrics"
+	"junoplugin/models"
+	"log/slog"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -11,9 +16,42 @@ import (
 
 type DB struct {
 	Pool *pgxpool.Pool
-	tx   pgx.Tx
 	ctx  context.Context
 	url  string
+	log  *slog.Logger
+}
+
+// Tx is the handle of a transaction begun by DB.InTx. It writes the blocks,
+// events, vaults and driver events, and reads what the transaction has
+// written so far. It is only valid until the function it was passed to
+// returns, and must not be shared between goroutines.
+type Tx interface {
+	InsertBlock(block *models.StarknetBlocks) error
+	RevertBlock(blockNumber uint64, blockHash string) error
+	GetBlocksFrom(blockNumber uint64) ([]*models.StarknetBlocks, error)
+	GetBlockRange(fromBlock, toBlock uint64) ([]*models.StarknetBlocks, error)
+	CountEvents(blockHash string) (int64, error)
+	RevertEvents(blockHash string) (int64, error)
+	RewindVaultCursors(revertedHashes []string, parentHash string) ([]string, error)
+	DeleteVaultsDeployedAt(revertedHashes []string) ([]string, error)
+	StoreEvent(txHash, vaultAddress, fromAddress string, blockNumber uint64, blockHash string, eventIndex uint64, eventName string, eventKeys []string, eventData []string) error
+	InsertVault(vault *models.VaultRegistry) error
+	UpdateVaultRegistry(address string, blockHash string) error
+	SetVaultCursor(address string, blockHash *string) error
+	SaveVaultCatchupProgress(progress *models.VaultCatchupProgress) error
+	StoreDriverEvent(eventType driverevents.Type, blockHash string) error
+	StoreVaultCatchupEvent(vaultAddress string, startBlockHash, endBlockHash string) error
+}
+
+// tx implements Tx on a transaction of the pool
+type tx struct {
+	tx  pgx.Tx
+	log *slog.Logger
+}
+
+// querier is the pool or transaction the reads shared by DB and tx run on
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
 }
 
 func Init(dbUrl string) (*DB, error) {
@@ -46,6 +84,7 @@ func Init(dbUrl string) (*DB, error) {
 		Pool: pool,
 		ctx:  context.Background(),
 		url:  dbUrl, //Unsafe possibly, need to consolidate config better
+		log:  slog.Default(),
 	}, nil
 
 }
@@ -54,21 +93,41 @@ func (db *DB) Shutdown() {
 	db.Pool.Close()
 }
 
-func (db *DB) BeginTx() {
-	tx, err := db.Pool.Begin(context.TODO())
+// InTx runs fn in a transaction of its own, which is committed when fn returns
+// nil and rolled back when it returns an error or panics. The error of fn is
+// returned as is, so that callers can match it. Each call gets its own
+// transaction, so concurrent callers never commit each other's writes.
+func (db *DB) InTx(fn func(tx Tx) error) error {
+	pgxTx, err := db.Pool.Begin(db.ctx)
 	if err != nil {
-		log.Printf("WTHELLY TX WAALA")
-		log.Fatal(err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	db.tx = tx
-}
+	start := time.Now()
+
+	defer func() {
+		if p := recover(); p != nil {
+			db.rollback(pgxTx, start)
+			panic(p)
+		}
+	}()
 
-func (db *DB) CommitTx() {
-	db.tx.Commit(db.ctx)
-	db.tx = nil
+	if err := fn(&tx{tx: pgxTx, log: db.log}); err != nil {
+		db.rollback(pgxTx, start)
+		return err
+	}
+	if err := pgxTx.Commit(db.ctx); err != nil {
+		metrics.ObserveTx(metrics.TxRolledBack, start)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	metrics.ObserveTx(metrics.TxCommitted, start)
+	return nil
 }
 
-func (db *DB) RollbackTx() {
-	db.tx.Rollback(db.ctx)
-	db.tx = nil
+// rollback rolls back a transaction of InTx, logging the failures since the
+// error that caused the rollback is the one returned
+func (db *DB) rollback(pgxTx pgx.Tx, start time.Time) {
+	if err := pgxTx.Rollback(db.ctx); err != nil {
+		db.log.Error("Failed to roll back transaction", logging.Err(err))
+	}
+	metrics.ObserveTx(metrics.TxRolledBack, start)
 }
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_events_block_hash;
+ALTER TABLE "events" DROP COLUMN IF EXISTS is_reverted;
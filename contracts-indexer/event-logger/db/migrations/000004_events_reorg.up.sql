@@ -0,0 +1,5 @@
+-- Events of reverted blocks are kept so that the event-processor can undo
+-- them when it handles the RevertBlock driver event
+ALTER TABLE "events" ADD COLUMN IF NOT EXISTS is_reverted BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_events_block_hash ON "events" (block_hash);
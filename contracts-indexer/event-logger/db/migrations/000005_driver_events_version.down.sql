@@ -0,0 +1,22 @@
+CREATE OR REPLACE FUNCTION notify_driver_event()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('driver_events',
+        json_build_object(
+            'id', NEW.id,
+            'sequence_index', NEW.sequence_index,
+            'type', NEW.type,
+            'timestamp', NEW.timestamp,
+            'is_processed', NEW.is_processed,
+            'block_hash', NEW.block_hash,
+            'start_block_hash', NEW.start_block_hash,
+            'end_block_hash', NEW.end_block_hash,
+            'vault_address', NEW.vault_address
+        )::text
+    );
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE "driver_events" DROP CONSTRAINT IF EXISTS driver_events_type_check;
+ALTER TABLE "driver_events" DROP COLUMN IF EXISTS version;
@@ -0,0 +1,28 @@
+-- Driver events follow the contract of the driverevents package: the type is
+-- one of its constants and every row records the payload version it was
+-- written with, so that the event-processor can reject what it does not know
+ALTER TABLE "driver_events" ADD COLUMN IF NOT EXISTS version SMALLINT NOT NULL DEFAULT 1;
+
+ALTER TABLE "driver_events" ADD CONSTRAINT driver_events_type_check
+    CHECK (type IN ('StartBlock', 'RevertBlock', 'CatchupVault'));
+
+CREATE OR REPLACE FUNCTION notify_driver_event()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('driver_events',
+        json_build_object(
+            'id', NEW.id,
+            'version', NEW.version,
+            'sequence_index', NEW.sequence_index,
+            'type', NEW.type,
+            'timestamp', NEW.timestamp,
+            'is_processed', NEW.is_processed,
+            'block_hash', NEW.block_hash,
+            'start_block_hash', NEW.start_block_hash,
+            'end_block_hash', NEW.end_block_hash,
+            'vault_address', NEW.vault_address
+        )::text
+    );
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
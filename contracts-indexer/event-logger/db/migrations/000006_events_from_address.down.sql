@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_events_from_address;
+ALTER TABLE "events" DROP COLUMN IF EXISTS from_address;
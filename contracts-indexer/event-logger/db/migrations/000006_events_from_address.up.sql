@@ -0,0 +1,9 @@
+-- The contract that emitted the event, a vault or one of its option rounds.
+-- vault_address stays the vault the event belongs to.
+ALTER TABLE "events" ADD COLUMN IF NOT EXISTS from_address VARCHAR(66);
+
+-- Only vaults were indexed so far
+UPDATE "events" SET from_address = vault_address
+WHERE from_address IS NULL AND event_name <> 'ContractDeployed';
+
+CREATE INDEX IF NOT EXISTS idx_events_from_address ON "events" (from_address);
@@ -0,0 +1,4 @@
+ALTER TABLE "vault_registry" DROP COLUMN IF EXISTS last_event_nonce;
+DROP FUNCTION IF EXISTS normalize_hex_address(TEXT);
+DROP INDEX IF EXISTS events_block_tx_index_key;
+ALTER TABLE "events" DROP COLUMN IF EXISTS event_index;
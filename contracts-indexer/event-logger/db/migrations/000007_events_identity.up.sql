@@ -0,0 +1,40 @@
+-- Events are identified by their block, transaction and position instead of a
+-- per-vault COUNT(*), so that storing a block again is a no-op.
+--
+-- event_index is the position of the event in its transaction receipt. It is
+-- unknown for the events stored before, which are numbered within their
+-- transaction in event_nonce order instead.
+ALTER TABLE "events" ADD COLUMN IF NOT EXISTS event_index BIGINT;
+
+UPDATE "events" e SET event_index = numbered.event_index
+FROM (
+    SELECT ctid, ROW_NUMBER() OVER (
+        PARTITION BY block_hash, transaction_hash
+        ORDER BY event_nonce
+    ) - 1 AS event_index
+    FROM "events"
+) numbered
+WHERE e.ctid = numbered.ctid AND e.event_index IS NULL;
+
+ALTER TABLE "events" ALTER COLUMN event_index SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS events_block_tx_index_key
+    ON "events" (block_hash, transaction_hash, event_index);
+
+-- Registry addresses are inserted as given, events use the normalized form
+CREATE OR REPLACE FUNCTION normalize_hex_address(address TEXT)
+RETURNS TEXT AS $$
+    SELECT '0x' || COALESCE(NULLIF(ltrim(lower(regexp_replace(address, '^0[xX]', '')), '0'), ''), '0')
+$$ LANGUAGE sql IMMUTABLE;
+
+-- Last event_nonce allocated to the vault, incremented in the transaction that
+-- stores the event
+ALTER TABLE "vault_registry" ADD COLUMN IF NOT EXISTS last_event_nonce BIGINT NOT NULL DEFAULT 0;
+
+UPDATE "vault_registry" v SET last_event_nonce = nonces.last_event_nonce
+FROM (
+    SELECT vault_address, MAX(event_nonce) AS last_event_nonce
+    FROM "events"
+    GROUP BY vault_address
+) nonces
+WHERE normalize_hex_address(v.vault_address) = normalize_hex_address(nonces.vault_address);
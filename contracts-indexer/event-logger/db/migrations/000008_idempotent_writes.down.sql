@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS driver_events_catchup_key;
+DROP INDEX IF EXISTS vault_registry_address_key;
@@ -0,0 +1,26 @@
+-- Every write of the logger is keyed on what it records, so that replaying a
+-- block or a catchup range after a crash leaves the tables unchanged.
+
+-- A vault is registered once, whatever the form of its address
+DELETE FROM "vault_registry" v
+USING "vault_registry" kept
+WHERE normalize_hex_address(v.vault_address) = normalize_hex_address(kept.vault_address)
+    AND v.id > kept.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS vault_registry_address_key
+    ON "vault_registry" (normalize_hex_address(vault_address));
+
+-- A vault catchup is notified once per range. Block events are not unique, a
+-- block can be started again after it was reverted, so the logger skips a
+-- block event that repeats the last one of its block instead.
+DELETE FROM "driver_events" d
+USING "driver_events" kept
+WHERE d.type = 'CatchupVault' AND kept.type = 'CatchupVault'
+    AND d.vault_address = kept.vault_address
+    AND d.start_block_hash = kept.start_block_hash
+    AND d.end_block_hash = kept.end_block_hash
+    AND d.sequence_index > kept.sequence_index;
+
+CREATE UNIQUE INDEX IF NOT EXISTS driver_events_catchup_key
+    ON "driver_events" (vault_address, start_block_hash, end_block_hash)
+    WHERE type = 'CatchupVault';
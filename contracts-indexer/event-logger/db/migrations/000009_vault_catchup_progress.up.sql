@@ -0,0 +1,12 @@
+-- Progress of the catchup of each vault, committed with the events of every
+-- window so that an interrupted catchup resumes where it stopped.
+-- last_block_hash is the last_block_indexed of the vault at that point.
+CREATE TABLE IF NOT EXISTS "vault_catchup_progress"
+(
+    "vault_address" VARCHAR(66) PRIMARY KEY,
+    "start_block" BIGINT NOT NULL,
+    "target_block" BIGINT NOT NULL,
+    "last_block_number" BIGINT NOT NULL,
+    "last_block_hash" VARCHAR(66) NOT NULL,
+    "updated_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,3 @@
+ALTER TABLE "vault_registry" DROP COLUMN IF EXISTS last_error_at;
+ALTER TABLE "vault_registry" DROP COLUMN IF EXISTS error_count;
+ALTER TABLE "vault_registry" DROP COLUMN IF EXISTS last_error;
@@ -0,0 +1,5 @@
+-- Failed initializations of a vault, cleared once it is initialized. The
+-- listener retries a failing vault with backoff and records each failure.
+ALTER TABLE "vault_registry" ADD COLUMN IF NOT EXISTS last_error TEXT;
+ALTER TABLE "vault_registry" ADD COLUMN IF NOT EXISTS error_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE "vault_registry" ADD COLUMN IF NOT EXISTS last_error_at TIMESTAMP WITH TIME ZONE;
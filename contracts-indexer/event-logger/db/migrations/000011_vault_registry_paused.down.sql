@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS update_vault_registry_trigger ON "vault_registry";
+DROP FUNCTION IF EXISTS notify_update_registry();
+ALTER TABLE "vault_registry" DROP COLUMN IF EXISTS paused;
@@ -0,0 +1,27 @@
+-- A paused vault is neither tracked nor caught up until it is resumed. The
+-- listener is notified on vault_update when a vault is paused or resumed.
+ALTER TABLE "vault_registry" ADD COLUMN IF NOT EXISTS paused BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE OR REPLACE FUNCTION notify_update_registry()
+RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('vault_update',
+        json_build_object(
+            'id', NEW.id,
+            'vault_address', NEW.vault_address,
+            'deployed_at', NEW.deployed_at,
+            'last_block_indexed', NEW.last_block_indexed,
+            'last_block_processed', NEW.last_block_processed,
+            'paused', NEW.paused
+        )::text
+    );
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS update_vault_registry_trigger ON "vault_registry";
+CREATE TRIGGER update_vault_registry_trigger
+AFTER UPDATE OF paused ON "vault_registry"
+FOR EACH ROW
+WHEN (OLD.paused IS DISTINCT FROM NEW.paused)
+EXECUTE FUNCTION notify_update_registry();
@@ -0,0 +1,16 @@
+DROP INDEX IF EXISTS idx_events_legacy_index;
+
+-- The events left without a position are numbered like 000007 did, after the
+-- positions already known
+UPDATE "events" e SET event_index = numbered.event_index
+FROM (
+    SELECT ctid, COALESCE(MAX(event_index) OVER (PARTITION BY block_hash, transaction_hash), -1)
+        + ROW_NUMBER() OVER (
+            PARTITION BY block_hash, transaction_hash, event_index IS NULL
+            ORDER BY event_nonce
+        ) AS event_index
+    FROM "events"
+) numbered
+WHERE e.ctid = numbered.ctid AND e.event_index IS NULL;
+
+ALTER TABLE "events" ALTER COLUMN event_index SET NOT NULL;
@@ -0,0 +1,13 @@
+-- 000007 numbered the events stored before it within their transaction in
+-- event_nonce order, which is not their position in the receipt: storing such
+-- a block again duplicated its events or matched the wrong ones. The numbers
+-- are cleared, 000007 ships in the same release so every event stored so far
+-- was numbered by it. A legacy event keeps a NULL event_index until its block
+-- is stored again, which claims it by content, or until `admin backfill` reads
+-- its position from RPC.
+ALTER TABLE "events" ALTER COLUMN event_index DROP NOT NULL;
+
+UPDATE "events" SET event_index = NULL;
+
+CREATE INDEX IF NOT EXISTS idx_events_legacy_index
+    ON "events" (block_number) WHERE event_index IS NULL;
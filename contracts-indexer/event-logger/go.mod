@@ -3,9 +3,12 @@ module junoplugin
 go 1.25.0
 
 require (
+	driverevents v0.0.0
+	eventabi v0.0.0
 	github.com/NethermindEth/juno v0.15.3
 	github.com/NethermindEth/starknet.go v0.15.0
 	github.com/jackc/pgx/v5 v5.7.5
+	github.com/prometheus/client_golang v1.23.0
 	golang.org/x/crypto v0.41.0
 )
 
@@ -46,7 +49,6 @@ require (
 	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
-	github.com/prometheus/client_golang v1.23.0 // indirect
 	github.com/prometheus/client_model v0.6.2 // indirect
 	github.com/prometheus/common v0.65.0 // indirect
 	github.com/prometheus/procfs v0.17.0 // indirect
@@ -68,3 +70,7 @@ require (
 )
 
 replace github.com/davecgh/go-spew => github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc
+
+replace driverevents => ../driverevents
+
+replace eventabi => ../eventabi
@@ -0,0 +1,68 @@
+// Package logging configures the leveled, structured logs of the event logger.
+// Components log through log/slog with the attributes of this package so that
+// the records of a block, vault or event can be filtered on the same keys.
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Keys of the attributes shared by the log records
+const (
+	BlockKey     = "block"
+	BlockHashKey = "block_hash"
+	VaultKey     = "vault"
+	EventKey     = "event"
+	ErrorKey     = "err"
+)
+
+// ParseLevel parses a level of config.LogLevels, an empty level is info
+func ParseLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if level == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := l.UnmarshalText([]byte(strings.ToUpper(level))); err != nil {
+		return l, fmt.Errorf("invalid log level %q", level)
+	}
+	return l, nil
+}
+
+// Setup makes a logger writing text records of level and above to stderr the
+// default one, which the standard log package writes through as well
+func Setup(level string) error {
+	l, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: l})))
+	return nil
+}
+
+// Block is the number of the block a record is about
+func Block(number uint64) slog.Attr {
+	return slog.Uint64(BlockKey, number)
+}
+
+// BlockHash is the hash of the block a record is about
+func BlockHash(hash string) slog.Attr {
+	return slog.String(BlockHashKey, hash)
+}
+
+// Vault is the address of the vault a record is about
+func Vault(address string) slog.Attr {
+	return slog.String(VaultKey, address)
+}
+
+// Event is the name of the event a record is about
+func Event(name string) slog.Attr {
+	return slog.String(EventKey, name)
+}
+
+// Err is the error a record reports
+func Err(err error) slog.Attr {
+	return slog.Any(ErrorKey, err)
+}
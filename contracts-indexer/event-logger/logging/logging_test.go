@@ -0,0 +1,33 @@
+package logging
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		level string
+		want  slog.Level
+	}{
+		{"", slog.LevelInfo},
+		{"debug", slog.LevelDebug},
+		{"info", slog.LevelInfo},
+		{"warn", slog.LevelWarn},
+		{"ERROR", slog.LevelError},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.level)
+		if err != nil {
+			t.Errorf("ParseLevel(%q) failed: %v", tt.level, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLevel(%q) = %s, want %s", tt.level, got, tt.want)
+		}
+	}
+
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Errorf("Expected error for an unknown level")
+	}
+}
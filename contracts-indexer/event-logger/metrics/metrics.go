@@ -0,0 +1,90 @@
+// Package metrics holds the Prometheus metrics of the event logger. They are
+// registered on their own registry and served by a Server on a port of their
+// own, apart from the metrics of Juno.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "pitchlake"
+
+// Registry holds every metric of the event logger
+var Registry = prometheus.NewRegistry()
+
+var (
+	// BlocksProcessed counts the blocks stored, new or caught up
+	BlocksProcessed = promauto.With(Registry).NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "blocks_processed_total",
+		Help:      "Blocks stored by the event logger, new or caught up.",
+	})
+
+	// EventsStored counts the vault and option round events stored, by name
+	EventsStored = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_stored_total",
+		Help:      "Vault and option round events stored, by event name.",
+	}, []string{"event"})
+
+	// BlocksReverted counts the stored blocks reverted by reorgs and repairs
+	BlocksReverted = promauto.With(Registry).NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "blocks_reverted_total",
+		Help:      "Stored blocks reverted by reorgs and chain repairs.",
+	})
+
+	// CatchupLag is the number of blocks a vault catchup has left, by vault
+	CatchupLag = promauto.With(Registry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "vault_catchup_lag_blocks",
+		Help:      "Blocks left to the catchup of a vault.",
+	}, []string{"vault"})
+
+	// RPCDuration is the latency of the RPC requests, by method
+	RPCDuration = promauto.With(Registry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_request_duration_seconds",
+		Help:      "Latency of the RPC requests, by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	// RPCErrors counts the failed RPC requests, by method
+	RPCErrors = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_errors_total",
+		Help:      "Failed RPC requests, by method. Retried requests count each failure.",
+	}, []string{"method"})
+
+	// TxDuration is the duration of the database transactions, by outcome
+	TxDuration = promauto.With(Registry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_transaction_duration_seconds",
+		Help:      "Duration of the database transactions from begin to commit or rollback.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"result"})
+)
+
+// Outcomes of a database transaction
+const (
+	TxCommitted  = "commit"
+	TxRolledBack = "rollback"
+)
+
+// ObserveRPC records an RPC request to method started at start that returned
+// err
+func ObserveRPC(method string, start time.Time, err error) {
+	RPCDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		RPCErrors.WithLabelValues(method).Inc()
+	}
+}
+
+// ObserveTx records a database transaction begun at start that ended with
+// result
+func ObserveTx(result string, start time.Time) {
+	TxDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+}
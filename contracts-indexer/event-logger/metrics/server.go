@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"junoplugin/logging"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// shutdownTimeout bounds the scrapes still running when the server stops
+const shutdownTimeout = 5 * time.Second
+
+// Server serves the metrics of Registry on /metrics
+type Server struct {
+	server   *http.Server
+	listener net.Listener
+	log      *slog.Logger
+}
+
+// NewServer creates a server listening on addr, such as ":9102"
+func NewServer(addr string) *Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", handler())
+	return &Server{
+		server: &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: shutdownTimeout},
+		log:    slog.Default(),
+	}
+}
+
+func handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Start listens on the address of the server and serves in the background
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.server.Addr, err)
+	}
+	s.listener = listener
+	s.log.Info("Serving metrics", "addr", listener.Addr().String())
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log.Error("Metrics server stopped", logging.Err(err))
+		}
+	}()
+	return nil
+}
+
+// Addr returns the address the server listens on once started
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return s.server.Addr
+	}
+	return s.listener.Addr().String()
+}
+
+// Stop stops the server, waiting for the running scrapes
+func (s *Server) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.log.Error("Error stopping metrics server", logging.Err(err))
+	}
+}
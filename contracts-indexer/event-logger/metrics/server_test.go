@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerServesMetrics(t *testing.T) {
+	s := NewServer("127.0.0.1:0")
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	BlocksProcessed.Inc()
+	EventsStored.WithLabelValues("Deposit").Inc()
+	ObserveRPC("starknet_getEvents", time.Now(), errors.New("timeout"))
+	ObserveTx(TxCommitted, time.Now())
+
+	resp, err := http.Get("http://" + s.Addr() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"pitchlake_blocks_processed_total",
+		`pitchlake_events_stored_total{event="Deposit"}`,
+		`pitchlake_rpc_errors_total{method="starknet_getEvents"} 1`,
+		`pitchlake_rpc_request_duration_seconds_count{method="starknet_getEvents"} 1`,
+		`pitchlake_db_transaction_duration_seconds_count{result="commit"} 1`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics do not contain %s", want)
+		}
+	}
+}
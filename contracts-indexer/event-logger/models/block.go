@@ -0,0 +1,62 @@
+package models
+
+import (
+	"github.com/NethermindEth/juno/core/felt"
+	"github.com/NethermindEth/starknet.go/rpc"
+)
+
+// Block is a block with the events emitted by its transactions, the model
+// every block source delivers whether it reads Juno, an RPC node or a
+// fixture file. Felts encode to JSON as hex strings.
+type Block struct {
+	Number     uint64     `json:"block_number"`
+	Hash       *felt.Felt `json:"block_hash"`
+	ParentHash *felt.Felt `json:"parent_hash"`
+	Timestamp  uint64     `json:"timestamp"`
+	Receipts   []*Receipt `json:"receipts"`
+}
+
+// Receipt holds the events emitted by a transaction, in order
+type Receipt struct {
+	TransactionHash *felt.Felt    `json:"transaction_hash"`
+	Events          []*BlockEvent `json:"events"`
+}
+
+// BlockEvent is an event emitted by a contract in a block. Index is its
+// position among the events of its transaction receipt, which identifies it
+// along with the block and transaction hashes.
+type BlockEvent struct {
+	From  *felt.Felt   `json:"from_address"`
+	Keys  []*felt.Felt `json:"keys"`
+	Data  []*felt.Felt `json:"data"`
+	Index uint64       `json:"index"`
+}
+
+// StarknetBlock returns the row of the block in starknet_blocks
+func (b *Block) StarknetBlock() StarknetBlocks {
+	parentHash := ""
+	if b.ParentHash != nil {
+		parentHash = b.ParentHash.String()
+	}
+	return StarknetBlocks{
+		BlockNumber: b.Number,
+		BlockHash:   b.Hash.String(),
+		ParentHash:  parentHash,
+		Timestamp:   b.Timestamp,
+		Status:      "MINED",
+	}
+}
+
+// LocatedEvent is an event of a starknet_getEvents result along with the
+// position of its transaction in the block and its position in the receipt,
+// which starknet_getEvents does not return
+type LocatedEvent struct {
+	rpc.EmittedEvent
+	TransactionIndex uint64
+	Index            uint64
+}
+
+// BlockEvent returns the event as a block event
+func (e *LocatedEvent) BlockEvent() *BlockEvent {
+	return &BlockEvent{From: e.FromAddress, Keys: e.Keys, Data: e.Data, Index: e.Index}
+}
@@ -2,11 +2,11 @@ package models
 
 import (
 	"database/sql/driver"
+	"driverevents"
 	"fmt"
 	"math/big"
 	"time"
 
-	"github.com/NethermindEth/juno/core"
 	"github.com/NethermindEth/starknet.go/rpc"
 )
 
@@ -53,17 +53,6 @@ func (b BigInt) Value() (driver.Value, error) {
 	return b.Int.String(), nil
 }
 
-func CoreToStarknetBlock(block core.Block) StarknetBlocks {
-	starknetBlock := StarknetBlocks{
-		BlockNumber: block.Number,
-		BlockHash:   block.Hash.String(),
-		ParentHash:  block.ParentHash.String(),
-		Timestamp:   block.Timestamp,
-		Status:      "MINED",
-	}
-	return starknetBlock
-}
-
 func RPCBlockToStarknetBlock(rpcBlock *rpc.BlockTxHashes) *StarknetBlocks {
 	return &StarknetBlocks{
 		BlockNumber: rpcBlock.BlockHeader.Number, // Keep the * to dereference
@@ -79,10 +68,12 @@ type Event struct {
 	TransactionHash string   `json:"transaction_hash"`
 	BlockNumber     uint64   `json:"block_number"`
 	VaultAddress    string   `json:"vault_address"`
+	FromAddress     string   `json:"from_address"`
 	Timestamp       uint64   `json:"timestamp"`
 	EventName       string   `json:"event_name"`
 	EventKeys       []string `json:"event_keys"`
 	EventData       []string `json:"event_data"`
+	EventIndex      uint64   `json:"event_index"`
 	EventNonce      int      `json:"event_nonce"`
 }
 
@@ -100,23 +91,37 @@ type VaultRegistry struct {
 	DeployedAt         string  `json:"deployed_at"`
 	LastBlockIndexed   *string `json:"last_block_indexed"`
 	LastBlockProcessed *string `json:"last_block_processed"`
+	Paused             bool    `json:"paused"`
 }
 
-// DriverEvent represents a unified driver notification event
-type DriverEvent struct {
-	ID            int       `json:"id"`            // Database ID
-	SequenceIndex int64     `json:"sequence_index"` // Sequential counter for ordering
-	Type          string    `json:"type"`          // "StartBlock", "RevertBlock", or "CatchupVault"
-	Timestamp     time.Time `json:"timestamp"`
-	IsProcessed   bool      `json:"is_processed"`
-	
-	// Basic driver event fields (NULL for CatchupVault)
-	BlockHash     string    `json:"block_hash,omitempty"`
-	
-	// Vault catchup event fields (NULL for basic driver events)
-	VaultAddress  string    `json:"vault_address,omitempty"`
-	StartBlockHash string   `json:"start_block_hash,omitempty"` // Changed from StartBlock to StartBlockHash
-	EndBlockHash   string   `json:"end_block_hash,omitempty"`   // Changed from EndBlock to EndBlockHash
+// VaultStatus is a registered vault with its error state, as recorded by the
+// vault registry listener
+type VaultStatus struct {
+	VaultRegistry
+	LastError   *string    `json:"last_error"`
+	ErrorCount  int        `json:"error_count"`
+	LastErrorAt *time.Time `json:"last_error_at"`
 }
 
+// VaultCatchupProgress is how far the catchup of a vault went: it started at
+// StartBlock, is heading to TargetBlock and committed every block up to
+// LastBlockNumber
+type VaultCatchupProgress struct {
+	VaultAddress    string `json:"vault_address"`
+	StartBlock      uint64 `json:"start_block"`
+	TargetBlock     uint64 `json:"target_block"`
+	LastBlockNumber uint64 `json:"last_block_number"`
+	LastBlockHash   string `json:"last_block_hash"`
+}
+
+// OptionRound is an option round contract of a vault, learned from the
+// OptionRoundDeployed event of the vault
+type OptionRound struct {
+	Address      string `json:"address"`
+	VaultAddress string `json:"vault_address"`
+	DeployedAt   string `json:"deployed_at"`
+}
 
+// DriverEvent represents a unified driver notification event, as defined by
+// the contract shared with the event-processor
+type DriverEvent = driverevents.Event
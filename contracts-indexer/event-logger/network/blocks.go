@@ -0,0 +1,147 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"junoplugin/metrics"
+	"junoplugin/models"
+	"sync"
+	"time"
+
+	"github.com/NethermindEth/starknet.go/rpc"
+)
+
+// Defaults used for the backfill fields left empty in BackfillConfig
+const (
+	DefaultBackfillConcurrency = 8
+	DefaultBackfillMaxRetries  = 5
+	DefaultBackfillRetryDelay  = 500 * time.Millisecond
+)
+
+// BackfillConfig controls how block ranges are fetched from the RPC node
+type BackfillConfig struct {
+	// Concurrency is the number of block requests in flight
+	Concurrency int
+	// MaxRetries is how many times a failed block request is retried
+	MaxRetries int
+	// RetryDelay is the delay before the first retry, doubled on each retry
+	// and jittered
+	RetryDelay time.Duration
+}
+
+func (c BackfillConfig) withDefaults() BackfillConfig {
+	if c.Concurrency <= 0 {
+		c.Concurrency = DefaultBackfillConcurrency
+	}
+	if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	}
+	if c.RetryDelay <= 0 {
+		c.RetryDelay = DefaultBackfillRetryDelay
+	}
+	return c
+}
+
+// blockProvider is the part of the RPC provider used to fetch blocks
+type blockProvider interface {
+	BlockWithTxHashes(ctx context.Context, blockID rpc.BlockID) (interface{}, error)
+}
+
+// blockFetcher fetches ranges of block headers with a bounded number of
+// concurrent requests
+type blockFetcher struct {
+	provider blockProvider
+	limiter  *rateLimiter
+	retry    retryPolicy
+	workers  int
+}
+
+func newBlockFetcher(provider blockProvider, config BackfillConfig, limiter *rateLimiter) *blockFetcher {
+	config = config.withDefaults()
+	return &blockFetcher{
+		provider: provider,
+		limiter:  limiter,
+		retry:    newRetryPolicy(config.MaxRetries, config.RetryDelay),
+		workers:  config.Concurrency,
+	}
+}
+
+// fetch returns the blocks from..to in block order. The first block that
+// cannot be fetched after its retries cancels the remaining requests.
+func (f *blockFetcher) fetch(ctx context.Context, from, to uint64) ([]*models.StarknetBlocks, error) {
+	if to < from {
+		return nil, nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	blocks := make([]*models.StarknetBlocks, to-from+1)
+	numbers := make(chan uint64)
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	workers := min(f.workers, len(blocks))
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for number := range numbers {
+				block, err := f.fetchBlock(ctx, number)
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					return
+				}
+				blocks[number-from] = block
+			}
+		}()
+	}
+
+feed:
+	for number := from; number <= to; number++ {
+		select {
+		case numbers <- number:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(numbers)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+func (f *blockFetcher) fetchBlock(ctx context.Context, number uint64) (*models.StarknetBlocks, error) {
+	var block *models.StarknetBlocks
+	err := f.retry.do(ctx, fmt.Sprintf("block %d", number), func() error {
+		if err := f.limiter.Wait(ctx); err != nil {
+			return err
+		}
+		start := time.Now()
+		result, err := f.provider.BlockWithTxHashes(ctx, rpc.BlockID{Number: &number})
+		metrics.ObserveRPC("starknet_getBlockWithTxHashes", start, err)
+		if err != nil {
+			return err
+		}
+		blockTxHashes, ok := result.(*rpc.BlockTxHashes)
+		if !ok {
+			// Pending blocks have no hash yet, the range is past the head
+			return fmt.Errorf("unexpected block type %T for block %d", result, number)
+		}
+		block = RPCBlockToStarknetBlock(blockTxHashes)
+		block.BlockNumber = number
+		return nil
+	})
+	return block, err
+}
@@ -0,0 +1,143 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+	"github.com/NethermindEth/starknet.go/rpc"
+)
+
+// fakeBlockProvider serves blocks whose hash is their number, with a small
+// random-looking delay so that responses come back out of order
+type fakeBlockProvider struct {
+	mu       sync.Mutex
+	failures map[uint64]int
+	inFlight atomic.Int32
+	maxSeen  atomic.Int32
+	calls    atomic.Int32
+}
+
+func (p *fakeBlockProvider) BlockWithTxHashes(ctx context.Context, blockID rpc.BlockID) (interface{}, error) {
+	p.calls.Add(1)
+	n := p.inFlight.Add(1)
+	defer p.inFlight.Add(-1)
+	for {
+		seen := p.maxSeen.Load()
+		if n <= seen || p.maxSeen.CompareAndSwap(seen, n) {
+			break
+		}
+	}
+
+	number := *blockID.Number
+	time.Sleep(time.Duration((number*7)%5) * time.Millisecond)
+
+	p.mu.Lock()
+	if p.failures[number] > 0 {
+		p.failures[number]--
+		p.mu.Unlock()
+		return nil, errors.New("rpc unavailable")
+	}
+	p.mu.Unlock()
+
+	return &rpc.BlockTxHashes{BlockHeader: rpc.BlockHeader{
+		Number:     number,
+		Hash:       new(felt.Felt).SetUint64(number),
+		ParentHash: new(felt.Felt).SetUint64(number - 1),
+		Timestamp:  1000 + number,
+	}}, nil
+}
+
+func newTestFetcher(provider blockProvider, config BackfillConfig) *blockFetcher {
+	f := newBlockFetcher(provider, config, nil)
+	f.retry.sleep = func(time.Duration) {}
+	return f
+}
+
+func TestBlockFetcherOrdersResults(t *testing.T) {
+	provider := &fakeBlockProvider{}
+	f := newTestFetcher(provider, BackfillConfig{Concurrency: 4})
+
+	blocks, err := f.fetch(context.Background(), 10, 49)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(blocks) != 40 {
+		t.Fatalf("Expected 40 blocks, got %d", len(blocks))
+	}
+	for i, block := range blocks {
+		want := uint64(10 + i)
+		if block.BlockNumber != want || block.BlockHash != new(felt.Felt).SetUint64(want).String() {
+			t.Errorf("Expected block %d at index %d, got %+v", want, i, block)
+		}
+	}
+	if max := provider.maxSeen.Load(); max > 4 {
+		t.Errorf("Expected at most 4 requests in flight, saw %d", max)
+	}
+}
+
+func TestBlockFetcherRetries(t *testing.T) {
+	provider := &fakeBlockProvider{failures: map[uint64]int{3: 2, 5: 1}}
+	f := newTestFetcher(provider, BackfillConfig{Concurrency: 2, MaxRetries: 2})
+
+	blocks, err := f.fetch(context.Background(), 1, 6)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(blocks) != 6 || blocks[2].BlockNumber != 3 || blocks[4].BlockNumber != 5 {
+		t.Errorf("Expected blocks 1 to 6, got %v", blocks)
+	}
+	if calls := provider.calls.Load(); calls != 9 {
+		t.Errorf("Expected 9 requests, got %d", calls)
+	}
+}
+
+func TestBlockFetcherStopsOnError(t *testing.T) {
+	provider := &fakeBlockProvider{failures: map[uint64]int{2: 10}}
+	f := newTestFetcher(provider, BackfillConfig{Concurrency: 2, MaxRetries: 1})
+
+	if _, err := f.fetch(context.Background(), 1, 200); err == nil {
+		t.Fatal("Expected an error for a block failing past its retries")
+	}
+	if calls := provider.calls.Load(); calls >= 200 {
+		t.Errorf("Expected the remaining blocks to be cancelled, got %d requests", calls)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(200)
+	start := time.Now()
+	for range 5 {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	// The first request goes out at once, the next four are 5ms apart
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Expected requests to be spaced out, 5 took %v", elapsed)
+	}
+
+	if err := newRateLimiter(0).Wait(context.Background()); err != nil {
+		t.Errorf("Expected a disabled limiter not to wait, got %v", err)
+	}
+}
+
+func TestRetryDelayJitter(t *testing.T) {
+	policy := newRetryPolicy(5, 100*time.Millisecond)
+	for retry := 1; retry <= 5; retry++ {
+		base := 100 * time.Millisecond << (retry - 1)
+		for range 20 {
+			d := policy.delay(retry)
+			if d < base/2 || d > base {
+				t.Fatalf("Retry %d delay %v outside [%v, %v]", retry, d, base/2, base)
+			}
+		}
+	}
+	if d := policy.delay(40); d > maxRetryDelay {
+		t.Errorf("Expected delay capped at %v, got %v", maxRetryDelay, d)
+	}
+}
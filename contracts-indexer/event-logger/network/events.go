@@ -0,0 +1,171 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"junoplugin/metrics"
+	"junoplugin/utils"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+	"github.com/NethermindEth/starknet.go/rpc"
+)
+
+// Defaults used for the events fields left empty in EventsConfig
+const (
+	DefaultEventsChunkSize  = 100
+	DefaultEventsMaxRetries = 3
+	DefaultEventsRetryDelay = time.Second
+)
+
+// EventsConfig controls how events are paged out of the RPC node
+type EventsConfig struct {
+	// ChunkSize is the number of events requested per page
+	ChunkSize int
+	// MaxRetries is how many times a failed page request is retried
+	MaxRetries int
+	// RetryDelay is the delay before the first retry, doubled on each retry
+	// and jittered
+	RetryDelay time.Duration
+}
+
+func (c EventsConfig) withDefaults() EventsConfig {
+	if c.ChunkSize <= 0 {
+		c.ChunkSize = DefaultEventsChunkSize
+	}
+	if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	}
+	if c.RetryDelay <= 0 {
+		c.RetryDelay = DefaultEventsRetryDelay
+	}
+	return c
+}
+
+// EventQuery selects the events to fetch. Address and Keys are optional, Keys
+// follows the starknet_getEvents layout where each position lists the accepted
+// values for that key.
+type EventQuery struct {
+	FromBlock rpc.BlockID
+	ToBlock   rpc.BlockID
+	Address   *string
+	Keys      [][]*felt.Felt
+}
+
+// eventsProvider is the part of the RPC provider used to page events
+type eventsProvider interface {
+	Events(ctx context.Context, input rpc.EventsInput) (*rpc.EventChunk, error)
+}
+
+// EventIterator pages through the events matching a query by following the
+// continuation tokens returned by the node:
+//
+//	it, err := n.IterateEvents(query)
+//	for it.Next() {
+//		for _, event := range it.Page() { ... }
+//	}
+//	if err := it.Err(); err != nil { ... }
+type EventIterator struct {
+	provider eventsProvider
+	ctx      context.Context
+	config   EventsConfig
+	input    rpc.EventsInput
+	limiter  *rateLimiter
+	retry    retryPolicy
+	page     []rpc.EmittedEvent
+	done     bool
+	err      error
+}
+
+func newEventIterator(ctx context.Context, provider eventsProvider, config EventsConfig, limiter *rateLimiter, filter rpc.EventFilter) *EventIterator {
+	config = config.withDefaults()
+	return &EventIterator{
+		provider: provider,
+		ctx:      ctx,
+		config:   config,
+		input: rpc.EventsInput{
+			EventFilter:       filter,
+			ResultPageRequest: rpc.ResultPageRequest{ChunkSize: config.ChunkSize},
+		},
+		limiter: limiter,
+		retry:   newRetryPolicy(config.MaxRetries, config.RetryDelay),
+	}
+}
+
+// Next fetches the next page, returning false once every page was read or a
+// request failed after its retries
+func (it *EventIterator) Next() bool {
+	if it.done || it.err != nil {
+		return false
+	}
+	chunk, err := it.fetch()
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.page = chunk.Events
+	if chunk.ContinuationToken == "" {
+		it.done = true
+	}
+	it.input.ContinuationToken = chunk.ContinuationToken
+	return true
+}
+
+// Page returns the events of the page fetched by the last call to Next
+func (it *EventIterator) Page() []rpc.EmittedEvent {
+	return it.page
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *EventIterator) Err() error {
+	return it.err
+}
+
+// fetch requests the current page, retrying with a jittered backoff
+func (it *EventIterator) fetch() (*rpc.EventChunk, error) {
+	var chunk *rpc.EventChunk
+	err := it.retry.do(it.ctx, "events page", func() error {
+		if err := it.limiter.Wait(it.ctx); err != nil {
+			return err
+		}
+		var err error
+		start := time.Now()
+		chunk, err = it.provider.Events(it.ctx, it.input)
+		metrics.ObserveRPC("starknet_getEvents", start, err)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return chunk, nil
+}
+
+// IterateEvents returns an iterator over every event matching the query
+func (n *Network) IterateEvents(query EventQuery) (*EventIterator, error) {
+	filter := rpc.EventFilter{
+		FromBlock: query.FromBlock,
+		ToBlock:   query.ToBlock,
+		Keys:      query.Keys,
+	}
+	if query.Address != nil {
+		addressBytes, err := utils.HexStringToFelt(*query.Address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event address %s: %w", *query.Address, err)
+		}
+		addressFelt := felt.FromBytes(addressBytes)
+		filter.Address = &addressFelt
+	}
+	return newEventIterator(n.ctx, n.provider, n.config.Events, n.limiter, filter), nil
+}
+
+// collectEvents reads every page of an iterator into a single chunk
+func collectEvents(it *EventIterator) (*rpc.EventChunk, error) {
+	all := &rpc.EventChunk{}
+	for it.Next() {
+		all.Events = append(all.Events, it.Page()...)
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
@@ -0,0 +1,134 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+	"github.com/NethermindEth/starknet.go/rpc"
+)
+
+// fakeEventsProvider serves a fixed list of events in pages of the requested
+// chunk size, failing the first failures requests
+type fakeEventsProvider struct {
+	events   []rpc.EmittedEvent
+	failures int
+	inputs   []rpc.EventsInput
+}
+
+func (p *fakeEventsProvider) Events(ctx context.Context, input rpc.EventsInput) (*rpc.EventChunk, error) {
+	p.inputs = append(p.inputs, input)
+	if p.failures > 0 {
+		p.failures--
+		return nil, errors.New("rpc unavailable")
+	}
+
+	start := 0
+	if input.ContinuationToken != "" {
+		var err error
+		if start, err = strconv.Atoi(input.ContinuationToken); err != nil {
+			return nil, err
+		}
+	}
+	end := min(start+input.ChunkSize, len(p.events))
+	chunk := &rpc.EventChunk{Events: p.events[start:end]}
+	if end < len(p.events) {
+		chunk.ContinuationToken = strconv.Itoa(end)
+	}
+	return chunk, nil
+}
+
+func testEvents(n int) []rpc.EmittedEvent {
+	events := make([]rpc.EmittedEvent, n)
+	for i := range events {
+		events[i] = rpc.EmittedEvent{BlockNumber: uint64(i)}
+	}
+	return events
+}
+
+func newTestIterator(provider eventsProvider, config EventsConfig) *EventIterator {
+	it := newEventIterator(context.Background(), provider, config, nil, rpc.EventFilter{})
+	it.retry.sleep = func(time.Duration) {}
+	return it
+}
+
+func TestEventIteratorFollowsContinuationTokens(t *testing.T) {
+	provider := &fakeEventsProvider{events: testEvents(25)}
+	it := newTestIterator(provider, EventsConfig{ChunkSize: 10})
+
+	chunk, err := collectEvents(it)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(chunk.Events) != 25 {
+		t.Fatalf("Expected 25 events, got %d", len(chunk.Events))
+	}
+	for i, event := range chunk.Events {
+		if event.BlockNumber != uint64(i) {
+			t.Errorf("Expected event %d in order, got block %d", i, event.BlockNumber)
+		}
+	}
+	if len(provider.inputs) != 3 {
+		t.Errorf("Expected 3 page requests, got %d", len(provider.inputs))
+	}
+	for _, input := range provider.inputs {
+		if input.ChunkSize != 10 {
+			t.Errorf("Expected chunk size 10, got %d", input.ChunkSize)
+		}
+	}
+}
+
+func TestEventIteratorEmptyRange(t *testing.T) {
+	provider := &fakeEventsProvider{}
+	it := newTestIterator(provider, EventsConfig{})
+
+	chunk, err := collectEvents(it)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(chunk.Events) != 0 || len(provider.inputs) != 1 {
+		t.Errorf("Expected a single empty page, got %d events in %d requests", len(chunk.Events), len(provider.inputs))
+	}
+	if provider.inputs[0].ChunkSize != DefaultEventsChunkSize {
+		t.Errorf("Expected default chunk size %d, got %d", DefaultEventsChunkSize, provider.inputs[0].ChunkSize)
+	}
+}
+
+func TestEventIteratorRetries(t *testing.T) {
+	provider := &fakeEventsProvider{events: testEvents(5), failures: 2}
+	it := newTestIterator(provider, EventsConfig{ChunkSize: 10, MaxRetries: 2})
+
+	chunk, err := collectEvents(it)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(chunk.Events) != 5 {
+		t.Errorf("Expected 5 events, got %d", len(chunk.Events))
+	}
+
+	provider = &fakeEventsProvider{events: testEvents(5), failures: 3}
+	it = newTestIterator(provider, EventsConfig{ChunkSize: 10, MaxRetries: 2})
+	if _, err := collectEvents(it); err == nil {
+		t.Error("Expected an error once the retries are exhausted")
+	}
+	if len(provider.inputs) != 3 {
+		t.Errorf("Expected 3 attempts, got %d", len(provider.inputs))
+	}
+}
+
+func TestEventIteratorPassesFilter(t *testing.T) {
+	provider := &fakeEventsProvider{}
+	selector := new(felt.Felt).SetUint64(7)
+	keys := [][]*felt.Felt{{selector}}
+	it := newEventIterator(context.Background(), provider, EventsConfig{}, nil, rpc.EventFilter{Keys: keys})
+	if _, err := collectEvents(it); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got := provider.inputs[0].Keys
+	if len(got) != 1 || len(got[0]) != 1 || !got[0][0].Equal(selector) {
+		t.Errorf("Expected keys filter %v, got %v", keys, got)
+	}
+}
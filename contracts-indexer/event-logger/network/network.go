@@ -3,28 +3,62 @@ package network
 import (
 	"context"
 	"fmt"
+	"junoplugin/logging"
+	"junoplugin/metrics"
 	"junoplugin/models"
 	"junoplugin/utils"
-	"log"
-	"os"
+	"log/slog"
+	"net/http"
+	"time"
 
 	"github.com/NethermindEth/juno/core/felt"
+	"github.com/NethermindEth/starknet.go/client"
 	"github.com/NethermindEth/starknet.go/rpc"
 )
 
+// DefaultRateLimit is the RPC requests per second allowed by default
+const DefaultRateLimit = 20
+
+// DefaultTimeout bounds an RPC request when Config.Timeout is empty
+const DefaultTimeout = 30 * time.Second
+
+// Config holds the RPC settings of a Network. Empty fields take their
+// defaults.
+type Config struct {
+	Events   EventsConfig
+	Backfill BackfillConfig
+	// RateLimit caps the RPC requests sent per second, 0 disables the limit
+	RateLimit float64
+	// Timeout bounds each RPC request
+	Timeout time.Duration
+}
+
 type Network struct {
 	provider *rpc.Provider
 	ctx      context.Context
+	config   Config
+	limiter  *rateLimiter
+	blocks   *blockFetcher
 }
 
-func NewNetwork() (*Network, error) {
-	provider, err := rpc.NewProvider(os.Getenv("RPC_URL"))
+// NewNetwork connects to the RPC node at rpcURL
+func NewNetwork(rpcURL string, config Config) (*Network, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	provider, err := rpc.NewProvider(rpcURL, client.WithHTTPClient(&http.Client{Timeout: config.Timeout}))
 	if err != nil {
 		return nil, err
 	}
+	config.Events = config.Events.withDefaults()
+	config.Backfill = config.Backfill.withDefaults()
+	limiter := newRateLimiter(config.RateLimit)
 	return &Network{
 		provider: provider,
 		ctx:      context.Background(),
+		config:   config,
+		limiter:  limiter,
+		blocks:   newBlockFetcher(provider, config.Backfill, limiter),
 	}, nil
 }
 
@@ -34,7 +68,12 @@ func (n *Network) GetBlockByHash(hash string) (*rpc.BlockTxHashes, error) {
 		return nil, err
 	}
 	hashFelt := felt.FromBytes(feltString)
+	if err := n.limiter.Wait(n.ctx); err != nil {
+		return nil, err
+	}
+	start := time.Now()
 	block, err := n.provider.BlockWithTxHashes(n.ctx, rpc.BlockID{Hash: &hashFelt})
+	metrics.ObserveRPC("starknet_getBlockWithTxHashes", start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -45,134 +84,38 @@ func (n *Network) GetBlockByHash(hash string) (*rpc.BlockTxHashes, error) {
 	return blockTxHashes, nil
 }
 
-func (n *Network) GetEvents(fromBlock rpc.BlockID, toBlock rpc.BlockID, address *string) (*rpc.EventChunk, error) {
-
-	//Should be written bettern
-	var addressFelt felt.Felt
-	var addressBytes []byte
-	var err error
-	filter := rpc.EventFilter{
+// GetEvents returns every event between two blocks, following the
+// continuation tokens until the range is exhausted. The address and keys
+// filters are optional.
+func (n *Network) GetEvents(fromBlock rpc.BlockID, toBlock rpc.BlockID, address *string, keys [][]*felt.Felt) (*rpc.EventChunk, error) {
+	it, err := n.IterateEvents(EventQuery{
 		FromBlock: fromBlock,
 		ToBlock:   toBlock,
-	}
-	if address != nil {
-		addressBytes, err = utils.HexStringToFelt(*address)
-		addressFelt = felt.FromBytes(addressBytes)
-		filter.Address = &addressFelt
-
-	}
+		Address:   address,
+		Keys:      keys,
+	})
 	if err != nil {
-		log.Printf("Error getting felt %f", err)
+		slog.Error("Error building events filter", logging.Err(err))
 		return nil, err
 	}
-
-	log.Printf("Filter: %v", filter)
-	input := rpc.EventsInput{
-		EventFilter: filter,
-		ResultPageRequest: rpc.ResultPageRequest{
-			ChunkSize: 10,
-		},
-	}
-	events, err := n.provider.Events(n.ctx, input)
+	events, err := collectEvents(it)
 	if err != nil {
-		log.Printf("Error getting events %f", err)
+		slog.Error("Error getting events", logging.Err(err))
 		return nil, err
 	}
 	return events, nil
 }
 
+// GetBlocks fetches the headers of the blocks fromBlock..toBlock concurrently,
+// within the rate limit, and returns them in block order
 func (n *Network) GetBlocks(fromBlock uint64, toBlock uint64) ([]*models.StarknetBlocks, error) {
-
-	numBlocks := toBlock - fromBlock + 1
-	blocks := make([]*models.StarknetBlocks, 0, numBlocks)
-
-	for i := fromBlock; i <= toBlock; i++ {
-		log.Printf("Getting block %v", i)
-
-		block, err := n.provider.BlockWithTxHashes(n.ctx, rpc.BlockID{Number: &i})
-		if err != nil {
-			log.Printf("Error getting block %v: %v", i, err)
-			return nil, fmt.Errorf("failed to get block %d: %w", i, err)
-		}
-
-		blockTxHashes, ok := block.(*rpc.BlockTxHashes)
-		if !ok {
-			return nil, fmt.Errorf("unexpected block type for block %v", i)
-		}
-
-		blockHeader := blockTxHashes.BlockHeader
-		starknetBlock := &models.StarknetBlocks{
-			BlockNumber: i,
-			BlockHash:   blockHeader.Hash.String(),
-			ParentHash:  blockHeader.ParentHash.String(),
-			Timestamp:   blockHeader.Timestamp,
-		}
-
-		blocks = append(blocks, starknetBlock)
-		log.Printf("Processed block %v", i)
+	slog.Debug("Getting blocks", "from", fromBlock, "to", toBlock)
+	blocks, err := n.blocks.fetch(n.ctx, fromBlock, toBlock)
+	if err != nil {
+		slog.Error("Error getting blocks", "from", fromBlock, "to", toBlock, logging.Err(err))
+		return nil, err
 	}
-
 	return blocks, nil
-
-	// Concurrent requests, debug further before using
-	// numBlocks := toBlock - fromBlock + 1
-	// blocks := make([]*models.StarknetBlocks, numBlocks)
-
-	// // Use a WaitGroup to wait for all goroutines to finish
-	// var wg sync.WaitGroup
-	// // Channel for errors
-	// errCh := make(chan error, numBlocks)
-	// // Semaphore to limit concurrency
-	// maxConcurrent := 10 // Adjust based on your needs
-	// sem := make(chan struct{}, maxConcurrent)
-
-	// for i := fromBlock; i <= toBlock; i++ {
-	// 	wg.Add(1)
-	// 	sem <- struct{}{} // Acquire semaphore
-
-	// 	go func(blockNum uint64, index uint64) {
-	// 		defer wg.Done()
-	// 		defer func() { <-sem }() // Release semaphore
-
-	// 		log.Printf("Getting block %v", blockNum)
-	// 		block, err := n.provider.BlockWithTxHashes(n.ctx, rpc.BlockID{Number: &blockNum})
-	// 		if err != nil {
-	// 			log.Printf("Error getting block %v: %v", blockNum, err)
-	// 			errCh <- err
-	// 			return
-	// 		}
-
-	// 		blockTxHashes, ok := block.(*rpc.BlockTxHashes)
-	// 		if !ok {
-	// 			errCh <- fmt.Errorf("unexpected block type for block %v", blockNum)
-	// 			return
-	// 		}
-
-	// 		blockHeader := blockTxHashes.BlockHeader
-	// 		starknetBlock := &models.StarknetBlocks{
-	// 			BlockNumber: blockNum,
-	// 			BlockHash:   blockHeader.Hash.String(),
-	// 			ParentHash:  blockHeader.ParentHash.String(),
-	// 			Timestamp:   blockHeader.Timestamp,
-	// 		}
-
-	// 		// Store in the correct position in the result slice
-	// 		blocks[index-fromBlock] = starknetBlock
-	// 		log.Printf("Processed block %v", blockNum)
-	// 	}(i, i)
-	// }
-
-	// // Wait for all goroutines to finish
-	// wg.Wait()
-	// close(errCh)
-
-	// // Check for any errors
-	// select {
-	// case err := <-errCh:
-	// 	return nil, err
-	// default:
-	// 	return blocks, nil
-	// }
 }
 
 func RPCBlockToStarknetBlock(rpcBlock *rpc.BlockTxHashes) *models.StarknetBlocks {
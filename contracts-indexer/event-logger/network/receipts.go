@@ -0,0 +1,156 @@
+package network
+
+import (
+	"fmt"
+	"junoplugin/metrics"
+	"junoplugin/models"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+	"github.com/NethermindEth/starknet.go/rpc"
+)
+
+// GetHeadNumber returns the number of the latest block accepted by the node
+func (n *Network) GetHeadNumber() (uint64, error) {
+	var head uint64
+	err := n.blocks.retry.do(n.ctx, "head block number", func() error {
+		if err := n.limiter.Wait(n.ctx); err != nil {
+			return err
+		}
+		var err error
+		start := time.Now()
+		head, err = n.provider.BlockNumber(n.ctx)
+		metrics.ObserveRPC("starknet_blockNumber", start, err)
+		return err
+	})
+	return head, err
+}
+
+// GetBlockWithEvents returns a block with the events of its transactions
+func (n *Network) GetBlockWithEvents(number uint64) (*models.Block, error) {
+	var block *models.Block
+	err := n.blocks.retry.do(n.ctx, fmt.Sprintf("block %d with receipts", number), func() error {
+		if err := n.limiter.Wait(n.ctx); err != nil {
+			return err
+		}
+		start := time.Now()
+		result, err := n.provider.BlockWithReceipts(n.ctx, rpc.BlockID{Number: &number})
+		metrics.ObserveRPC("starknet_getBlockWithReceipts", start, err)
+		if err != nil {
+			return err
+		}
+		withReceipts, ok := result.(*rpc.BlockWithReceipts)
+		if !ok {
+			// Pre-confirmed blocks have no hash yet, the block is past the head
+			return fmt.Errorf("unexpected block type %T for block %d", result, number)
+		}
+		block = RPCBlockToBlock(withReceipts)
+		return nil
+	})
+	return block, err
+}
+
+// RPCBlockToBlock converts a block with receipts to the block model
+func RPCBlockToBlock(rpcBlock *rpc.BlockWithReceipts) *models.Block {
+	receipts := make([]*models.Receipt, 0, len(rpcBlock.Transactions))
+	for _, tx := range rpcBlock.Transactions {
+		events := make([]*models.BlockEvent, 0, len(tx.Receipt.Events))
+		for i, event := range tx.Receipt.Events {
+			events = append(events, &models.BlockEvent{
+				From:  event.FromAddress,
+				Keys:  event.Keys,
+				Data:  event.Data,
+				Index: uint64(i),
+			})
+		}
+		receipts = append(receipts, &models.Receipt{
+			TransactionHash: tx.Receipt.Hash,
+			Events:          events,
+		})
+	}
+	return &models.Block{
+		Number:     rpcBlock.Number,
+		Hash:       rpcBlock.Hash,
+		ParentHash: rpcBlock.ParentHash,
+		Timestamp:  rpcBlock.Timestamp,
+		Receipts:   receipts,
+	}
+}
+
+// LocateEvents returns the events of a starknet_getEvents result with their
+// position in their transaction receipt, read from the receipts of their
+// blocks. Each block is fetched once. An event whose block is no longer
+// canonical, or that its receipt does not have, is an error.
+func (n *Network) LocateEvents(events []rpc.EmittedEvent) ([]models.LocatedEvent, error) {
+	blocks := make(map[uint64]*models.Block)
+	for _, event := range events {
+		if _, ok := blocks[event.BlockNumber]; ok {
+			continue
+		}
+		block, err := n.GetBlockWithEvents(event.BlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		blocks[event.BlockNumber] = block
+	}
+	return locateEvents(events, blocks)
+}
+
+// eventPosition is an event of a receipt, by transaction hash and index
+type eventPosition struct {
+	txHash string
+	index  uint64
+}
+
+// locateEvents matches every event to the first equal event of its receipt in
+// blocks, by number, that no other event was matched to. Receipts are in the
+// order of the transactions of the block.
+func locateEvents(events []rpc.EmittedEvent, blocks map[uint64]*models.Block) ([]models.LocatedEvent, error) {
+	matched := make(map[eventPosition]struct{})
+	located := make([]models.LocatedEvent, 0, len(events))
+	for _, event := range events {
+		block := blocks[event.BlockNumber]
+		if block == nil || event.BlockHash == nil || !block.Hash.Equal(event.BlockHash) {
+			return nil, fmt.Errorf("event of transaction %s is not in the canonical block %d", event.TransactionHash, event.BlockNumber)
+		}
+		txIndex, index, ok := locateEvent(event, block, matched)
+		if !ok {
+			return nil, fmt.Errorf("event of transaction %s is not in its receipt in block %d", event.TransactionHash, event.BlockNumber)
+		}
+		located = append(located, models.LocatedEvent{EmittedEvent: event, TransactionIndex: txIndex, Index: index})
+	}
+	return located, nil
+}
+
+func locateEvent(event rpc.EmittedEvent, block *models.Block, matched map[eventPosition]struct{}) (txIndex, index uint64, ok bool) {
+	for i, receipt := range block.Receipts {
+		if !receipt.TransactionHash.Equal(event.TransactionHash) {
+			continue
+		}
+		for _, candidate := range receipt.Events {
+			position := eventPosition{txHash: receipt.TransactionHash.String(), index: candidate.Index}
+			if _, ok := matched[position]; ok || !sameEvent(candidate, event.Event) {
+				continue
+			}
+			matched[position] = struct{}{}
+			return uint64(i), candidate.Index, true
+		}
+	}
+	return 0, 0, false
+}
+
+func sameEvent(a *models.BlockEvent, b rpc.Event) bool {
+	return a.From.Equal(b.FromAddress) && sameFelts(a.Keys, b.Keys) && sameFelts(a.Data, b.Data)
+}
+
+func sameFelts(a, b []*felt.Felt) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package network
+
+import (
+	"testing"
+
+	"junoplugin/models"
+
+	"github.com/NethermindEth/juno/core/felt"
+	"github.com/NethermindEth/starknet.go/rpc"
+)
+
+func TestRPCBlockToBlock(t *testing.T) {
+	from := new(felt.Felt).SetUint64(0x7a417)
+	rpcBlock := &rpc.BlockWithReceipts{
+		BlockHeader: rpc.BlockHeader{
+			Number:     12,
+			Hash:       new(felt.Felt).SetUint64(0x12),
+			ParentHash: new(felt.Felt).SetUint64(0x11),
+			Timestamp:  1012,
+		},
+		BlockBodyWithReceipts: rpc.BlockBodyWithReceipts{
+			Transactions: []rpc.TransactionWithReceipt{
+				{Receipt: rpc.TransactionReceipt{Hash: new(felt.Felt).SetUint64(0xa)}},
+				{Receipt: rpc.TransactionReceipt{
+					Hash: new(felt.Felt).SetUint64(0xb),
+					Events: []rpc.Event{{
+						FromAddress: from,
+						EventContent: rpc.EventContent{
+							Keys: []*felt.Felt{new(felt.Felt).SetUint64(1)},
+							Data: []*felt.Felt{new(felt.Felt).SetUint64(2), new(felt.Felt).SetUint64(3)},
+						},
+					}},
+				}},
+			},
+		},
+	}
+
+	block := RPCBlockToBlock(rpcBlock)
+	if block.Number != 12 || block.Hash.String() != "0x12" || block.ParentHash.String() != "0x11" || block.Timestamp != 1012 {
+		t.Errorf("block = %+v, want block 12 0x12 on 0x11", block)
+	}
+	if len(block.Receipts) != 2 || len(block.Receipts[0].Events) != 0 {
+		t.Fatalf("expected 2 receipts, the first without events, got %+v", block.Receipts)
+	}
+	receipt := block.Receipts[1]
+	if receipt.TransactionHash.String() != "0xb" || len(receipt.Events) != 1 {
+		t.Fatalf("receipt = %+v, want tx 0xb with one event", receipt)
+	}
+	event := receipt.Events[0]
+	if !event.From.Equal(from) || len(event.Keys) != 1 || len(event.Data) != 2 || event.Data[1].String() != "0x3" || event.Index != 0 {
+		t.Errorf("event = %+v, want the event of %s", event, from)
+	}
+}
+
+func TestLocateEvents(t *testing.T) {
+	vault := new(felt.Felt).SetUint64(0x7a417)
+	other := new(felt.Felt).SetUint64(0x0dc)
+	key := []*felt.Felt{new(felt.Felt).SetUint64(1)}
+	tx := new(felt.Felt).SetUint64(0xb)
+	block := &models.Block{
+		Number: 12,
+		Hash:   new(felt.Felt).SetUint64(0x12),
+		Receipts: []*models.Receipt{
+			{TransactionHash: new(felt.Felt).SetUint64(0xa), Events: []*models.BlockEvent{{From: vault, Keys: key}}},
+			{TransactionHash: tx, Events: []*models.BlockEvent{
+				{From: other, Keys: key, Index: 0},
+				{From: vault, Keys: key, Index: 1},
+				{From: other, Keys: key, Index: 2},
+				{From: vault, Keys: key, Index: 3},
+			}},
+		},
+	}
+	emitted := func(blockHash uint64) rpc.EmittedEvent {
+		return rpc.EmittedEvent{
+			Event:           rpc.Event{FromAddress: vault, EventContent: rpc.EventContent{Keys: key}},
+			BlockHash:       new(felt.Felt).SetUint64(blockHash),
+			BlockNumber:     12,
+			TransactionHash: tx,
+		}
+	}
+	blocks := map[uint64]*models.Block{12: block}
+
+	// The two equal events of the vault take its two positions in order
+	located, err := locateEvents([]rpc.EmittedEvent{emitted(0x12), emitted(0x12)}, blocks)
+	if err != nil {
+		t.Fatalf("locateEvents failed: %v", err)
+	}
+	if len(located) != 2 || located[0].Index != 1 || located[1].Index != 3 {
+		t.Errorf("located = %+v, want the events at 1 and 3", located)
+	}
+	if located[0].TransactionIndex != 1 || located[1].TransactionIndex != 1 {
+		t.Errorf("located = %+v, want the events of the second transaction", located)
+	}
+	if event := located[1].BlockEvent(); event.Index != 3 || !event.From.Equal(vault) {
+		t.Errorf("block event = %+v, want the event of %s at 3", event, vault)
+	}
+
+	if _, err := locateEvents([]rpc.EmittedEvent{emitted(0x12), emitted(0x12), emitted(0x12)}, blocks); err == nil {
+		t.Error("expected an error for an event missing from its receipt")
+	}
+	if _, err := locateEvents([]rpc.EmittedEvent{emitted(0x13)}, blocks); err == nil {
+		t.Error("expected an error for an event of a reorged block")
+	}
+}
@@ -0,0 +1,98 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"junoplugin/logging"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// maxRetryDelay caps the exponential backoff between two attempts
+const maxRetryDelay = 30 * time.Second
+
+// rateLimiter spaces out RPC requests so that at most perSecond are sent each
+// second across every goroutine sharing it. A nil limiter never waits.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// Wait blocks until the next request slot, or until ctx is done
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryPolicy retries failed RPC calls with an exponential backoff. Each delay
+// is jittered between half and all of its value so that concurrent workers
+// hitting the same failure do not retry in lockstep.
+type retryPolicy struct {
+	maxRetries int
+	baseDelay  time.Duration
+	sleep      func(time.Duration)
+}
+
+func newRetryPolicy(maxRetries int, baseDelay time.Duration) retryPolicy {
+	return retryPolicy{maxRetries: maxRetries, baseDelay: baseDelay, sleep: time.Sleep}
+}
+
+// delay returns the jittered backoff before the given retry, starting at 1
+func (p retryPolicy) delay(retry int) time.Duration {
+	d := p.baseDelay << (retry - 1)
+	if d <= 0 || d > maxRetryDelay {
+		d = maxRetryDelay
+	}
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// do calls fn until it succeeds, the retries are exhausted or ctx is done
+func (p retryPolicy) do(ctx context.Context, what string, fn func() error) error {
+	var err error
+	for attempt := 0; attempt <= p.maxRetries; attempt++ {
+		if attempt > 0 {
+			slog.Warn("Retrying RPC request", "request", what, "attempt", attempt, "max_retries", p.maxRetries, logging.Err(err))
+			p.sleep(p.delay(attempt))
+		}
+		if err = fn(); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return fmt.Errorf("failed to get %s after %d retries: %w", what, p.maxRetries, err)
+}
@@ -1,34 +1,73 @@
 package block
 
 import (
+	"driverevents"
+	"fmt"
 	"junoplugin/db"
+	"junoplugin/logging"
+	"junoplugin/metrics"
 	"junoplugin/models"
-	"junoplugin/network"
-	"junoplugin/plugin/vault"
-	"log"
+	"log/slog"
 	"sync"
 
-	"github.com/NethermindEth/juno/core"
 	"github.com/NethermindEth/juno/core/felt"
-	junoplugin "github.com/NethermindEth/juno/plugin"
+	"github.com/NethermindEth/starknet.go/rpc"
 )
 
+// Store is the storage used by the processor, implemented by *db.DB. Writes
+// go through the db.Tx of InTx.
+type Store interface {
+	InTx(fn func(tx db.Tx) error) error
+	GetBlockRange(fromBlock, toBlock uint64) ([]*models.StarknetBlocks, error)
+	GetFirstBlock() (*models.StarknetBlocks, error)
+	GetLastBlock() (*models.StarknetBlocks, error)
+}
+
+// VaultHandler indexes the events of vaults and of their option rounds,
+// implemented by *vault.Manager
+type VaultHandler interface {
+	IsVaultAddress(address string) bool
+	IsRoundAddress(address string) bool
+	ProcessVaultEvent(tx db.Tx, txHash string, vaultAddress string, event *models.BlockEvent, blockNumber uint64, blockHash felt.Felt) error
+	ProcessRoundEvent(tx db.Tx, txHash string, roundAddress string, event *models.BlockEvent, blockNumber uint64, blockHash felt.Felt) error
+	RewindVaults(addresses []string, parentHash string)
+	RevertRounds(blockHashes []string)
+	DiscoverVault(tx db.Tx, txHash string, event *models.BlockEvent, blockNumber uint64, blockHash felt.Felt) (*models.VaultRegistry, error)
+	TrackVaults(vaults []*models.VaultRegistry)
+	UntrackVaults(addresses []string)
+}
+
+// Network fetches chain data over RPC, implemented by *network.Network
+type Network interface {
+	GetBlocks(fromBlock uint64, toBlock uint64) ([]*models.StarknetBlocks, error)
+	GetEvents(fromBlock rpc.BlockID, toBlock rpc.BlockID, address *string, keys [][]*felt.Felt) (*rpc.EventChunk, error)
+	LocateEvents(events []rpc.EmittedEvent) ([]models.LocatedEvent, error)
+}
+
+// backfillBatchSize is the number of blocks fetched and committed at a time
+// by CatchupBlocks
+const backfillBatchSize = 1000
+
+// backfillIndex is how many blocks below the head are stored when nothing is
+// indexed yet, in case the starting block gets reorged
+const backfillIndex = 3
+
 // Processor handles block processing logic
 type Processor struct {
-	db           *db.DB
-	network      *network.Network
-	vaultManager *vault.Manager
+	db           Store
+	network      Network
+	vaultManager VaultHandler
 	lastBlockDB  *models.StarknetBlocks
 	cursor       uint64
 	mu           sync.Mutex
-	log          *log.Logger
+	log          *slog.Logger
 }
 
 // NewProcessor creates a new block processor
 func NewProcessor(
-	db *db.DB,
-	network *network.Network,
-	vaultManager *vault.Manager,
+	db Store,
+	network Network,
+	vaultManager VaultHandler,
 	lastBlockDB *models.StarknetBlocks,
 	cursor uint64,
 ) *Processor {
@@ -38,16 +77,12 @@ func NewProcessor(
 		vaultManager: vaultManager,
 		lastBlockDB:  lastBlockDB,
 		cursor:       cursor,
-		log:          log.Default(),
+		log:          slog.Default(),
 	}
 }
 
 // ProcessNewBlock processes a new block
-func (bp *Processor) ProcessNewBlock(
-	block *core.Block,
-	stateUpdate *core.StateUpdate,
-	newClasses map[felt.Felt]core.Class,
-) error {
+func (bp *Processor) ProcessNewBlock(block *models.Block) error {
 	if block.Number < bp.cursor {
 		return nil
 	}
@@ -56,98 +91,167 @@ func (bp *Processor) ProcessNewBlock(
 	defer bp.mu.Unlock()
 	// Check if we need to catch up
 
-	bp.db.BeginTx()
-	bp.log.Println("Processing new block", block.Number)
+	bp.log.Info("Processing new block", logging.Block(block.Number), logging.BlockHash(block.Hash.String()))
 
-	// Process events in the block
-	err := bp.processBlockEvents(block)
-	if err != nil {
-		bp.db.RollbackTx()
-		bp.log.Println("Error processing block events", err)
-		return err
-	}
+	starknetBlock := block.StarknetBlock()
+	var discovered []*models.VaultRegistry
+	err := bp.db.InTx(func(tx db.Tx) error {
+		// Process events in the block
+		var err error
+		discovered, err = bp.processBlockEvents(tx, block)
+		if err != nil {
+			bp.log.Error("Error processing block events", logging.Block(block.Number), logging.Err(err))
+			return err
+		}
 
-	// Store the block
-	starknetBlock := models.CoreToStarknetBlock(*block)
+		// Store the block
+		if err := tx.InsertBlock(&starknetBlock); err != nil {
+			bp.log.Error("Error inserting block", logging.Block(block.Number), logging.Err(err))
+			return err
+		}
 
-	err = bp.db.InsertBlock(&starknetBlock)
+		// Send StartBlock event right before commit
+		return bp.sendDriverEvent(tx, driverevents.TypeStartBlock, block.Hash.String())
+	})
 	if err != nil {
-		bp.db.RollbackTx()
-		bp.log.Println("Error inserting block", err)
 		return err
 	}
-
+	metrics.BlocksProcessed.Inc()
+	bp.vaultManager.TrackVaults(discovered)
 	bp.lastBlockDB = &starknetBlock
 
-	// Send StartBlock event right before commit
-	bp.sendDriverEvent("StartBlock", block.Hash.String())
-	bp.db.CommitTx()
-
 	return nil
 }
 
-// RevertBlock reverts a block
-func (bp *Processor) RevertBlock(
-	from,
-	to *junoplugin.BlockAndStateUpdate,
-	reverseStateDiff *core.StateDiff,
-) error {
-	// FIXED: Add proper transaction handling for revert
-	bp.db.BeginTx()
+// RevertBlock reverts a block. Juno reverts a multi-block reorg one block at
+// a time from the head down, but blocks stored above the reverted one (for
+// instance by a catchup) are on the abandoned branch as well, so every mined
+// block at or above it is reverted, highest first. The events of each block
+// are flagged, a RevertBlock driver event is emitted per block, and the vault
+// cursors pointing into the abandoned branch are rewound to the parent.
+func (bp *Processor) RevertBlock(from, to *models.Block) error {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	var blocks []*models.StarknetBlocks
+	var hashes, vaults, removed []string
+	parentHash := from.ParentHash.String()
+	err := bp.db.InTx(func(tx db.Tx) error {
+		var err error
+		blocks, err = tx.GetBlocksFrom(from.Number)
+		if err != nil {
+			return err
+		}
+		revertedHash := from.Hash.String()
+		found := false
+		for _, b := range blocks {
+			if b.BlockHash == revertedHash {
+				found = true
+			}
+		}
+		if !found {
+			// Not stored (below the cursor or never indexed), still flag its events
+			blocks = append(blocks, &models.StarknetBlocks{BlockNumber: from.Number, BlockHash: revertedHash})
+		}
 
-	err := bp.db.RevertBlock(from.Block.Number, from.Block.Hash.String())
+		hashes = make([]string, 0, len(blocks))
+		for _, b := range blocks {
+			if err := tx.RevertBlock(b.BlockNumber, b.BlockHash); err != nil {
+				return err
+			}
+			reverted, err := tx.RevertEvents(b.BlockHash)
+			if err != nil {
+				return err
+			}
+			bp.log.Info("Reverted block", logging.Block(b.BlockNumber), logging.BlockHash(b.BlockHash), "events", reverted)
+			// Send RevertBlock events right before commit, highest block first
+			if err := bp.sendDriverEvent(tx, driverevents.TypeRevertBlock, b.BlockHash); err != nil {
+				return err
+			}
+			hashes = append(hashes, b.BlockHash)
+		}
+
+		if vaults, err = tx.RewindVaultCursors(hashes, parentHash); err != nil {
+			return err
+		}
+		removed, err = tx.DeleteVaultsDeployedAt(hashes)
+		return err
+	})
 	if err != nil {
-		bp.db.RollbackTx()
 		return err
 	}
-
-	// TODO: Implement vault event reversion if needed
-	// This was commented out in the original code
-
-	// Send RevertBlock event right before commit
-	bp.sendDriverEvent("RevertBlock", from.Block.Hash.String())
-	bp.db.CommitTx()
-
+	metrics.BlocksReverted.Add(float64(len(blocks)))
+
+	bp.vaultManager.RewindVaults(vaults, parentHash)
+	bp.vaultManager.UntrackVaults(removed)
+	bp.vaultManager.RevertRounds(hashes)
+	if to != nil {
+		parent := to.StarknetBlock()
+		bp.lastBlockDB = &parent
+	} else {
+		bp.lastBlockDB = nil
+	}
 	return nil
 }
 
+// CatchupBlocks backfills the headers of the blocks missing between the last
+// stored block and latestBlock, the block Juno is about to deliver. Blocks are
+// fetched and committed in batches, so the last stored block is a checkpoint
+// and a restart resumes from there. A batch that does not chain onto the
+// checkpoint means the stored chain was reorged while the plugin was down, and
+// stops the backfill.
 func (bp *Processor) CatchupBlocks(latestBlock uint64) error {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
 
-	//Leaving this as a potential usage,  we use this to decide how much block data we want to back fill (in case of a very edge case of clean starting block getting reorged)
-	backFillIndex := uint64(3)
-	startBlock := latestBlock - uint64(backFillIndex)
+	if latestBlock == 0 {
+		return nil
+	}
+	endBlock := latestBlock - 1
+
+	var startBlock uint64
+	parentHash := ""
 	if bp.lastBlockDB != nil {
-		startBlock = bp.lastBlockDB.BlockNumber
+		startBlock = bp.lastBlockDB.BlockNumber + 1
+		parentHash = bp.lastBlockDB.BlockHash
+	} else {
+		startBlock = max(bp.cursor, latestBlock-min(latestBlock, backfillIndex))
 	}
 
-	for startBlock < latestBlock-1 {
-		endBlock := startBlock + 1000
-		if endBlock >= latestBlock {
-			endBlock = latestBlock - 1
-		}
+	for startBlock <= endBlock {
+		batchEnd := min(startBlock+backfillBatchSize-1, endBlock)
 
-		bp.log.Println("Catching up indexer from", startBlock, "to", endBlock)
-		blocks, err := bp.network.GetBlocks(startBlock, endBlock)
+		bp.log.Info("Catching up indexer", "from", startBlock, "to", batchEnd)
+		blocks, err := bp.network.GetBlocks(startBlock, batchEnd)
 		if err != nil {
-			bp.log.Println("Error getting blocks", err)
+			bp.log.Error("Error getting blocks", "from", startBlock, "to", batchEnd, logging.Err(err))
 			return err
 		}
 
 		// Process all blocks in the batch with a single transaction
-		bp.db.BeginTx()
-
-		for _, block := range blocks {
-			err := bp.db.InsertBlock(block)
-			if err != nil {
-				bp.db.RollbackTx()
-				bp.log.Println("Error inserting block", err)
-				return err
+		err = bp.db.InTx(func(tx db.Tx) error {
+			for _, block := range blocks {
+				if parentHash != "" && block.ParentHash != parentHash {
+					return fmt.Errorf("block %d %s does not extend the stored chain: parent %s, expected %s",
+						block.BlockNumber, block.BlockHash, block.ParentHash, parentHash)
+				}
+				if err := tx.InsertBlock(block); err != nil {
+					bp.log.Error("Error inserting block", logging.Block(block.BlockNumber), logging.Err(err))
+					return err
+				}
+				parentHash = block.BlockHash
 			}
-
+			return nil
+		})
+		if err != nil {
+			return err
 		}
+		metrics.BlocksProcessed.Add(float64(len(blocks)))
 
-		bp.db.CommitTx()
-		startBlock = endBlock
+		if len(blocks) > 0 {
+			bp.lastBlockDB = blocks[len(blocks)-1]
+		}
+		startBlock = batchEnd + 1
 	}
 	return nil
 }
@@ -162,33 +266,63 @@ func (bp *Processor) UpdateLastBlock(block *models.StarknetBlocks) {
 	bp.lastBlockDB = block
 }
 
-// processBlockEvents processes all events in a block
-func (bp *Processor) processBlockEvents(block *core.Block) error {
-	bp.log.Println("Processing block events for block", block.Number)
+// processBlockEvents stores the events of the vaults and option rounds in a
+// block in tx and returns the vaults deployed in it. Deployments are looked for
+// first, a vault emits its constructor events before the UDC emits
+// ContractDeployed. Rounds are tracked as their OptionRoundDeployed event is
+// processed.
+func (bp *Processor) processBlockEvents(tx db.Tx, block *models.Block) ([]*models.VaultRegistry, error) {
+	bp.log.Debug("Processing block events", logging.Block(block.Number))
+
+	var discovered []*models.VaultRegistry
+	deployed := make(map[string]struct{})
+	for _, receipt := range block.Receipts {
+		for _, event := range receipt.Events {
+			vault, err := bp.vaultManager.DiscoverVault(tx, receipt.TransactionHash.String(), event, block.Number, *block.Hash)
+			if err != nil {
+				bp.log.Error("Error discovering vault", logging.Block(block.Number), logging.Err(err))
+				return nil, err
+			}
+			if vault != nil {
+				discovered = append(discovered, vault)
+				deployed[vault.Address] = struct{}{}
+			}
+		}
+	}
 
 	for _, receipt := range block.Receipts {
 		for _, event := range receipt.Events {
 			fromAddress := event.From.String()
-			if bp.vaultManager.IsVaultAddress(fromAddress) {
-				err := bp.vaultManager.ProcessVaultEvent(receipt.TransactionHash.String(), fromAddress, event, block.Number, *block.Hash)
+			_, isDeployed := deployed[fromAddress]
+			if isDeployed || bp.vaultManager.IsVaultAddress(fromAddress) {
+				err := bp.vaultManager.ProcessVaultEvent(tx, receipt.TransactionHash.String(), fromAddress, event, block.Number, *block.Hash)
 				if err != nil {
-					bp.log.Println("Error processing vault event", err)
-					return err
+					bp.log.Error("Error processing vault event", logging.Block(block.Number), logging.Vault(fromAddress), logging.Err(err))
+					return nil, err
+				}
+			} else if bp.vaultManager.IsRoundAddress(fromAddress) {
+				err := bp.vaultManager.ProcessRoundEvent(tx, receipt.TransactionHash.String(), fromAddress, event, block.Number, *block.Hash)
+				if err != nil {
+					bp.log.Error("Error processing round event", logging.Block(block.Number), "round", fromAddress, logging.Err(err))
+					return nil, err
 				}
 			}
 		}
 	}
 
-	return nil
+	return discovered, nil
 }
 
-// sendDriverEvent stores a driver event and triggers PostgreSQL NOTIFY
-func (bp *Processor) sendDriverEvent(eventType string, blockHash string) {
+// sendDriverEvent stores a driver event in tx and triggers PostgreSQL NOTIFY. A
+// block must not be committed without its driver event, otherwise the
+// event-processor never applies it, so the error is returned to the caller.
+func (bp *Processor) sendDriverEvent(tx db.Tx, eventType driverevents.Type, blockHash string) error {
 	// Store event (triggers NOTIFY automatically via database trigger)
-	err := bp.db.StoreDriverEvent(eventType, blockHash)
+	err := tx.StoreDriverEvent(eventType, blockHash)
 	if err != nil {
-		bp.log.Printf("Error storing driver event: %v", err)
-	} else {
-		bp.log.Printf("Stored and notified driver event: %s for block %s", eventType, blockHash)
+		bp.log.Error("Error storing driver event", "type", eventType, logging.BlockHash(blockHash), logging.Err(err))
+		return err
 	}
+	bp.log.Debug("Stored and notified driver event", "type", eventType, logging.BlockHash(blockHash))
+	return nil
 }
@@ -0,0 +1,919 @@
+package block
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"driverevents"
+	"junoplugin/db"
+	"junoplugin/models"
+	"junoplugin/plugin/source"
+	"junoplugin/plugin/vault"
+	"junoplugin/utils"
+
+	"github.com/NethermindEth/juno/core/felt"
+	"github.com/NethermindEth/starknet.go/rpc"
+)
+
+const testVault = "0x7a417"
+
+const testVaultClass = "0xc1a55"
+
+func TestMain(m *testing.M) {
+	// The fakes decode the deployments with the contract classes of eventabi
+	if err := utils.LoadABIs("../../../eventabi/testdata/pitch_lake_Vault.contract_class.json"); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+// roundDeployedData is the data of an OptionRoundDeployed event of the Vault
+// ABI: the round id and address, its dates and its pricing data
+func roundDeployedData(roundID uint64, round *felt.Felt) []*felt.Felt {
+	data := []*felt.Felt{new(felt.Felt).SetUint64(roundID), round}
+	for range 8 {
+		data = append(data, new(felt.Felt))
+	}
+	return data
+}
+
+type storedEvent struct {
+	blockHash string
+	txHash    string
+	from      string
+	index     uint64
+	reverted  bool
+}
+
+// fakeStore keeps blocks, events, vault cursors, vault deploy blocks and
+// driver events in memory. It is its own transaction, the writes the processor
+// does not make are left to the embedded nil db.Tx.
+type fakeStore struct {
+	db.Tx
+	// commitErr fails the commit of every transaction, after fn has run
+	commitErr    error
+	blocks       map[uint64]*models.StarknetBlocks
+	events       []*storedEvent
+	cursors      map[string]string
+	deployments  map[string]string
+	driverEvents []string
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		blocks:      make(map[uint64]*models.StarknetBlocks),
+		cursors:     make(map[string]string),
+		deployments: make(map[string]string),
+	}
+}
+
+// storeEvent stores an event like DB.StoreEvent: an event stored again under
+// the same block, transaction and index is only unreverted
+func (s *fakeStore) storeEvent(event *storedEvent) {
+	for _, e := range s.events {
+		if e.blockHash == event.blockHash && e.txHash == event.txHash && e.index == event.index {
+			e.reverted = false
+			return
+		}
+	}
+	s.events = append(s.events, event)
+}
+
+func (s *fakeStore) InTx(fn func(tx db.Tx) error) error {
+	if err := fn(s); err != nil {
+		return err
+	}
+	return s.commitErr
+}
+
+func (s *fakeStore) InsertBlock(block *models.StarknetBlocks) error {
+	if existing, ok := s.blocks[block.BlockNumber]; ok && existing.Status == "MINED" && existing.BlockHash != block.BlockHash {
+		return fmt.Errorf("block %d is already indexed with a different hash", block.BlockNumber)
+	}
+	copied := *block
+	copied.Status = "MINED"
+	s.blocks[block.BlockNumber] = &copied
+	return nil
+}
+
+func (s *fakeStore) RevertBlock(blockNumber uint64, blockHash string) error {
+	if b, ok := s.blocks[blockNumber]; ok && b.BlockHash == blockHash {
+		b.Status = "REVERTED"
+	}
+	return nil
+}
+
+func (s *fakeStore) GetBlocksFrom(blockNumber uint64) ([]*models.StarknetBlocks, error) {
+	var blocks []*models.StarknetBlocks
+	for _, b := range s.blocks {
+		if b.BlockNumber >= blockNumber && b.Status == "MINED" {
+			blocks = append(blocks, b)
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].BlockNumber > blocks[j].BlockNumber })
+	return blocks, nil
+}
+
+func (s *fakeStore) RevertEvents(blockHash string) (int64, error) {
+	var n int64
+	for _, e := range s.events {
+		if e.blockHash == blockHash && !e.reverted {
+			e.reverted = true
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *fakeStore) RewindVaultCursors(revertedHashes []string, parentHash string) ([]string, error) {
+	var rewound []string
+	for address, cursor := range s.cursors {
+		for _, hash := range revertedHashes {
+			if cursor == hash {
+				s.cursors[address] = parentHash
+				rewound = append(rewound, address)
+				break
+			}
+		}
+	}
+	return rewound, nil
+}
+
+func (s *fakeStore) DeleteVaultsDeployedAt(revertedHashes []string) ([]string, error) {
+	var removed []string
+	for address, deployedAt := range s.deployments {
+		for _, hash := range revertedHashes {
+			if deployedAt == hash {
+				delete(s.deployments, address)
+				removed = append(removed, address)
+				break
+			}
+		}
+	}
+	return removed, nil
+}
+
+func (s *fakeStore) GetBlockRange(fromBlock, toBlock uint64) ([]*models.StarknetBlocks, error) {
+	var blocks []*models.StarknetBlocks
+	for _, b := range s.blocks {
+		if b.BlockNumber >= fromBlock && b.BlockNumber <= toBlock {
+			blocks = append(blocks, b)
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].BlockNumber < blocks[j].BlockNumber })
+	return blocks, nil
+}
+
+func (s *fakeStore) GetFirstBlock() (*models.StarknetBlocks, error) {
+	var first *models.StarknetBlocks
+	for _, b := range s.blocks {
+		if b.Status == "MINED" && (first == nil || b.BlockNumber < first.BlockNumber) {
+			first = b
+		}
+	}
+	return first, nil
+}
+
+func (s *fakeStore) GetLastBlock() (*models.StarknetBlocks, error) {
+	var last *models.StarknetBlocks
+	for _, b := range s.blocks {
+		if b.Status == "MINED" && (last == nil || b.BlockNumber > last.BlockNumber) {
+			last = b
+		}
+	}
+	return last, nil
+}
+
+func (s *fakeStore) CountEvents(blockHash string) (int64, error) {
+	return int64(s.eventCount(blockHash, false)), nil
+}
+
+// StoreDriverEvent skips an event that repeats the last one of its block, like
+// DB.StoreDriverEvent
+func (s *fakeStore) StoreDriverEvent(eventType driverevents.Type, blockHash string) error {
+	event := string(eventType) + ":" + blockHash
+	for i := len(s.driverEvents) - 1; i >= 0; i-- {
+		if strings.HasSuffix(s.driverEvents[i], ":"+blockHash) {
+			if s.driverEvents[i] == event {
+				return nil
+			}
+			break
+		}
+	}
+	s.driverEvents = append(s.driverEvents, event)
+	return nil
+}
+
+func (s *fakeStore) eventCount(blockHash string, reverted bool) int {
+	n := 0
+	for _, e := range s.events {
+		if e.blockHash == blockHash && e.reverted == reverted {
+			n++
+		}
+	}
+	return n
+}
+
+// fakeVaults stores the events of testVault, of the vaults of class
+// testVaultClass it discovers and of their option rounds, and tracks the
+// cursor of testVault
+type fakeVaults struct {
+	store      *fakeStore
+	cursor     string
+	discovered map[string]bool
+	rounds     map[string]string
+}
+
+func (v *fakeVaults) IsVaultAddress(address string) bool {
+	return address == testVault || v.discovered[address]
+}
+
+func (v *fakeVaults) IsRoundAddress(address string) bool {
+	_, ok := v.rounds[address]
+	return ok
+}
+
+func (v *fakeVaults) ProcessRoundEvent(tx db.Tx, txHash string, roundAddress string, event *models.BlockEvent, blockNumber uint64, blockHash felt.Felt) error {
+	v.store.storeEvent(&storedEvent{
+		blockHash: blockHash.String(),
+		txHash:    txHash,
+		from:      roundAddress,
+		index:     event.Index,
+	})
+	return nil
+}
+
+func (v *fakeVaults) RevertRounds(blockHashes []string) {
+	for address, deployedAt := range v.rounds {
+		for _, hash := range blockHashes {
+			if deployedAt == hash {
+				delete(v.rounds, address)
+			}
+		}
+	}
+}
+
+func (v *fakeVaults) DiscoverVault(tx db.Tx, txHash string, event *models.BlockEvent, blockNumber uint64, blockHash felt.Felt) (*models.VaultRegistry, error) {
+	address, classHash, ok, err := utils.DecodeContractDeployed(event.Keys, event.Data)
+	if err != nil || !ok || classHash.String() != testVaultClass {
+		return nil, err
+	}
+	deployedAt := blockHash.String()
+	v.store.deployments[address.String()] = deployedAt
+	return &models.VaultRegistry{Address: address.String(), DeployedAt: deployedAt, LastBlockIndexed: &deployedAt}, nil
+}
+
+func (v *fakeVaults) TrackVaults(vaults []*models.VaultRegistry) {
+	for _, vault := range vaults {
+		v.discovered[vault.Address] = true
+	}
+}
+
+func (v *fakeVaults) UntrackVaults(addresses []string) {
+	for _, address := range addresses {
+		delete(v.discovered, address)
+	}
+}
+
+func (v *fakeVaults) ProcessVaultEvent(tx db.Tx, txHash string, vaultAddress string, event *models.BlockEvent, blockNumber uint64, blockHash felt.Felt) error {
+	v.store.storeEvent(&storedEvent{
+		blockHash: blockHash.String(),
+		txHash:    txHash,
+		from:      vaultAddress,
+		index:     event.Index,
+	})
+	round, ok, err := utils.DecodeOptionRoundDeployed(event.Keys, event.Data)
+	if err != nil {
+		return err
+	}
+	if ok {
+		v.rounds[round.String()] = blockHash.String()
+	}
+	return nil
+}
+
+func (v *fakeVaults) RewindVaults(addresses []string, parentHash string) {
+	for _, address := range addresses {
+		if address == testVault {
+			v.cursor = parentHash
+		}
+	}
+}
+
+// chain builds consecutive blocks of a branch on top of parent, each with one
+// vault event. Branches are told apart by their hashes.
+func chain(branch uint64, parent *models.Block, from, to uint64) []*models.Block {
+	var blocks []*models.Block
+	parentHash := new(felt.Felt)
+	if parent != nil {
+		parentHash = parent.Hash
+	}
+	vault, _ := new(felt.Felt).SetString(testVault)
+	for n := from; n <= to; n++ {
+		hash := new(felt.Felt).SetUint64(branch*1000 + n)
+		blocks = append(blocks, &models.Block{
+			Number:     n,
+			Hash:       hash,
+			ParentHash: parentHash,
+			Timestamp:  1000 + n,
+			Receipts: []*models.Receipt{{
+				TransactionHash: new(felt.Felt).SetUint64(branch*1000 + n),
+				Events: []*models.BlockEvent{{
+					From: vault,
+					Keys: []*felt.Felt{new(felt.Felt).SetUint64(1)},
+				}},
+			}},
+		})
+		parentHash = hash
+	}
+	return blocks
+}
+
+func newTestProcessor() (*Processor, *fakeStore, *fakeVaults) {
+	store := newFakeStore()
+	vaults := &fakeVaults{store: store, discovered: make(map[string]bool), rounds: make(map[string]string)}
+	bp := NewProcessor(store, nil, vaults, nil, 0)
+	bp.log = slog.New(slog.DiscardHandler)
+	return bp, store, vaults
+}
+
+func revert(t *testing.T, bp *Processor, block, parent *models.Block) {
+	t.Helper()
+	if err := bp.RevertBlock(block, parent); err != nil {
+		t.Fatalf("RevertBlock(%d) failed: %v", block.Number, err)
+	}
+}
+
+func process(t *testing.T, bp *Processor, blocks []*models.Block) {
+	t.Helper()
+	for _, b := range blocks {
+		if err := bp.ProcessNewBlock(b); err != nil {
+			t.Fatalf("ProcessNewBlock(%d) failed: %v", b.Number, err)
+		}
+	}
+}
+
+func TestRevertBlockReplaysFork(t *testing.T) {
+	bp, store, vaults := newTestProcessor()
+
+	a := chain(1, nil, 1, 5)
+	process(t, bp, a)
+	store.cursors[testVault] = a[4].Hash.String()
+	vaults.cursor = a[4].Hash.String()
+
+	// Juno reverts a two block reorg one block at a time, head first
+	revert(t, bp, a[4], a[3])
+	if store.eventCount(a[4].Hash.String(), true) != 1 {
+		t.Errorf("expected the events of block 5 to be reverted")
+	}
+	if got := store.cursors[testVault]; got != a[3].Hash.String() {
+		t.Errorf("vault cursor = %s, want block 4 %s", got, a[3].Hash)
+	}
+	if vaults.cursor != a[3].Hash.String() {
+		t.Errorf("in memory vault cursor = %s, want block 4 %s", vaults.cursor, a[3].Hash)
+	}
+	if bp.GetLastBlock().BlockHash != a[3].Hash.String() {
+		t.Errorf("last block = %s, want block 4", bp.GetLastBlock().BlockHash)
+	}
+
+	revert(t, bp, a[3], a[2])
+	if store.eventCount(a[3].Hash.String(), true) != 1 || store.eventCount(a[2].Hash.String(), false) != 1 {
+		t.Errorf("expected only the events of block 4 to be reverted")
+	}
+	if got := store.cursors[testVault]; got != a[2].Hash.String() {
+		t.Errorf("vault cursor = %s, want block 3 %s", got, a[2].Hash)
+	}
+
+	// The new branch replaces the reverted heights
+	b := chain(2, a[2], 4, 6)
+	process(t, bp, b)
+	for _, block := range b {
+		stored := store.blocks[block.Number]
+		if stored.BlockHash != block.Hash.String() || stored.Status != "MINED" {
+			t.Errorf("block %d = %+v, want %s mined", block.Number, stored, block.Hash)
+		}
+		if store.eventCount(block.Hash.String(), false) != 1 {
+			t.Errorf("expected the events of block %d on the new branch", block.Number)
+		}
+	}
+
+	var want []string
+	for _, block := range a {
+		want = append(want, "StartBlock:"+block.Hash.String())
+	}
+	want = append(want, "RevertBlock:"+a[4].Hash.String(), "RevertBlock:"+a[3].Hash.String())
+	for _, block := range b {
+		want = append(want, "StartBlock:"+block.Hash.String())
+	}
+	if fmt.Sprint(store.driverEvents) != fmt.Sprint(want) {
+		t.Errorf("driver events = %v, want %v", store.driverEvents, want)
+	}
+}
+
+// processorHandler hands the blocks of a source to a processor
+type processorHandler struct {
+	bp *Processor
+}
+
+func (h processorHandler) NewBlock(block *models.Block) error {
+	return h.bp.ProcessNewBlock(block)
+}
+
+func (h processorHandler) RevertBlock(from, to *models.Block) error {
+	return h.bp.RevertBlock(from, to)
+}
+
+func TestProcessFixtureReorg(t *testing.T) {
+	bp, store, _ := newTestProcessor()
+
+	fixture, err := source.LoadFixture("testdata/reorg.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fixture.Run(context.Background(), processorHandler{bp}); err != nil {
+		t.Fatalf("fixture failed: %v", err)
+	}
+
+	// Block 3 was reorged out for 0x7d3, on which block 4 was built
+	if store.eventCount("0x3eb", true) != 1 || store.eventCount("0x3eb", false) != 0 {
+		t.Errorf("expected the events of the reorged block 3 to be reverted")
+	}
+	for n, hash := range map[uint64]string{1: "0x3e9", 2: "0x3ea", 3: "0x7d3", 4: "0x7d4"} {
+		if stored := store.blocks[n]; stored.BlockHash != hash || stored.Status != "MINED" {
+			t.Errorf("block %d = %+v, want %s mined", n, stored, hash)
+		}
+		if store.eventCount(hash, false) != 1 {
+			t.Errorf("expected the event of block %d %s", n, hash)
+		}
+	}
+	if bp.GetLastBlock().BlockHash != "0x7d4" {
+		t.Errorf("last block = %s, want 0x7d4", bp.GetLastBlock().BlockHash)
+	}
+}
+
+func TestRevertBlockRevertsStoredDescendants(t *testing.T) {
+	bp, store, _ := newTestProcessor()
+
+	a := chain(1, nil, 1, 7)
+	process(t, bp, a)
+	store.cursors[testVault] = a[6].Hash.String()
+	store.driverEvents = nil
+
+	// Blocks 6 and 7 were stored above the block Juno reverts
+	revert(t, bp, a[4], a[3])
+
+	want := []string{
+		"RevertBlock:" + a[6].Hash.String(),
+		"RevertBlock:" + a[5].Hash.String(),
+		"RevertBlock:" + a[4].Hash.String(),
+	}
+	if fmt.Sprint(store.driverEvents) != fmt.Sprint(want) {
+		t.Errorf("driver events = %v, want %v", store.driverEvents, want)
+	}
+	for _, block := range a[4:] {
+		if store.blocks[block.Number].Status != "REVERTED" || store.eventCount(block.Hash.String(), true) != 1 {
+			t.Errorf("expected block %d and its events to be reverted", block.Number)
+		}
+	}
+	if got := store.cursors[testVault]; got != a[3].Hash.String() {
+		t.Errorf("vault cursor = %s, want block 4 %s", got, a[3].Hash)
+	}
+}
+
+func TestProcessNewBlockRejectsUnrevertedFork(t *testing.T) {
+	bp, _, _ := newTestProcessor()
+
+	a := chain(1, nil, 1, 3)
+	process(t, bp, a)
+
+	fork := chain(2, a[1], 3, 3)
+	if err := bp.ProcessNewBlock(fork[0]); err == nil {
+		t.Error("expected a block at an indexed height to be rejected without a revert")
+	}
+}
+
+func TestProcessNewBlockReplayIsIdempotent(t *testing.T) {
+	bp, store, vaults := newTestProcessor()
+
+	newVault := new(felt.Felt).SetUint64(0xbeef)
+	a := chain(1, nil, 1, 5)
+	deployVault(a[1], newVault)
+	// Two events of the same contract in one transaction
+	a[2].Receipts[0].Events = append(a[2].Receipts[0].Events, &models.BlockEvent{
+		From:  a[2].Receipts[0].Events[0].From,
+		Keys:  []*felt.Felt{new(felt.Felt).SetUint64(2)},
+		Index: 1,
+	})
+	process(t, bp, a)
+
+	events := len(store.events)
+	driverEvents := fmt.Sprint(store.driverEvents)
+	if events != 7 {
+		t.Fatalf("stored %d events, want 7", events)
+	}
+
+	// Plugin restarted from a stale checkpoint, the same range comes again
+	process(t, bp, a)
+	if len(store.events) != events {
+		t.Errorf("replay stored %d events, want %d", len(store.events), events)
+	}
+	if fmt.Sprint(store.driverEvents) != driverEvents {
+		t.Errorf("replay driver events = %v, want %v", store.driverEvents, driverEvents)
+	}
+	if !vaults.IsVaultAddress(newVault.String()) || len(store.deployments) != 1 {
+		t.Errorf("expected vault %s to be registered once, got %v", newVault, store.deployments)
+	}
+	for _, block := range a {
+		if stored := store.blocks[block.Number]; stored.BlockHash != block.Hash.String() || stored.Status != "MINED" {
+			t.Errorf("block %d = %+v, want %s mined", block.Number, stored, block.Hash)
+		}
+	}
+
+	// A reverted block that comes back gets its events back, not new ones
+	revert(t, bp, a[4], a[3])
+	process(t, bp, a[4:])
+	process(t, bp, a[4:])
+	if len(store.events) != events || store.eventCount(a[4].Hash.String(), false) != 1 {
+		t.Errorf("expected the events of block 5 to be restored, got %d events", len(store.events))
+	}
+	want := driverEvents[:len(driverEvents)-1] + " RevertBlock:" + a[4].Hash.String() + " StartBlock:" + a[4].Hash.String() + "]"
+	if fmt.Sprint(store.driverEvents) != want {
+		t.Errorf("driver events = %v, want %v", store.driverEvents, want)
+	}
+}
+
+// testDB resets the database at TEST_DB_URL to the migrations of the logger
+// and connects to it
+func testDB(t *testing.T) *db.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DB_URL")
+	if url == "" {
+		t.Skip("TEST_DB_URL is not set")
+	}
+	dbClient, err := db.Init(url)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(dbClient.Shutdown)
+
+	ctx := context.Background()
+	if _, err := dbClient.Pool.Exec(ctx, `DROP SCHEMA public CASCADE; CREATE SCHEMA public;`); err != nil {
+		t.Fatalf("Failed to reset the database: %v", err)
+	}
+	files, err := filepath.Glob("../../db/migrations/*.up.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("No migrations: %v", err)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		migration, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := dbClient.Pool.Exec(ctx, string(migration)); err != nil {
+			t.Fatalf("Failed to apply %s: %v", file, err)
+		}
+	}
+	return dbClient
+}
+
+// newDBProcessor returns a processor indexing the vaults of the registry of
+// dbClient, as the plugin does when it starts
+func newDBProcessor(t *testing.T, dbClient *db.DB) *Processor {
+	t.Helper()
+	vm := vault.NewManager(dbClient, nil, "0x0dc", []string{testVaultClass}, nil)
+	if err := vm.TrackRegistry(); err != nil {
+		t.Fatal(err)
+	}
+	lastBlock, err := dbClient.GetLastBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bp := NewProcessor(dbClient, nil, vm, lastBlock, 0)
+	bp.log = slog.New(slog.DiscardHandler)
+	return bp
+}
+
+// emit adds an event named name of contract from to the first receipt of
+// block
+func emit(block *models.Block, from *felt.Felt, name string, data ...*felt.Felt) {
+	selector, _ := new(felt.Felt).SetString(utils.Keccak256(name))
+	receipt := block.Receipts[0]
+	receipt.Events = append(receipt.Events, &models.BlockEvent{
+		From:  from,
+		Keys:  []*felt.Felt{selector},
+		Data:  data,
+		Index: uint64(len(receipt.Events)),
+	})
+}
+
+func TestProcessNewBlockReplayIsIdempotentInDB(t *testing.T) {
+	dbClient := testDB(t)
+	genesis := "0x0"
+	if err := dbClient.InTx(func(tx db.Tx) error {
+		return tx.InsertVault(&models.VaultRegistry{Address: testVault, DeployedAt: genesis, LastBlockIndexed: &genesis})
+	}); err != nil {
+		t.Fatalf("Failed to register the vault: %v", err)
+	}
+
+	// A deposit to the vault in every block, a vault deployed in block 2 and
+	// a round deployed in block 3 that starts its auction in block 4
+	vault, _ := new(felt.Felt).SetString(testVault)
+	round := new(felt.Felt).SetUint64(0x40d)
+	a := chain(1, nil, 1, 5)
+	for _, block := range a {
+		block.Receipts[0].Events = nil
+		emit(block, vault, "Deposit")
+	}
+	deployVault(a[1], new(felt.Felt).SetUint64(0xbeef))
+	emit(a[2], vault, "OptionRoundDeployed", roundDeployedData(1, round)...)
+	emit(a[3], round, "AuctionStarted")
+
+	counts := func() string {
+		var events, blocks, driverEvents int
+		err := dbClient.Pool.QueryRow(context.Background(), `
+			SELECT (SELECT COUNT(*) FROM events), (SELECT COUNT(*) FROM starknet_blocks), (SELECT COUNT(*) FROM driver_events)`,
+		).Scan(&events, &blocks, &driverEvents)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fmt.Sprintf("%d events, %d blocks, %d driver events", events, blocks, driverEvents)
+	}
+
+	process(t, newDBProcessor(t, dbClient), a)
+	// The deposits, the vault deployment, the round deployment and the round
+	// event, one StartBlock per block
+	want := "8 events, 5 blocks, 5 driver events"
+	if got := counts(); got != want {
+		t.Fatalf("after the first pass: %s, want %s", got, want)
+	}
+
+	// Plugin restarted from a stale checkpoint, the same range comes again
+	process(t, newDBProcessor(t, dbClient), a)
+	if got := counts(); got != want {
+		t.Errorf("after the replay: %s, want %s", got, want)
+	}
+}
+
+// deployVault adds to block the deployment of a vault of class testVaultClass
+// through the UDC, the vault emitting an event from its constructor first
+func deployVault(block *models.Block, address *felt.Felt) {
+	selector, _ := new(felt.Felt).SetString(utils.Keccak256("ContractDeployed"))
+	classHash, _ := new(felt.Felt).SetString(testVaultClass)
+	block.Receipts = append(block.Receipts, &models.Receipt{
+		TransactionHash: new(felt.Felt).SetUint64(block.Number + 500),
+		Events: []*models.BlockEvent{
+			{From: address, Keys: []*felt.Felt{new(felt.Felt).SetUint64(1)}},
+			{
+				From:  new(felt.Felt).SetUint64(0x0dc),
+				Keys:  []*felt.Felt{selector},
+				Data:  []*felt.Felt{address, new(felt.Felt).SetUint64(1), new(felt.Felt), classHash, new(felt.Felt), new(felt.Felt)},
+				Index: 1,
+			},
+		},
+	})
+}
+
+func TestProcessNewBlockDiscoversVault(t *testing.T) {
+	bp, store, vaults := newTestProcessor()
+
+	newVault := new(felt.Felt).SetUint64(0xbeef)
+	a := chain(1, nil, 1, 4)
+	deployVault(a[1], newVault)
+	a[2].Receipts[0].Events = append(a[2].Receipts[0].Events, &models.BlockEvent{
+		From:  newVault,
+		Keys:  []*felt.Felt{new(felt.Felt).SetUint64(1)},
+		Index: 1,
+	})
+	process(t, bp, a)
+
+	if !vaults.IsVaultAddress(newVault.String()) {
+		t.Fatalf("expected vault %s to be tracked", newVault)
+	}
+	if got := store.deployments[newVault.String()]; got != a[1].Hash.String() {
+		t.Errorf("vault deployed at %s, want block 2 %s", got, a[1].Hash)
+	}
+	// The constructor event is indexed along with the testVault event
+	if got := store.eventCount(a[1].Hash.String(), false); got != 2 {
+		t.Errorf("block 2 has %d events, want 2", got)
+	}
+	if got := store.eventCount(a[2].Hash.String(), false); got != 2 {
+		t.Errorf("block 3 has %d events, want 2", got)
+	}
+
+	// The deploy block is reorged out, so is the vault
+	revert(t, bp, a[1], a[0])
+	if vaults.IsVaultAddress(newVault.String()) {
+		t.Errorf("expected vault %s to be untracked after its deploy block was reverted", newVault)
+	}
+	if _, ok := store.deployments[newVault.String()]; ok {
+		t.Errorf("expected vault %s to be removed from the registry", newVault)
+	}
+}
+
+func TestProcessNewBlockCommitFailure(t *testing.T) {
+	bp, store, vaults := newTestProcessor()
+
+	a := chain(1, nil, 1, 2)
+	process(t, bp, a[:1])
+
+	newVault := new(felt.Felt).SetUint64(0xbeef)
+	deployVault(a[1], newVault)
+	store.commitErr = errors.New("connection lost")
+	if err := bp.ProcessNewBlock(a[1]); !errors.Is(err, store.commitErr) {
+		t.Fatalf("ProcessNewBlock(2) = %v, want the commit error", err)
+	}
+	// Nothing of the block is applied in memory without its commit
+	if last := bp.GetLastBlock(); last == nil || last.BlockNumber != 1 {
+		t.Errorf("last block = %+v, want block 1", last)
+	}
+	if vaults.IsVaultAddress(newVault.String()) {
+		t.Errorf("expected vault %s not to be tracked", newVault)
+	}
+}
+
+func TestProcessNewBlockIndexesRoundEvents(t *testing.T) {
+	bp, store, vaults := newTestProcessor()
+
+	vault, _ := new(felt.Felt).SetString(testVault)
+	round := new(felt.Felt).SetUint64(0x40d)
+	deployed, _ := new(felt.Felt).SetString(utils.Keccak256("OptionRoundDeployed"))
+	started, _ := new(felt.Felt).SetString(utils.Keccak256("AuctionStarted"))
+
+	a := chain(1, nil, 1, 4)
+	a[1].Receipts[0].Events = append(a[1].Receipts[0].Events, &models.BlockEvent{
+		From:  vault,
+		Keys:  []*felt.Felt{deployed},
+		Data:  roundDeployedData(1, round),
+		Index: 1,
+	})
+	for _, block := range a[1:3] {
+		events := block.Receipts[0].Events
+		block.Receipts[0].Events = append(events, &models.BlockEvent{
+			From:  round,
+			Keys:  []*felt.Felt{started},
+			Index: uint64(len(events)),
+		})
+	}
+	process(t, bp, a)
+
+	if !vaults.IsRoundAddress(round.String()) {
+		t.Fatalf("expected round %s to be tracked", round)
+	}
+	// The round event of the deploy block follows OptionRoundDeployed
+	for _, block := range a[1:3] {
+		n := 0
+		for _, e := range store.events {
+			if e.blockHash == block.Hash.String() && e.from == round.String() {
+				n++
+			}
+		}
+		if n != 1 {
+			t.Errorf("block %d has %d round events, want 1", block.Number, n)
+		}
+	}
+
+	revert(t, bp, a[1], a[0])
+	if vaults.IsRoundAddress(round.String()) {
+		t.Errorf("expected round %s to be untracked after its deploy block was reverted", round)
+	}
+}
+
+// fakeFetcher serves the headers of a chain built with chain(), failing the
+// requests that reach failAt
+type fakeFetcher struct {
+	blocks   map[uint64]*models.StarknetBlocks
+	failAt   uint64
+	requests [][2]uint64
+}
+
+func newFakeFetcher(blocks []*models.Block) *fakeFetcher {
+	f := &fakeFetcher{blocks: make(map[uint64]*models.StarknetBlocks)}
+	for _, b := range blocks {
+		block := b.StarknetBlock()
+		f.blocks[b.Number] = &block
+	}
+	return f
+}
+
+func (f *fakeFetcher) GetBlocks(fromBlock uint64, toBlock uint64) ([]*models.StarknetBlocks, error) {
+	f.requests = append(f.requests, [2]uint64{fromBlock, toBlock})
+	var blocks []*models.StarknetBlocks
+	for n := fromBlock; n <= toBlock; n++ {
+		if f.failAt != 0 && n >= f.failAt {
+			return nil, fmt.Errorf("block %d unavailable", n)
+		}
+		blocks = append(blocks, f.blocks[n])
+	}
+	return blocks, nil
+}
+
+// GetEvents returns the vault event of every block of the chain in range
+func (f *fakeFetcher) GetEvents(fromBlock rpc.BlockID, toBlock rpc.BlockID, address *string, keys [][]*felt.Felt) (*rpc.EventChunk, error) {
+	vault, _ := new(felt.Felt).SetString(testVault)
+	chunk := &rpc.EventChunk{}
+	for n := *fromBlock.Number; n <= *toBlock.Number; n++ {
+		block := f.blocks[n]
+		hash, _ := new(felt.Felt).SetString(block.BlockHash)
+		chunk.Events = append(chunk.Events, rpc.EmittedEvent{
+			Event: rpc.Event{
+				FromAddress:  vault,
+				EventContent: rpc.EventContent{Keys: []*felt.Felt{new(felt.Felt).SetUint64(1)}},
+			},
+			BlockHash:       hash,
+			BlockNumber:     n,
+			TransactionHash: hash,
+		})
+	}
+	return chunk, nil
+}
+
+// LocateEvents places the events of each transaction at consecutive indexes
+func (f *fakeFetcher) LocateEvents(events []rpc.EmittedEvent) ([]models.LocatedEvent, error) {
+	next := make(map[string]uint64)
+	located := make([]models.LocatedEvent, 0, len(events))
+	for _, event := range events {
+		txHash := event.TransactionHash.String()
+		located = append(located, models.LocatedEvent{EmittedEvent: event, Index: next[txHash]})
+		next[txHash]++
+	}
+	return located, nil
+}
+
+func TestCatchupBlocksResumesFromCheckpoint(t *testing.T) {
+	bp, store, _ := newTestProcessor()
+	a := chain(1, nil, 1, 2500)
+	fetcher := newFakeFetcher(a)
+	fetcher.failAt = 1500
+	bp.network = fetcher
+
+	process(t, bp, a[:10])
+	if err := bp.CatchupBlocks(2501); err == nil {
+		t.Fatal("expected the backfill to fail on the unavailable block")
+	}
+	// The first batch was committed and is the checkpoint
+	if last := bp.GetLastBlock(); last.BlockNumber != 1010 {
+		t.Fatalf("checkpoint = %d, want 1010", last.BlockNumber)
+	}
+
+	fetcher.failAt = 0
+	fetcher.requests = nil
+	if err := bp.CatchupBlocks(2501); err != nil {
+		t.Fatalf("CatchupBlocks failed: %v", err)
+	}
+	want := [][2]uint64{{1011, 2010}, {2011, 2500}}
+	if fmt.Sprint(fetcher.requests) != fmt.Sprint(want) {
+		t.Errorf("requests = %v, want %v", fetcher.requests, want)
+	}
+	for n := uint64(1); n <= 2500; n++ {
+		if store.blocks[n] == nil || store.blocks[n].BlockHash != a[n-1].Hash.String() {
+			t.Fatalf("block %d was not backfilled", n)
+		}
+	}
+	if last := bp.GetLastBlock(); last.BlockNumber != 2500 {
+		t.Errorf("last block = %d, want 2500", last.BlockNumber)
+	}
+}
+
+func TestCatchupBlocksStopsOnFork(t *testing.T) {
+	bp, store, _ := newTestProcessor()
+	a := chain(1, nil, 1, 5)
+	process(t, bp, a)
+
+	// The node moved to another branch above block 3 while the plugin was down
+	b := chain(2, a[2], 4, 8)
+	bp.network = newFakeFetcher(append(a[:3:3], b...))
+
+	if err := bp.CatchupBlocks(9); err == nil {
+		t.Fatal("expected a backfill that does not extend the stored chain to fail")
+	}
+	if _, ok := store.blocks[6]; ok {
+		t.Error("expected no block of the other branch to be stored")
+	}
+}
+
+func TestCatchupBlocksFromEmptyStore(t *testing.T) {
+	bp, store, _ := newTestProcessor()
+	a := chain(1, nil, 1, 100)
+	fetcher := newFakeFetcher(a)
+	bp.network = fetcher
+
+	if err := bp.CatchupBlocks(100); err != nil {
+		t.Fatalf("CatchupBlocks failed: %v", err)
+	}
+	want := [][2]uint64{{97, 99}}
+	if fmt.Sprint(fetcher.requests) != fmt.Sprint(want) {
+		t.Errorf("requests = %v, want %v", fetcher.requests, want)
+	}
+	if len(store.blocks) != 3 {
+		t.Errorf("expected 3 stored blocks, got %d", len(store.blocks))
+	}
+}
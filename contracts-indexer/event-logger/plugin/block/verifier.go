@@ -0,0 +1,360 @@
+package block
+
+import (
+	"driverevents"
+	"fmt"
+	"junoplugin/db"
+	"junoplugin/logging"
+	"junoplugin/metrics"
+	"junoplugin/models"
+	"junoplugin/utils"
+	"sort"
+
+	"github.com/NethermindEth/starknet.go/rpc"
+)
+
+// verifyPageSize is the number of stored blocks read at a time by VerifyChain
+const verifyPageSize = 1000
+
+// Kinds of chain issues found by VerifyChain
+const (
+	// IssueMissing is a height with no stored block
+	IssueMissing = "missing"
+	// IssueParentMismatch is a block whose parent hash is not the hash of the
+	// block stored below it
+	IssueParentMismatch = "parent_mismatch"
+	// IssueOrphanedRevert is a reverted block that was never replaced by the
+	// block of the new branch
+	IssueOrphanedRevert = "orphaned_revert"
+)
+
+// ChainIssue is a break in the stored chain
+type ChainIssue struct {
+	Kind        string `json:"kind"`
+	BlockNumber uint64 `json:"blockNumber"`
+	BlockHash   string `json:"blockHash,omitempty"`
+}
+
+// VerifyReport describes what VerifyChain found and repaired
+type VerifyReport struct {
+	FromBlock uint64       `json:"fromBlock"`
+	ToBlock   uint64       `json:"toBlock"`
+	Issues    []ChainIssue `json:"issues"`
+	// Reverted lists the heights whose stored block was not canonical
+	Reverted []uint64 `json:"reverted"`
+	// Repaired lists the heights stored again from the canonical chain
+	Repaired []uint64 `json:"repaired"`
+}
+
+// VerifyChain checks that the stored blocks between fromBlock and toBlock form
+// a chain and repairs them from RPC. A toBlock of 0 verifies up to the last
+// stored block.
+//
+// Missing heights, orphaned reverted rows and parent hash mismatches are
+// refetched. Stored blocks that are not canonical are reverted like in
+// RevertBlock, with a RevertBlock driver event each, and every block stored
+// from the canonical chain gets its vault events indexed and a StartBlock
+// driver event, unless its events are already stored, so that the
+// event-processor replays the affected range.
+func (bp *Processor) VerifyChain(fromBlock, toBlock uint64) (*VerifyReport, error) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	report, first, err := bp.checkChain(fromBlock, toBlock)
+	if err != nil || first == nil {
+		return report, err
+	}
+
+	for _, segment := range issueSegments(report.Issues) {
+		if err := bp.repairSegment(segment[0], segment[1], first.BlockNumber, report); err != nil {
+			return report, fmt.Errorf("failed to repair blocks %d to %d: %w", segment[0], segment[1], err)
+		}
+	}
+	return report, nil
+}
+
+// CheckChain reports the breaks in the stored blocks between fromBlock and
+// toBlock like VerifyChain, without repairing them
+func (bp *Processor) CheckChain(fromBlock, toBlock uint64) (*VerifyReport, error) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	report, _, err := bp.checkChain(fromBlock, toBlock)
+	return report, err
+}
+
+// checkChain finds the issues of the stored blocks in range and returns them
+// with the first stored block, nil when there is nothing to verify
+func (bp *Processor) checkChain(fromBlock, toBlock uint64) (*VerifyReport, *models.StarknetBlocks, error) {
+	first, err := bp.db.GetFirstBlock()
+	if err != nil {
+		return nil, nil, err
+	}
+	last, err := bp.db.GetLastBlock()
+	if err != nil {
+		return nil, nil, err
+	}
+	report := &VerifyReport{}
+	if first == nil || last == nil {
+		return report, nil, nil
+	}
+
+	report.FromBlock = max(fromBlock, first.BlockNumber)
+	report.ToBlock = last.BlockNumber
+	if toBlock != 0 && toBlock < report.ToBlock {
+		report.ToBlock = toBlock
+	}
+	if report.FromBlock > report.ToBlock {
+		return report, nil, nil
+	}
+
+	if err := bp.findIssues(report); err != nil {
+		return nil, nil, err
+	}
+	bp.log.Info("Verified blocks", "from", report.FromBlock, "to", report.ToBlock, "issues", len(report.Issues))
+	return report, first, nil
+}
+
+// findIssues scans the stored blocks of the report range page by page
+func (bp *Processor) findIssues(report *VerifyReport) error {
+	var previous *models.StarknetBlocks
+	for pageFrom := report.FromBlock; pageFrom <= report.ToBlock; pageFrom += verifyPageSize {
+		pageTo := min(pageFrom+verifyPageSize-1, report.ToBlock)
+
+		blocks, err := bp.db.GetBlockRange(pageFrom, pageTo)
+		if err != nil {
+			return err
+		}
+
+		next := pageFrom
+		for _, block := range blocks {
+			for ; next < block.BlockNumber; next++ {
+				report.Issues = append(report.Issues, ChainIssue{Kind: IssueMissing, BlockNumber: next})
+				previous = nil
+			}
+			next = block.BlockNumber + 1
+
+			if block.Status == "REVERTED" {
+				report.Issues = append(report.Issues, ChainIssue{Kind: IssueOrphanedRevert, BlockNumber: block.BlockNumber, BlockHash: block.BlockHash})
+				previous = nil
+				continue
+			}
+			if previous != nil && block.ParentHash != previous.BlockHash {
+				report.Issues = append(report.Issues, ChainIssue{Kind: IssueParentMismatch, BlockNumber: block.BlockNumber, BlockHash: block.BlockHash})
+			}
+			previous = block
+		}
+		for ; next <= pageTo; next++ {
+			report.Issues = append(report.Issues, ChainIssue{Kind: IssueMissing, BlockNumber: next})
+			previous = nil
+		}
+	}
+	return nil
+}
+
+// issueSegments merges the heights to refetch into contiguous ranges. A parent
+// mismatch also covers the block below, either of the two may be wrong.
+func issueSegments(issues []ChainIssue) [][2]uint64 {
+	var segments [][2]uint64
+	for _, issue := range issues {
+		lo, hi := issue.BlockNumber, issue.BlockNumber
+		if issue.Kind == IssueParentMismatch && lo > 0 {
+			lo--
+		}
+		if n := len(segments); n > 0 && lo <= segments[n-1][1]+1 {
+			segments[n-1][1] = max(segments[n-1][1], hi)
+			continue
+		}
+		segments = append(segments, [2]uint64{lo, hi})
+	}
+	return segments
+}
+
+// repairSegment replaces the stored blocks between lo and hi that are missing,
+// reverted or not canonical. When the canonical chain does not connect to the
+// block stored below lo, the segment is extended downwards until it does.
+func (bp *Processor) repairSegment(lo, hi, firstBlock uint64, report *VerifyReport) error {
+	var replace []*models.StarknetBlocks
+	var revertedHashes, rewound, removed []string
+	var reverted []uint64
+	parentHash := ""
+	err := bp.db.InTx(func(tx db.Tx) error {
+		stored, err := storedBlocks(tx, lo, hi)
+		if err != nil {
+			return err
+		}
+		canonical, err := bp.network.GetBlocks(lo, hi)
+		if err != nil {
+			return err
+		}
+
+		for lo > firstBlock {
+			below, err := storedBlocks(tx, lo-1, lo-1)
+			if err != nil {
+				return err
+			}
+			b, ok := below[lo-1]
+			if !ok || b.Status != "MINED" || b.BlockHash == canonical[0].ParentHash {
+				break
+			}
+			more, err := bp.network.GetBlocks(lo-1, lo-1)
+			if err != nil {
+				return err
+			}
+			lo--
+			stored[lo] = b
+			canonical = append(more, canonical...)
+		}
+
+		// Revert the stored blocks that are not canonical, highest first
+		lowestReverted := -1
+		for i := len(canonical) - 1; i >= 0; i-- {
+			block := canonical[i]
+			s, ok := stored[block.BlockNumber]
+			switch {
+			case !ok || s.Status != "MINED":
+			case s.BlockHash == block.BlockHash:
+				continue
+			default:
+				if err := bp.revertStoredBlock(tx, s); err != nil {
+					return err
+				}
+				revertedHashes = append(revertedHashes, s.BlockHash)
+				reverted = append(reverted, s.BlockNumber)
+				lowestReverted = i
+			}
+			replace = append(replace, block)
+		}
+
+		if lowestReverted >= 0 {
+			parentHash = canonical[lowestReverted].ParentHash
+			if rewound, err = tx.RewindVaultCursors(revertedHashes, parentHash); err != nil {
+				return err
+			}
+			if removed, err = tx.DeleteVaultsDeployedAt(revertedHashes); err != nil {
+				return err
+			}
+		}
+
+		if len(replace) > 0 {
+			sort.Slice(replace, func(i, j int) bool { return replace[i].BlockNumber < replace[j].BlockNumber })
+			return bp.storeCanonicalBlocks(tx, replace)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	report.Reverted = append(report.Reverted, reverted...)
+	for _, block := range replace {
+		report.Repaired = append(report.Repaired, block.BlockNumber)
+	}
+	metrics.BlocksReverted.Add(float64(len(revertedHashes)))
+	metrics.BlocksProcessed.Add(float64(len(replace)))
+
+	if len(rewound) > 0 {
+		bp.vaultManager.RewindVaults(rewound, parentHash)
+	}
+	bp.vaultManager.UntrackVaults(removed)
+	bp.vaultManager.RevertRounds(revertedHashes)
+	if bp.lastBlockDB != nil {
+		for _, block := range replace {
+			if block.BlockNumber == bp.lastBlockDB.BlockNumber {
+				bp.lastBlockDB = block
+			}
+		}
+	}
+	return nil
+}
+
+// storedBlocks returns the blocks stored in tx between lo and hi by number
+func storedBlocks(tx db.Tx, lo, hi uint64) (map[uint64]*models.StarknetBlocks, error) {
+	blocks, err := tx.GetBlockRange(lo, hi)
+	if err != nil {
+		return nil, err
+	}
+	stored := make(map[uint64]*models.StarknetBlocks, len(blocks))
+	for _, block := range blocks {
+		stored[block.BlockNumber] = block
+	}
+	return stored, nil
+}
+
+// revertStoredBlock reverts a block that is not canonical and its events in tx
+func (bp *Processor) revertStoredBlock(tx db.Tx, block *models.StarknetBlocks) error {
+	if err := tx.RevertBlock(block.BlockNumber, block.BlockHash); err != nil {
+		return err
+	}
+	reverted, err := tx.RevertEvents(block.BlockHash)
+	if err != nil {
+		return err
+	}
+	bp.log.Info("Reverted non canonical block", logging.Block(block.BlockNumber), logging.BlockHash(block.BlockHash), "events", reverted)
+	return bp.sendDriverEvent(tx, driverevents.TypeRevertBlock, block.BlockHash)
+}
+
+// storeCanonicalBlocks stores consecutive canonical blocks in tx and indexes
+// the vault and round events of those whose events are not stored yet
+func (bp *Processor) storeCanonicalBlocks(tx db.Tx, blocks []*models.StarknetBlocks) error {
+	from, to := blocks[0].BlockNumber, blocks[len(blocks)-1].BlockNumber
+	chunk, err := bp.network.GetEvents(
+		rpc.BlockID{Number: &from},
+		rpc.BlockID{Number: &to},
+		nil,
+		utils.VaultEventKeys(),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Blocks whose events are stored were already sent to the event-processor
+	unindexed := make(map[string]bool, len(blocks))
+	for _, block := range blocks {
+		stored, err := tx.CountEvents(block.BlockHash)
+		if err != nil {
+			return err
+		}
+		unindexed[block.BlockHash] = stored == 0
+	}
+	var candidates []rpc.EmittedEvent
+	for _, event := range chunk.Events {
+		if event.BlockHash != nil && unindexed[event.BlockHash.String()] {
+			candidates = append(candidates, event)
+		}
+	}
+	located, err := bp.network.LocateEvents(candidates)
+	if err != nil {
+		return err
+	}
+	events := make(map[string][]models.LocatedEvent)
+	for _, event := range located {
+		events[event.BlockHash.String()] = append(events[event.BlockHash.String()], event)
+	}
+
+	for _, block := range blocks {
+		if err := tx.InsertBlock(block); err != nil {
+			return err
+		}
+		if !unindexed[block.BlockHash] {
+			continue
+		}
+		for _, event := range events[block.BlockHash] {
+			fromAddress := event.FromAddress.String()
+			blockEvent := event.BlockEvent()
+			var err error
+			switch {
+			case bp.vaultManager.IsVaultAddress(fromAddress):
+				err = bp.vaultManager.ProcessVaultEvent(tx, event.TransactionHash.String(), fromAddress, blockEvent, block.BlockNumber, *event.BlockHash)
+			case bp.vaultManager.IsRoundAddress(fromAddress):
+				err = bp.vaultManager.ProcessRoundEvent(tx, event.TransactionHash.String(), fromAddress, blockEvent, block.BlockNumber, *event.BlockHash)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if err := bp.sendDriverEvent(tx, driverevents.TypeStartBlock, block.BlockHash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package block
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestVerifyChainHealthy(t *testing.T) {
+	bp, store, _ := newTestProcessor()
+	a := chain(1, nil, 1, 10)
+	process(t, bp, a)
+	bp.network = newFakeFetcher(a)
+	store.driverEvents = nil
+
+	report, err := bp.VerifyChain(0, 0)
+	if err != nil {
+		t.Fatalf("VerifyChain failed: %v", err)
+	}
+	if report.FromBlock != 1 || report.ToBlock != 10 {
+		t.Errorf("verified %d to %d, want 1 to 10", report.FromBlock, report.ToBlock)
+	}
+	if len(report.Issues) != 0 || len(report.Repaired) != 0 || len(store.driverEvents) != 0 {
+		t.Errorf("expected nothing to repair, got %+v and driver events %v", report, store.driverEvents)
+	}
+}
+
+func TestVerifyChainRepairsGaps(t *testing.T) {
+	bp, store, _ := newTestProcessor()
+	a := chain(1, nil, 1, 10)
+	process(t, bp, a)
+	bp.network = newFakeFetcher(a)
+	store.driverEvents = nil
+
+	// Block 4 and its events were never stored
+	delete(store.blocks, 4)
+	var kept []*storedEvent
+	for _, e := range store.events {
+		if e.blockHash != a[3].Hash.String() {
+			kept = append(kept, e)
+		}
+	}
+	store.events = kept
+	// Block 7 was reverted but its replacement never arrived
+	store.blocks[7].Status = "REVERTED"
+	store.RevertEvents(a[6].Hash.String())
+	// Block 9 is missing but its events are stored
+	delete(store.blocks, 9)
+
+	report, err := bp.VerifyChain(0, 0)
+	if err != nil {
+		t.Fatalf("VerifyChain failed: %v", err)
+	}
+	wantIssues := []ChainIssue{
+		{Kind: IssueMissing, BlockNumber: 4},
+		{Kind: IssueOrphanedRevert, BlockNumber: 7, BlockHash: a[6].Hash.String()},
+		{Kind: IssueMissing, BlockNumber: 9},
+	}
+	if fmt.Sprint(report.Issues) != fmt.Sprint(wantIssues) {
+		t.Errorf("issues = %v, want %v", report.Issues, wantIssues)
+	}
+	if fmt.Sprint(report.Repaired) != fmt.Sprint([]uint64{4, 7, 9}) || len(report.Reverted) != 0 {
+		t.Errorf("repaired %v reverted %v, want [4 7 9] and none", report.Repaired, report.Reverted)
+	}
+	for _, n := range []uint64{4, 7, 9} {
+		if b := store.blocks[n]; b == nil || b.Status != "MINED" || b.BlockHash != a[n-1].Hash.String() {
+			t.Errorf("block %d = %+v, want %s mined", n, b, a[n-1].Hash)
+		}
+		if got := store.eventCount(a[n-1].Hash.String(), false); got != 1 {
+			t.Errorf("block %d has %d events, want 1", n, got)
+		}
+	}
+	// Block 9 already had its events, the event-processor has seen them
+	want := []string{
+		"StartBlock:" + a[3].Hash.String(),
+		"StartBlock:" + a[6].Hash.String(),
+	}
+	if fmt.Sprint(store.driverEvents) != fmt.Sprint(want) {
+		t.Errorf("driver events = %v, want %v", store.driverEvents, want)
+	}
+}
+
+func TestCheckChainDoesNotRepair(t *testing.T) {
+	bp, store, _ := newTestProcessor()
+	a := chain(1, nil, 1, 10)
+	process(t, bp, a)
+	bp.network = nil
+	store.driverEvents = nil
+	delete(store.blocks, 4)
+
+	report, err := bp.CheckChain(2, 8)
+	if err != nil {
+		t.Fatalf("CheckChain failed: %v", err)
+	}
+	if report.FromBlock != 2 || report.ToBlock != 8 {
+		t.Errorf("checked %d to %d, want 2 to 8", report.FromBlock, report.ToBlock)
+	}
+	wantIssues := []ChainIssue{{Kind: IssueMissing, BlockNumber: 4}}
+	if fmt.Sprint(report.Issues) != fmt.Sprint(wantIssues) {
+		t.Errorf("issues = %v, want %v", report.Issues, wantIssues)
+	}
+	if store.blocks[4] != nil || len(report.Repaired) != 0 || len(store.driverEvents) != 0 {
+		t.Errorf("expected no repair, got %+v and driver events %v", report, store.driverEvents)
+	}
+}
+
+func TestVerifyChainRepairsFork(t *testing.T) {
+	bp, store, vaults := newTestProcessor()
+	a := chain(1, nil, 1, 8)
+	process(t, bp, a)
+	bp.network = newFakeFetcher(a)
+	store.driverEvents = nil
+
+	// Blocks 5 and 6 of another branch were stored in place of the canonical
+	// ones, so block 7 does not chain onto block 6
+	b := chain(2, a[3], 5, 6)
+	for _, block := range b {
+		stored := block.StarknetBlock()
+		stored.Status = "MINED"
+		store.blocks[block.Number] = &stored
+		store.events = append(store.events, &storedEvent{blockHash: block.Hash.String()})
+	}
+	for _, e := range store.events {
+		if e.blockHash == a[4].Hash.String() || e.blockHash == a[5].Hash.String() {
+			e.reverted = true
+		}
+	}
+	store.cursors[testVault] = b[1].Hash.String()
+
+	report, err := bp.VerifyChain(0, 0)
+	if err != nil {
+		t.Fatalf("VerifyChain failed: %v", err)
+	}
+	wantIssues := []ChainIssue{{Kind: IssueParentMismatch, BlockNumber: 7, BlockHash: a[6].Hash.String()}}
+	if fmt.Sprint(report.Issues) != fmt.Sprint(wantIssues) {
+		t.Errorf("issues = %v, want %v", report.Issues, wantIssues)
+	}
+	if fmt.Sprint(report.Reverted) != fmt.Sprint([]uint64{6, 5}) || fmt.Sprint(report.Repaired) != fmt.Sprint([]uint64{5, 6}) {
+		t.Errorf("reverted %v repaired %v, want [6 5] and [5 6]", report.Reverted, report.Repaired)
+	}
+
+	want := []string{
+		"RevertBlock:" + b[1].Hash.String(),
+		"RevertBlock:" + b[0].Hash.String(),
+		"StartBlock:" + a[4].Hash.String(),
+		"StartBlock:" + a[5].Hash.String(),
+	}
+	if fmt.Sprint(store.driverEvents) != fmt.Sprint(want) {
+		t.Errorf("driver events = %v, want %v", store.driverEvents, want)
+	}
+	for _, block := range b {
+		if store.eventCount(block.Hash.String(), true) != 1 {
+			t.Errorf("expected the events of block %s to be reverted", block.Hash)
+		}
+	}
+	for _, block := range a[4:6] {
+		if store.blocks[block.Number].BlockHash != block.Hash.String() || store.eventCount(block.Hash.String(), false) != 1 {
+			t.Errorf("expected canonical block %d with its events", block.Number)
+		}
+	}
+	if got := store.cursors[testVault]; got != a[3].Hash.String() {
+		t.Errorf("vault cursor = %s, want block 4 %s", got, a[3].Hash)
+	}
+	if vaults.cursor != a[3].Hash.String() {
+		t.Errorf("in memory vault cursor = %s, want block 4 %s", vaults.cursor, a[3].Hash)
+	}
+}
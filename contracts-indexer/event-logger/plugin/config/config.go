@@ -1,38 +1,135 @@
 package config
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"junoplugin/network"
+	"junoplugin/plugin/source"
+	"junoplugin/utils"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
 )
 
-// Config holds all configuration for the plugin
+// DefaultVerifyDepth is the number of blocks below the head verified at startup
+const DefaultVerifyDepth = 10000
+
+// DefaultLogLevel is the log level used when none is configured
+const DefaultLogLevel = "info"
+
+// Contract classes built by scarb, used when VAULT_ABI_PATH or
+// OPTION_ROUND_ABI_PATH is not set
+const (
+	DefaultVaultABIPath       = "../../contracts/target/dev/pitch_lake_Vault.contract_class.json"
+	DefaultOptionRoundABIPath = "../../contracts/target/dev/pitch_lake_OptionRound.contract_class.json"
+)
+
+// LogLevels are the accepted log levels, most verbose first
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+// VaultConfig is a vault pinned by the configuration, registered at startup
+// and indexed from Cursor unless it is registered already
+type VaultConfig struct {
+	Address string `json:"address"`
+	// Cursor is the block the vault is indexed from, the global cursor when 0
+	Cursor uint64 `json:"cursor"`
+}
+
+// Config holds all configuration for the plugin. It is read from the JSON
+// file at CONFIG_FILE, if any, then from the environment variables, which
+// override the file. Durations are Go duration strings in the file.
 type Config struct {
-	DatabaseURL string
-	RPCURL      string
-	UDCAddress  string
-	Cursor      uint64
+	DatabaseURL string `json:"db_url"`
+	RPCURL      string `json:"rpc_url"`
+	UDCAddress  string `json:"udc_address"`
+	Cursor      uint64 `json:"cursor"`
+
+	// EventsChunkSize is the page size used when fetching events over RPC
+	EventsChunkSize int `json:"events_chunk_size"`
+	// EventsMaxRetries is how many times a failed events page is retried
+	EventsMaxRetries int `json:"events_max_retries"`
+	// BackfillConcurrency is the number of block requests in flight while
+	// backfilling missed blocks
+	BackfillConcurrency int `json:"backfill_concurrency"`
+	// RPCRateLimit caps the RPC requests sent per second, 0 disables the limit
+	RPCRateLimit float64 `json:"rpc_rate_limit"`
+	// RPCTimeout bounds each RPC request
+	RPCTimeout time.Duration `json:"-"`
+	// VerifyDepth is how many blocks below the head are verified at startup,
+	// 0 verifies every stored block
+	VerifyDepth uint64 `json:"verify_depth"`
+	// VaultClassHashes are the vault class hashes whose UDC deployments are
+	// registered automatically, empty disables the discovery
+	VaultClassHashes []string `json:"vault_class_hashes"`
+	// PollInterval is how often the standalone indexer polls the RPC node for
+	// new blocks, unused by the Juno plugin
+	PollInterval time.Duration `json:"-"`
+	// ReorgDepth is how many blocks deep a reorg the standalone indexer can
+	// revert, unused by the Juno plugin
+	ReorgDepth int `json:"reorg_depth"`
+	// LogLevel is one of LogLevels
+	LogLevel string `json:"log_level"`
+	// MetricsPort is the port the metrics are served on, 0 disables them
+	MetricsPort int `json:"metrics_port"`
+	// EventNames are the vault and option round events stored, empty stores
+	// all of them
+	EventNames []string `json:"event_names"`
+	// Vaults are registered at startup
+	Vaults []VaultConfig `json:"vaults"`
+	// VaultABIPath and OptionRoundABIPath are the contract classes the events
+	// are decoded with
+	VaultABIPath       string `json:"vault_abi_path"`
+	OptionRoundABIPath string `json:"option_round_abi_path"`
 }
 
-// LoadConfig loads configuration from environment variables
+// LoadConfig loads configuration from the file at CONFIG_FILE and from
+// environment variables
 func LoadConfig() (*Config, error) {
-	config := &Config{}
+	config := &Config{
+		EventsChunkSize:     network.DefaultEventsChunkSize,
+		EventsMaxRetries:    network.DefaultEventsMaxRetries,
+		BackfillConcurrency: network.DefaultBackfillConcurrency,
+		RPCRateLimit:        network.DefaultRateLimit,
+		RPCTimeout:          network.DefaultTimeout,
+		VerifyDepth:         DefaultVerifyDepth,
+		PollInterval:        source.DefaultPollInterval,
+		ReorgDepth:          source.DefaultReorgDepth,
+		LogLevel:            DefaultLogLevel,
+		VaultABIPath:        DefaultVaultABIPath,
+		OptionRoundABIPath:  DefaultOptionRoundABIPath,
+	}
 
-	// Required environment variables
-	dbURL := os.Getenv("DB_URL")
-	if dbURL == "" {
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := config.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	// Required settings
+	if dbURL := os.Getenv("DB_URL"); dbURL != "" {
+		config.DatabaseURL = dbURL
+	}
+	if config.DatabaseURL == "" {
 		return nil, fmt.Errorf("DB_URL environment variable is required")
 	}
-	config.DatabaseURL = dbURL
 
-	rpcURL := os.Getenv("RPC_URL")
-	if rpcURL == "" {
+	if rpcURL := os.Getenv("RPC_URL"); rpcURL != "" {
+		config.RPCURL = rpcURL
+	}
+	if config.RPCURL == "" {
 		return nil, fmt.Errorf("RPC_URL environment variable is required")
 	}
-	config.RPCURL = rpcURL
 
 	// Optional environment variables
-	config.UDCAddress = os.Getenv("UDC_ADDRESS")
+	if udcAddress := os.Getenv("UDC_ADDRESS"); udcAddress != "" {
+		config.UDCAddress = udcAddress
+	}
 
 	cursor := os.Getenv("CURSOR")
 	if cursor != "" {
@@ -43,10 +140,192 @@ func LoadConfig() (*Config, error) {
 		}
 	}
 
+	if chunkSize := os.Getenv("EVENTS_CHUNK_SIZE"); chunkSize != "" {
+		var err error
+		config.EventsChunkSize, err = strconv.Atoi(chunkSize)
+		if err != nil || config.EventsChunkSize <= 0 {
+			return nil, fmt.Errorf("invalid EVENTS_CHUNK_SIZE value: %s", chunkSize)
+		}
+	}
+
+	if maxRetries := os.Getenv("EVENTS_MAX_RETRIES"); maxRetries != "" {
+		var err error
+		config.EventsMaxRetries, err = strconv.Atoi(maxRetries)
+		if err != nil || config.EventsMaxRetries < 0 {
+			return nil, fmt.Errorf("invalid EVENTS_MAX_RETRIES value: %s", maxRetries)
+		}
+	}
+
+	if concurrency := os.Getenv("BACKFILL_CONCURRENCY"); concurrency != "" {
+		var err error
+		config.BackfillConcurrency, err = strconv.Atoi(concurrency)
+		if err != nil || config.BackfillConcurrency <= 0 {
+			return nil, fmt.Errorf("invalid BACKFILL_CONCURRENCY value: %s", concurrency)
+		}
+	}
+
+	if rateLimit := os.Getenv("RPC_RATE_LIMIT"); rateLimit != "" {
+		var err error
+		config.RPCRateLimit, err = strconv.ParseFloat(rateLimit, 64)
+		if err != nil || config.RPCRateLimit < 0 {
+			return nil, fmt.Errorf("invalid RPC_RATE_LIMIT value: %s", rateLimit)
+		}
+	}
+
+	if timeout := os.Getenv("RPC_TIMEOUT"); timeout != "" {
+		var err error
+		config.RPCTimeout, err = time.ParseDuration(timeout)
+		if err != nil || config.RPCTimeout <= 0 {
+			return nil, fmt.Errorf("invalid RPC_TIMEOUT value: %s", timeout)
+		}
+	}
+
+	if depth := os.Getenv("VERIFY_DEPTH"); depth != "" {
+		var err error
+		config.VerifyDepth, err = strconv.ParseUint(depth, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid VERIFY_DEPTH value: %w", err)
+		}
+	}
+
+	if classHashes := os.Getenv("VAULT_CLASS_HASHES"); classHashes != "" {
+		config.VaultClassHashes = splitList(classHashes)
+	}
+	var err error
+	config.VaultClassHashes, err = normalizeFelts(config.VaultClassHashes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAULT_CLASS_HASHES value %w", err)
+	}
+
+	if interval := os.Getenv("POLL_INTERVAL"); interval != "" {
+		var err error
+		config.PollInterval, err = time.ParseDuration(interval)
+		if err != nil || config.PollInterval <= 0 {
+			return nil, fmt.Errorf("invalid POLL_INTERVAL value: %s", interval)
+		}
+	}
+
+	if reorgDepth := os.Getenv("REORG_DEPTH"); reorgDepth != "" {
+		var err error
+		config.ReorgDepth, err = strconv.Atoi(reorgDepth)
+		if err != nil || config.ReorgDepth <= 0 {
+			return nil, fmt.Errorf("invalid REORG_DEPTH value: %s", reorgDepth)
+		}
+	}
+
+	if logLevel := os.Getenv("LOG_LEVEL"); logLevel != "" {
+		config.LogLevel = strings.ToLower(logLevel)
+	}
+
+	if metricsPort := os.Getenv("METRICS_PORT"); metricsPort != "" {
+		var err error
+		config.MetricsPort, err = strconv.Atoi(metricsPort)
+		if err != nil {
+			return nil, fmt.Errorf("invalid METRICS_PORT value: %s", metricsPort)
+		}
+	}
+
+	if eventNames := os.Getenv("EVENT_NAMES"); eventNames != "" {
+		config.EventNames = splitList(eventNames)
+	}
+
+	if path := os.Getenv("VAULT_ABI_PATH"); path != "" {
+		config.VaultABIPath = path
+	}
+	if path := os.Getenv("OPTION_ROUND_ABI_PATH"); path != "" {
+		config.OptionRoundABIPath = path
+	}
+
+	// VAULTS lists address[:cursor] entries
+	if vaults := os.Getenv("VAULTS"); vaults != "" {
+		config.Vaults = nil
+		for _, entry := range splitList(vaults) {
+			vault := VaultConfig{Address: entry}
+			if address, cursor, ok := strings.Cut(entry, ":"); ok {
+				vault.Address = address
+				vault.Cursor, err = strconv.ParseUint(cursor, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid VAULTS cursor of %s: %w", address, err)
+				}
+			}
+			config.Vaults = append(config.Vaults, vault)
+		}
+	}
+	for i, vault := range config.Vaults {
+		address, err := normalizeFelt(vault.Address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vault address %s: %w", vault.Address, err)
+		}
+		config.Vaults[i].Address = address
+	}
+
 	return config, nil
 }
 
-// Validate validates the configuration
+// loadFile reads the settings present in a JSON configuration file
+func (c *Config) loadFile(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration file: %w", err)
+	}
+	file := struct {
+		*Config
+		RPCTimeout   string `json:"rpc_timeout"`
+		PollInterval string `json:"poll_interval"`
+	}{Config: c}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+	if file.RPCTimeout != "" {
+		if c.RPCTimeout, err = time.ParseDuration(file.RPCTimeout); err != nil {
+			return fmt.Errorf("invalid rpc_timeout in %s: %w", path, err)
+		}
+	}
+	if file.PollInterval != "" {
+		if c.PollInterval, err = time.ParseDuration(file.PollInterval); err != nil {
+			return fmt.Errorf("invalid poll_interval in %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// splitList splits a comma separated list, trimming its items
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		items = append(items, strings.TrimSpace(item))
+	}
+	return items
+}
+
+// normalizeFelt returns the canonical form of a hex felt, lowercase without
+// leading zeros
+func normalizeFelt(value string) (string, error) {
+	if !strings.HasPrefix(value, "0x") {
+		return "", fmt.Errorf("%s is not a 0x prefixed hex value", value)
+	}
+	f, err := new(felt.Felt).SetString(value)
+	if err != nil {
+		return "", err
+	}
+	return f.String(), nil
+}
+
+func normalizeFelts(values []string) ([]string, error) {
+	for i, value := range values {
+		normalized, err := normalizeFelt(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", value, err)
+		}
+		values[i] = normalized
+	}
+	return values, nil
+}
+
+// Validate validates the configuration. Empty numeric settings take their
+// defaults where they are used.
 func (c *Config) Validate() error {
 	if c.DatabaseURL == "" {
 		return fmt.Errorf("database URL is required")
@@ -54,5 +333,61 @@ func (c *Config) Validate() error {
 	if c.RPCURL == "" {
 		return fmt.Errorf("RPC URL is required")
 	}
+	if u, err := url.Parse(c.RPCURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid RPC URL %s", c.RPCURL)
+	}
+	if c.UDCAddress != "" {
+		if _, err := normalizeFelt(c.UDCAddress); err != nil {
+			return fmt.Errorf("invalid UDC address: %w", err)
+		}
+	}
+	if len(c.VaultClassHashes) > 0 && c.UDCAddress == "" {
+		return fmt.Errorf("UDC address is required to discover vaults")
+	}
+	for _, classHash := range c.VaultClassHashes {
+		if _, err := normalizeFelt(classHash); err != nil {
+			return fmt.Errorf("invalid vault class hash: %w", err)
+		}
+	}
+
+	pinned := make(map[string]struct{}, len(c.Vaults))
+	for _, vault := range c.Vaults {
+		address, err := normalizeFelt(vault.Address)
+		if err != nil {
+			return fmt.Errorf("invalid vault address: %w", err)
+		}
+		if _, ok := pinned[address]; ok {
+			return fmt.Errorf("vault %s is listed twice", vault.Address)
+		}
+		pinned[address] = struct{}{}
+	}
+
+	switch {
+	case c.EventsChunkSize < 0:
+		return fmt.Errorf("events chunk size must not be negative")
+	case c.EventsMaxRetries < 0:
+		return fmt.Errorf("events max retries must not be negative")
+	case c.BackfillConcurrency < 0:
+		return fmt.Errorf("backfill concurrency must not be negative")
+	case c.RPCRateLimit < 0:
+		return fmt.Errorf("RPC rate limit must not be negative")
+	case c.RPCTimeout < 0:
+		return fmt.Errorf("RPC timeout must not be negative")
+	case c.PollInterval < 0:
+		return fmt.Errorf("poll interval must not be negative")
+	case c.ReorgDepth < 0:
+		return fmt.Errorf("reorg depth must not be negative")
+	case c.MetricsPort < 0 || c.MetricsPort > 65535:
+		return fmt.Errorf("invalid metrics port %d", c.MetricsPort)
+	}
+
+	if c.LogLevel != "" && !slices.Contains(LogLevels, c.LogLevel) {
+		return fmt.Errorf("invalid log level %s, expected one of %s", c.LogLevel, strings.Join(LogLevels, ", "))
+	}
+	for _, name := range c.EventNames {
+		if !utils.IsEventName(name) {
+			return fmt.Errorf("unknown event name %s", name)
+		}
+	}
 	return nil
 }
@@ -2,7 +2,10 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -11,6 +14,22 @@ func TestLoadConfig(t *testing.T) {
 	originalRPCURL := os.Getenv("RPC_URL")
 	originalUDCAddress := os.Getenv("UDC_ADDRESS")
 	originalCursor := os.Getenv("CURSOR")
+	originalChunkSize := os.Getenv("EVENTS_CHUNK_SIZE")
+	originalMaxRetries := os.Getenv("EVENTS_MAX_RETRIES")
+	originalConcurrency := os.Getenv("BACKFILL_CONCURRENCY")
+	originalRateLimit := os.Getenv("RPC_RATE_LIMIT")
+	originalVerifyDepth := os.Getenv("VERIFY_DEPTH")
+	originalClassHashes := os.Getenv("VAULT_CLASS_HASHES")
+	originalPollInterval := os.Getenv("POLL_INTERVAL")
+	originalRPCTimeout := os.Getenv("RPC_TIMEOUT")
+	originalReorgDepth := os.Getenv("REORG_DEPTH")
+	originalLogLevel := os.Getenv("LOG_LEVEL")
+	originalMetricsPort := os.Getenv("METRICS_PORT")
+	originalEventNames := os.Getenv("EVENT_NAMES")
+	originalVaults := os.Getenv("VAULTS")
+	originalVaultABIPath := os.Getenv("VAULT_ABI_PATH")
+	originalOptionRoundABIPath := os.Getenv("OPTION_ROUND_ABI_PATH")
+	originalConfigFile := os.Getenv("CONFIG_FILE")
 
 	// Clean up after test
 	defer func() {
@@ -18,6 +37,22 @@ func TestLoadConfig(t *testing.T) {
 		os.Setenv("RPC_URL", originalRPCURL)
 		os.Setenv("UDC_ADDRESS", originalUDCAddress)
 		os.Setenv("CURSOR", originalCursor)
+		os.Setenv("EVENTS_CHUNK_SIZE", originalChunkSize)
+		os.Setenv("EVENTS_MAX_RETRIES", originalMaxRetries)
+		os.Setenv("BACKFILL_CONCURRENCY", originalConcurrency)
+		os.Setenv("RPC_RATE_LIMIT", originalRateLimit)
+		os.Setenv("VERIFY_DEPTH", originalVerifyDepth)
+		os.Setenv("VAULT_CLASS_HASHES", originalClassHashes)
+		os.Setenv("POLL_INTERVAL", originalPollInterval)
+		os.Setenv("RPC_TIMEOUT", originalRPCTimeout)
+		os.Setenv("REORG_DEPTH", originalReorgDepth)
+		os.Setenv("LOG_LEVEL", originalLogLevel)
+		os.Setenv("METRICS_PORT", originalMetricsPort)
+		os.Setenv("EVENT_NAMES", originalEventNames)
+		os.Setenv("VAULTS", originalVaults)
+		os.Setenv("VAULT_ABI_PATH", originalVaultABIPath)
+		os.Setenv("OPTION_ROUND_ABI_PATH", originalOptionRoundABIPath)
+		os.Setenv("CONFIG_FILE", originalConfigFile)
 	}()
 
 	tests := []struct {
@@ -29,17 +64,47 @@ func TestLoadConfig(t *testing.T) {
 		{
 			name: "valid config with all variables",
 			envVars: map[string]string{
-				"DB_URL":      "postgres://localhost:5432/test",
-				"RPC_URL":     "https://starknet-mainnet.infura.io",
-				"UDC_ADDRESS": "0x123",
-				"CURSOR":      "1000",
+				"DB_URL":                "postgres://localhost:5432/test",
+				"RPC_URL":               "https://starknet-mainnet.infura.io",
+				"UDC_ADDRESS":           "0x123",
+				"CURSOR":                "1000",
+				"EVENTS_CHUNK_SIZE":     "500",
+				"EVENTS_MAX_RETRIES":    "0",
+				"BACKFILL_CONCURRENCY":  "4",
+				"RPC_RATE_LIMIT":        "0",
+				"VERIFY_DEPTH":          "0",
+				"VAULT_CLASS_HASHES":    "0x0abc, 0xdef",
+				"POLL_INTERVAL":         "500ms",
+				"RPC_TIMEOUT":           "5s",
+				"REORG_DEPTH":           "64",
+				"LOG_LEVEL":             "DEBUG",
+				"METRICS_PORT":          "9102",
+				"EVENT_NAMES":           "Deposit, Withdrawal",
+				"VAULTS":                "0x07a417:1200,0xbeef",
+				"VAULT_ABI_PATH":        "abi/vault.json",
+				"OPTION_ROUND_ABI_PATH": "abi/round.json",
 			},
 			expectError: false,
 			expected: &Config{
-				DatabaseURL: "postgres://localhost:5432/test",
-				RPCURL:      "https://starknet-mainnet.infura.io",
-				UDCAddress:  "0x123",
-				Cursor:      1000,
+				DatabaseURL:         "postgres://localhost:5432/test",
+				RPCURL:              "https://starknet-mainnet.infura.io",
+				UDCAddress:          "0x123",
+				Cursor:              1000,
+				EventsChunkSize:     500,
+				EventsMaxRetries:    0,
+				BackfillConcurrency: 4,
+				RPCRateLimit:        0,
+				VerifyDepth:         0,
+				VaultClassHashes:    []string{"0xabc", "0xdef"},
+				PollInterval:        500 * time.Millisecond,
+				RPCTimeout:          5 * time.Second,
+				ReorgDepth:          64,
+				LogLevel:            "debug",
+				MetricsPort:         9102,
+				EventNames:          []string{"Deposit", "Withdrawal"},
+				Vaults:              []VaultConfig{{Address: "0x7a417", Cursor: 1200}, {Address: "0xbeef"}},
+				VaultABIPath:        "abi/vault.json",
+				OptionRoundABIPath:  "abi/round.json",
 			},
 		},
 		{
@@ -50,10 +115,21 @@ func TestLoadConfig(t *testing.T) {
 			},
 			expectError: false,
 			expected: &Config{
-				DatabaseURL: "postgres://localhost:5432/test",
-				RPCURL:      "https://starknet-mainnet.infura.io",
-				UDCAddress:  "",
-				Cursor:      0,
+				DatabaseURL:         "postgres://localhost:5432/test",
+				RPCURL:              "https://starknet-mainnet.infura.io",
+				UDCAddress:          "",
+				Cursor:              0,
+				EventsChunkSize:     100,
+				EventsMaxRetries:    3,
+				BackfillConcurrency: 8,
+				RPCRateLimit:        20,
+				VerifyDepth:         10000,
+				PollInterval:        2 * time.Second,
+				RPCTimeout:          30 * time.Second,
+				ReorgDepth:          128,
+				LogLevel:            "info",
+				VaultABIPath:        DefaultVaultABIPath,
+				OptionRoundABIPath:  DefaultOptionRoundABIPath,
 			},
 		},
 		{
@@ -79,6 +155,123 @@ func TestLoadConfig(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "invalid EVENTS_CHUNK_SIZE value",
+			envVars: map[string]string{
+				"DB_URL":            "postgres://localhost:5432/test",
+				"RPC_URL":           "https://starknet-mainnet.infura.io",
+				"EVENTS_CHUNK_SIZE": "0",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid BACKFILL_CONCURRENCY value",
+			envVars: map[string]string{
+				"DB_URL":               "postgres://localhost:5432/test",
+				"RPC_URL":              "https://starknet-mainnet.infura.io",
+				"BACKFILL_CONCURRENCY": "none",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid RPC_RATE_LIMIT value",
+			envVars: map[string]string{
+				"DB_URL":         "postgres://localhost:5432/test",
+				"RPC_URL":        "https://starknet-mainnet.infura.io",
+				"RPC_RATE_LIMIT": "-5",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid VERIFY_DEPTH value",
+			envVars: map[string]string{
+				"DB_URL":       "postgres://localhost:5432/test",
+				"RPC_URL":      "https://starknet-mainnet.infura.io",
+				"VERIFY_DEPTH": "-1",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid VAULT_CLASS_HASHES value",
+			envVars: map[string]string{
+				"DB_URL":             "postgres://localhost:5432/test",
+				"RPC_URL":            "https://starknet-mainnet.infura.io",
+				"VAULT_CLASS_HASHES": "0xabc,vault",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid POLL_INTERVAL value",
+			envVars: map[string]string{
+				"DB_URL":        "postgres://localhost:5432/test",
+				"RPC_URL":       "https://starknet-mainnet.infura.io",
+				"POLL_INTERVAL": "2",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid EVENTS_MAX_RETRIES value",
+			envVars: map[string]string{
+				"DB_URL":             "postgres://localhost:5432/test",
+				"RPC_URL":            "https://starknet-mainnet.infura.io",
+				"EVENTS_MAX_RETRIES": "-1",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid RPC_TIMEOUT value",
+			envVars: map[string]string{
+				"DB_URL":      "postgres://localhost:5432/test",
+				"RPC_URL":     "https://starknet-mainnet.infura.io",
+				"RPC_TIMEOUT": "0s",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid REORG_DEPTH value",
+			envVars: map[string]string{
+				"DB_URL":      "postgres://localhost:5432/test",
+				"RPC_URL":     "https://starknet-mainnet.infura.io",
+				"REORG_DEPTH": "0",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid METRICS_PORT value",
+			envVars: map[string]string{
+				"DB_URL":       "postgres://localhost:5432/test",
+				"RPC_URL":      "https://starknet-mainnet.infura.io",
+				"METRICS_PORT": "metrics",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid VAULTS cursor",
+			envVars: map[string]string{
+				"DB_URL":  "postgres://localhost:5432/test",
+				"RPC_URL": "https://starknet-mainnet.infura.io",
+				"VAULTS":  "0xabc:latest",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid VAULTS address",
+			envVars: map[string]string{
+				"DB_URL":  "postgres://localhost:5432/test",
+				"RPC_URL": "https://starknet-mainnet.infura.io",
+				"VAULTS":  "vault:10",
+			},
+			expectError: true,
+		},
+		{
+			name: "missing CONFIG_FILE",
+			envVars: map[string]string{
+				"DB_URL":      "postgres://localhost:5432/test",
+				"RPC_URL":     "https://starknet-mainnet.infura.io",
+				"CONFIG_FILE": "testdata/missing.json",
+			},
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -88,6 +281,22 @@ func TestLoadConfig(t *testing.T) {
 			os.Unsetenv("RPC_URL")
 			os.Unsetenv("UDC_ADDRESS")
 			os.Unsetenv("CURSOR")
+			os.Unsetenv("EVENTS_CHUNK_SIZE")
+			os.Unsetenv("EVENTS_MAX_RETRIES")
+			os.Unsetenv("BACKFILL_CONCURRENCY")
+			os.Unsetenv("RPC_RATE_LIMIT")
+			os.Unsetenv("VERIFY_DEPTH")
+			os.Unsetenv("VAULT_CLASS_HASHES")
+			os.Unsetenv("POLL_INTERVAL")
+			os.Unsetenv("RPC_TIMEOUT")
+			os.Unsetenv("REORG_DEPTH")
+			os.Unsetenv("LOG_LEVEL")
+			os.Unsetenv("METRICS_PORT")
+			os.Unsetenv("EVENT_NAMES")
+			os.Unsetenv("VAULTS")
+			os.Unsetenv("VAULT_ABI_PATH")
+			os.Unsetenv("OPTION_ROUND_ABI_PATH")
+			os.Unsetenv("CONFIG_FILE")
 
 			// Set test environment variables
 			for key, value := range tt.envVars {
@@ -123,10 +332,130 @@ func TestLoadConfig(t *testing.T) {
 			if config.Cursor != tt.expected.Cursor {
 				t.Errorf("Expected Cursor %d, got %d", tt.expected.Cursor, config.Cursor)
 			}
+
+			if config.EventsChunkSize != tt.expected.EventsChunkSize {
+				t.Errorf("Expected EventsChunkSize %d, got %d", tt.expected.EventsChunkSize, config.EventsChunkSize)
+			}
+
+			if config.EventsMaxRetries != tt.expected.EventsMaxRetries {
+				t.Errorf("Expected EventsMaxRetries %d, got %d", tt.expected.EventsMaxRetries, config.EventsMaxRetries)
+			}
+
+			if config.BackfillConcurrency != tt.expected.BackfillConcurrency {
+				t.Errorf("Expected BackfillConcurrency %d, got %d", tt.expected.BackfillConcurrency, config.BackfillConcurrency)
+			}
+
+			if config.RPCRateLimit != tt.expected.RPCRateLimit {
+				t.Errorf("Expected RPCRateLimit %v, got %v", tt.expected.RPCRateLimit, config.RPCRateLimit)
+			}
+
+			if config.VerifyDepth != tt.expected.VerifyDepth {
+				t.Errorf("Expected VerifyDepth %d, got %d", tt.expected.VerifyDepth, config.VerifyDepth)
+			}
+
+			if !reflect.DeepEqual(config.VaultClassHashes, tt.expected.VaultClassHashes) {
+				t.Errorf("Expected VaultClassHashes %v, got %v", tt.expected.VaultClassHashes, config.VaultClassHashes)
+			}
+
+			if config.PollInterval != tt.expected.PollInterval {
+				t.Errorf("Expected PollInterval %s, got %s", tt.expected.PollInterval, config.PollInterval)
+			}
+
+			if config.RPCTimeout != tt.expected.RPCTimeout {
+				t.Errorf("Expected RPCTimeout %s, got %s", tt.expected.RPCTimeout, config.RPCTimeout)
+			}
+
+			if config.ReorgDepth != tt.expected.ReorgDepth {
+				t.Errorf("Expected ReorgDepth %d, got %d", tt.expected.ReorgDepth, config.ReorgDepth)
+			}
+
+			if config.LogLevel != tt.expected.LogLevel {
+				t.Errorf("Expected LogLevel %s, got %s", tt.expected.LogLevel, config.LogLevel)
+			}
+
+			if config.MetricsPort != tt.expected.MetricsPort {
+				t.Errorf("Expected MetricsPort %d, got %d", tt.expected.MetricsPort, config.MetricsPort)
+			}
+
+			if !reflect.DeepEqual(config.EventNames, tt.expected.EventNames) {
+				t.Errorf("Expected EventNames %v, got %v", tt.expected.EventNames, config.EventNames)
+			}
+
+			if !reflect.DeepEqual(config.Vaults, tt.expected.Vaults) {
+				t.Errorf("Expected Vaults %v, got %v", tt.expected.Vaults, config.Vaults)
+			}
+
+			if config.VaultABIPath != tt.expected.VaultABIPath || config.OptionRoundABIPath != tt.expected.OptionRoundABIPath {
+				t.Errorf("Expected ABI paths %s and %s, got %s and %s", tt.expected.VaultABIPath, tt.expected.OptionRoundABIPath, config.VaultABIPath, config.OptionRoundABIPath)
+			}
 		})
 	}
 }
 
+func TestLoadConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	file := `{
+		"db_url": "postgres://localhost:5432/file",
+		"rpc_url": "https://starknet-mainnet.infura.io",
+		"cursor": 500,
+		"rpc_timeout": "10s",
+		"poll_interval": "1s",
+		"log_level": "warn",
+		"event_names": ["Deposit"],
+		"vaults": [{"address": "0x0ABC", "cursor": 900}, {"address": "0xdef"}]
+	}`
+	if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("DB_URL", "")
+	t.Setenv("RPC_URL", "")
+	t.Setenv("CURSOR", "")
+	t.Setenv("VAULTS", "")
+	// The environment overrides the file
+	t.Setenv("LOG_LEVEL", "error")
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.DatabaseURL != "postgres://localhost:5432/file" {
+		t.Errorf("Expected DatabaseURL from the file, got %s", config.DatabaseURL)
+	}
+	if config.Cursor != 500 {
+		t.Errorf("Expected Cursor 500, got %d", config.Cursor)
+	}
+	if config.RPCTimeout != 10*time.Second || config.PollInterval != time.Second {
+		t.Errorf("Expected durations 10s and 1s, got %s and %s", config.RPCTimeout, config.PollInterval)
+	}
+	if config.LogLevel != "error" {
+		t.Errorf("Expected LogLevel error from the environment, got %s", config.LogLevel)
+	}
+	if config.EventsChunkSize != 100 {
+		t.Errorf("Expected default EventsChunkSize 100, got %d", config.EventsChunkSize)
+	}
+	if !reflect.DeepEqual(config.EventNames, []string{"Deposit"}) {
+		t.Errorf("Expected EventNames [Deposit], got %v", config.EventNames)
+	}
+	expected := []VaultConfig{{Address: "0xabc", Cursor: 900}, {Address: "0xdef"}}
+	if !reflect.DeepEqual(config.Vaults, expected) {
+		t.Errorf("Expected Vaults %v, got %v", expected, config.Vaults)
+	}
+}
+
+func TestLoadConfigFileRejectsUnknownSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"db_url": "postgres://localhost:5432/file", "rpc_urls": "typo"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("RPC_URL", "https://starknet-mainnet.infura.io")
+
+	if _, err := LoadConfig(); err == nil {
+		t.Errorf("Expected error but got none")
+	}
+}
+
 func TestConfigValidate(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -155,11 +484,116 @@ func TestConfigValidate(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "vault discovery without UDC address",
+			config: &Config{
+				DatabaseURL:      "postgres://localhost:5432/test",
+				RPCURL:           "https://starknet-mainnet.infura.io",
+				VaultClassHashes: []string{"0xabc"},
+			},
+			expectError: true,
+		},
 		{
 			name:        "both URLs missing",
 			config:      &Config{},
 			expectError: true,
 		},
+		{
+			name: "invalid RPC URL",
+			config: &Config{
+				DatabaseURL: "postgres://localhost:5432/test",
+				RPCURL:      "localhost",
+			},
+			expectError: true,
+		},
+		{
+			name: "valid config with all settings",
+			config: &Config{
+				DatabaseURL:      "postgres://localhost:5432/test",
+				RPCURL:           "https://starknet-mainnet.infura.io",
+				UDCAddress:       "0x123",
+				VaultClassHashes: []string{"0xabc"},
+				RPCTimeout:       time.Second,
+				ReorgDepth:       64,
+				LogLevel:         "warn",
+				EventNames:       []string{"Deposit", "OptionRoundDeployed", "BidPlaced"},
+				Vaults:           []VaultConfig{{Address: "0x1", Cursor: 10}, {Address: "0x2"}},
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid UDC address",
+			config: &Config{
+				DatabaseURL: "postgres://localhost:5432/test",
+				RPCURL:      "https://starknet-mainnet.infura.io",
+				UDCAddress:  "123",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid vault class hash",
+			config: &Config{
+				DatabaseURL:      "postgres://localhost:5432/test",
+				RPCURL:           "https://starknet-mainnet.infura.io",
+				UDCAddress:       "0x123",
+				VaultClassHashes: []string{"vault"},
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid pinned vault address",
+			config: &Config{
+				DatabaseURL: "postgres://localhost:5432/test",
+				RPCURL:      "https://starknet-mainnet.infura.io",
+				Vaults:      []VaultConfig{{Address: "0xnothex"}},
+			},
+			expectError: true,
+		},
+		{
+			name: "vault pinned twice",
+			config: &Config{
+				DatabaseURL: "postgres://localhost:5432/test",
+				RPCURL:      "https://starknet-mainnet.infura.io",
+				Vaults:      []VaultConfig{{Address: "0xabc"}, {Address: "0x0ABC", Cursor: 5}},
+			},
+			expectError: true,
+		},
+		{
+			name: "negative reorg depth",
+			config: &Config{
+				DatabaseURL: "postgres://localhost:5432/test",
+				RPCURL:      "https://starknet-mainnet.infura.io",
+				ReorgDepth:  -1,
+			},
+			expectError: true,
+		},
+		{
+			name: "metrics port out of range",
+			config: &Config{
+				DatabaseURL: "postgres://localhost:5432/test",
+				RPCURL:      "https://starknet-mainnet.infura.io",
+				MetricsPort: 70000,
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid log level",
+			config: &Config{
+				DatabaseURL: "postgres://localhost:5432/test",
+				RPCURL:      "https://starknet-mainnet.infura.io",
+				LogLevel:    "verbose",
+			},
+			expectError: true,
+		},
+		{
+			name: "unknown event name",
+			config: &Config{
+				DatabaseURL: "postgres://localhost:5432/test",
+				RPCURL:      "https://starknet-mainnet.infura.io",
+				EventNames:  []string{"Deposit", "Transfer"},
+			},
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
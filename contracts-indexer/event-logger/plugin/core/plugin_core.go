@@ -3,16 +3,16 @@ package core
 import (
 	"fmt"
 	"junoplugin/db"
+	"junoplugin/logging"
+	"junoplugin/metrics"
 	"junoplugin/models"
 	"junoplugin/network"
 	"junoplugin/plugin/block"
 	"junoplugin/plugin/config"
+	"junoplugin/plugin/source"
 	"junoplugin/plugin/vault"
-	"log"
-
-	"github.com/NethermindEth/juno/core"
-	"github.com/NethermindEth/juno/core/felt"
-	junoplugin "github.com/NethermindEth/juno/plugin"
+	"junoplugin/utils"
+	"log/slog"
 )
 
 // PluginCore orchestrates all plugin components
@@ -22,10 +22,13 @@ type PluginCore struct {
 	network        *network.Network
 	vaultManager   *vault.Manager
 	blockProcessor *block.Processor
-	log            *log.Logger
+	metricsServer  *metrics.Server
+	log            *slog.Logger
 	synced         bool
 }
 
+var _ source.Handler = (*PluginCore)(nil)
+
 // NewPluginCore creates a new plugin core
 func NewPluginCore() (*PluginCore, error) {
 	// Load configuration
@@ -39,6 +42,16 @@ func NewPluginCore() (*PluginCore, error) {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
+	// Every component logs through the default logger
+	if err := logging.Setup(cfg.LogLevel); err != nil {
+		return nil, err
+	}
+
+	// Events are decoded with the contract ABIs
+	if err := utils.LoadABIs(cfg.VaultABIPath, cfg.OptionRoundABIPath); err != nil {
+		return nil, err
+	}
+
 	// Initialize database
 	dbClient, err := db.Init(cfg.DatabaseURL)
 	if err != nil {
@@ -52,13 +65,23 @@ func NewPluginCore() (*PluginCore, error) {
 	}
 
 	// Initialize network
-	networkClient, err := network.NewNetwork()
+	networkClient, err := network.NewNetwork(cfg.RPCURL, network.Config{
+		Events: network.EventsConfig{
+			ChunkSize:  cfg.EventsChunkSize,
+			MaxRetries: cfg.EventsMaxRetries,
+		},
+		Backfill: network.BackfillConfig{
+			Concurrency: cfg.BackfillConcurrency,
+		},
+		RateLimit: cfg.RPCRateLimit,
+		Timeout:   cfg.RPCTimeout,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize network: %w", err)
 	}
 
 	// Initialize vault manager
-	vaultManager := vault.NewManager(dbClient, networkClient, cfg.UDCAddress)
+	vaultManager := vault.NewManager(dbClient, networkClient, cfg.UDCAddress, cfg.VaultClassHashes, cfg.EventNames)
 
 	// Initialize block processor
 	blockProcessor := block.NewProcessor(
@@ -76,18 +99,37 @@ func NewPluginCore() (*PluginCore, error) {
 		network:        networkClient,
 		vaultManager:   vaultManager,
 		blockProcessor: blockProcessor,
-		log:            log.Default(),
+		log:            slog.Default(),
 	}, nil
 }
 
-// Initialize initializes the plugin
+// Initialize initializes the plugin, registering the vaults pinned by the
+// configuration and serving the metrics when a port is configured
 func (pc *PluginCore) Initialize() error {
+	if pc.config.MetricsPort != 0 {
+		pc.metricsServer = metrics.NewServer(fmt.Sprintf(":%d", pc.config.MetricsPort))
+		if err := pc.metricsServer.Start(); err != nil {
+			return err
+		}
+	}
+	for _, pinned := range pc.config.Vaults {
+		cursor := pinned.Cursor
+		if cursor == 0 {
+			cursor = pc.config.Cursor
+		}
+		if err := pc.vaultManager.PinVault(pinned.Address, cursor); err != nil {
+			return fmt.Errorf("failed to pin vault %s: %w", pinned.Address, err)
+		}
+	}
 	return nil
 }
 
 // Shutdown shuts down the plugin
 func (pc *PluginCore) Shutdown() error {
-	pc.log.Println("Shutting down plugin core")
+	pc.log.Info("Shutting down plugin core")
+	if pc.metricsServer != nil {
+		pc.metricsServer.Stop()
+	}
 	pc.db.Shutdown()
 	return nil
 }
@@ -98,12 +140,33 @@ func (pc *PluginCore) CheckAndSync(block *models.StarknetBlocks) error {
 		return nil
 	}
 
-	log.Printf("Syncing vaults")
+	// Fill the blocks missed while the plugin was down before the vaults
+	// catch up on top of them
+	lastBlock := pc.blockProcessor.GetLastBlock()
+	if err := pc.blockProcessor.CatchupBlocks(block.BlockNumber); err != nil {
+		return fmt.Errorf("failed to backfill blocks: %w", err)
+	}
+
+	// Register the vaults deployed in the missed blocks so that they are
+	// caught up with the others
+	if lastBlock != nil && block.BlockNumber > 0 {
+		if err := pc.vaultManager.DiscoverVaults(lastBlock.BlockNumber+1, block.BlockNumber-1); err != nil {
+			return fmt.Errorf("failed to discover vaults: %w", err)
+		}
+	}
+
+	pc.log.Info("Syncing vaults", logging.Block(block.BlockNumber))
 	if err := pc.vaultManager.LoadVaultsFromRegistry(block); err != nil {
 		return fmt.Errorf("failed to initialize vaults: %w", err)
 	}
 
-	pc.log.Println("Plugin core initialized successfully")
+	// Vaults are caught up first so that a repaired block whose events they
+	// already stored is not indexed twice
+	if _, err := pc.VerifyChain(pc.verifyFrom(block.BlockNumber), 0); err != nil {
+		return fmt.Errorf("failed to verify stored blocks: %w", err)
+	}
+
+	pc.log.Info("Plugin core initialized successfully")
 
 	//Only set this to true if no failures
 	pc.synced = true
@@ -111,30 +174,46 @@ func (pc *PluginCore) CheckAndSync(block *models.StarknetBlocks) error {
 }
 
 // NewBlock processes a new block
-func (pc *PluginCore) NewBlock(
-	block *core.Block,
-	stateUpdate *core.StateUpdate,
-	newClasses map[felt.Felt]core.Class,
-) error {
-	starknetBlock := models.CoreToStarknetBlock(*block)
+func (pc *PluginCore) NewBlock(block *models.Block) error {
+	starknetBlock := block.StarknetBlock()
 	if err := pc.CheckAndSync(&starknetBlock); err != nil {
 		return err
 	}
-	return pc.blockProcessor.ProcessNewBlock(block, stateUpdate, newClasses)
+	return pc.blockProcessor.ProcessNewBlock(block)
 }
 
-// RevertBlock reverts a block
-func (pc *PluginCore) RevertBlock(
-	from,
-	to *junoplugin.BlockAndStateUpdate,
-	reverseStateDiff *core.StateDiff,
-) error {
-
-	starknetBlock := models.CoreToStarknetBlock(*from.Block)
+// RevertBlock reverts from, the head, back to its parent to
+func (pc *PluginCore) RevertBlock(from, to *models.Block) error {
+	starknetBlock := from.StarknetBlock()
 	if err := pc.CheckAndSync(&starknetBlock); err != nil {
 		return err
 	}
-	return pc.blockProcessor.RevertBlock(from, to, reverseStateDiff)
+	return pc.blockProcessor.RevertBlock(from, to)
+}
+
+// VerifyChain checks the stored blocks between fromBlock and toBlock and
+// repairs the gaps and forks it finds, see block.Processor.VerifyChain
+func (pc *PluginCore) VerifyChain(fromBlock, toBlock uint64) (*block.VerifyReport, error) {
+	report, err := pc.blockProcessor.VerifyChain(fromBlock, toBlock)
+	if err != nil {
+		return report, err
+	}
+	for _, issue := range report.Issues {
+		pc.log.Warn("Chain issue", logging.Block(issue.BlockNumber), "kind", issue.Kind, logging.BlockHash(issue.BlockHash))
+	}
+	if len(report.Repaired) > 0 {
+		pc.log.Info("Repaired blocks", "repaired", report.Repaired, "reverted", report.Reverted)
+	}
+	return report, nil
+}
+
+// verifyFrom is the first block verified at startup, VerifyDepth blocks below
+// the head or every stored block when the depth is 0
+func (pc *PluginCore) verifyFrom(head uint64) uint64 {
+	if pc.config.VerifyDepth == 0 || head < pc.config.VerifyDepth {
+		return 0
+	}
+	return head - pc.config.VerifyDepth
 }
 
 // GetVaultManager returns the vault manager
@@ -142,6 +221,16 @@ func (pc *PluginCore) GetVaultManager() *vault.Manager {
 	return pc.vaultManager
 }
 
+// GetNetwork returns the RPC client
+func (pc *PluginCore) GetNetwork() *network.Network {
+	return pc.network
+}
+
+// GetConfig returns the configuration the core was created with
+func (pc *PluginCore) GetConfig() *config.Config {
+	return pc.config
+}
+
 // GetDB returns the database instance
 func (pc *PluginCore) GetDB() *db.DB {
 	return pc.db
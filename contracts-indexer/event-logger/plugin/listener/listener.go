@@ -4,107 +4,378 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"junoplugin/logging"
 	"junoplugin/models"
 	"junoplugin/plugin/vault"
-	"log"
-	"os"
+	"junoplugin/utils"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 )
 
-// Service handles listening for new vault registrations
+// Bounds of the delays between two connection attempts and between two
+// initializations of a failing vault, doubled on each failure
+const (
+	minBackoff = time.Second
+	maxBackoff = 5 * time.Minute
+)
+
+// Initializer initializes the vaults the listener is notified of, catches
+// them up to the blocks already processed and keeps their error state in the
+// registry. Paused vaults are untracked until they are resumed.
+type Initializer interface {
+	InitializeVault(vault *models.VaultRegistry) error
+	CatchupVaultToHead(address string) error
+	TrackVaults(vaults []*models.VaultRegistry)
+	UntrackVaults(addresses []string)
+	UninitializedVaults() ([]*models.VaultRegistry, error)
+	RecordVaultError(address string, cause error) error
+	ClearVaultError(address string) error
+}
+
+var _ Initializer = (*vault.Manager)(nil)
+
+// Notification channels of the vault registry triggers
+const (
+	insertChannel = "vault_insert"
+	updateChannel = "vault_update"
+)
+
+// Service handles listening for new vault registrations and for vaults paused
+// or resumed. It reconnects when
+// the connection drops and retries the vaults that fail to initialize, without
+// holding back the others.
 type Service struct {
-	conn         *pgx.Conn
-	vaultManager *vault.Manager
-	channel      chan models.VaultRegistry
-	log          *log.Logger
-	ctx          context.Context
-	cancel       context.CancelFunc
+	dbURL       string
+	initializer Initializer
+	log         *slog.Logger
+	ctx         context.Context
+	cancel      context.CancelFunc
+	done        chan struct{}
 }
 
-// NewService creates a new listener service
-func NewListenerService(vaultManager *vault.Manager) *Service {
+// NewListenerService creates a new listener service on the database at dbURL
+func NewListenerService(initializer Initializer, dbURL string) *Service {
 	ctx, cancel := context.WithCancel(context.Background())
-	dbUrl := os.Getenv("DB_URL")
-	conn, err := pgx.Connect(ctx, dbUrl)
-	if err != nil {
-		fmt.Errorf("unable to connect to database: %w", err)
-	}
 	return &Service{
-		vaultManager: vaultManager,
-		channel:      make(chan models.VaultRegistry),
-		log:          log.Default(),
-		ctx:          ctx,
-		cancel:       cancel,
-		conn:         conn,
+		dbURL:       dbURL,
+		initializer: initializer,
+		log:         slog.Default(),
+		ctx:         ctx,
+		cancel:      cancel,
 	}
 }
 
-// Start starts the listener service
+// Start starts the listener service in the background, it connects to the
+// database until Stop is called
 func (ls *Service) Start() error {
-	ls.log.Println("Starting vault registry listener")
-	if ls.conn == nil {
-		return nil
-	}
-	// Start listening for new vaults in a goroutine
-	go ls.listen()
-
+	ls.log.Info("Starting vault registry listener")
+	ls.done = make(chan struct{})
+	go ls.run()
 	return nil
 }
 
-// listen listens for new vault registrations
-func (ls *Service) listen() {
-	ls.log.Println("Starting to listen for vault notifications...")
+// Stop stops the listener service and waits for it to return
+func (ls *Service) Stop() {
+	ls.log.Info("Stopping vault registry listener")
+	ls.cancel()
+	if ls.done != nil {
+		<-ls.done
+	}
+}
 
-	// Start the database listener in a goroutine
-	go ls.ListenerNewVault(ls.channel)
+// run keeps a listening session open, reconnecting with backoff
+func (ls *Service) run() {
+	defer close(ls.done)
 
-	var err error
+	retries := newRetryQueue()
+	failures := 0
 	for {
+		listened, err := ls.session(retries)
+		if ls.ctx.Err() != nil {
+			ls.log.Info("Vault registry listener stopped")
+			return
+		}
+		if listened {
+			failures = 0
+		}
+		failures++
+		delay := backoff(failures)
+		ls.log.Warn("Vault registry listener disconnected, reconnecting", "delay", delay, logging.Err(err))
 		select {
 		case <-ls.ctx.Done():
-			ls.log.Println("Listener context cancelled, shutting down")
+			ls.log.Info("Vault registry listener stopped")
 			return
-		case vault := <-ls.channel:
-			ls.log.Printf("Received new vault registration: %s", vault.Address)
-			if err = ls.vaultManager.InitializeVault(&vault); err != nil {
-				ls.log.Printf("Error initializing vault %s: %v", vault.Address, err)
-				return
-			} else {
-				ls.log.Printf("Successfully initialized vault: %s", vault.Address)
-			}
+		case <-time.After(delay):
 		}
 	}
 }
 
-// Stop stops the listener service
-func (ls *Service) Stop() {
-	ls.log.Println("Stopping vault registry listener")
-	ls.cancel() // This will signal the context to cancel
-	close(ls.channel)
-}
+// session listens for vault notifications on a new connection until it fails
+// or the listener stops. It reports whether it got to listen. The vaults
+// registered while no session was listening are queued once it listens.
+func (ls *Service) session(retries *retryQueue) (bool, error) {
+	conn, err := pgx.Connect(ls.ctx, ls.dbURL)
+	if err != nil {
+		return false, fmt.Errorf("unable to connect to database: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	for _, channel := range []string{insertChannel, updateChannel} {
+		if _, err := conn.Exec(ls.ctx, "LISTEN "+channel); err != nil {
+			return false, fmt.Errorf("failed to start listening on %s: %w", channel, err)
+		}
+	}
+	ls.log.Info("Listening for vault notifications")
 
-func (ls *Service) ListenerNewVault(channel chan<- models.VaultRegistry) {
-	_, err := ls.conn.Exec(context.Background(), "LISTEN vault_insert")
+	pending, err := ls.initializer.UninitializedVaults()
 	if err != nil {
-		log.Printf("Failed to start listening: %v", err)
-		return
+		return true, fmt.Errorf("failed to get uninitialized vaults: %w", err)
+	}
+	now := time.Now()
+	for _, vault := range pending {
+		retries.add(*vault, now)
+	}
+
+	// The receiver owns the connection until it returns, which it does before
+	// the connection is closed
+	ctx, cancel := context.WithCancel(ls.ctx)
+	notifications := make(chan registryChange)
+	errs := make(chan error, 1)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ls.receive(ctx, conn, notifications, errs)
+	}()
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	for {
+		ls.initializeDue(retries)
+
+		var wake <-chan time.Time
+		if next, ok := retries.next(); ok {
+			wake = time.After(time.Until(next))
+		}
+		select {
+		case <-ls.ctx.Done():
+			return true, ls.ctx.Err()
+		case err := <-errs:
+			return true, err
+		case change := <-notifications:
+			ls.apply(change, retries)
+		case <-wake:
+		}
 	}
+}
+
+// apply queues the vaults to initialize or resume and untracks paused ones
+func (ls *Service) apply(change registryChange, retries *retryQueue) {
+	vault := change.vault
+	switch {
+	case change.channel == insertChannel:
+		ls.log.Info("Received new vault registration", logging.Vault(vault.Address))
+		if vault.LastBlockIndexed != nil || vault.Paused {
+			// Registered by vault discovery, already indexed from its deploy
+			// block, or registered paused
+			return
+		}
+		retries.add(vault, time.Now())
+	case vault.Paused:
+		ls.log.Info("Vault paused", logging.Vault(vault.Address))
+		retries.remove(vault.Address)
+		ls.initializer.UntrackVaults([]string{vault.Address})
+	default:
+		ls.log.Info("Vault resumed", logging.Vault(vault.Address))
+		retries.add(vault, time.Now())
+	}
+}
 
+// receive forwards the vault notifications of conn until it fails or ctx is
+// done
+func (ls *Service) receive(ctx context.Context, conn *pgx.Conn, notifications chan<- registryChange, errs chan<- error) {
 	for {
-		notification, err := ls.conn.WaitForNotification(context.Background())
+		notification, err := conn.WaitForNotification(ctx)
 		if err != nil {
-			log.Printf("Error waiting for notification: %v", err)
+			errs <- fmt.Errorf("error waiting for notification: %w", err)
+			return
+		}
+		vault, err := decodeNotification(notification.Payload)
+		if err != nil {
+			ls.log.Error("Error decoding vault notification", logging.Err(err))
 			continue
 		}
+		select {
+		case notifications <- registryChange{channel: notification.Channel, vault: vault}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
 
-		var vault models.VaultRegistry
-		if err := json.Unmarshal([]byte(notification.Payload), &vault); err != nil {
-			log.Printf("Error unmarshaling vault data: %v", err)
+// initializeDue initializes the queued vaults whose attempt is due. A failure
+// is recorded in the registry and the vault retried later with backoff.
+func (ls *Service) initializeDue(retries *retryQueue) {
+	for _, vault := range retries.due(time.Now()) {
+		if ls.ctx.Err() != nil {
+			return
+		}
+		if err := ls.initialize(&vault); err != nil {
+			delay := retries.fail(vault.Address, time.Now())
+			ls.log.Error("Error initializing vault, retrying", logging.Vault(vault.Address), "delay", delay, logging.Err(err))
+			if err := ls.initializer.RecordVaultError(vault.Address, err); err != nil {
+				ls.log.Error("Error recording the failure of vault", logging.Vault(vault.Address), logging.Err(err))
+			}
 			continue
 		}
+		retries.remove(vault.Address)
+		ls.log.Info("Successfully initialized vault", logging.Vault(vault.Address))
+		if err := ls.initializer.ClearVaultError(vault.Address); err != nil {
+			ls.log.Error("Error clearing the failures of vault", logging.Vault(vault.Address), logging.Err(err))
+		}
+	}
+}
+
+// initialize initializes a new vault, or tracks a resumed one, and catches it
+// up, turning a panic into an error so that one vault cannot bring the
+// listener down. Both steps are idempotent, a failed catchup retries the
+// whole.
+func (ls *Service) initialize(vault *models.VaultRegistry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	if vault.LastBlockIndexed == nil {
+		if err := ls.initializer.InitializeVault(vault); err != nil {
+			return err
+		}
+	} else {
+		ls.initializer.TrackVaults([]*models.VaultRegistry{vault})
+	}
+	return ls.initializer.CatchupVaultToHead(vault.Address)
+}
+
+// registryChange is a vault notified on channel
+type registryChange struct {
+	channel string
+	vault   models.VaultRegistry
+}
+
+// registryNotification is the vault_registry row sent by the vault_insert and
+// vault_update triggers
+type registryNotification struct {
+	ID                 uint    `json:"id"`
+	Address            string  `json:"vault_address"`
+	DeployedAt         string  `json:"deployed_at"`
+	LastBlockIndexed   *string `json:"last_block_indexed"`
+	LastBlockProcessed *string `json:"last_block_processed"`
+	Paused             bool    `json:"paused"`
+}
+
+// decodeNotification decodes the payload of a vault registry notification
+func decodeNotification(payload string) (models.VaultRegistry, error) {
+	var row registryNotification
+	if err := json.Unmarshal([]byte(payload), &row); err != nil {
+		return models.VaultRegistry{}, err
+	}
+	if row.Address == "" {
+		return models.VaultRegistry{}, fmt.Errorf("notification without vault address: %s", payload)
+	}
+	return models.VaultRegistry{
+		ID:                 row.ID,
+		Address:            row.Address,
+		DeployedAt:         row.DeployedAt,
+		LastBlockIndexed:   row.LastBlockIndexed,
+		LastBlockProcessed: row.LastBlockProcessed,
+		Paused:             row.Paused,
+	}, nil
+}
+
+// backoff returns the delay after the given number of consecutive failures
+func backoff(failures int) time.Duration {
+	delay := minBackoff
+	for i := 1; i < failures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
 
-		log.Printf("Received vault notification: %s", vault.Address)
-		channel <- vault
+// retryQueue holds the vaults waiting for their initialization with the time
+// of their next attempt
+type retryQueue struct {
+	entries map[string]*retryEntry
+}
+
+type retryEntry struct {
+	vault    models.VaultRegistry
+	failures int
+	at       time.Time
+}
+
+func newRetryQueue() *retryQueue {
+	return &retryQueue{entries: make(map[string]*retryEntry)}
+}
+
+// key identifies a vault whatever the form of its address
+func key(address string) string {
+	if normalized, err := utils.NormalizeHexAddress(address); err == nil {
+		return normalized
+	}
+	return address
+}
+
+// add queues a vault for an attempt at now, a queued vault keeps its schedule
+func (q *retryQueue) add(vault models.VaultRegistry, now time.Time) {
+	if _, ok := q.entries[key(vault.Address)]; ok {
+		return
+	}
+	q.entries[key(vault.Address)] = &retryEntry{vault: vault, at: now}
+}
+
+// due returns the vaults whose attempt is due at now, by address
+func (q *retryQueue) due(now time.Time) []models.VaultRegistry {
+	var vaults []models.VaultRegistry
+	for _, entry := range q.entries {
+		if !entry.at.After(now) {
+			vaults = append(vaults, entry.vault)
+		}
+	}
+	sort.Slice(vaults, func(i, j int) bool { return vaults[i].Address < vaults[j].Address })
+	return vaults
+}
+
+// fail schedules the next attempt of a vault after a failure and returns its
+// delay
+func (q *retryQueue) fail(address string, now time.Time) time.Duration {
+	entry, ok := q.entries[key(address)]
+	if !ok {
+		return 0
+	}
+	entry.failures++
+	delay := backoff(entry.failures)
+	entry.at = now.Add(delay)
+	return delay
+}
+
+// remove drops an initialized vault from the queue
+func (q *retryQueue) remove(address string) {
+	delete(q.entries, key(address))
+}
+
+// next returns the time of the earliest attempt, false when the queue is empty
+func (q *retryQueue) next() (time.Time, bool) {
+	var next time.Time
+	for _, entry := range q.entries {
+		if next.IsZero() || entry.at.Before(next) {
+			next = entry.at
+		}
 	}
+	return next, !next.IsZero()
 }
@@ -0,0 +1,219 @@
+package listener
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"junoplugin/models"
+)
+
+// fakeInitializer fails the vaults of failing and records the error state and
+// the tracked vaults
+type fakeInitializer struct {
+	failing     map[string]bool
+	initialized []string
+	tracked     map[string]bool
+	errors      map[string]int
+}
+
+func newFakeInitializer(failing ...string) *fakeInitializer {
+	f := &fakeInitializer{failing: make(map[string]bool), tracked: make(map[string]bool), errors: make(map[string]int)}
+	for _, address := range failing {
+		f.failing[address] = true
+	}
+	return f
+}
+
+func (f *fakeInitializer) InitializeVault(vault *models.VaultRegistry) error {
+	if f.failing[vault.Address] {
+		return errors.New("rpc unavailable")
+	}
+	f.initialized = append(f.initialized, vault.Address)
+	f.tracked[vault.Address] = true
+	return nil
+}
+
+func (f *fakeInitializer) TrackVaults(vaults []*models.VaultRegistry) {
+	for _, vault := range vaults {
+		f.tracked[vault.Address] = true
+	}
+}
+
+func (f *fakeInitializer) UntrackVaults(addresses []string) {
+	for _, address := range addresses {
+		delete(f.tracked, address)
+	}
+}
+
+func (f *fakeInitializer) CatchupVaultToHead(address string) error {
+	return nil
+}
+
+func (f *fakeInitializer) UninitializedVaults() ([]*models.VaultRegistry, error) {
+	return nil, nil
+}
+
+func (f *fakeInitializer) RecordVaultError(address string, cause error) error {
+	f.errors[address]++
+	return nil
+}
+
+func (f *fakeInitializer) ClearVaultError(address string) error {
+	delete(f.errors, address)
+	return nil
+}
+
+func TestDecodeNotification(t *testing.T) {
+	payload := `{"id": 3, "vault_address": "0x7a417", "deployed_at": "0x3e9", "last_block_indexed": null, "last_block_processed": null, "last_event_nonce": 0}`
+	vault, err := decodeNotification(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vault.ID != 3 || vault.Address != "0x7a417" || vault.DeployedAt != "0x3e9" || vault.LastBlockIndexed != nil || vault.Paused {
+		t.Errorf("decoded %+v", vault)
+	}
+
+	vault, err = decodeNotification(`{"id": 3, "vault_address": "0x7a417", "deployed_at": "0x3e9", "last_block_indexed": "0x3ea", "paused": true}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !vault.Paused || vault.LastBlockIndexed == nil {
+		t.Errorf("decoded %+v, want a paused vault indexed up to 0x3ea", vault)
+	}
+
+	if _, err := decodeNotification(`{"address": "0x7a417"}`); err == nil {
+		t.Errorf("Expected error for a notification without vault_address")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{100, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.failures); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestRetryQueue(t *testing.T) {
+	now := time.Now()
+	q := newRetryQueue()
+	q.add(models.VaultRegistry{Address: "0x2"}, now)
+	q.add(models.VaultRegistry{Address: "0x1"}, now)
+	// The same vault notified again keeps its entry
+	q.add(models.VaultRegistry{Address: "0x02"}, now)
+
+	if due := q.due(now); len(due) != 2 || due[0].Address != "0x1" || due[1].Address != "0x2" {
+		t.Fatalf("due = %+v, want 0x1 and 0x2", due)
+	}
+
+	if delay := q.fail("0x2", now); delay != time.Second {
+		t.Errorf("first retry delay = %s, want 1s", delay)
+	}
+	if delay := q.fail("0x2", now); delay != 2*time.Second {
+		t.Errorf("second retry delay = %s, want 2s", delay)
+	}
+	q.remove("0x1")
+
+	if due := q.due(now); len(due) != 0 {
+		t.Errorf("due = %+v, want none before the retry", due)
+	}
+	if next, ok := q.next(); !ok || !next.Equal(now.Add(2*time.Second)) {
+		t.Errorf("next = %s %v, want %s", next, ok, now.Add(2*time.Second))
+	}
+	if due := q.due(now.Add(2 * time.Second)); len(due) != 1 || due[0].Address != "0x2" {
+		t.Errorf("due = %+v, want 0x2", due)
+	}
+}
+
+func TestInitializeDueIsolatesFailures(t *testing.T) {
+	initializer := newFakeInitializer("0x1")
+	ls := NewListenerService(initializer, "")
+	q := newRetryQueue()
+	q.add(models.VaultRegistry{Address: "0x1"}, time.Now())
+	q.add(models.VaultRegistry{Address: "0x2"}, time.Now())
+
+	ls.initializeDue(q)
+
+	if len(initializer.initialized) != 1 || initializer.initialized[0] != "0x2" {
+		t.Errorf("initialized %v, want 0x2 despite the failure of 0x1", initializer.initialized)
+	}
+	if initializer.errors["0x1"] != 1 {
+		t.Errorf("recorded %d errors for 0x1, want 1", initializer.errors["0x1"])
+	}
+	if _, ok := q.next(); !ok {
+		t.Fatalf("0x1 is not queued for a retry")
+	}
+
+	// The retry succeeds once the cause is gone
+	delete(initializer.failing, "0x1")
+	q.entries["0x1"].at = time.Now()
+	ls.initializeDue(q)
+	if _, ok := q.next(); ok {
+		t.Errorf("queue not empty after the retry succeeded")
+	}
+	if _, ok := initializer.errors["0x1"]; ok {
+		t.Errorf("error state of 0x1 not cleared")
+	}
+}
+
+// panickingInitializer panics on every vault
+type panickingInitializer struct{ fakeInitializer }
+
+func (p *panickingInitializer) InitializeVault(vault *models.VaultRegistry) error {
+	panic("nil block")
+}
+
+func TestInitializeRecoversPanics(t *testing.T) {
+	initializer := &panickingInitializer{*newFakeInitializer()}
+	ls := NewListenerService(initializer, "")
+	q := newRetryQueue()
+	q.add(models.VaultRegistry{Address: "0x1"}, time.Now())
+
+	ls.initializeDue(q)
+
+	if initializer.errors["0x1"] != 1 {
+		t.Errorf("recorded %d errors for 0x1, want 1", initializer.errors["0x1"])
+	}
+}
+
+func TestApplyPauseAndResume(t *testing.T) {
+	initializer := newFakeInitializer()
+	ls := NewListenerService(initializer, "")
+	q := newRetryQueue()
+	cursor := "0x3ea"
+	vault := models.VaultRegistry{Address: "0x1", DeployedAt: "0x3e9", LastBlockIndexed: &cursor}
+	initializer.tracked["0x1"] = true
+
+	// Discovered vaults are already indexed
+	ls.apply(registryChange{channel: insertChannel, vault: vault}, q)
+	if _, ok := q.next(); ok {
+		t.Errorf("queued a vault registered with a cursor")
+	}
+
+	paused := vault
+	paused.Paused = true
+	ls.apply(registryChange{channel: updateChannel, vault: paused}, q)
+	if initializer.tracked["0x1"] {
+		t.Errorf("paused vault still tracked")
+	}
+
+	// A resumed vault is tracked again without being initialized
+	ls.apply(registryChange{channel: updateChannel, vault: vault}, q)
+	ls.initializeDue(q)
+	if !initializer.tracked["0x1"] {
+		t.Errorf("resumed vault not tracked")
+	}
+	if len(initializer.initialized) != 0 {
+		t.Errorf("initialized %v, want none for a resumed vault", initializer.initialized)
+	}
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
+	"junoplugin/logging"
 	pluginCore "junoplugin/plugin/core"
 	"junoplugin/plugin/listener"
-	"log"
+	"junoplugin/plugin/source/junosource"
+	"log/slog"
 
 	"github.com/NethermindEth/juno/core"
 	"github.com/NethermindEth/juno/core/felt"
@@ -16,7 +19,9 @@ import (
 type pitchlakePlugin struct {
 	core     *pluginCore.PluginCore
 	listener *listener.Service
-	log      *log.Logger
+	source   *junosource.Source
+	stop     context.CancelFunc
+	log      *slog.Logger
 }
 
 // Important: "JunoPluginInstance" needs to be exported for Juno to load the plugin correctly
@@ -27,15 +32,15 @@ var _ junoplugin.JunoPlugin = (*pitchlakePlugin)(nil)
 
 // Init initializes the plugin
 func (p *pitchlakePlugin) Init() error {
-	p.log = log.Default()
-	p.log.Println("Initializing Pitchlake Plugin")
+	slog.Info("Initializing Pitchlake Plugin")
 
-	// Initialize the plugin core
+	// Initialize the plugin core, which sets up the logger
 	pluginCoreInstance, err := pluginCore.NewPluginCore()
 	if err != nil {
 		return err
 	}
 	p.core = pluginCoreInstance
+	p.log = slog.Default()
 
 	// Initialize the plugin
 	if err := p.core.Initialize(); err != nil {
@@ -43,18 +48,32 @@ func (p *pitchlakePlugin) Init() error {
 	}
 
 	// Start the vault registry listener
-	p.listener = listener.NewListenerService(p.core.GetVaultManager())
+	p.listener = listener.NewListenerService(p.core.GetVaultManager(), p.core.GetConfig().DatabaseURL)
 	if err := p.listener.Start(); err != nil {
 		return err
 	}
 
-	p.log.Println("Pitchlake Plugin initialized successfully")
+	// Hand the blocks pushed by Juno to the core
+	ctx, stop := context.WithCancel(context.Background())
+	p.source = junosource.New()
+	p.stop = stop
+	go func() {
+		if err := p.source.Run(ctx, p.core); err != nil {
+			p.log.Error("Block source stopped", logging.Err(err))
+		}
+	}()
+
+	p.log.Info("Pitchlake Plugin initialized successfully")
 	return nil
 }
 
 // Shutdown shuts down the plugin
 func (p *pitchlakePlugin) Shutdown() error {
-	p.log.Println("Shutting down Pitchlake Plugin")
+	slog.Info("Shutting down Pitchlake Plugin")
+
+	if p.stop != nil {
+		p.stop()
+	}
 
 	if p.listener != nil {
 		p.listener.Stop()
@@ -73,7 +92,7 @@ func (p *pitchlakePlugin) NewBlock(
 	stateUpdate *core.StateUpdate,
 	newClasses map[felt.Felt]core.Class,
 ) error {
-	return p.core.NewBlock(block, stateUpdate, newClasses)
+	return p.source.NewBlock(block)
 }
 
 // RevertBlock reverts a block
@@ -82,5 +101,5 @@ func (p *pitchlakePlugin) RevertBlock(
 	to *junoplugin.BlockAndStateUpdate,
 	reverseStateDiff *core.StateDiff,
 ) error {
-	return p.core.RevertBlock(from, to, reverseStateDiff)
+	return p.source.RevertBlock(from, to)
 }
@@ -0,0 +1,89 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"junoplugin/models"
+	"os"
+)
+
+// Step is an entry of a fixture: a new block, or the revert of the last
+// block onto its parent
+type Step struct {
+	Block  *models.Block `json:"block,omitempty"`
+	Revert *Revert       `json:"revert,omitempty"`
+}
+
+// Revert is the revert of From onto its parent To
+type Revert struct {
+	From *models.Block `json:"from"`
+	To   *models.Block `json:"to"`
+}
+
+// Fixture is a BlockSource replaying recorded steps, so that the processing
+// can be tested without a node. A fixture file is a JSON array of steps.
+type Fixture struct {
+	Steps []Step
+}
+
+var _ BlockSource = (*Fixture)(nil)
+
+// LoadFixture reads a fixture file
+func LoadFixture(path string) (*Fixture, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixture Fixture
+	if err := json.Unmarshal(raw, &fixture); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+	return &fixture, nil
+}
+
+// MarshalJSON encodes the fixture as its array of steps
+func (f *Fixture) MarshalJSON() ([]byte, error) {
+	steps := f.Steps
+	if steps == nil {
+		steps = []Step{}
+	}
+	return json.Marshal(steps)
+}
+
+// UnmarshalJSON decodes an array of steps, each either a block or a revert
+func (f *Fixture) UnmarshalJSON(raw []byte) error {
+	var steps []Step
+	if err := json.Unmarshal(raw, &steps); err != nil {
+		return err
+	}
+	for i, step := range steps {
+		if (step.Block == nil) == (step.Revert == nil) {
+			return fmt.Errorf("step %d must have either a block or a revert", i)
+		}
+		if step.Revert != nil && (step.Revert.From == nil || step.Revert.To == nil) {
+			return fmt.Errorf("revert of step %d needs from and to", i)
+		}
+	}
+	f.Steps = steps
+	return nil
+}
+
+// Run hands every step to the handler in order and stops at the first error
+func (f *Fixture) Run(ctx context.Context, handler Handler) error {
+	for i, step := range f.Steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var err error
+		if step.Block != nil {
+			err = handler.NewBlock(step.Block)
+		} else {
+			err = handler.RevertBlock(step.Revert.From, step.Revert.To)
+		}
+		if err != nil {
+			return fmt.Errorf("step %d: %w", i, err)
+		}
+	}
+	return nil
+}
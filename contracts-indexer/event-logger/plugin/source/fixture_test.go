@@ -0,0 +1,85 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"junoplugin/models"
+)
+
+func writeFixture(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// failingHandler fails every block
+type failingHandler struct {
+	err error
+}
+
+func (h *failingHandler) NewBlock(block *models.Block) error {
+	return h.err
+}
+
+func (h *failingHandler) RevertBlock(from, to *models.Block) error {
+	return h.err
+}
+
+func TestFixtureRunsSteps(t *testing.T) {
+	path := writeFixture(t, `[
+		{"block": {"block_number": 1, "block_hash": "0x3e9", "parent_hash": "0x0"}},
+		{"block": {"block_number": 2, "block_hash": "0x3ea", "parent_hash": "0x3e9"}},
+		{"revert": {"from": {"block_number": 2, "block_hash": "0x3ea"}, "to": {"block_number": 1, "block_hash": "0x3e9"}}}
+	]`)
+	fixture, err := LoadFixture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &recorder{}
+	if err := fixture.Run(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	want := "new:0x3e9,new:0x3ea,revert:0x3ea->0x3e9"
+	if got := strings.Join(r.calls, ","); got != want {
+		t.Errorf("calls = %s, want %s", got, want)
+	}
+}
+
+func TestFixtureStopsAtFirstError(t *testing.T) {
+	path := writeFixture(t, `[
+		{"block": {"block_number": 1, "block_hash": "0x3e9"}},
+		{"block": {"block_number": 2, "block_hash": "0x3ea"}}
+	]`)
+	fixture, err := LoadFixture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed := errors.New("failed")
+	err = fixture.Run(context.Background(), &failingHandler{err: failed})
+	if !errors.Is(err, failed) || !strings.HasPrefix(err.Error(), "step 0") {
+		t.Errorf("Run() = %v, want the error of step 0", err)
+	}
+}
+
+func TestLoadFixtureRejectsInvalidSteps(t *testing.T) {
+	for name, content := range map[string]string{
+		"empty step":        `[{}]`,
+		"block and revert":  `[{"block": {"block_number": 1}, "revert": {"from": {}, "to": {}}}]`,
+		"revert without to": `[{"revert": {"from": {"block_number": 1}}}]`,
+		"not an array":      `{}`,
+	} {
+		if _, err := LoadFixture(writeFixture(t, content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Package junosource is the BlockSource of the Juno plugin. It is the only
+// package to depend on Juno core types and is kept apart from the source
+// package so that the standalone indexer does not link Juno.
+package junosource
+
+import (
+	"context"
+	"errors"
+	"junoplugin/models"
+	"junoplugin/plugin/source"
+
+	"github.com/NethermindEth/juno/core"
+	junoplugin "github.com/NethermindEth/juno/plugin"
+)
+
+// ErrNotRunning is returned for a block pushed while Run is not running
+var ErrNotRunning = errors.New("juno block source is not running")
+
+// update is a block pushed by Juno, answered with the error of its handler.
+// A revert reverts block onto parent.
+type update struct {
+	block  *models.Block
+	revert bool
+	parent *models.Block
+	done   chan error
+}
+
+// Source converts the blocks Juno pushes to the plugin and hands them to the
+// handler given to Run. NewBlock and RevertBlock wait for the handler, so that
+// Juno gets its error back.
+type Source struct {
+	updates chan update
+	stopped chan struct{}
+}
+
+var _ source.BlockSource = (*Source)(nil)
+
+// New creates a source, Run must be started before Juno pushes blocks
+func New() *Source {
+	return &Source{
+		updates: make(chan update),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Run hands the pushed blocks to handler until ctx is done
+func (s *Source) Run(ctx context.Context, handler source.Handler) error {
+	defer close(s.stopped)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case u := <-s.updates:
+			if u.revert {
+				u.done <- handler.RevertBlock(u.block, u.parent)
+			} else {
+				u.done <- handler.NewBlock(u.block)
+			}
+		}
+	}
+}
+
+// NewBlock hands a block delivered by Juno to the handler
+func (s *Source) NewBlock(block *core.Block) error {
+	return s.push(update{block: CoreToBlock(block)})
+}
+
+// RevertBlock hands a block reverted by Juno to the handler, with a nil
+// parent when Juno reverts its first block
+func (s *Source) RevertBlock(from, to *junoplugin.BlockAndStateUpdate) error {
+	var parent *models.Block
+	if to != nil && to.Block != nil {
+		parent = CoreToBlock(to.Block)
+	}
+	return s.push(update{block: CoreToBlock(from.Block), revert: true, parent: parent})
+}
+
+func (s *Source) push(u update) error {
+	u.done = make(chan error, 1)
+	select {
+	case s.updates <- u:
+		return <-u.done
+	case <-s.stopped:
+		return ErrNotRunning
+	}
+}
+
+// CoreToBlock converts a Juno block to the block model
+func CoreToBlock(block *core.Block) *models.Block {
+	receipts := make([]*models.Receipt, 0, len(block.Receipts))
+	for _, receipt := range block.Receipts {
+		events := make([]*models.BlockEvent, 0, len(receipt.Events))
+		for i, event := range receipt.Events {
+			events = append(events, &models.BlockEvent{From: event.From, Keys: event.Keys, Data: event.Data, Index: uint64(i)})
+		}
+		receipts = append(receipts, &models.Receipt{TransactionHash: receipt.TransactionHash, Events: events})
+	}
+	return &models.Block{
+		Number:     block.Number,
+		Hash:       block.Hash,
+		ParentHash: block.ParentHash,
+		Timestamp:  block.Timestamp,
+		Receipts:   receipts,
+	}
+}
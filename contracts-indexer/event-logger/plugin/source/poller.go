@@ -0,0 +1,152 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"junoplugin/logging"
+	"junoplugin/models"
+	"log/slog"
+	"time"
+
+	"github.com/NethermindEth/juno/core/felt"
+)
+
+// DefaultPollInterval is the time between two polls of the head
+const DefaultPollInterval = 2 * time.Second
+
+// DefaultReorgDepth is the number of delivered blocks remembered to detect
+// and revert a reorg
+const DefaultReorgDepth = 128
+
+// Chain is the RPC node followed by the poller, implemented by
+// *network.Network
+type Chain interface {
+	GetHeadNumber() (uint64, error)
+	GetBlockWithEvents(number uint64) (*models.Block, error)
+}
+
+// Poller is the BlockSource of the standalone indexer. It polls the head of a
+// Chain and delivers its blocks one at a time, in order. A block whose parent
+// is not the last delivered block means the chain was reorged: delivered
+// blocks are reverted, newest first, until the new branch connects.
+type Poller struct {
+	chain      Chain
+	interval   time.Duration
+	reorgDepth int
+	// delivered holds the headers of the last delivered blocks, oldest first
+	delivered []*models.Block
+	log       *slog.Logger
+}
+
+var _ BlockSource = (*Poller)(nil)
+
+// NewPoller creates a poller that checks the head of chain every interval and
+// can revert reorgs up to reorgDepth blocks deep. Empty values take their
+// defaults.
+func NewPoller(chain Chain, interval time.Duration, reorgDepth int) *Poller {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	if reorgDepth <= 0 {
+		reorgDepth = DefaultReorgDepth
+	}
+	return &Poller{
+		chain:      chain,
+		interval:   interval,
+		reorgDepth: reorgDepth,
+		log:        slog.Default(),
+	}
+}
+
+// Run polls until ctx is done. The first block delivered is the head at
+// startup, the handler catches up on what it missed before it. A failed poll
+// is logged and retried at the next tick from the block that failed.
+func (p *Poller) Run(ctx context.Context, handler Handler) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		if err := p.Poll(handler); err != nil {
+			p.log.Error("Error polling blocks", logging.Err(err))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll delivers every block up to the current head
+func (p *Poller) Poll(handler Handler) error {
+	head, err := p.chain.GetHeadNumber()
+	if err != nil {
+		return fmt.Errorf("failed to get head: %w", err)
+	}
+
+	next := head
+	if last := p.last(); last != nil {
+		next = last.Number + 1
+	}
+	for next <= head {
+		block, err := p.chain.GetBlockWithEvents(next)
+		if err != nil {
+			return err
+		}
+
+		last := p.last()
+		if last != nil && !block.ParentHash.Equal(last.Hash) {
+			p.log.Warn("Block does not extend the last delivered block, reverting it",
+				logging.Block(block.Number), logging.BlockHash(block.Hash.String()), "last", last.Hash.String())
+			if err := p.revertLast(handler); err != nil {
+				return err
+			}
+			// Retry the height of the reverted block on the new branch
+			next = last.Number
+			continue
+		}
+
+		if err := handler.NewBlock(block); err != nil {
+			return fmt.Errorf("failed to process block %d: %w", block.Number, err)
+		}
+		p.delivered = append(p.delivered, header(block))
+		if len(p.delivered) > p.reorgDepth {
+			p.delivered = p.delivered[1:]
+		}
+		next++
+	}
+	return nil
+}
+
+// last returns the header of the last delivered block, nil before the first
+func (p *Poller) last() *models.Block {
+	if len(p.delivered) == 0 {
+		return nil
+	}
+	return p.delivered[len(p.delivered)-1]
+}
+
+// revertLast reverts the last delivered block onto its parent. The parent of
+// the oldest remembered block is only known by its hash, which is all the
+// handler reads from it.
+func (p *Poller) revertLast(handler Handler) error {
+	from := p.delivered[len(p.delivered)-1]
+	to := &models.Block{Number: from.Number - 1, Hash: from.ParentHash, ParentHash: new(felt.Felt)}
+	if len(p.delivered) > 1 {
+		to = p.delivered[len(p.delivered)-2]
+	}
+	if err := handler.RevertBlock(from, to); err != nil {
+		return fmt.Errorf("failed to revert block %d: %w", from.Number, err)
+	}
+	p.delivered = p.delivered[:len(p.delivered)-1]
+	return nil
+}
+
+// header returns a copy of a block without its receipts
+func header(block *models.Block) *models.Block {
+	return &models.Block{
+		Number:     block.Number,
+		Hash:       block.Hash,
+		ParentHash: block.ParentHash,
+		Timestamp:  block.Timestamp,
+	}
+}
@@ -0,0 +1,180 @@
+package source
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"junoplugin/models"
+
+	"github.com/NethermindEth/juno/core/felt"
+)
+
+// fakeChain serves the blocks of its canonical branch up to head. Blocks of
+// branch b at height n have the hash b*1000+n.
+type fakeChain struct {
+	blocks map[uint64]*models.Block
+	head   uint64
+	fail   map[uint64]bool
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{blocks: make(map[uint64]*models.Block), fail: make(map[uint64]bool)}
+}
+
+// extend sets the blocks from..to of branch on top of the block at from-1
+func (c *fakeChain) extend(branch, from, to uint64) {
+	parent := new(felt.Felt)
+	if b, ok := c.blocks[from-1]; ok {
+		parent = b.Hash
+	}
+	for n := from; n <= to; n++ {
+		hash := new(felt.Felt).SetUint64(branch*1000 + n)
+		c.blocks[n] = &models.Block{Number: n, Hash: hash, ParentHash: parent}
+		parent = hash
+	}
+	c.head = to
+}
+
+func (c *fakeChain) GetHeadNumber() (uint64, error) {
+	return c.head, nil
+}
+
+func (c *fakeChain) GetBlockWithEvents(number uint64) (*models.Block, error) {
+	if c.fail[number] {
+		return nil, errors.New("rpc unavailable")
+	}
+	b, ok := c.blocks[number]
+	if !ok || number > c.head {
+		return nil, fmt.Errorf("no block %d", number)
+	}
+	return b, nil
+}
+
+// recorder records the calls of a source as "new:<hash>" and
+// "revert:<hash>-><hash>"
+type recorder struct {
+	calls []string
+}
+
+func (r *recorder) NewBlock(block *models.Block) error {
+	r.calls = append(r.calls, "new:"+block.Hash.String())
+	return nil
+}
+
+func (r *recorder) RevertBlock(from, to *models.Block) error {
+	r.calls = append(r.calls, "revert:"+from.Hash.String()+"->"+to.Hash.String())
+	return nil
+}
+
+func newTestPoller(chain *fakeChain) (*Poller, *recorder) {
+	r := &recorder{}
+	p := NewPoller(chain, 0, 0)
+	p.log = slog.New(slog.DiscardHandler)
+	return p, r
+}
+
+func hash(branch, n uint64) string {
+	return new(felt.Felt).SetUint64(branch*1000 + n).String()
+}
+
+func TestPollFollowsHead(t *testing.T) {
+	chain := newFakeChain()
+	chain.extend(1, 1, 5)
+	p, r := newTestPoller(chain)
+
+	// Starts at the head, the handler catches up on the blocks below it
+	if err := p.Poll(r); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	chain.extend(1, 6, 8)
+	if err := p.Poll(r); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+
+	want := []string{"new:" + hash(1, 5), "new:" + hash(1, 6), "new:" + hash(1, 7), "new:" + hash(1, 8)}
+	if fmt.Sprint(r.calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %v, want %v", r.calls, want)
+	}
+}
+
+func TestPollRevertsReorg(t *testing.T) {
+	chain := newFakeChain()
+	chain.extend(1, 1, 5)
+	p, r := newTestPoller(chain)
+	if err := p.Poll(r); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	chain.extend(1, 6, 7)
+	if err := p.Poll(r); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	r.calls = nil
+
+	// Blocks 6 and 7 are replaced by a longer branch
+	chain.extend(2, 6, 8)
+	if err := p.Poll(r); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+
+	want := []string{
+		"revert:" + hash(1, 7) + "->" + hash(1, 6),
+		"revert:" + hash(1, 6) + "->" + hash(1, 5),
+		"new:" + hash(2, 6),
+		"new:" + hash(2, 7),
+		"new:" + hash(2, 8),
+	}
+	if fmt.Sprint(r.calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %v, want %v", r.calls, want)
+	}
+}
+
+func TestPollRevertsFirstBlock(t *testing.T) {
+	chain := newFakeChain()
+	chain.extend(1, 1, 5)
+	p, r := newTestPoller(chain)
+	if err := p.Poll(r); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	r.calls = nil
+
+	// The head the poller started from is reorged out
+	chain.extend(2, 5, 6)
+	if err := p.Poll(r); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+
+	want := []string{
+		"revert:" + hash(1, 5) + "->" + hash(1, 4),
+		"new:" + hash(2, 5),
+		"new:" + hash(2, 6),
+	}
+	if fmt.Sprint(r.calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %v, want %v", r.calls, want)
+	}
+}
+
+func TestPollResumesAfterError(t *testing.T) {
+	chain := newFakeChain()
+	chain.extend(1, 1, 3)
+	p, r := newTestPoller(chain)
+	if err := p.Poll(r); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+
+	chain.extend(1, 4, 6)
+	chain.fail[5] = true
+	if err := p.Poll(r); err == nil {
+		t.Fatal("expected the unavailable block to fail the poll")
+	}
+	delete(chain.fail, 5)
+	if err := p.Poll(r); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+
+	want := []string{"new:" + hash(1, 3), "new:" + hash(1, 4), "new:" + hash(1, 5), "new:" + hash(1, 6)}
+	if fmt.Sprint(r.calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %v, want %v", r.calls, want)
+	}
+}
@@ -0,0 +1,26 @@
+// Package source delivers blocks to the indexer. A BlockSource reads a chain,
+// from Juno, an RPC node or a fixture file, and hands its blocks to a Handler
+// in order, along with the reverts of the blocks a reorg removed, so that the
+// processing does not depend on where the blocks come from.
+package source
+
+import (
+	"context"
+	"junoplugin/models"
+)
+
+// Handler processes the blocks of a source, implemented by *core.PluginCore
+type Handler interface {
+	// NewBlock processes the next block of the chain
+	NewBlock(block *models.Block) error
+	// RevertBlock reverts from, the last block handed to NewBlock, onto its
+	// parent to. Only the headers of both blocks are read, to is nil when the
+	// parent is not known.
+	RevertBlock(from, to *models.Block) error
+}
+
+// BlockSource hands the blocks of a chain to a handler until ctx is done or
+// the source is exhausted
+type BlockSource interface {
+	Run(ctx context.Context, handler Handler) error
+}
@@ -0,0 +1,83 @@
+package vault
+
+import (
+	"junoplugin/models"
+	"sync"
+	"sync/atomic"
+)
+
+// registry is a snapshot of the tracked vaults and option rounds. A published
+// snapshot is never modified: the block processing reads it without locking
+// while the listener and the catchups publish modified copies.
+type registry struct {
+	vaults map[string]*models.VaultRegistry
+	rounds map[string]*models.OptionRound
+}
+
+func newRegistry() *registry {
+	return &registry{
+		vaults: make(map[string]*models.VaultRegistry),
+		rounds: make(map[string]*models.OptionRound),
+	}
+}
+
+// clone copies the maps of the snapshot, the entries are shared as they are
+// replaced instead of modified
+func (r *registry) clone() *registry {
+	next := &registry{
+		vaults: make(map[string]*models.VaultRegistry, len(r.vaults)),
+		rounds: make(map[string]*models.OptionRound, len(r.rounds)),
+	}
+	for address, vault := range r.vaults {
+		next.vaults[address] = vault
+	}
+	for address, round := range r.rounds {
+		next.rounds[address] = round
+	}
+	return next
+}
+
+// trackVault tracks a copy of vault, so that the caller keeps its own
+func (r *registry) trackVault(vault *models.VaultRegistry) {
+	tracked := *vault
+	r.vaults[vault.Address] = &tracked
+}
+
+// setCursor replaces a tracked vault by a copy indexed up to blockHash
+func (r *registry) setCursor(address string, blockHash string) bool {
+	vault, ok := r.vaults[address]
+	if !ok {
+		return false
+	}
+	moved := *vault
+	moved.LastBlockIndexed = &blockHash
+	r.vaults[address] = &moved
+	return true
+}
+
+// trackedRegistry publishes the registry snapshots of a Manager. Writers are
+// serialized so that none of them loses the changes of another.
+type trackedRegistry struct {
+	mu      sync.Mutex
+	current atomic.Pointer[registry]
+}
+
+func newTrackedRegistry() *trackedRegistry {
+	t := &trackedRegistry{}
+	t.current.Store(newRegistry())
+	return t
+}
+
+// load returns the current snapshot, which must not be modified
+func (t *trackedRegistry) load() *registry {
+	return t.current.Load()
+}
+
+// update applies change to a copy of the current snapshot and publishes it
+func (t *trackedRegistry) update(change func(r *registry)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	next := t.current.Load().clone()
+	change(next)
+	t.current.Store(next)
+}
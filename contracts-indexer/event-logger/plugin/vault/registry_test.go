@@ -0,0 +1,73 @@
+package vault
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"junoplugin/models"
+)
+
+func TestRegistrySnapshotsAreNotModified(t *testing.T) {
+	tracked := newTrackedRegistry()
+	deployedAt := "0x1"
+	vault := &models.VaultRegistry{Address: "0xa", DeployedAt: deployedAt, LastBlockIndexed: &deployedAt}
+	tracked.update(func(r *registry) { r.trackVault(vault) })
+
+	before := tracked.load()
+	tracked.update(func(r *registry) {
+		r.setCursor("0xa", "0x2")
+		r.rounds["0xb"] = &models.OptionRound{Address: "0xb", VaultAddress: "0xa"}
+	})
+
+	if got := *before.vaults["0xa"].LastBlockIndexed; got != "0x1" {
+		t.Errorf("snapshot cursor = %s, want 0x1", got)
+	}
+	if _, ok := before.rounds["0xb"]; ok {
+		t.Errorf("round added to an older snapshot")
+	}
+	if got := *tracked.load().vaults["0xa"].LastBlockIndexed; got != "0x2" {
+		t.Errorf("cursor = %s, want 0x2", got)
+	}
+	// The caller keeps its own vault
+	if *vault.LastBlockIndexed != "0x1" {
+		t.Errorf("tracked vault modified in place")
+	}
+}
+
+func TestManagerRegistryConcurrentAccess(t *testing.T) {
+	vm := NewManager(nil, nil, "", nil, nil)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				address := fmt.Sprintf("0x%x", w*1000+i)
+				vm.TrackVaults([]*models.VaultRegistry{{Address: address, DeployedAt: "0x1"}})
+				vm.RewindVaults([]string{address}, "0x0")
+			}
+		}(w)
+	}
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				vm.IsVaultAddress("0x1")
+				vm.IsRoundAddress("0x1")
+				vm.GetVaultAddresses()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// No writer lost the vaults of another
+	if got := len(vm.GetVaultAddresses()); got != 400 {
+		t.Errorf("tracking %d vaults, want 400", got)
+	}
+	if !vm.IsVaultAddress("0x3e8") {
+		t.Errorf("vault 0x3e8 not tracked")
+	}
+}
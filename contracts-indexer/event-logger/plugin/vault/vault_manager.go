@@ -3,93 +3,165 @@ package vault
 import (
 	"fmt"
 	"junoplugin/db"
+	"junoplugin/logging"
+	"junoplugin/metrics"
 	"junoplugin/models"
-	"junoplugin/network"
 	"junoplugin/utils"
-	"log"
+	"log/slog"
+	"sort"
 
-	"github.com/NethermindEth/juno/core"
 	"github.com/NethermindEth/juno/core/felt"
 	"github.com/NethermindEth/starknet.go/rpc"
 )
 
+// Store is the storage used by the manager, implemented by *db.DB. Writes go
+// through the db.Tx of InTx.
+type Store interface {
+	InTx(fn func(tx db.Tx) error) error
+	GetVaultRegistry() ([]*models.VaultRegistry, error)
+	GetUninitializedVaults() ([]*models.VaultRegistry, error)
+	GetOptionRounds() ([]*models.OptionRound, error)
+	GetVaultCatchupProgress(address string) (*models.VaultCatchupProgress, error)
+	GetBlock(hash string) (*models.StarknetBlocks, error)
+	GetLastBlock() (*models.StarknetBlocks, error)
+	RecordVaultError(address string, cause error) error
+	ClearVaultError(address string) error
+}
+
+// Network fetches chain data over RPC, implemented by *network.Network
+type Network interface {
+	GetBlockByHash(hash string) (*rpc.BlockTxHashes, error)
+	GetBlocks(fromBlock uint64, toBlock uint64) ([]*models.StarknetBlocks, error)
+	GetEvents(fromBlock rpc.BlockID, toBlock rpc.BlockID, address *string, keys [][]*felt.Felt) (*rpc.EventChunk, error)
+	LocateEvents(events []rpc.EmittedEvent) ([]models.LocatedEvent, error)
+}
+
 // Manager handles vault-related operations
 type Manager struct {
-	db               *db.DB
-	network          *network.Network
-	vaultRegistryMap map[string]*models.VaultRegistry
+	db               Store
+	network          Network
+	registry         *trackedRegistry
 	udcAddress       string
-	log              *log.Logger
+	vaultClassHashes map[string]struct{}
+	eventNames       map[string]struct{}
+	log              *slog.Logger
 }
 
-// NewManager creates a new vault manager
-func NewManager(db *db.DB, network *network.Network, udcAddress string) *Manager {
+// NewManager creates a new vault manager. Vaults deployed through the UDC
+// with one of vaultClassHashes are registered automatically, an empty list
+// only tracks the vaults inserted into the registry. Only the vault and round
+// events in eventNames are stored, all of them when it is empty.
+func NewManager(db Store, network Network, udcAddress string, vaultClassHashes []string, eventNames []string) *Manager {
+	if normalized, err := utils.NormalizeHexAddress(udcAddress); err == nil {
+		udcAddress = normalized
+	}
+	classHashes := make(map[string]struct{}, len(vaultClassHashes))
+	for _, classHash := range vaultClassHashes {
+		classHashes[classHash] = struct{}{}
+	}
+	names := make(map[string]struct{}, len(eventNames))
+	for _, name := range eventNames {
+		names[name] = struct{}{}
+	}
 	return &Manager{
 		db:               db,
 		network:          network,
-		vaultRegistryMap: make(map[string]*models.VaultRegistry),
+		registry:         newTrackedRegistry(),
 		udcAddress:       udcAddress,
-		log:              log.Default(),
+		vaultClassHashes: classHashes,
+		eventNames:       names,
+		log:              slog.Default(),
 	}
 }
 
-// InitializeVaults initializes existing vaults from the database
-func (vm *Manager) LoadVaultsFromRegistry(latestBlock *models.StarknetBlocks) error {
+// TrackRegistry tracks the initialized vaults of the registry that are not
+// paused, and their option rounds, without initializing or catching up any
+func (vm *Manager) TrackRegistry() error {
 	vaultRegistry, err := vm.db.GetVaultRegistry()
 	if err != nil {
 		return fmt.Errorf("failed to get vault registry: %w", err)
 	}
-
-	// Catchup vaults while loading in mem to avoid reiterating later with SyncVaults call
-	if len(vaultRegistry) > 0 {
+	rounds, err := vm.db.GetOptionRounds()
+	if err != nil {
+		return fmt.Errorf("failed to get option rounds: %w", err)
+	}
+	vm.registry.update(func(r *registry) {
 		for _, vault := range vaultRegistry {
-			if vault.LastBlockIndexed == nil {
-				vm.InitializeVault(vault)
+			if !vault.Paused && vault.LastBlockIndexed != nil {
+				r.trackVault(vault)
 			}
+		}
+		for _, round := range rounds {
+			r.rounds[round.Address] = round
+		}
+	})
+	return nil
+}
 
-			//Do this before the lastBlock escape
-			vm.vaultRegistryMap[vault.Address] = vault
+// InitializeVaults initializes existing vaults from the database
+func (vm *Manager) LoadVaultsFromRegistry(latestBlock *models.StarknetBlocks) error {
+	vaultRegistry, err := vm.db.GetVaultRegistry()
+	if err != nil {
+		return fmt.Errorf("failed to get vault registry: %w", err)
+	}
 
-			//Escape if we don't have the latest block, we shouldn't need this if used only after initialization
-			if latestBlock == nil {
-				return nil
-			}
+	// Rounds are needed before the catchup to index their events as well
+	rounds, err := vm.db.GetOptionRounds()
+	if err != nil {
+		return fmt.Errorf("failed to get option rounds: %w", err)
+	}
+	vm.registry.update(func(r *registry) {
+		for _, round := range rounds {
+			r.rounds[round.Address] = round
+		}
+	})
 
-			lastBlockIndexed, err := vm.db.GetBlock(*vault.LastBlockIndexed)
-			if err != nil {
-				return err
+	// Catchup vaults while loading in mem to avoid reiterating later with SyncVaults call
+	for _, vault := range vaultRegistry {
+		if vault.Paused {
+			vm.log.Info("Vault is paused", logging.Vault(vault.Address))
+			continue
+		}
+		if vault.LastBlockIndexed == nil {
+			// Tracks the vault once initialized
+			if err := vm.InitializeVault(vault); err != nil {
+				return fmt.Errorf("failed to initialize vault %s: %w", vault.Address, err)
 			}
+		} else {
+			vm.TrackVaults([]*models.VaultRegistry{vault})
+		}
 
-			if lastBlockIndexed == nil || lastBlockIndexed.BlockNumber < latestBlock.BlockNumber-1 {
-				if err := vm.CatchupVault(*vault, latestBlock.BlockNumber); err != nil {
-					return fmt.Errorf("failed to catchup vault %s: %w", vault.Address, err)
-				}
+		// Without the latest block the vaults are only tracked, we shouldn't
+		// need this if used only after initialization
+		if latestBlock == nil {
+			continue
+		}
 
-			}
+		if err := vm.CatchupVault(*vault, latestBlock.BlockNumber); err != nil {
+			return fmt.Errorf("failed to catchup vault %s: %w", vault.Address, err)
 		}
 	}
 
-	vm.log.Printf("Vault addresses: %v", vm.vaultRegistryMap)
-	vm.log.Printf("Last block: %v", latestBlock)
+	vm.log.Info("Loaded vaults from registry", "vaults", len(vm.GetVaultAddresses()))
 
 	return nil
 }
 
 func (vm *Manager) SyncVaults(head *models.StarknetBlocks) error {
-	vaultRegistry := &vm.vaultRegistryMap
-	// if err != nil {
-	// 	return fmt.Errorf("failed to get vault registry: %w", err)
-	// }
-	for _, vault := range *vaultRegistry {
+	for _, tracked := range vm.registry.load().vaults {
+		// The tracked entry is shared, the initialization updates a copy
+		vault := *tracked
 		if vault.LastBlockIndexed == nil {
-			vm.InitializeVault(vault)
+			if err := vm.InitializeVault(&vault); err != nil {
+				return fmt.Errorf("failed to initialize vault %s: %w", vault.Address, err)
+			}
 		}
 		if head == nil {
-			log.Printf("No last block found, starting node to find current block")
+			vm.log.Info("No last block found, starting node to find current block")
 			return nil
 		}
 		if *vault.LastBlockIndexed != head.BlockHash {
-			if err := vm.CatchupVault(*vault, head.BlockNumber); err != nil {
+			if err := vm.CatchupVault(vault, head.BlockNumber); err != nil {
 				return fmt.Errorf("failed to catchup vault %s: %w", vault.Address, err)
 			}
 		}
@@ -98,11 +170,88 @@ func (vm *Manager) SyncVaults(head *models.StarknetBlocks) error {
 	return nil
 }
 
-// InitializeVault initializes a new vault
+// CatchupVaultToHead catches a tracked vault up to the last stored block, for
+// a vault initialized while blocks are processed
+func (vm *Manager) CatchupVaultToHead(address string) error {
+	vault, ok := vm.registry.load().vaults[address]
+	if !ok {
+		return fmt.Errorf("vault %s is not tracked", address)
+	}
+	head, err := vm.db.GetLastBlock()
+	if err != nil {
+		return fmt.Errorf("failed to get last block: %w", err)
+	}
+	if head == nil {
+		return nil
+	}
+	return vm.CatchupVault(*vault, head.BlockNumber)
+}
+
+// UninitializedVaults returns the registered vaults whose deployment was never
+// indexed, such as the ones registered while the listener was down
+func (vm *Manager) UninitializedVaults() ([]*models.VaultRegistry, error) {
+	return vm.db.GetUninitializedVaults()
+}
+
+// RecordVaultError records a failed initialization of a vault in the registry
+func (vm *Manager) RecordVaultError(address string, cause error) error {
+	return vm.db.RecordVaultError(address, cause)
+}
+
+// ClearVaultError clears the error state of an initialized vault
+func (vm *Manager) ClearVaultError(address string) error {
+	return vm.db.ClearVaultError(address)
+}
+
+// PinVault registers a vault configured by address, indexed from fromBlock.
+// A vault already in the registry keeps its cursor.
+func (vm *Manager) PinVault(address string, fromBlock uint64) error {
+	address, err := utils.NormalizeHexAddress(address)
+	if err != nil {
+		return err
+	}
+	block, err := vm.blockAt(fromBlock)
+	if err != nil {
+		return fmt.Errorf("failed to get block %d: %w", fromBlock, err)
+	}
+	// The cursor is the parent so that the events of fromBlock are indexed,
+	// genesis has none
+	lastBlockIndexed := block.ParentHash
+	if fromBlock == 0 {
+		lastBlockIndexed = block.BlockHash
+	}
+	vault := &models.VaultRegistry{
+		Address:          address,
+		DeployedAt:       block.BlockHash,
+		LastBlockIndexed: &lastBlockIndexed,
+	}
+
+	if err := vm.db.InTx(func(tx db.Tx) error {
+		return tx.InsertVault(vault)
+	}); err != nil {
+		return err
+	}
+	vm.log.Info("Pinned vault", logging.Vault(address), logging.Block(fromBlock))
+	return nil
+}
+
+// storesEvent reports whether events named name are stored. The option
+// rounds are loaded from their OptionRoundDeployed events, which are always
+// stored. Filtering leaves the event_index of the stored events unchanged, it
+// is their position in the receipt.
+func (vm *Manager) storesEvent(name string) bool {
+	if len(vm.eventNames) == 0 || name == "OptionRoundDeployed" {
+		return true
+	}
+	_, ok := vm.eventNames[name]
+	return ok
+}
+
+// InitializeVault indexes the deploy block of a new vault and tracks it
 func (vm *Manager) InitializeVault(vault *models.VaultRegistry) error {
 	deployBlockHash, err := utils.HexStringToFelt(vault.DeployedAt)
 	if err != nil {
-		vm.log.Println("Error getting felt", err)
+		vm.log.Error("Invalid deploy block hash", logging.Vault(vault.Address), logging.BlockHash(vault.DeployedAt), logging.Err(err))
 		return err
 	}
 
@@ -112,229 +261,600 @@ func (vm *Manager) InitializeVault(vault *models.VaultRegistry) error {
 	deployBlock := rpc.BlockID{
 		Hash: &hash,
 	}
-	vm.log.Printf("Deploy block: %v", deployBlock)
+	vm.log.Info("Initializing vault", logging.Vault(vault.Address), logging.BlockHash(vault.DeployedAt))
 
-	events, err := vm.network.GetEvents(deployBlock, deployBlock, nil)
+	events, err := vm.network.GetEvents(deployBlock, deployBlock, nil, nil)
+	if err != nil {
+		vm.log.Error("Error getting events", logging.Vault(vault.Address), logging.Err(err))
+		return err
+	}
+	vm.log.Debug("Fetched deploy block events", logging.Vault(vault.Address), "events", len(events.Events))
+	located, err := vm.network.LocateEvents(events.Events)
 	if err != nil {
-		vm.log.Println("Error getting events", err)
+		vm.log.Error("Error locating events", logging.Vault(vault.Address), logging.Err(err))
 		return err
 	}
-	log.Printf("events list %v", len(events.Events))
 
-	vm.db.BeginTx()
-	err = vm.processDeploymentBlockEvents(events, vault)
+	err = vm.db.InTx(func(tx db.Tx) error {
+		if err := vm.processDeploymentBlockEvents(tx, located, vault); err != nil {
+			return err
+		}
+		if vault.LastBlockIndexed == nil {
+			return fmt.Errorf("vault %s is not deployed in block %s", vault.Address, vault.DeployedAt)
+		}
+		return nil
+	})
 	if err != nil {
-		vm.log.Println("Error processing deployment events", err)
-		vm.db.RollbackTx()
+		vm.log.Error("Error processing deployment events", logging.Vault(vault.Address), logging.Err(err))
 		return err
 	}
 
-	// Save the block as well in db if it doesn't exist
-	// block, err := vm.db.GetBlock(vault.DeployedAt)
-	// if err != nil {
-	// 	return err
-	// }
+	// Live blocks index the vault from now on, the blocks since its deployment
+	// are caught up from its cursor by CatchupVaultToHead
+	vm.TrackVaults([]*models.VaultRegistry{vault})
+	return nil
+}
 
-	// if block == nil {
-	// 	//get block from network
-	// 	networkBlock, err := vm.network.GetBlockByHash(vault.DeployedAt)
+// catchupWindowSize is the number of blocks whose events are fetched and
+// committed at a time by CatchupVault
+const catchupWindowSize = 1000
 
-	// 	if err != nil {
-	// 		return nil
-	// 	}
+// CatchupVault indexes the events of a vault and of its option rounds from
+// the block after its last indexed block up to toBlock. The range is walked in
+// windows of catchupWindowSize blocks, each committed with the vault cursor
+// and its catchup progress, so that an interrupted catchup resumes after the
+// last committed window.
+func (vm *Manager) CatchupVault(vault models.VaultRegistry, toBlock uint64) error {
+	if vault.LastBlockIndexed == nil {
+		return fmt.Errorf("vault %s has no indexed block, it must be initialized first", vault.Address)
+	}
 
-	// 	starknetBlock := models.RPCBlockToStarknetBlock(networkBlock)
+	progress, err := vm.catchupProgress(vault, toBlock)
+	if err != nil {
+		return err
+	}
+	if progress.LastBlockNumber >= toBlock {
+		metrics.CatchupLag.WithLabelValues(vault.Address).Set(0)
+		vm.log.Info("Vault is indexed", logging.Vault(vault.Address), logging.Block(progress.LastBlockNumber))
+		return nil
+	}
 
-	// 	err = vm.db.InsertBlock(starknetBlock)
-	// 	if err != nil {
-	// 		vm.db.RollbackTx()
-	// 		vm.log.Println("Error inserting block", err)
-	// 		return err
-	// 	}
-	// }
-	vm.db.CommitTx()
+	vm.log.Info("Catching up vault", logging.Vault(vault.Address), "from", progress.LastBlockNumber+1, "to", toBlock)
+	for progress.LastBlockNumber < toBlock {
+		metrics.CatchupLag.WithLabelValues(vault.Address).Set(float64(toBlock - progress.LastBlockNumber))
+		fromBlock := progress.LastBlockNumber + 1
+		endBlock := min(fromBlock+catchupWindowSize-1, toBlock)
+		if err := vm.catchupWindow(vault.Address, progress, fromBlock, endBlock); err != nil {
+			return fmt.Errorf("failed to catch up blocks %d to %d: %w", fromBlock, endBlock, err)
+		}
+		vm.registry.update(func(r *registry) {
+			r.setCursor(vault.Address, progress.LastBlockHash)
+		})
+		vm.log.Info("Vault caught up", logging.Vault(vault.Address), logging.Block(progress.LastBlockNumber), "to", toBlock)
+	}
+	metrics.CatchupLag.WithLabelValues(vault.Address).Set(0)
 	return nil
 }
 
-// CatchupVault catches up a vault to a specific block
-func (vm *Manager) CatchupVault(vault models.VaultRegistry, toBlock uint64) error {
+// catchupProgress returns the progress to resume the catchup of a vault from.
+// The stored progress is used while it still matches the cursor of the vault,
+// otherwise the catchup starts over from the block of the cursor.
+func (vm *Manager) catchupProgress(vault models.VaultRegistry, toBlock uint64) (*models.VaultCatchupProgress, error) {
+	cursor := *vault.LastBlockIndexed
+	progress, err := vm.db.GetVaultCatchupProgress(vault.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get catchup progress: %w", err)
+	}
+	if progress != nil && progress.LastBlockHash == cursor {
+		progress.TargetBlock = toBlock
+		return progress, nil
+	}
 
-	var fromBlock *rpc.BlockID
-	vm.log.Printf("Vault registry: %v", vault.LastBlockIndexed)
-	vm.log.Printf("Last block indexed: %v", vault)
-	hash := *vault.LastBlockIndexed
+	var cursorNumber uint64
+	block, err := vm.db.GetBlock(cursor)
+	if err != nil {
+		return nil, err
+	}
+	if block != nil {
+		cursorNumber = block.BlockNumber
+	} else {
+		networkBlock, err := vm.network.GetBlockByHash(cursor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get last indexed block %s: %w", cursor, err)
+		}
+		cursorNumber = networkBlock.BlockHeader.Number
+	}
+	return &models.VaultCatchupProgress{
+		VaultAddress:    vault.Address,
+		StartBlock:      cursorNumber + 1,
+		TargetBlock:     toBlock,
+		LastBlockNumber: cursorNumber,
+		LastBlockHash:   cursor,
+	}, nil
+}
 
-	nextBlock, err := vm.db.GetNextBlock(hash)
+// catchupWindow indexes the events of a vault between two blocks in one
+// transaction, advances the vault cursor to endBlock and records the progress.
+// The event-processor is notified of the window only if it had events, and
+// then every block of the window is stored, so that a revert of any of them
+// reverts the events of the window it holds.
+func (vm *Manager) catchupWindow(vaultAddress string, progress *models.VaultCatchupProgress, fromBlock, endBlock uint64) error {
+	events, err := vm.catchupEvents(vaultAddress, rpc.BlockID{Number: &fromBlock}, endBlock)
 	if err != nil {
 		return err
 	}
-
-	if nextBlock == nil {
-		//get last block from db
-		lastBlock, err := vm.db.GetBlock(hash)
+	var blocks []*models.StarknetBlocks
+	if len(events) > 0 {
+		blocks, err = vm.network.GetBlocks(fromBlock, endBlock)
 		if err != nil {
 			return err
 		}
+		if uint64(len(blocks)) != endBlock-fromBlock+1 {
+			return fmt.Errorf("got %d blocks from %d to %d", len(blocks), fromBlock, endBlock)
+		}
+		for _, event := range events {
+			if hash := blocks[event.BlockNumber-fromBlock].BlockHash; hash != event.BlockHash.String() {
+				return fmt.Errorf("block %d is %s, not the block %s of the events", event.BlockNumber, hash, event.BlockHash)
+			}
+		}
+	} else {
+		last, err := vm.blockAt(endBlock)
+		if err != nil {
+			return err
+		}
+		blocks = []*models.StarknetBlocks{last}
+	}
+	first, last := blocks[0], blocks[len(blocks)-1]
+	normalizedVaultAddress, err := utils.NormalizeHexAddress(vaultAddress)
+	if err != nil {
+		return err
+	}
 
-		if lastBlock == nil {
-			lastBlockNetwork, err := vm.network.GetBlockByHash(hash)
+	next := *progress
+	next.LastBlockNumber = last.BlockNumber
+	next.LastBlockHash = last.BlockHash
+	err = vm.db.InTx(func(tx db.Tx) error {
+		for _, event := range events {
+			blockEvent := event.BlockEvent()
+			fromAddress := event.FromAddress.String()
+			var err error
+			switch {
+			case fromAddress == normalizedVaultAddress:
+				err = vm.ProcessVaultEvent(tx, event.TransactionHash.String(), vaultAddress, blockEvent, event.BlockNumber, *event.BlockHash)
+			case vm.IsRoundAddress(fromAddress):
+				err = vm.ProcessRoundEvent(tx, event.TransactionHash.String(), fromAddress, blockEvent, event.BlockNumber, *event.BlockHash)
+			default:
+				// Emitted by a round before the vault deployed it, skipped
+				// like in a live block
+			}
 			if err != nil {
 				return err
 			}
-			nextBlocks, err := vm.network.GetBlocks(lastBlockNetwork.BlockHeader.Number+1, lastBlockNetwork.BlockHeader.Number+1)
-			if len(nextBlocks) == 0 {
-				vm.log.Printf("No blocks returned for block number: %d", lastBlockNetwork.BlockHeader.Number+1)
-				return fmt.Errorf("no block found at number %d", lastBlockNetwork.BlockHeader.Number+1)
+		}
+
+		// The event-processor reads the window bounds from starknet_blocks
+		if len(events) > 0 {
+			for _, block := range blocks {
+				if err := tx.InsertBlock(block); err != nil {
+					return err
+				}
+			}
+			if err := tx.StoreVaultCatchupEvent(vaultAddress, first.BlockHash, last.BlockHash); err != nil {
+				vm.log.Error("Error storing vault catchup event", logging.Vault(vaultAddress), logging.Err(err))
+				return err
 			}
-			log.Printf("nextBlocks %v", nextBlocks)
-			nextBlock = nextBlocks[0]
-			//create new int
-			fromBlock = &rpc.BlockID{Number: &nextBlock.BlockNumber}
 		}
 
+		if err := tx.UpdateVaultRegistry(vaultAddress, last.BlockHash); err != nil {
+			return err
+		}
+		return tx.SaveVaultCatchupProgress(&next)
+	})
+	if err != nil {
+		return err
 	}
+	*progress = next
+	return nil
+}
 
-	if *fromBlock.Number > toBlock {
-		vm.log.Println("From block is greater than to block, wait to catch up")
-		return nil
+// blockAt returns the header of a block by number
+func (vm *Manager) blockAt(number uint64) (*models.StarknetBlocks, error) {
+	blocks, err := vm.network.GetBlocks(number, number)
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("no block found at number %d", number)
 	}
+	return blocks[0], nil
+}
 
-	events, err := vm.network.GetEvents(*fromBlock, rpc.BlockID{Number: &toBlock}, &vault.Address)
+// catchupEvents returns the events of a vault and of its option rounds, the
+// ones it already had and the ones it deploys, from fromBlock to toBlock, with
+// their position in their block. They are in chain order, by block,
+// transaction and index in the receipt, so that they are stored like the
+// events of a live block.
+func (vm *Manager) catchupEvents(vaultAddress string, fromBlock rpc.BlockID, toBlock uint64) ([]models.LocatedEvent, error) {
+	to := rpc.BlockID{Number: &toBlock}
+	vaultEvents, err := vm.network.GetEvents(fromBlock, to, &vaultAddress, utils.VaultEventKeys())
 	if err != nil {
-		vm.log.Println("Error getting events", err)
-		return err
+		return nil, err
 	}
+	events := vaultEvents.Events
 
-	vm.db.BeginTx()
-	for _, event := range events.Events {
-		coreEvent := core.Event{
-			From: event.FromAddress,
-			Keys: event.Keys,
-			Data: event.Data,
+	normalizedVaultAddress, err := utils.NormalizeHexAddress(vaultAddress)
+	if err != nil {
+		return nil, err
+	}
+	var rounds []string
+	for address, round := range vm.registry.load().rounds {
+		if round.VaultAddress == normalizedVaultAddress {
+			rounds = append(rounds, address)
 		}
-		err := vm.ProcessVaultEvent(event.TransactionHash.String(), vault.Address, &coreEvent, event.BlockNumber, *event.BlockHash)
+	}
+	for _, event := range vaultEvents.Events {
+		roundAddress, ok, err := utils.DecodeOptionRoundDeployed(event.Keys, event.Data)
 		if err != nil {
-			vm.log.Println("Error processing vault event", err)
-			vm.db.RollbackTx()
-			return err
-
+			return nil, fmt.Errorf("invalid event of tx %s: %w", event.TransactionHash, err)
+		}
+		if ok {
+			rounds = append(rounds, roundAddress.String())
 		}
 	}
 
-	//Store block as well, nextBlock should never be null by the time we reach here
-	if err := vm.db.InsertBlock(nextBlock); err != nil {
-		vm.db.RollbackTx()
-		return err
+	for _, roundAddress := range rounds {
+		roundEvents, err := vm.network.GetEvents(fromBlock, to, &roundAddress, utils.RoundEventKeys())
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, roundEvents.Events...)
 	}
-	startBlockHash := hash              // fromBlock hash
-	endBlockHash := nextBlock.BlockHash // toBlock hash
-
-	err = vm.db.StoreVaultCatchupEvent(vault.Address, startBlockHash, endBlockHash)
+	located, err := vm.network.LocateEvents(events)
 	if err != nil {
-		vm.log.Printf("Error storing vault catchup event: %v", err)
-		vm.db.RollbackTx()
-		return err
+		return nil, err
 	}
-	vm.db.CommitTx()
+	sort.Slice(located, func(i, j int) bool {
+		a, b := located[i], located[j]
+		if a.BlockNumber != b.BlockNumber {
+			return a.BlockNumber < b.BlockNumber
+		}
+		if a.TransactionIndex != b.TransactionIndex {
+			return a.TransactionIndex < b.TransactionIndex
+		}
+		return a.Index < b.Index
+	})
+	return located, nil
+}
 
-	// Send vault catchup event after successful catchup
-	vm.log.Printf("Stored and notified vault catchup event for vault %s, blocks %s-%s", vault.Address, startBlockHash, endBlockHash)
-	return nil
+// IsRoundAddress checks if an address is an option round of a tracked vault
+func (vm *Manager) IsRoundAddress(address string) bool {
+	_, exists := vm.registry.load().rounds[address]
+	return exists
+}
+
+// RevertRounds stops tracking the option rounds deployed in reverted blocks
+func (vm *Manager) RevertRounds(blockHashes []string) {
+	vm.registry.update(func(r *registry) {
+		for address, round := range r.rounds {
+			for _, hash := range blockHashes {
+				if round.DeployedAt == hash {
+					delete(r.rounds, address)
+					vm.log.Info("Untracked option round", "round", address, logging.BlockHash(hash))
+					break
+				}
+			}
+		}
+	})
 }
 
 // IsVaultAddress checks if an address is a tracked vault
 func (vm *Manager) IsVaultAddress(address string) bool {
-	_, exists := vm.vaultRegistryMap[address]
+	_, exists := vm.registry.load().vaults[address]
 	return exists
 }
 
+// RewindVaults moves the in-memory cursor of vaults whose indexed blocks were
+// reverted back to the parent of the reorg
+func (vm *Manager) RewindVaults(addresses []string, parentHash string) {
+	vm.registry.update(func(r *registry) {
+		for _, address := range addresses {
+			if r.setCursor(address, parentHash) {
+				vm.log.Info("Rewound vault", logging.Vault(address), logging.BlockHash(parentHash))
+			}
+		}
+	})
+}
+
+// TrackVaults starts tracking vaults registered by DiscoverVault, once the
+// transaction storing them is committed, and the ones initialized by
+// InitializeVault
+func (vm *Manager) TrackVaults(vaults []*models.VaultRegistry) {
+	if len(vaults) == 0 {
+		return
+	}
+	vm.registry.update(func(r *registry) {
+		for _, vault := range vaults {
+			r.trackVault(vault)
+		}
+	})
+}
+
+// UntrackVaults stops tracking vaults removed from the registry
+func (vm *Manager) UntrackVaults(addresses []string) {
+	vm.registry.update(func(r *registry) {
+		for _, address := range addresses {
+			if _, ok := r.vaults[address]; ok {
+				delete(r.vaults, address)
+				metrics.CatchupLag.DeleteLabelValues(address)
+				vm.log.Info("Untracked vault", logging.Vault(address))
+			}
+		}
+	})
+}
+
+// DiscoverVault registers the vault deployed by a UDC ContractDeployed event
+// when its class hash is one of the vault class hashes. The vault and its
+// deploy event are stored in tx, indexed from the deploy
+// block, and returned for the caller to track with TrackVaults once committed.
+// nil is returned for any other event.
+func (vm *Manager) DiscoverVault(tx db.Tx, txHash string, event *models.BlockEvent, blockNumber uint64, blockHash felt.Felt) (*models.VaultRegistry, error) {
+	if len(vm.vaultClassHashes) == 0 || event.From.String() != vm.udcAddress {
+		return nil, nil
+	}
+	address, classHash, ok, err := utils.DecodeContractDeployed(event.Keys, event.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid UDC event of tx %s: %w", txHash, err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	if _, ok := vm.vaultClassHashes[classHash.String()]; !ok {
+		return nil, nil
+	}
+	vaultAddress := address.String()
+	if vm.IsVaultAddress(vaultAddress) {
+		return nil, nil
+	}
+
+	deployedAt := blockHash.String()
+	vault := &models.VaultRegistry{
+		Address:          vaultAddress,
+		DeployedAt:       deployedAt,
+		LastBlockIndexed: &deployedAt,
+	}
+	if err := tx.InsertVault(vault); err != nil {
+		return nil, fmt.Errorf("failed to register vault %s: %w", vaultAddress, err)
+	}
+	eventKeys, eventData := utils.EventToStringArrays(*event)
+	if err := tx.StoreEvent(txHash, vaultAddress, event.From.String(), blockNumber, deployedAt, event.Index, "ContractDeployed", eventKeys, eventData); err != nil {
+		return nil, err
+	}
+	vm.log.Info("Discovered vault", logging.Vault(vaultAddress), "class_hash", classHash.String(), logging.Block(blockNumber))
+	return vault, nil
+}
+
+// DiscoverVaults registers the vaults deployed between fromBlock and toBlock,
+// the blocks missed while the plugin was down, along with the vault events of
+// their deploy block. LoadVaultsFromRegistry catches them up from there.
+func (vm *Manager) DiscoverVaults(fromBlock, toBlock uint64) error {
+	if len(vm.vaultClassHashes) == 0 || fromBlock > toBlock {
+		return nil
+	}
+	registry, err := vm.db.GetVaultRegistry()
+	if err != nil {
+		return fmt.Errorf("failed to get vault registry: %w", err)
+	}
+	registered := make(map[string]struct{}, len(registry))
+	for _, vault := range registry {
+		if address, err := utils.NormalizeHexAddress(vault.Address); err == nil {
+			registered[address] = struct{}{}
+		}
+	}
+
+	selector, err := new(felt.Felt).SetString(utils.Keccak256("ContractDeployed"))
+	if err != nil {
+		return err
+	}
+	events, err := vm.network.GetEvents(
+		rpc.BlockID{Number: &fromBlock},
+		rpc.BlockID{Number: &toBlock},
+		&vm.udcAddress,
+		[][]*felt.Felt{{selector}},
+	)
+	if err != nil {
+		return err
+	}
+
+	// Only the deployments of unregistered vaults are located in their receipt
+	var deployments []rpc.EmittedEvent
+	for _, event := range events.Events {
+		address, classHash, ok, err := utils.DecodeContractDeployed(event.Keys, event.Data)
+		if err != nil {
+			return fmt.Errorf("invalid UDC event of tx %s: %w", event.TransactionHash, err)
+		}
+		if !ok {
+			continue
+		}
+		if _, ok := vm.vaultClassHashes[classHash.String()]; !ok {
+			continue
+		}
+		if _, ok := registered[address.String()]; !ok {
+			deployments = append(deployments, event)
+		}
+	}
+	located, err := vm.network.LocateEvents(deployments)
+	if err != nil {
+		return err
+	}
+
+	var discovered []*models.VaultRegistry
+	err = vm.db.InTx(func(tx db.Tx) error {
+		for _, event := range located {
+			vault, err := vm.DiscoverVault(tx, event.TransactionHash.String(), event.BlockEvent(), event.BlockNumber, *event.BlockHash)
+			if err != nil {
+				return err
+			}
+			if vault == nil {
+				continue
+			}
+
+			// The constructor events are emitted in the deploy block
+			deployBlock := rpc.BlockID{Hash: event.BlockHash}
+			vaultEvents, err := vm.network.GetEvents(deployBlock, deployBlock, &vault.Address, utils.VaultEventKeys())
+			if err != nil {
+				return err
+			}
+			locatedVaultEvents, err := vm.network.LocateEvents(vaultEvents.Events)
+			if err != nil {
+				return err
+			}
+			for _, vaultEvent := range locatedVaultEvents {
+				if err := vm.ProcessVaultEvent(tx, vaultEvent.TransactionHash.String(), vault.Address, vaultEvent.BlockEvent(), vaultEvent.BlockNumber, *vaultEvent.BlockHash); err != nil {
+					return err
+				}
+			}
+			discovered = append(discovered, vault)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	vm.TrackVaults(discovered)
+	return nil
+}
+
 // GetVaultAddresses returns all tracked vault addresses
 func (vm *Manager) GetVaultAddresses() map[string]struct{} {
 
 	//Hacky faster fix, instead update the usage of this function to avoid translating here
 	addresses := make(map[string]struct{})
-	for _, vault := range vm.vaultRegistryMap {
+	for _, vault := range vm.registry.load().vaults {
 		addresses[vault.Address] = struct{}{}
 	}
 	return addresses
 }
 
-// processDeploymentBlockEvents processes events from the deployment block
-func (vm *Manager) processDeploymentBlockEvents(events *rpc.EventChunk, vault *models.VaultRegistry) error {
-	eventNameHash := utils.Keccak256("ContractDeployed")
-	for index, event := range events.Events {
-		vm.log.Printf("index: %v", index)
-		vm.log.Printf("Event from address: %v", event.FromAddress.String())
-		vm.log.Printf("UDC address: %v", vm.udcAddress)
-
-		if eventNameHash == event.Keys[0].String() && event.FromAddress.String() == vm.udcAddress {
-			vm.log.Printf("Match")
-			address := utils.FeltToHexString(event.Data[0].Bytes())
-			vm.log.Printf("Address: %v", address)
-			vm.log.Printf("Vault address: %v", vault.Address)
-
-			normalizedVaultAddress, err := utils.NormalizeHexAddress(vault.Address)
-			if err != nil {
-				vm.log.Printf("Error normalizing address: %v", err)
-				return err
-			}
+// processDeploymentBlockEvents stores the events of the deployment block in tx
+func (vm *Manager) processDeploymentBlockEvents(tx db.Tx, events []models.LocatedEvent, vault *models.VaultRegistry) error {
+	for _, event := range events {
+		if event.FromAddress.String() != vm.udcAddress {
+			continue
+		}
+		deployed, _, ok, err := utils.DecodeContractDeployed(event.Keys, event.Data)
+		if err != nil {
+			return fmt.Errorf("invalid UDC event of tx %s: %w", event.TransactionHash, err)
+		}
+		if !ok {
+			continue
+		}
+		address := deployed.String()
+		vm.log.Debug("UDC deployment in deploy block", logging.Vault(vault.Address), "deployed", address)
+
+		normalizedVaultAddress, err := utils.NormalizeHexAddress(vault.Address)
+		if err != nil {
+			vm.log.Error("Error normalizing address", logging.Vault(vault.Address), logging.Err(err))
+			return err
+		}
 
-			if address == normalizedVaultAddress {
-				txHash := utils.FeltToHexString(event.TransactionHash.Bytes())
-				eventKeys := utils.FeltArrayToStringArrays(event.Keys)
-				eventData := utils.FeltArrayToStringArrays(event.Data)
-				blockHash := utils.FeltToHexString(event.BlockHash.Bytes())
+		if address == normalizedVaultAddress {
+			txHash := utils.FeltToHexString(event.TransactionHash.Bytes())
+			eventKeys := utils.FeltArrayToStringArrays(event.Keys)
+			eventData := utils.FeltArrayToStringArrays(event.Data)
+			blockHash := utils.FeltToHexString(event.BlockHash.Bytes())
 
-				vm.db.StoreEvent(txHash, address, event.BlockNumber, blockHash, "ContractDeployed", eventKeys, eventData)
-				vault.LastBlockIndexed = &blockHash
-				break
+			if err := tx.StoreEvent(txHash, address, event.FromAddress.String(), event.BlockNumber, blockHash, event.Index, "ContractDeployed", eventKeys, eventData); err != nil {
+				return err
 			}
+			vault.LastBlockIndexed = &blockHash
+			break
 		}
 	}
 
 	// Process other vault events in this block
-	for _, event := range events.Events {
-		junoEvent := core.Event{
-			From: event.FromAddress,
-			Keys: event.Keys,
-			Data: event.Data,
-		}
+	for _, event := range events {
+		blockEvent := event.BlockEvent()
 		normalizedVaultAddress, err := utils.NormalizeHexAddress(vault.Address)
 		if err != nil {
-			vm.log.Printf("Error normalizing address %v", err)
+			vm.log.Error("Error normalizing address", logging.Vault(vault.Address), logging.Err(err))
 			return err
 		}
 		if utils.FeltToHexString(event.FromAddress.Bytes()) == normalizedVaultAddress {
-			err := vm.ProcessVaultEvent(event.TransactionHash.String(), vault.Address, &junoEvent, event.BlockNumber, *event.BlockHash)
+			err := vm.ProcessVaultEvent(tx, event.TransactionHash.String(), vault.Address, blockEvent, event.BlockNumber, *event.BlockHash)
 			if err != nil {
 				return err
 			}
-			vm.db.UpdateVaultRegistry(vault.Address, event.BlockHash.String())
+			if err := tx.UpdateVaultRegistry(vault.Address, event.BlockHash.String()); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
-// ProcessVaultEvent processes a vault event
-func (vm *Manager) ProcessVaultEvent(txHash string, vaultAddress string, event *core.Event, blockNumber uint64, blockHash felt.Felt) error {
+// ProcessVaultEvent stores a vault event in tx
+func (vm *Manager) ProcessVaultEvent(tx db.Tx, txHash string, vaultAddress string, event *models.BlockEvent, blockNumber uint64, blockHash felt.Felt) error {
 	// Store the event in the database
 	normalizedVaultAddress, err := utils.NormalizeHexAddress(vaultAddress)
 	if err != nil {
-		vm.log.Printf("Error normalizing address %v", err)
+		vm.log.Error("Error normalizing address", logging.Vault(vaultAddress), logging.Err(err))
 		return err
 	}
 
-	eventName, err := utils.DecodeEventNameVault(event.Keys[0].String())
+	eventName, err := utils.DecodeEventNameVault(event.Keys)
 	if err != nil {
-		vm.log.Printf("Unknown Event")
+		vm.log.Debug("Unknown vault event", logging.Vault(vaultAddress), logging.Block(blockNumber), logging.Err(err))
+		return nil
+	}
+	if !vm.storesEvent(eventName) {
 		return nil
 	}
+	roundAddress, isRoundDeployed, err := utils.DecodeOptionRoundDeployed(event.Keys, event.Data)
+	if err != nil {
+		return fmt.Errorf("invalid event of tx %s: %w", txHash, err)
+	}
 
 	// Store the event in the database
 	eventKeys, eventData := utils.EventToStringArrays(*event)
 	blockHashNormalized := utils.FeltToHexString(blockHash.Bytes())
-	if err := vm.db.StoreEvent(txHash, normalizedVaultAddress, blockNumber, blockHashNormalized, eventName, eventKeys, eventData); err != nil {
+	if err := tx.StoreEvent(txHash, normalizedVaultAddress, normalizedVaultAddress, blockNumber, blockHashNormalized, event.Index, eventName, eventKeys, eventData); err != nil {
 		return err
 	}
+
+	// Track the deployed round right away, its events may follow in the same
+	// block. Should the block be rolled back, Juno delivers it again.
+	if isRoundDeployed {
+		round := &models.OptionRound{
+			Address:      roundAddress.String(),
+			VaultAddress: normalizedVaultAddress,
+			DeployedAt:   blockHashNormalized,
+		}
+		vm.registry.update(func(r *registry) {
+			r.rounds[round.Address] = round
+		})
+		vm.log.Info("Tracking option round", "round", roundAddress.String(), logging.Vault(normalizedVaultAddress), logging.Block(blockNumber))
+	}
 	return nil
 }
+
+// ProcessRoundEvent stores an event emitted by an option round in tx, under the
+// vault that deployed the round
+func (vm *Manager) ProcessRoundEvent(tx db.Tx, txHash string, roundAddress string, event *models.BlockEvent, blockNumber uint64, blockHash felt.Felt) error {
+	round, ok := vm.registry.load().rounds[roundAddress]
+	if !ok {
+		return fmt.Errorf("unknown option round %s", roundAddress)
+	}
+	eventName, err := utils.DecodeEventNameRound(event.Keys)
+	if err != nil {
+		vm.log.Debug("Unknown round event", "round", roundAddress, logging.Vault(round.VaultAddress), logging.Block(blockNumber))
+		return nil
+	}
+	if !vm.storesEvent(eventName) {
+		return nil
+	}
+
+	eventKeys, eventData := utils.EventToStringArrays(*event)
+	blockHashNormalized := utils.FeltToHexString(blockHash.Bytes())
+	return tx.StoreEvent(txHash, round.VaultAddress, roundAddress, blockNumber, blockHashNormalized, event.Index, eventName, eventKeys, eventData)
+}
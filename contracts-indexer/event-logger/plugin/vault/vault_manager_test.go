@@ -0,0 +1,455 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"testing"
+
+	"eventabi"
+	"junoplugin/db"
+	"junoplugin/models"
+	"junoplugin/utils"
+
+	"github.com/NethermindEth/juno/core/felt"
+	"github.com/NethermindEth/starknet.go/rpc"
+)
+
+const (
+	testVault = "0x7a417"
+	testUDC   = "0xdc"
+)
+
+func TestMain(m *testing.M) {
+	// Deployments are decoded with the contract classes of eventabi
+	if err := utils.LoadABIs("../../../eventabi/testdata/pitch_lake_Vault.contract_class.json"); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeStore keeps blocks, events, cursors, catchup progress and catchup
+// driver events in memory. It is its own transaction, the reads and writes
+// the manager does not make are left to the embedded nil Store and db.Tx.
+type fakeStore struct {
+	Store
+	db.Tx
+	blocks   map[uint64]*models.StarknetBlocks
+	events   []string
+	cursors  map[string]string
+	progress map[string]*models.VaultCatchupProgress
+	catchups []string
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		blocks:   make(map[uint64]*models.StarknetBlocks),
+		cursors:  make(map[string]string),
+		progress: make(map[string]*models.VaultCatchupProgress),
+	}
+}
+
+func (s *fakeStore) InTx(fn func(tx db.Tx) error) error {
+	return fn(s)
+}
+
+func (s *fakeStore) GetBlock(hash string) (*models.StarknetBlocks, error) {
+	for _, block := range s.blocks {
+		if block.BlockHash == hash {
+			return block, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *fakeStore) GetVaultCatchupProgress(address string) (*models.VaultCatchupProgress, error) {
+	if progress, ok := s.progress[address]; ok {
+		copied := *progress
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (s *fakeStore) InsertBlock(block *models.StarknetBlocks) error {
+	s.blocks[block.BlockNumber] = block
+	return nil
+}
+
+func (s *fakeStore) StoreEvent(txHash, vaultAddress, fromAddress string, blockNumber uint64, blockHash string, eventIndex uint64, eventName string, eventKeys []string, eventData []string) error {
+	s.events = append(s.events, fmt.Sprintf("%s:%s@%d", txHash, eventName, eventIndex))
+	return nil
+}
+
+func (s *fakeStore) UpdateVaultRegistry(address string, blockHash string) error {
+	s.cursors[address] = blockHash
+	return nil
+}
+
+func (s *fakeStore) SaveVaultCatchupProgress(progress *models.VaultCatchupProgress) error {
+	copied := *progress
+	s.progress[progress.VaultAddress] = &copied
+	return nil
+}
+
+func (s *fakeStore) StoreVaultCatchupEvent(vaultAddress string, startBlockHash, endBlockHash string) error {
+	s.catchups = append(s.catchups, vaultAddress+":"+startBlockHash+"-"+endBlockHash)
+	return nil
+}
+
+// fakeChain serves the blocks up to head and the events emitted in them, at
+// the position they are given
+type fakeChain struct {
+	Network
+	head     uint64
+	events   []models.LocatedEvent
+	requests [][2]uint64
+}
+
+func hashAt(number uint64) *felt.Felt {
+	return new(felt.Felt).SetUint64(0x100000 + number)
+}
+
+func txHashAt(number, tx uint64) *felt.Felt {
+	return new(felt.Felt).SetUint64(number*100 + tx)
+}
+
+// emit adds an event named name to the chain, at index in the receipt of
+// transaction tx of a block
+func (c *fakeChain) emit(number, tx, index uint64, from *felt.Felt, name string, data ...*felt.Felt) {
+	key, _ := new(felt.Felt).SetString(utils.Keccak256(name))
+	c.emitKeys(number, tx, index, from, []*felt.Felt{key}, data)
+}
+
+// emitKeys adds an event with the given keys to the chain
+func (c *fakeChain) emitKeys(number, tx, index uint64, from *felt.Felt, keys, data []*felt.Felt) {
+	c.events = append(c.events, models.LocatedEvent{
+		EmittedEvent: rpc.EmittedEvent{
+			Event: rpc.Event{
+				FromAddress:  from,
+				EventContent: rpc.EventContent{Keys: keys, Data: data},
+			},
+			BlockHash:       hashAt(number),
+			BlockNumber:     number,
+			TransactionHash: txHashAt(number, tx),
+		},
+		TransactionIndex: tx,
+		Index:            index,
+	})
+}
+
+func (c *fakeChain) GetBlocks(fromBlock uint64, toBlock uint64) ([]*models.StarknetBlocks, error) {
+	var blocks []*models.StarknetBlocks
+	for n := fromBlock; n <= toBlock && n <= c.head; n++ {
+		parent := ""
+		if n > 0 {
+			parent = hashAt(n - 1).String()
+		}
+		blocks = append(blocks, &models.StarknetBlocks{BlockNumber: n, BlockHash: hashAt(n).String(), ParentHash: parent})
+	}
+	return blocks, nil
+}
+
+// GetEvents returns the events of address in range, those of a vault are
+// recorded in requests
+func (c *fakeChain) GetEvents(fromBlock rpc.BlockID, toBlock rpc.BlockID, address *string, keys [][]*felt.Felt) (*rpc.EventChunk, error) {
+	number := func(id rpc.BlockID) uint64 {
+		if id.Number != nil {
+			return *id.Number
+		}
+		return id.Hash.Uint64() - 0x100000
+	}
+	from, to := number(fromBlock), number(toBlock)
+	if address != nil && *address == testVault {
+		c.requests = append(c.requests, [2]uint64{from, to})
+	}
+	chunk := &rpc.EventChunk{}
+	for _, event := range c.events {
+		if event.BlockNumber < from || event.BlockNumber > to {
+			continue
+		}
+		if address != nil && event.FromAddress.String() != *address {
+			continue
+		}
+		chunk.Events = append(chunk.Events, event.EmittedEvent)
+	}
+	return chunk, nil
+}
+
+func (c *fakeChain) LocateEvents(events []rpc.EmittedEvent) ([]models.LocatedEvent, error) {
+	key := func(e rpc.EmittedEvent) string {
+		return fmt.Sprint(e.TransactionHash, e.FromAddress, e.Keys, e.Data)
+	}
+	var located []models.LocatedEvent
+	for _, event := range events {
+		found := false
+		for _, candidate := range c.events {
+			if key(candidate.EmittedEvent) == key(event) {
+				located = append(located, candidate)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("event of transaction %s is not on the chain", event.TransactionHash)
+		}
+	}
+	return located, nil
+}
+
+func newTestManager(store Store, chain Network, eventNames []string) *Manager {
+	vm := NewManager(store, chain, "", nil, eventNames)
+	vm.log = slog.New(slog.DiscardHandler)
+	return vm
+}
+
+// roundDeployedData is the data of an OptionRoundDeployed event of the Vault
+// ABI: the round id and address, its dates and its pricing data
+func roundDeployedData(roundID uint64, round *felt.Felt) []*felt.Felt {
+	data := []*felt.Felt{new(felt.Felt).SetUint64(roundID), round}
+	for range 8 {
+		data = append(data, new(felt.Felt))
+	}
+	return data
+}
+
+// contractDeployedData is the data of a UDC ContractDeployed event without
+// constructor calldata
+func contractDeployedData(address *felt.Felt) []*felt.Felt {
+	return []*felt.Felt{address, new(felt.Felt).SetUint64(1), new(felt.Felt), new(felt.Felt).SetUint64(0xc1a55), new(felt.Felt), new(felt.Felt)}
+}
+
+// trackedVault tracks testVault in vm with its cursor at block number
+func trackedVault(vm *Manager, number uint64) models.VaultRegistry {
+	cursor := hashAt(number).String()
+	vault := &models.VaultRegistry{Address: testVault, DeployedAt: hashAt(0).String(), LastBlockIndexed: &cursor}
+	vm.TrackVaults([]*models.VaultRegistry{vault})
+	return *vault
+}
+
+func TestCatchupVaultSpansWindows(t *testing.T) {
+	store := newFakeStore()
+	chain := &fakeChain{head: 3000}
+	vault, _ := new(felt.Felt).SetString(testVault)
+	round := new(felt.Felt).SetUint64(0x40d)
+	// getEvents returns the events of the vault before those of the round,
+	// they are stored in chain order
+	chain.emit(5, 0, 0, vault, "OptionRoundDeployed", roundDeployedData(1, round)...)
+	chain.emit(5, 1, 0, round, "AuctionStarted")
+	chain.emit(5, 1, 1, vault, "Deposit")
+	chain.emit(1500, 0, 2, vault, "Deposit")
+	chain.emit(2400, 3, 0, round, "AuctionStarted")
+
+	vm := newTestManager(store, chain, nil)
+	store.blocks[0] = &models.StarknetBlocks{BlockNumber: 0, BlockHash: hashAt(0).String()}
+	if err := vm.CatchupVault(trackedVault(vm, 0), 2500); err != nil {
+		t.Fatalf("CatchupVault failed: %v", err)
+	}
+
+	want := [][2]uint64{{1, 1000}, {1001, 2000}, {2001, 2500}}
+	if fmt.Sprint(chain.requests) != fmt.Sprint(want) {
+		t.Errorf("requests = %v, want %v", chain.requests, want)
+	}
+	wantEvents := []string{
+		txHashAt(5, 0).String() + ":OptionRoundDeployed@0",
+		txHashAt(5, 1).String() + ":AuctionStarted@0",
+		txHashAt(5, 1).String() + ":Deposit@1",
+		txHashAt(1500, 0).String() + ":Deposit@2",
+		txHashAt(2400, 3).String() + ":AuctionStarted@0",
+	}
+	if fmt.Sprint(store.events) != fmt.Sprint(wantEvents) {
+		t.Errorf("events = %v, want %v", store.events, wantEvents)
+	}
+	wantCatchups := []string{
+		testVault + ":" + hashAt(1).String() + "-" + hashAt(1000).String(),
+		testVault + ":" + hashAt(1001).String() + "-" + hashAt(2000).String(),
+		testVault + ":" + hashAt(2001).String() + "-" + hashAt(2500).String(),
+	}
+	if fmt.Sprint(store.catchups) != fmt.Sprint(wantCatchups) {
+		t.Errorf("catchups = %v, want %v", store.catchups, wantCatchups)
+	}
+	// Every block of a window with events is stored, for its revert to find
+	// the events
+	for n := uint64(1); n <= 2500; n++ {
+		if b := store.blocks[n]; b == nil || b.BlockHash != hashAt(n).String() {
+			t.Fatalf("block %d = %+v, want %s", n, b, hashAt(n))
+		}
+	}
+
+	last := hashAt(2500).String()
+	if store.cursors[testVault] != last || *vm.registry.load().vaults[testVault].LastBlockIndexed != last {
+		t.Errorf("cursor = %s, want block 2500 %s", store.cursors[testVault], last)
+	}
+	progress := store.progress[testVault]
+	if progress.StartBlock != 1 || progress.TargetBlock != 2500 || progress.LastBlockNumber != 2500 || progress.LastBlockHash != last {
+		t.Errorf("progress = %+v, want blocks 1 to 2500 done", progress)
+	}
+}
+
+func TestCatchupVaultResumesFromProgress(t *testing.T) {
+	store := newFakeStore()
+	chain := &fakeChain{head: 3000}
+	vault, _ := new(felt.Felt).SetString(testVault)
+	chain.emit(1200, 0, 0, vault, "Deposit")
+
+	// An earlier catchup to 2500 was interrupted after its first window
+	vm := newTestManager(store, chain, nil)
+	store.progress[testVault] = &models.VaultCatchupProgress{
+		VaultAddress:    testVault,
+		StartBlock:      1,
+		TargetBlock:     2500,
+		LastBlockNumber: 1000,
+		LastBlockHash:   hashAt(1000).String(),
+	}
+	if err := vm.CatchupVault(trackedVault(vm, 1000), 1500); err != nil {
+		t.Fatalf("CatchupVault failed: %v", err)
+	}
+	if want := [][2]uint64{{1001, 1500}}; fmt.Sprint(chain.requests) != fmt.Sprint(want) {
+		t.Errorf("requests = %v, want %v", chain.requests, want)
+	}
+	progress := store.progress[testVault]
+	if progress.StartBlock != 1 || progress.TargetBlock != 1500 || progress.LastBlockNumber != 1500 {
+		t.Errorf("progress = %+v, want the catchup from block 1 done to 1500", progress)
+	}
+	if want := []string{txHashAt(1200, 0).String() + ":Deposit@0"}; fmt.Sprint(store.events) != fmt.Sprint(want) {
+		t.Errorf("events = %v, want %v", store.events, want)
+	}
+
+	// The progress of another cursor, rewound by a reorg since, is not used
+	chain.requests = nil
+	store.blocks[1400] = &models.StarknetBlocks{BlockNumber: 1400, BlockHash: hashAt(1400).String()}
+	if err := vm.CatchupVault(trackedVault(vm, 1400), 1600); err != nil {
+		t.Fatalf("CatchupVault failed: %v", err)
+	}
+	if want := [][2]uint64{{1401, 1600}}; fmt.Sprint(chain.requests) != fmt.Sprint(want) {
+		t.Errorf("requests = %v, want %v", chain.requests, want)
+	}
+	if progress := store.progress[testVault]; progress.StartBlock != 1401 || progress.LastBlockNumber != 1600 {
+		t.Errorf("progress = %+v, want the catchup from block 1401 done to 1600", progress)
+	}
+}
+
+func TestCatchupVaultWithoutDeployBlock(t *testing.T) {
+	store := newFakeStore()
+	chain := &fakeChain{head: 10}
+	vault, _ := new(felt.Felt).SetString(testVault)
+	// The deploy block has an event of the vault but not its deployment
+	chain.emit(3, 0, 0, vault, "Deposit")
+	vm := newTestManager(store, chain, nil)
+
+	unset := models.VaultRegistry{Address: testVault, DeployedAt: hashAt(3).String()}
+	if err := vm.CatchupVault(unset, 10); err == nil {
+		t.Error("expected the catchup of a vault without cursor to fail")
+	}
+
+	if err := vm.InitializeVault(&unset); err == nil {
+		t.Error("expected the initialization to fail without the deployment")
+	}
+	if vm.IsVaultAddress(testVault) {
+		t.Errorf("expected vault %s not to be tracked", testVault)
+	}
+
+	// A tracked vault whose initialization fails is not caught up
+	vm.TrackVaults([]*models.VaultRegistry{&unset})
+	if err := vm.SyncVaults(&models.StarknetBlocks{BlockNumber: 10, BlockHash: hashAt(10).String()}); err == nil {
+		t.Error("expected the sync of a vault without deployment to fail")
+	}
+	if len(chain.requests) != 0 {
+		t.Errorf("requests = %v, want no catchup", chain.requests)
+	}
+}
+
+func TestProcessVaultEventKeepsIndexesWhenFiltered(t *testing.T) {
+	vault, _ := new(felt.Felt).SetString(testVault)
+	var events []*models.BlockEvent
+	for i, name := range []string{"Deposit", "Withdrawal", "Deposit", "StashWithdrawn"} {
+		key, _ := new(felt.Felt).SetString(utils.Keccak256(name))
+		events = append(events, &models.BlockEvent{From: vault, Keys: []*felt.Felt{key}, Index: uint64(i)})
+	}
+	process := func(vm *Manager) []string {
+		store := newFakeStore()
+		for _, event := range events {
+			if err := vm.ProcessVaultEvent(store, "0xa", testVault, event, 1, *new(felt.Felt).SetUint64(0xb1)); err != nil {
+				t.Fatalf("ProcessVaultEvent failed: %v", err)
+			}
+		}
+		return store.events
+	}
+
+	all := process(newTestManager(nil, nil, nil))
+	want := []string{"0xa:Deposit@0", "0xa:Withdrawal@1", "0xa:Deposit@2", "0xa:StashWithdrawn@3"}
+	if fmt.Sprint(all) != fmt.Sprint(want) {
+		t.Errorf("stored %v, want %v", all, want)
+	}
+
+	// The events left by the filter keep the index they have without it
+	filtered := process(newTestManager(nil, nil, []string{"Deposit", "StashWithdrawn"}))
+	want = []string{"0xa:Deposit@0", "0xa:Deposit@2", "0xa:StashWithdrawn@3"}
+	if fmt.Sprint(filtered) != fmt.Sprint(want) {
+		t.Errorf("stored %v with the filter, want %v", filtered, want)
+	}
+}
+
+func TestInitializeVaultIgnoresEventsWithoutKeys(t *testing.T) {
+	store := newFakeStore()
+	chain := &fakeChain{head: 10}
+	vault, _ := new(felt.Felt).SetString(testVault)
+	udc, _ := new(felt.Felt).SetString(testUDC)
+	// Events without keys of another contract, of the UDC and of the vault
+	// surround the deployment in its block
+	chain.emitKeys(3, 0, 0, new(felt.Felt).SetUint64(0xe2c), nil, nil)
+	chain.emitKeys(3, 1, 0, vault, nil, nil)
+	chain.emitKeys(3, 1, 1, udc, nil, nil)
+	chain.emit(3, 1, 2, udc, "ContractDeployed", contractDeployedData(vault)...)
+	chain.emit(3, 2, 0, vault, "Deposit")
+
+	vm := NewManager(store, chain, testUDC, nil, nil)
+	vm.log = slog.New(slog.DiscardHandler)
+	vault3 := models.VaultRegistry{Address: testVault, DeployedAt: hashAt(3).String()}
+	if err := vm.InitializeVault(&vault3); err != nil {
+		t.Fatalf("InitializeVault failed: %v", err)
+	}
+	want := []string{txHashAt(3, 1).String() + ":ContractDeployed@2", txHashAt(3, 2).String() + ":Deposit@0"}
+	if fmt.Sprint(store.events) != fmt.Sprint(want) {
+		t.Errorf("events = %v, want %v", store.events, want)
+	}
+	if !vm.IsVaultAddress(testVault) {
+		t.Errorf("expected vault %s to be tracked", testVault)
+	}
+}
+
+func TestInitializeVaultRejectsShortDeployment(t *testing.T) {
+	store := newFakeStore()
+	chain := &fakeChain{head: 10}
+	vault, _ := new(felt.Felt).SetString(testVault)
+	udc, _ := new(felt.Felt).SetString(testUDC)
+	chain.emit(3, 0, 0, udc, "ContractDeployed", vault)
+
+	vm := NewManager(store, chain, testUDC, nil, nil)
+	vm.log = slog.New(slog.DiscardHandler)
+	vault3 := models.VaultRegistry{Address: testVault, DeployedAt: hashAt(3).String()}
+	if err := vm.InitializeVault(&vault3); !errors.Is(err, eventabi.ErrShortData) {
+		t.Errorf("InitializeVault = %v, want ErrShortData", err)
+	}
+}
+
+func TestProcessVaultEventRejectsShortRoundDeployment(t *testing.T) {
+	vault, _ := new(felt.Felt).SetString(testVault)
+	key, _ := new(felt.Felt).SetString(utils.Keccak256("OptionRoundDeployed"))
+	store := newFakeStore()
+	vm := newTestManager(store, nil, nil)
+
+	event := &models.BlockEvent{From: vault, Keys: []*felt.Felt{key}, Data: []*felt.Felt{new(felt.Felt).SetUint64(1)}}
+	if err := vm.ProcessVaultEvent(store, "0xa", testVault, event, 1, *hashAt(1)); !errors.Is(err, eventabi.ErrShortData) {
+		t.Errorf("ProcessVaultEvent = %v, want ErrShortData", err)
+	}
+	if len(store.events) != 0 || len(vm.registry.load().rounds) != 0 {
+		t.Errorf("expected the event not to be stored nor its round tracked, got %v", store.events)
+	}
+
+	// An event without keys is not a vault event
+	if err := vm.ProcessVaultEvent(store, "0xa", testVault, &models.BlockEvent{From: vault}, 1, *hashAt(1)); err != nil {
+		t.Errorf("ProcessVaultEvent of an event without keys = %v, want nil", err)
+	}
+}
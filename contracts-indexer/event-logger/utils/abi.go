@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"errors"
+	"eventabi"
+	"fmt"
+
+	"github.com/NethermindEth/juno/core/felt"
+)
+
+// registry holds the ABIs loaded by LoadABIs
+var registry *eventabi.Registry
+
+// udcABI declares the ContractDeployed event of the Universal Deployer
+// Contract, which the pitch_lake contract classes do not include
+const udcABI = `[{
+	"type": "event",
+	"name": "openzeppelin_presets::universal_deployer::UniversalDeployer::ContractDeployed",
+	"kind": "struct",
+	"members": [
+		{"name": "address", "type": "core::starknet::contract_address::ContractAddress", "kind": "data"},
+		{"name": "deployer", "type": "core::starknet::contract_address::ContractAddress", "kind": "data"},
+		{"name": "not_from_zero", "type": "core::bool", "kind": "data"},
+		{"name": "class_hash", "type": "core::starknet::class_hash::ClassHash", "kind": "data"},
+		{"name": "calldata", "type": "core::array::Span::<core::felt252>", "kind": "data"},
+		{"name": "salt", "type": "core::felt252", "kind": "data"}
+	]
+}]`
+
+type optionRoundDeployedEvent struct {
+	Address string `abi:"address"`
+}
+
+type contractDeployedEvent struct {
+	Address   string `abi:"address"`
+	ClassHash string `abi:"class_hash"`
+}
+
+// eventStructs maps every event decoded by this package to its struct, so that
+// LoadABIs can check them against the loaded ABIs
+var eventStructs = map[string]any{
+	"OptionRoundDeployed": optionRoundDeployedEvent{},
+	"ContractDeployed":    contractDeployedEvent{},
+}
+
+// LoadABIs loads the contract classes the events are decoded with, along with
+// the UDC ContractDeployed event, and checks that every event of this package
+// still matches them, so that a contract upgrade stops the plugin at startup
+// instead of registering the wrong rounds and vaults
+func LoadABIs(paths ...string) error {
+	r := eventabi.NewRegistry()
+	if err := r.Load([]byte(udcABI)); err != nil {
+		return fmt.Errorf("failed to load the UDC ABI: %w", err)
+	}
+	for _, path := range paths {
+		if err := r.LoadFile(path); err != nil {
+			return fmt.Errorf("failed to load ABI: %w", err)
+		}
+	}
+	for name, event := range eventStructs {
+		if err := r.Check(name, event); err != nil {
+			return fmt.Errorf("event %s does not match the ABI: %w", name, err)
+		}
+	}
+	registry = r
+	return nil
+}
+
+// decode decodes an event, keys and data as hex strings, that must be the
+// named one into out, a pointer to one of the event structs
+func decode(name string, keys, data []string, out any) error {
+	if registry == nil {
+		return errors.New("event ABIs are not loaded")
+	}
+	if err := registry.DecodeInto(name, keys, data, out); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+	return nil
+}
+
+// hasSelector reports whether the first key of an event is the selector of name
+func hasSelector(keys []*felt.Felt, name string) bool {
+	return len(keys) > 0 && keys[0].String() == eventabi.Selector(name)
+}
+
+// DecodeOptionRoundDeployed returns the address of the round deployed by a
+// vault OptionRoundDeployed event. ok is false for any other event, and an
+// OptionRoundDeployed event that does not match the Vault ABI is an error.
+func DecodeOptionRoundDeployed(keys, data []*felt.Felt) (roundAddress *felt.Felt, ok bool, err error) {
+	if !hasSelector(keys, "OptionRoundDeployed") {
+		return nil, false, nil
+	}
+	address, err := OptionRoundDeployedAddress(FeltArrayToStringArrays(keys), FeltArrayToStringArrays(data))
+	if err != nil {
+		return nil, false, err
+	}
+	roundAddress, err = new(felt.Felt).SetString(address)
+	if err != nil {
+		return nil, false, err
+	}
+	return roundAddress, true, nil
+}
+
+// OptionRoundDeployedAddress returns the address of the round deployed by a
+// stored OptionRoundDeployed event
+func OptionRoundDeployedAddress(keys, data []string) (string, error) {
+	var e optionRoundDeployedEvent
+	if err := decode("OptionRoundDeployed", keys, data, &e); err != nil {
+		return "", err
+	}
+	return e.Address, nil
+}
+
+// DecodeContractDeployed returns the deployed address and class hash of a UDC
+// ContractDeployed event. ok is false for any other event, and a
+// ContractDeployed event that does not match the UDC ABI is an error.
+func DecodeContractDeployed(keys, data []*felt.Felt) (address, classHash *felt.Felt, ok bool, err error) {
+	if !hasSelector(keys, "ContractDeployed") {
+		return nil, nil, false, nil
+	}
+	var e contractDeployedEvent
+	if err := decode("ContractDeployed", FeltArrayToStringArrays(keys), FeltArrayToStringArrays(data), &e); err != nil {
+		return nil, nil, false, err
+	}
+	if address, err = new(felt.Felt).SetString(e.Address); err != nil {
+		return nil, nil, false, err
+	}
+	if classHash, err = new(felt.Felt).SetString(e.ClassHash); err != nil {
+		return nil, nil, false, err
+	}
+	return address, classHash, true, nil
+}
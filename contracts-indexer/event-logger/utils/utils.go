@@ -1,31 +1,15 @@
 package utils
 
 import (
+	"eventabi"
 	"fmt"
 	"junoplugin/models"
 	"math/big"
 	"strings"
 
-	"github.com/NethermindEth/juno/core"
 	"github.com/NethermindEth/juno/core/felt"
-	"golang.org/x/crypto/sha3"
 )
 
-func CombineFeltToBigInt(highFelt, lowFelt [32]byte) models.BigInt {
-	combinedBytes := make([]byte, 64) // 32 bytes for highFelt and 32 bytes for lowFelt
-
-	// Copy highFelt into the first 32 bytes
-	copy(combinedBytes[0:32], highFelt[:])
-
-	// Copy lowFelt into the next 32 bytes
-	copy(combinedBytes[32:64], lowFelt[:])
-
-	// Convert the combined bytes to a big.Int
-	combinedInt := models.BigInt{Int: new(big.Int).SetBytes(combinedBytes)}
-
-	return combinedInt
-}
-
 func FeltToBigInt(felt [32]byte) models.BigInt {
 
 	byteData := make([]byte, 32)
@@ -96,61 +80,86 @@ var vaultEventNames = []string{
 	"OptionsExercised",
 }
 
-// var roundEventNames = []string{
+// roundEventNames are the events emitted by an option round contract
+var roundEventNames = []string{
+	"AuctionStarted",
+	"BidPlaced",
+	"BidUpdated",
+	"AuctionEnded",
+	"OptionRoundSettled",
+	"OptionsExercised",
+	"OptionsMinted",
+	"UnusedBidsRefunded",
+}
 
-// 	"AuctionStarted",
-// 	"BidPlaced",
-// 	"BidUpdated",
-// 	"AuctionEnded",
-// 	"OptionRoundSettled",
-// 	"OptionsExercised",
-// 	"OptionsMinted",
-// 	"UnusedBidsRefunded",
-// }
+// IsEventName reports whether name is a vault or an option round event
+func IsEventName(name string) bool {
+	for _, names := range [][]string{vaultEventNames, roundEventNames} {
+		for _, known := range names {
+			if known == name {
+				return true
+			}
+		}
+	}
+	return false
+}
 
-// keccak256 function to hash the event name
+// Keccak256 returns the Starknet selector of an event name
 func Keccak256(eventName string) string {
-	hasher := sha3.NewLegacyKeccak256()
-
-	// Write the event name as bytes to the hasher
-	hasher.Write([]byte(eventName))
+	return eventabi.Selector(eventName)
+}
 
-	// Compute the full 256-bit hash
-	hashBytes := hasher.Sum(nil)
+// DecodeEventNameRound returns the name of an option round event from its
+// selector, the first of its keys
+func DecodeEventNameRound(keys []*felt.Felt) (string, error) {
+	return eventName(keys, roundEventNames)
+}
 
-	// Convert the hash to a big integer
-	hashInt := new(big.Int).SetBytes(hashBytes)
+// DecodeEventNameVault returns the name of a vault event from its selector,
+// the first of its keys
+func DecodeEventNameVault(keys []*felt.Felt) (string, error) {
+	return eventName(keys, vaultEventNames)
+}
 
-	// Apply a 250-bit mask to fit StarkNet's felt requirements
-	mask := new(big.Int).Lsh(big.NewInt(1), 250)
-	mask.Sub(mask, big.NewInt(1))
-	hashInt.And(hashInt, mask)
+func eventName(keys []*felt.Felt, names []string) (string, error) {
+	if len(keys) == 0 {
+		return "", fmt.Errorf("%w: event has no keys", eventabi.ErrShortData)
+	}
+	for _, name := range names {
+		if hasSelector(keys, name) {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("%w: selector %s", eventabi.ErrUnknownEvent, keys[0])
+}
 
-	// Convert the masked hash to a hexadecimal string with "0x" prefix
-	return "0x" + hashInt.Text(16)
+// VaultEventKeys returns the events filter matching any vault event, the
+// selectors of vaultEventNames in the first key position
+func VaultEventKeys() [][]*felt.Felt {
+	return eventKeys(vaultEventNames)
 }
 
-// DecodeEventName decodes the event name from the keys of a StarkNet event
-// func DecodeEventNameRound(eventKey string) (string, error) {
-// 	for _, name := range roundEventNames {
-// 		if Keccak256(name) == eventKey {
-// 			return name, nil
-// 		}
-// 	}
-// 	return "", fmt.Errorf("event name not found for key: %s", eventKey)
-// }
+// RoundEventKeys returns the events filter matching any option round event,
+// the selectors of roundEventNames in the first key position
+func RoundEventKeys() [][]*felt.Felt {
+	return eventKeys(roundEventNames)
+}
 
-func DecodeEventNameVault(eventKey string) (string, error) {
-	for _, name := range vaultEventNames {
-		if Keccak256(name) == eventKey {
-			return name, nil
+func eventKeys(names []string) [][]*felt.Felt {
+	selectors := make([]*felt.Felt, 0, len(names))
+	for _, name := range names {
+		selector, err := new(felt.Felt).SetString(Keccak256(name))
+		if err != nil {
+			// Keccak256 always returns a valid felt
+			panic(err)
 		}
+		selectors = append(selectors, selector)
 	}
-	return "", fmt.Errorf("event name not found for key: %s", eventKey)
+	return [][]*felt.Felt{selectors}
 }
 
-// EventToStringArrays converts a Juno core.Event keys and data to string arrays
-func EventToStringArrays(event core.Event) ([]string, []string) {
+// EventToStringArrays converts the keys and data of an event to string arrays
+func EventToStringArrays(event models.BlockEvent) ([]string, []string) {
 	keys := make([]string, len(event.Keys))
 	data := make([]string, len(event.Data))
 
@@ -1,8 +1,13 @@
 package utils
 
 import (
+	"errors"
 	"math/big"
 	"testing"
+
+	"eventabi"
+
+	"github.com/NethermindEth/juno/core/felt"
 )
 
 func TestKeccak256(t *testing.T) {
@@ -38,9 +43,9 @@ func TestKeccak256(t *testing.T) {
 
 func TestDecodeEventNameVault(t *testing.T) {
 	// Test with a known event name hash
-	depositHash := Keccak256("Deposit")
+	depositHash, _ := new(felt.Felt).SetString(Keccak256("Deposit"))
 
-	result, err := DecodeEventNameVault(depositHash)
+	result, err := DecodeEventNameVault([]*felt.Felt{depositHash})
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -50,10 +55,148 @@ func TestDecodeEventNameVault(t *testing.T) {
 	}
 
 	// Test with unknown hash
-	unknownHash := "0x1234567890abcdef"
-	_, err = DecodeEventNameVault(unknownHash)
-	if err == nil {
-		t.Error("Expected error for unknown hash")
+	unknownHash := new(felt.Felt).SetUint64(0x1234567890abcdef)
+	_, err = DecodeEventNameVault([]*felt.Felt{unknownHash})
+	if !errors.Is(err, eventabi.ErrUnknownEvent) {
+		t.Errorf("Expected ErrUnknownEvent for unknown hash, got %v", err)
+	}
+
+	// An event without keys is an error, not a panic
+	if _, err := DecodeEventNameVault(nil); !errors.Is(err, eventabi.ErrShortData) {
+		t.Errorf("Expected ErrShortData for an event without keys, got %v", err)
+	}
+}
+
+func TestVaultEventKeys(t *testing.T) {
+	keys := VaultEventKeys()
+	if len(keys) != 1 || len(keys[0]) != len(vaultEventNames) {
+		t.Fatalf("Expected one key position with %d selectors, got %v", len(vaultEventNames), keys)
+	}
+
+	// Every selector must decode back to its event name
+	for i, selector := range keys[0] {
+		name, err := DecodeEventNameVault([]*felt.Felt{selector})
+		if err != nil {
+			t.Errorf("Unexpected error for selector %s: %v", selector, err)
+		}
+		if name != vaultEventNames[i] {
+			t.Errorf("Expected '%s', got '%s'", vaultEventNames[i], name)
+		}
+	}
+}
+
+func TestRoundEventKeys(t *testing.T) {
+	keys := RoundEventKeys()
+	if len(keys) != 1 || len(keys[0]) != len(roundEventNames) {
+		t.Fatalf("Expected one key position with %d selectors, got %v", len(roundEventNames), keys)
+	}
+	for i, selector := range keys[0] {
+		name, err := DecodeEventNameRound([]*felt.Felt{selector})
+		if err != nil {
+			t.Errorf("Unexpected error for selector %s: %v", selector, err)
+		}
+		if name != roundEventNames[i] {
+			t.Errorf("Expected '%s', got '%s'", roundEventNames[i], name)
+		}
+	}
+
+	// Vault only events are not round events
+	deposit, _ := new(felt.Felt).SetString(Keccak256("Deposit"))
+	if _, err := DecodeEventNameRound([]*felt.Felt{deposit}); err == nil {
+		t.Error("Expected error for a vault event")
+	}
+}
+
+// loadTestABIs loads the contract classes kept by eventabi
+func loadTestABIs(t *testing.T) {
+	t.Helper()
+	if err := LoadABIs(
+		"../../eventabi/testdata/pitch_lake_Vault.contract_class.json",
+		"../../eventabi/testdata/pitch_lake_OptionRound.contract_class.json",
+	); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func felts(values ...uint64) []*felt.Felt {
+	result := make([]*felt.Felt, len(values))
+	for i, value := range values {
+		result[i] = new(felt.Felt).SetUint64(value)
+	}
+	return result
+}
+
+func TestDecodeOptionRoundDeployed(t *testing.T) {
+	loadTestABIs(t)
+	selector, _ := new(felt.Felt).SetString(Keccak256("OptionRoundDeployed"))
+	roundAddress := new(felt.Felt).SetUint64(0x40d)
+	// round id, address, auction start, auction end, settlement, then the
+	// strike price (u256), cap level and reserve price (u256)
+	data := felts(1, 0x40d, 100, 200, 300, 5, 0, 2, 7, 0)
+
+	got, ok, err := DecodeOptionRoundDeployed([]*felt.Felt{selector}, data)
+	if err != nil || !ok || !got.Equal(roundAddress) {
+		t.Errorf("Expected round %s, got %v (ok %v, err %v)", roundAddress, got, ok, err)
+	}
+
+	other, _ := new(felt.Felt).SetString(Keccak256("AuctionStarted"))
+	if _, ok, err := DecodeOptionRoundDeployed([]*felt.Felt{other}, data); ok || err != nil {
+		t.Errorf("Expected other events to be ignored, got ok %v, err %v", ok, err)
+	}
+	if _, ok, err := DecodeOptionRoundDeployed(nil, data); ok || err != nil {
+		t.Errorf("Expected an event without keys to be ignored, got ok %v, err %v", ok, err)
+	}
+	if _, _, err := DecodeOptionRoundDeployed([]*felt.Felt{selector}, data[:2]); !errors.Is(err, eventabi.ErrShortData) {
+		t.Errorf("Expected ErrShortData for a short event, got %v", err)
+	}
+	if _, _, err := DecodeOptionRoundDeployed([]*felt.Felt{selector}, append(data, new(felt.Felt))); !errors.Is(err, eventabi.ErrTrailingData) {
+		t.Errorf("Expected ErrTrailingData for a long event, got %v", err)
+	}
+}
+
+func TestDecodeContractDeployed(t *testing.T) {
+	loadTestABIs(t)
+	selector, _ := new(felt.Felt).SetString(Keccak256("ContractDeployed"))
+	address := new(felt.Felt).SetUint64(0xabc)
+	classHash := new(felt.Felt).SetUint64(0xc1a55)
+	// address, deployer, not from zero, class hash, calldata of two felts, salt
+	data := felts(0xabc, 1, 0, 0xc1a55, 2, 0x10, 0x11, 0x5a17)
+
+	gotAddress, gotClassHash, ok, err := DecodeContractDeployed([]*felt.Felt{selector}, data)
+	if err != nil || !ok {
+		t.Fatalf("Expected a ContractDeployed event, got ok %v, err %v", ok, err)
+	}
+	if !gotAddress.Equal(address) || !gotClassHash.Equal(classHash) {
+		t.Errorf("Expected %s with class %s, got %s with class %s", address, classHash, gotAddress, gotClassHash)
+	}
+
+	other, _ := new(felt.Felt).SetString(Keccak256("Deposit"))
+	if _, _, ok, err := DecodeContractDeployed([]*felt.Felt{other}, data); ok || err != nil {
+		t.Errorf("Expected other events to be ignored, got ok %v, err %v", ok, err)
+	}
+	if _, _, ok, err := DecodeContractDeployed(nil, data); ok || err != nil {
+		t.Errorf("Expected an event without keys to be ignored, got ok %v, err %v", ok, err)
+	}
+	// The calldata length runs past the end of the data
+	if _, _, _, err := DecodeContractDeployed([]*felt.Felt{selector}, data[:6]); !errors.Is(err, eventabi.ErrShortData) {
+		t.Errorf("Expected ErrShortData for a short event, got %v", err)
+	}
+}
+
+func TestOptionRoundDeployedAddress(t *testing.T) {
+	loadTestABIs(t)
+	keys := []string{Keccak256("OptionRoundDeployed")}
+	data := []string{"0x1", "0x040d", "0x64", "0xc8", "0x12c", "0x5", "0x0", "0x2", "0x7", "0x0"}
+
+	address, err := OptionRoundDeployedAddress(keys, data)
+	if err != nil || address != "0x40d" {
+		t.Errorf("Expected round 0x40d, got %s (err %v)", address, err)
+	}
+	if _, err := OptionRoundDeployedAddress(keys, data[:1]); !errors.Is(err, eventabi.ErrShortData) {
+		t.Errorf("Expected ErrShortData for a short event, got %v", err)
+	}
+	if _, err := OptionRoundDeployedAddress([]string{Keccak256("Deposit")}, data); err == nil {
+		t.Error("Expected an error for another event")
 	}
 }
 
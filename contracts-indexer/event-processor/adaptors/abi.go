@@ -0,0 +1,61 @@
+package adaptors
+
+import (
+	"errors"
+	"event-processor/models"
+	"fmt"
+
+	"eventabi"
+)
+
+// registry holds the Vault and OptionRound ABIs loaded by LoadABIs
+var registry *eventabi.Registry
+
+// eventStructs maps every event decoded by this package to its struct, so that
+// LoadABIs can check them against the loaded ABIs
+var eventStructs = map[string]any{
+	"Deposit":             depositOrWithdrawalEvent{},
+	"Withdrawal":          depositOrWithdrawalEvent{},
+	"WithdrawalQueued":    withdrawalQueuedEvent{},
+	"StashWithdrawn":      stashWithdrawnEvent{},
+	"OptionRoundDeployed": optionRoundDeployedEvent{},
+	"PricingDataSet":      pricingDataSetEvent{},
+	"AuctionStarted":      auctionStartedEvent{},
+	"AuctionEnded":        auctionEndedEvent{},
+	"OptionRoundSettled":  optionRoundSettledEvent{},
+	"BidPlaced":           bidPlacedEvent{},
+	"BidUpdated":          bidUpdatedEvent{},
+	"OptionsMinted":       optionsMintedEvent{},
+	"OptionsExercised":    optionsExercisedEvent{},
+	"UnusedBidsRefunded":  unusedBidsRefundedEvent{},
+}
+
+// LoadABIs loads the contract classes the events are decoded with and checks
+// that every event of this package still matches them, so that a contract
+// upgrade stops the processor at startup instead of corrupting its state
+func LoadABIs(paths ...string) error {
+	r := eventabi.NewRegistry()
+	for _, path := range paths {
+		if err := r.LoadFile(path); err != nil {
+			return fmt.Errorf("failed to load ABI: %w", err)
+		}
+	}
+	for name, event := range eventStructs {
+		if err := r.Check(name, event); err != nil {
+			return fmt.Errorf("event %s does not match the ABI: %w", name, err)
+		}
+	}
+	registry = r
+	return nil
+}
+
+// decode decodes a stored event into out, a pointer to one of the event structs
+func decode(event models.Event, out any) error {
+	if registry == nil {
+		return errors.New("event ABIs are not loaded")
+	}
+	if err := registry.DecodeInto(event.EventName, event.EventKeys, event.EventData, out); err != nil {
+		return fmt.Errorf("failed to decode %s of tx %s: %w", event.EventName, event.TransactionHash, err)
+	}
+	return nil
+}
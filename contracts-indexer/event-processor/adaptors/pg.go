@@ -1,197 +1,250 @@
 package adaptors
 
 import (
-	"log"
+	"fmt"
 	"math/big"
 
 	"event-processor/models"
-
-	"github.com/NethermindEth/juno/core"
-	"github.com/NethermindEth/juno/core/felt"
 )
 
-func GetJunoEvent(event models.Event) core.Event {
-	var From felt.Felt
-	From.SetString(event.From)
-	// Convert EventData array to felt array
-	Data := make([]*felt.Felt, len(event.EventData))
-	for i, data := range event.EventData {
-		Data[i].SetString(data)
-	}
-
-	// Convert EventKeys array to felt array
-	Keys := make([]*felt.Felt, len(event.EventKeys))
-	for i, key := range event.EventKeys {
-		Keys[i].SetString(key)
-	}
-
-	junoEvent := core.Event{
-		From: &From,
-		Data: Data,
-		Keys: Keys,
-	}
-	return junoEvent
-}
-
-func ContractDeployed(event core.Event) (string, string, string, models.BigInt, models.BigInt, uint64, uint64, uint64) {
-
-	fossilClientAddress := FeltToHexString(event.Data[5].Bytes())
-	ethAddress := FeltToHexString(event.Data[6].Bytes())
-	optionRoundClassHash := FeltToHexString(event.Data[7].Bytes())
-	alpha := FeltToBigInt(event.Data[8].Bytes())
-	strikeLevel := FeltToBigInt(event.Data[9].Bytes())
-	roundTransitionDuration := event.Data[10].Uint64()
-	auctionDuration := event.Data[11].Uint64()
-	roundDuration := event.Data[12].Uint64()
-	return fossilClientAddress,
-		ethAddress,
-		optionRoundClassHash,
-		alpha,
-		strikeLevel,
-		roundTransitionDuration,
-		auctionDuration,
-		roundDuration
-}
-func PricingDataSet(event core.Event) (models.BigInt, models.BigInt, models.BigInt, string) {
-	strikePrice := CombineFeltToBigInt(event.Data[1].Bytes(), event.Data[0].Bytes())
-	capLevel := FeltToBigInt(event.Data[2].Bytes())
-	reservePrice := CombineFeltToBigInt(event.Data[4].Bytes(), event.Data[3].Bytes())
-	roundAddress := FeltToHexString(event.Data[5].Bytes())
-	return strikePrice, capLevel, reservePrice, roundAddress
-}
-func DepositOrWithdraw(event core.Event) (string, models.BigInt, models.BigInt) {
-	lpAddress := FeltToHexString(event.Keys[1].Bytes())
-	lpUnlocked := CombineFeltToBigInt(event.Data[3].Bytes(), event.Data[2].Bytes())
-	vaultUnlocked := CombineFeltToBigInt(event.Data[5].Bytes(), event.Data[4].Bytes())
-	return lpAddress, lpUnlocked, vaultUnlocked
-}
-
-func WithdrawalQueued(event core.Event) (string, models.BigInt, uint64, models.BigInt, models.BigInt, models.BigInt) {
-	lpAddress := FeltToHexString(event.Keys[1].Bytes())
-	bps := FeltToBigInt(event.Data[0].Bytes())
-	roundId := event.Data[1].Uint64()
-	accountQueuedNow := CombineFeltToBigInt(event.Data[3].Bytes(), event.Data[2].Bytes())
-	vaultQueuedNow := CombineFeltToBigInt(event.Data[5].Bytes(), event.Data[4].Bytes())
-
-	//Change this when using new cont
-	accountQueuedBefore := CombineFeltToBigInt(event.Data[3].Bytes(), event.Data[2].Bytes())
-
-	return lpAddress, bps, roundId, accountQueuedBefore, accountQueuedNow, vaultQueuedNow
-}
-
-func StashWithdrawn(event core.Event) (string, models.BigInt, models.BigInt) {
-	lpAddress := FeltToHexString(event.Keys[1].Bytes())
-	amount := CombineFeltToBigInt(event.Data[1].Bytes(), event.Data[0].Bytes())
-	vaultStashed := CombineFeltToBigInt(event.Data[3].Bytes(), event.Data[2].Bytes())
-	return lpAddress, amount, vaultStashed
-}
-
-func RoundDeployed(event core.Event) models.OptionRound {
-
-	log.Printf("event %v", event)
-	vaultAddress :=
-		event.From.String()
-	roundId := FeltToBigInt(event.Data[0].Bytes())
-	roundAddress := FeltToHexString(event.Data[1].Bytes())
-	startingBlock := event.Data[2].Uint64()
-	endingBlock := event.Data[3].Uint64()
-	settlementDate := event.Data[4].Uint64()
-	strikePrice := CombineFeltToBigInt(event.Data[6].Bytes(), event.Data[5].Bytes())
-	capLevel := FeltToBigInt(event.Data[7].Bytes())
-	reservePrice := CombineFeltToBigInt(event.Data[9].Bytes(), event.Data[8].Bytes())
-	optionRound := models.OptionRound{
-		RoundID:          roundId,
-		Address:          roundAddress,
-		VaultAddress:     vaultAddress,
-		AuctionStartDate: startingBlock,
-		AuctionEndDate:   endingBlock,
-		OptionSettleDate: settlementDate,
-		StrikePrice:      strikePrice,
-		CapLevel:         capLevel,
-		ReservePrice:     reservePrice,
-		RoundState:       "Open",
+// The event structs below mirror the Vault and OptionRound ABI events, their
+// abi tags naming the members. Round events carry no round address, it is the
+// address the event was emitted from.
+
+type depositOrWithdrawalEvent struct {
+	Account         string   `abi:"account"`
+	Amount          *big.Int `abi:"amount"`
+	AccountUnlocked *big.Int `abi:"account_unlocked_balance_now"`
+	VaultUnlocked   *big.Int `abi:"vault_unlocked_balance_now"`
+}
+
+type withdrawalQueuedEvent struct {
+	Account             string   `abi:"account"`
+	Bps                 *big.Int `abi:"bps"`
+	RoundID             uint64   `abi:"round_id"`
+	AccountQueuedBefore *big.Int `abi:"account_queued_liquidity_before"`
+	AccountQueuedNow    *big.Int `abi:"account_queued_liquidity_now"`
+	VaultQueuedNow      *big.Int `abi:"vault_queued_liquidity_now"`
+}
+
+type stashWithdrawnEvent struct {
+	Account      string   `abi:"account"`
+	Amount       *big.Int `abi:"amount"`
+	VaultStashed *big.Int `abi:"vault_stashed_balance_now"`
+}
+
+type pricingData struct {
+	StrikePrice  *big.Int `abi:"strike_price"`
+	CapLevel     *big.Int `abi:"cap_level"`
+	ReservePrice *big.Int `abi:"reserve_price"`
+}
+
+type optionRoundDeployedEvent struct {
+	RoundID          *big.Int    `abi:"round_id"`
+	Address          string      `abi:"address"`
+	AuctionStartDate uint64      `abi:"auction_start_date"`
+	AuctionEndDate   uint64      `abi:"auction_end_date"`
+	OptionSettleDate uint64      `abi:"option_settlement_date"`
+	PricingData      pricingData `abi:"pricing_data"`
+}
+
+type pricingDataSetEvent struct {
+	PricingData pricingData `abi:"pricing_data"`
+}
+
+type auctionStartedEvent struct {
+	StartingLiquidity *big.Int `abi:"starting_liquidity"`
+	OptionsAvailable  *big.Int `abi:"options_available"`
+}
+
+type auctionEndedEvent struct {
+	OptionsSold     *big.Int `abi:"options_sold"`
+	ClearingPrice   *big.Int `abi:"clearing_price"`
+	UnsoldLiquidity *big.Int `abi:"unsold_liquidity"`
+	ClearingNonce   uint64   `abi:"clearing_bid_tree_nonce"`
+}
+
+type optionRoundSettledEvent struct {
+	SettlementPrice *big.Int `abi:"settlement_price"`
+	PayoutPerOption *big.Int `abi:"payout_per_option"`
+}
+
+type bidPlacedEvent struct {
+	Account   string   `abi:"account"`
+	BidID     string   `abi:"bid_id"`
+	Amount    *big.Int `abi:"amount"`
+	Price     *big.Int `abi:"price"`
+	TreeNonce uint64   `abi:"bid_tree_nonce_now"`
+}
+
+type bidUpdatedEvent struct {
+	Account         string   `abi:"account"`
+	BidID           string   `abi:"bid_id"`
+	PriceIncrease   *big.Int `abi:"price_increase"`
+	TreeNonceBefore uint64   `abi:"bid_tree_nonce_before"`
+	TreeNonceNow    uint64   `abi:"bid_tree_nonce_now"`
+}
+
+type optionsMintedEvent struct {
+	Account      string   `abi:"account"`
+	MintedAmount *big.Int `abi:"minted_amount"`
+}
+
+type optionsExercisedEvent struct {
+	Account                  string   `abi:"account"`
+	TotalOptionsExercised    *big.Int `abi:"total_options_exercised"`
+	MintableOptionsExercised *big.Int `abi:"mintable_options_exercised"`
+	ExercisedAmount          *big.Int `abi:"exercised_amount"`
+}
+
+type unusedBidsRefundedEvent struct {
+	Account        string   `abi:"account"`
+	RefundedAmount *big.Int `abi:"refunded_amount"`
+}
+
+func bigInt(v *big.Int) models.BigInt {
+	return models.BigInt{Int: v}
+}
+
+func PricingDataSet(event models.Event) (models.BigInt, models.BigInt, models.BigInt, string, error) {
+	var e pricingDataSetEvent
+	if err := decode(event, &e); err != nil {
+		return models.BigInt{}, models.BigInt{}, models.BigInt{}, "", err
+	}
+	data := e.PricingData
+	return bigInt(data.StrikePrice), bigInt(data.CapLevel), bigInt(data.ReservePrice), event.From, nil
+}
+
+func DepositOrWithdraw(event models.Event) (string, models.BigInt, models.BigInt, error) {
+	var e depositOrWithdrawalEvent
+	if err := decode(event, &e); err != nil {
+		return "", models.BigInt{}, models.BigInt{}, err
 	}
-	return optionRound
+	return e.Account, bigInt(e.AccountUnlocked), bigInt(e.VaultUnlocked), nil
+}
 
+func WithdrawalQueued(event models.Event) (string, models.BigInt, uint64, models.BigInt, models.BigInt, models.BigInt, error) {
+	var e withdrawalQueuedEvent
+	if err := decode(event, &e); err != nil {
+		return "", models.BigInt{}, 0, models.BigInt{}, models.BigInt{}, models.BigInt{}, err
+	}
+	return e.Account,
+		bigInt(e.Bps),
+		e.RoundID,
+		bigInt(e.AccountQueuedBefore),
+		bigInt(e.AccountQueuedNow),
+		bigInt(e.VaultQueuedNow),
+		nil
+}
+
+func StashWithdrawn(event models.Event) (string, models.BigInt, models.BigInt, error) {
+	var e stashWithdrawnEvent
+	if err := decode(event, &e); err != nil {
+		return "", models.BigInt{}, models.BigInt{}, err
+	}
+	return e.Account, bigInt(e.Amount), bigInt(e.VaultStashed), nil
 }
 
-func AuctionStarted(event core.Event) (models.BigInt, models.BigInt, string) {
+func RoundDeployed(event models.Event) (models.OptionRound, error) {
+	var e optionRoundDeployedEvent
+	if err := decode(event, &e); err != nil {
+		return models.OptionRound{}, err
+	}
+	optionRound := models.OptionRound{
+		RoundID:          bigInt(e.RoundID),
+		Address:          e.Address,
+		VaultAddress:     event.VaultAddress,
+		AuctionStartDate: e.AuctionStartDate,
+		AuctionEndDate:   e.AuctionEndDate,
+		OptionSettleDate: e.OptionSettleDate,
+		StrikePrice:      bigInt(e.PricingData.StrikePrice),
+		CapLevel:         bigInt(e.PricingData.CapLevel),
+		ReservePrice:     bigInt(e.PricingData.ReservePrice),
+		RoundState:       "Open",
+	}
+	return optionRound, nil
+}
 
-	startingLiquidity := CombineFeltToBigInt(event.Data[1].Bytes(), event.Data[0].Bytes())
-	availableOptions := CombineFeltToBigInt(event.Data[3].Bytes(), event.Data[2].Bytes())
-	roundAddress := FeltToHexString(event.Data[5].Bytes())
-	return availableOptions, startingLiquidity, roundAddress
+func AuctionStarted(event models.Event) (models.BigInt, models.BigInt, string, error) {
+	var e auctionStartedEvent
+	if err := decode(event, &e); err != nil {
+		return models.BigInt{}, models.BigInt{}, "", err
+	}
+	return bigInt(e.OptionsAvailable), bigInt(e.StartingLiquidity), event.From, nil
 }
 
-func AuctionEnded(event core.Event) (models.BigInt, models.BigInt, models.BigInt, uint64, models.BigInt, string) {
-	optionsSold := CombineFeltToBigInt(event.Data[1].Bytes(), event.Data[0].Bytes())
-	clearingPrice := CombineFeltToBigInt(event.Data[3].Bytes(), event.Data[2].Bytes())
-	unsoldLiquidity := CombineFeltToBigInt(event.Data[5].Bytes(), event.Data[4].Bytes())
-	clearingNonce := event.Data[6].Uint64()
-	premiums := models.BigInt{Int: new(big.Int).Mul(optionsSold.Int, clearingPrice.Int)}
-	roundAddress := FeltToHexString(event.Data[7].Bytes())
-	return optionsSold, clearingPrice, unsoldLiquidity, clearingNonce, premiums, roundAddress
+func AuctionEnded(event models.Event) (models.BigInt, models.BigInt, models.BigInt, uint64, models.BigInt, string, error) {
+	var e auctionEndedEvent
+	if err := decode(event, &e); err != nil {
+		return models.BigInt{}, models.BigInt{}, models.BigInt{}, 0, models.BigInt{}, "", err
+	}
+	premiums := bigInt(new(big.Int).Mul(e.OptionsSold, e.ClearingPrice))
+	return bigInt(e.OptionsSold), bigInt(e.ClearingPrice), bigInt(e.UnsoldLiquidity), e.ClearingNonce, premiums, event.From, nil
 }
 
-func OptionRoundSettled(event core.Event) (models.BigInt, models.BigInt, string) {
-	settlementPrice := CombineFeltToBigInt(event.Data[1].Bytes(), event.Data[0].Bytes())
-	payoutPerOption := CombineFeltToBigInt(event.Data[3].Bytes(), event.Data[2].Bytes())
-	roundAddress := FeltToHexString(event.Data[5].Bytes())
-	return settlementPrice, payoutPerOption, roundAddress
+func OptionRoundSettled(event models.Event) (models.BigInt, models.BigInt, string, error) {
+	var e optionRoundSettledEvent
+	if err := decode(event, &e); err != nil {
+		return models.BigInt{}, models.BigInt{}, "", err
+	}
+	return bigInt(e.SettlementPrice), bigInt(e.PayoutPerOption), event.From, nil
 }
 
-func BidPlaced(event core.Event) (models.Bid, models.OptionBuyer) {
-	bidId := event.Data[0].String()
-	bidAmount := CombineFeltToBigInt(event.Data[2].Bytes(), event.Data[1].Bytes())
-	bidPrice := CombineFeltToBigInt(event.Data[4].Bytes(), event.Data[3].Bytes())
-	treeNonce := event.Data[6].Uint64() - 1
-	roundAddress := FeltToHexString(event.Data[6].Bytes())
+func BidPlaced(event models.Event) (models.Bid, models.OptionBuyer, error) {
+	var e bidPlacedEvent
+	if err := decode(event, &e); err != nil {
+		return models.Bid{}, models.OptionBuyer{}, err
+	}
+	// The event carries the nonce after the bid was inserted
+	if e.TreeNonce == 0 {
+		return models.Bid{}, models.OptionBuyer{}, fmt.Errorf("BidPlaced of tx %s has a zero tree nonce", event.TransactionHash)
+	}
 
 	bid := models.Bid{
-		BuyerAddress: FeltToHexString(event.Keys[1].Bytes()),
-		BidID:        bidId,
-		RoundAddress: roundAddress,
-		Amount:       bidAmount,
-		Price:        bidPrice,
-		TreeNonce:    treeNonce,
+		BuyerAddress: e.Account,
+		BidID:        e.BidID,
+		RoundAddress: event.From,
+		Amount:       bigInt(e.Amount),
+		Price:        bigInt(e.Price),
+		TreeNonce:    e.TreeNonce - 1,
 	}
 
 	buyer := models.OptionBuyer{
-		Address:      FeltToHexString(event.Keys[1].Bytes()),
-		RoundAddress: event.From.String(),
+		Address:      e.Account,
+		RoundAddress: event.From,
 	}
 
-	return bid, buyer
+	return bid, buyer, nil
 }
 
-func BidUpdated(event core.Event) (string, models.BigInt, uint64, uint64, string) {
-	bidId := event.Data[0].String()
-	price := CombineFeltToBigInt(event.Data[2].Bytes(), event.Data[1].Bytes())
-	treeNonceOld := event.Data[3].Uint64()
-	treeNonceNew := event.Data[4].Uint64()
-	roundAddress := FeltToHexString(event.Data[5].Bytes())
-	return bidId, price, treeNonceOld, treeNonceNew, roundAddress
+func BidUpdated(event models.Event) (string, models.BigInt, uint64, uint64, string, error) {
+	var e bidUpdatedEvent
+	if err := decode(event, &e); err != nil {
+		return "", models.BigInt{}, 0, 0, "", err
+	}
+	return e.BidID, bigInt(e.PriceIncrease), e.TreeNonceBefore, e.TreeNonceNow, event.From, nil
 }
 
-func OptionsMinted(event core.Event) (string, models.BigInt, string) {
-	buyerAddress := FeltToHexString(event.Keys[1].Bytes())
-	mintedAmount := CombineFeltToBigInt(event.Data[1].Bytes(), event.Data[0].Bytes())
-	roundAddress := FeltToHexString(event.Data[2].Bytes())
-	return buyerAddress, mintedAmount, roundAddress
+func OptionsMinted(event models.Event) (string, models.BigInt, string, error) {
+	var e optionsMintedEvent
+	if err := decode(event, &e); err != nil {
+		return "", models.BigInt{}, "", err
+	}
+	return e.Account, bigInt(e.MintedAmount), event.From, nil
 }
 
-func OptionsExercised(event core.Event) (string, models.BigInt, models.BigInt, models.BigInt, string) {
-	buyerAddress := FeltToHexString(event.Keys[1].Bytes())
-	totalOptionsExercised := CombineFeltToBigInt(event.Data[1].Bytes(), event.Data[0].Bytes())
-	mintableOptionsExercised := CombineFeltToBigInt(event.Data[3].Bytes(), event.Data[2].Bytes())
-	exercisedAmount := CombineFeltToBigInt(event.Data[5].Bytes(), event.Data[4].Bytes())
-	roundAddress := FeltToHexString(event.Data[7].Bytes())
-	return buyerAddress, totalOptionsExercised, mintableOptionsExercised, exercisedAmount, roundAddress
+func OptionsExercised(event models.Event) (string, models.BigInt, models.BigInt, models.BigInt, string, error) {
+	var e optionsExercisedEvent
+	if err := decode(event, &e); err != nil {
+		return "", models.BigInt{}, models.BigInt{}, models.BigInt{}, "", err
+	}
+	return e.Account, bigInt(e.TotalOptionsExercised), bigInt(e.MintableOptionsExercised), bigInt(e.ExercisedAmount), event.From, nil
 }
 
-func UnusedBidsRefunded(event core.Event) (string, models.BigInt, string) {
-	buyerAddress := FeltToHexString(event.Keys[1].Bytes())
-	refundedAmount := CombineFeltToBigInt(event.Data[1].Bytes(), event.Data[0].Bytes())
-	roundAddress := FeltToHexString(event.Data[2].Bytes())
-	return buyerAddress, refundedAmount, roundAddress
+func UnusedBidsRefunded(event models.Event) (string, models.BigInt, string, error) {
+	var e unusedBidsRefundedEvent
+	if err := decode(event, &e); err != nil {
+		return "", models.BigInt{}, "", err
+	}
+	return e.Account, bigInt(e.RefundedAmount), event.From, nil
 }
@@ -30,22 +30,28 @@ func (db *DB) BeginTx() {
 	db.tx = tx
 }
 
-func (db *DB) CommitTx() {
-	db.tx.Commit(db.ctx)
-	db.tx.Conn().Close(db.ctx)
+// CommitTx commits the transaction, which releases its connection back to
+// the pool
+func (db *DB) CommitTx() error {
+	err := db.tx.Commit(db.ctx)
 	db.tx = nil
+	return err
 }
 
 func (db *DB) RollbackTx() {
 	db.tx.Rollback(db.ctx)
-	db.tx.Conn().Close(db.ctx)
 	db.tx = nil
 }
 
 func (db *DB) Init() error {
+	return db.Connect(os.Getenv("DB_URL"))
+}
 
+// Connect opens the connection the driver events are listened on and the pool
+// they are processed with
+func (db *DB) Connect(connStr string) error {
 	db.logger = log.New(os.Stdout, "", log.LstdFlags)
-	connStr := os.Getenv("DB_URL")
+	db.ctx = context.Background()
 	config, err := pgxpool.ParseConfig(connStr)
 	if err != nil {
 		return fmt.Errorf("unable to parse connection string: %w", err)
@@ -74,21 +80,45 @@ func (db *DB) MarkDriverEventAsProcessed(id int) error {
 	}
 	return nil
 }
+
+// GetUnprocessedDriverEvents returns the driver events not processed yet, in
+// the order the event-logger emitted them
 func (db *DB) GetUnprocessedDriverEvents() ([]models.DriverEvent, error) {
 	query := `
-			SELECT * FROM driver_events WHERE is_processed = false;`
-	rows, err := db.tx.Query(context.Background(), query)
+	SELECT
+		id,
+		version,
+		sequence_index,
+		type,
+		timestamp,
+		COALESCE(is_processed, false),
+		COALESCE(block_hash, ''),
+		COALESCE(start_block_hash, ''),
+		COALESCE(end_block_hash, ''),
+		COALESCE(vault_address, '')
+	FROM driver_events
+	WHERE is_processed = false
+	ORDER BY sequence_index ASC;`
+	rows, err := db.Pool.Query(context.Background(), query)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, nil
-		}
 		return nil, fmt.Errorf("failed to query unprocessed driver events: %w", err)
 	}
 	defer rows.Close()
 	var events []models.DriverEvent
 	for rows.Next() {
 		var event models.DriverEvent
-		if err := rows.Scan(&event); err != nil {
+		if err := rows.Scan(
+			&event.ID,
+			&event.Version,
+			&event.SequenceIndex,
+			&event.Type,
+			&event.Timestamp,
+			&event.IsProcessed,
+			&event.BlockHash,
+			&event.StartBlockHash,
+			&event.EndBlockHash,
+			&event.VaultAddress,
+		); err != nil {
 			return nil, fmt.Errorf("failed to scan driver event: %w", err)
 		}
 		events = append(events, event)
@@ -100,9 +130,20 @@ func (db *DB) GetUnprocessedDriverEvents() ([]models.DriverEvent, error) {
 }
 
 func (db *DB) GetBlockByHash(blockHash string) (*models.StarknetBlock, error) {
-	query := `SELECT * FROM starknet_blocks WHERE block_hash = $1;`
+	query := `
+		SELECT block_number, timestamp, block_hash, parent_hash, status
+		FROM starknet_blocks
+		WHERE block_hash = $1;`
 	var block models.StarknetBlock
-	db.tx.QueryRow(context.Background(), query, blockHash).Scan(&block)
+	if err := db.tx.QueryRow(context.Background(), query, blockHash).Scan(
+		&block.BlockNumber,
+		&block.Timestamp,
+		&block.BlockHash,
+		&block.ParentHash,
+		&block.Status,
+	); err != nil {
+		return nil, fmt.Errorf("failed to get block %s: %w", blockHash, err)
+	}
 	return &block, nil
 }
 
@@ -117,19 +158,22 @@ func (db *DB) GetEventsForVault(vaultAddress string, startBlockHash string, endB
 		return nil, fmt.Errorf("failed to get end block: %w", err)
 	}
 	query := `
-		SELECT 
-			from,
-			event_nonce,
-			block_hash,
-			transaction_hash,
-			block_number,
-			vault_address,
-			timestamp,
-			event_name,
-			event_keys,
-			event_data
-		FROM events
-		WHERE vault_address = $1 AND block_number BETWEEN $2 AND $3;` //Including start and end block
+		SELECT
+			COALESCE(e.from_address, ''),
+			e.event_nonce,
+			e.block_hash,
+			e.transaction_hash,
+			e.block_number,
+			e.vault_address,
+			COALESCE(b.timestamp, 0),
+			e.event_name,
+			e.event_keys,
+			e.event_data
+		FROM events e
+		LEFT JOIN starknet_blocks b ON b.block_hash = e.block_hash
+		WHERE e.vault_address = $1 AND e.block_number BETWEEN $2 AND $3
+			AND NOT e.is_reverted
+		ORDER BY e.event_nonce ASC;` //Including start and end block, skipping abandoned branches
 
 	rows, err := db.tx.Query(context.Background(), query, vaultAddress, startBlock.BlockNumber, endBlock.BlockNumber)
 	if err != nil {
@@ -162,32 +206,39 @@ func (db *DB) GetEventsForVault(vaultAddress string, startBlockHash string, endB
 }
 
 func (db *DB) Shutdown() {
+	if db.tx != nil {
+		db.RollbackTx()
+	}
 	db.Pool.Close()
-	db.tx.Rollback(context.Background())
-	db.tx.Conn().Close(context.Background())
 	db.Conn.Close(context.Background())
 }
 
+// GetEventsByBlockHash returns the events of a block by nonce, orderBy is
+// "ASC" to apply them and "DESC" to revert them
 func (db *DB) GetEventsByBlockHash(blockHash string, orderBy string) ([]models.Event, error) {
+	direction := strings.ToUpper(orderBy)
+	if direction != "ASC" && direction != "DESC" {
+		return nil, fmt.Errorf("invalid event order %q", orderBy)
+	}
 
-
-	query := `
-		SELECT 
-			from,
-			event_nonce,
-			block_hash,
-			transaction_hash,
-			block_number,
-			vault_address,
-			timestamp,
-			event_name,
-			event_keys,
-			event_data
-		FROM events
-		WHERE block_hash = $1
-		ORDER BY event_nonce $2 ASC;`
-
-	rows, err := db.tx.Query(context.Background(), query, blockHash, orderBy)
+	query := fmt.Sprintf(`
+		SELECT
+			COALESCE(e.from_address, ''),
+			e.event_nonce,
+			e.block_hash,
+			e.transaction_hash,
+			e.block_number,
+			e.vault_address,
+			COALESCE(b.timestamp, 0),
+			e.event_name,
+			e.event_keys,
+			e.event_data
+		FROM events e
+		LEFT JOIN starknet_blocks b ON b.block_hash = e.block_hash
+		WHERE e.block_hash = $1
+		ORDER BY e.event_nonce %s;`, direction)
+
+	rows, err := db.tx.Query(context.Background(), query, blockHash)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query events by block number: %w", err)
 	}
@@ -197,6 +248,7 @@ func (db *DB) GetEventsByBlockHash(blockHash string, orderBy string) ([]models.E
 	for rows.Next() {
 		var event models.Event
 		if err := rows.Scan(
+			&event.From,
 			&event.EventNonce,
 			&event.BlockHash,
 			&event.TransactionHash,
@@ -221,7 +273,7 @@ func (db *DB) GetEventsByBlockHash(blockHash string, orderBy string) ([]models.E
 
 func (db *DB) CreateVault(vault *models.VaultState) error {
 	query := `
-		INSERT INTO vault_states (
+		INSERT INTO "VaultStates" (
 			address,
 			unlocked_balance,
 			locked_balance,
@@ -247,7 +299,7 @@ func (db *DB) CreateVault(vault *models.VaultState) error {
 
 func (db *DB) UpdateVaultBalanceAuctionStart(vaultAddress string, blockNumber uint64) error {
 	query := `
-		UPDATE vault_states
+		UPDATE "VaultStates"
 		SET
 			unlocked_balance = 0,
 			locked_balance = unlocked_balance,
@@ -278,7 +330,7 @@ func (db *DB) UpdateVaultBalancesAuctionEnd(
 	premiums models.BigInt,
 	blockNumber uint64) error {
 	query := `
-		UPDATE vault_states
+		UPDATE "VaultStates"
 		SET
 			unlocked_balance = unlocked_balance + ?,
 			locked_balance = locked_balance - ?,
@@ -307,7 +359,7 @@ func (db *DB) UpdateVaultBalancesAuctionEnd(
 
 func (db *DB) UpdateAllLiquidityProvidersBalancesAuctionStart(vaultAddress string, blockNumber uint64) error {
 
-	query := `UPDATE vault_states
+	query := `UPDATE "VaultStates"
 	SET
 		unlocked_balance = 0,
 		locked_balance = unlocked_balance,
@@ -344,7 +396,7 @@ func (db *DB) UpdateAllLiquidityProvidersBalancesAuctionEnd(
 	if startingLiquidity.Cmp(zero.Int) == 0 {
 		return nil
 	}
-	query := `UPDATE liquidity_provider_states
+	query := `UPDATE "Liquidity_Providers"
 	SET
 		locked_balance = locked_balance - FLOOR((locked_balance*?)/?),
 		unlocked_balance = unlocked_balance + FLOOR((locked_balance*?))/? + FLOOR((?*locked_balance)/?),
@@ -448,7 +500,7 @@ func (db *DB) UpdateVaultBalancesOptionSettle(
 	remainingLiquidityNotStashed models.BigInt,
 	blockNumber uint64,
 ) error {
-	query := `UPDATE vault_states SET
+	query := `UPDATE "VaultStates" SET
 		stashed_balance = stashed_balance + ?,
 		unlocked_balance = unlocked_balance + ?,
 		locked_balance = 0,
@@ -488,7 +540,7 @@ func (db *DB) UpdateAllLiquidityProvidersBalancesOptionSettle(
 	blockNumber uint64,
 ) error {
 
-	query := `UPDATE liquidity_provider_states SET
+	query := `UPDATE "Liquidity_Providers" SET
 	locked_balance = 0,
 	unlocked_balance = unlocked_balance + FLOOR(
 		CASE 
@@ -530,7 +582,7 @@ func (db *DB) UpdateAllLiquidityProvidersBalancesOptionSettle(
 	for _, queuedAmount := range queuedAmounts {
 
 		amountToAdd := &models.BigInt{Int: new(big.Int).Div(new(big.Int).Mul(remainingLiquidty.Int, queuedAmount.QueuedLiquidity.Int), (startingLiquidity.Int))}
-		query := `UPDATE liquidity_providers SET
+		query := `UPDATE "Liquidity_Providers" SET
 		stashed_balance = stashed_balance + $1,
 		unlocked_balance = unlocked_balance - $2
 		WHERE vault_address = $3 AND address = $4;`
@@ -553,13 +605,13 @@ func (db *DB) UpdateAllLiquidityProvidersBalancesOptionSettle(
 	/* Use this JOIN query to update this without creating 2 entries on the historic table
 	// Perform the update in a single query using JOINs and subqueries
 	err := db.Conn.Exec(`
-		UPDATE liquidity_provider_states lps
+		UPDATE "Liquidity_Providers" lps
 		JOIN (
 			SELECT
 				address,
 				queued_amount,
 				remaining_liquidity * queued_amount / ? AS amount_to_add
-			FROM queued_liquidity
+			FROM "Queued_Liquidity"
 			WHERE round_id = ?
 		) ql ON lps.address = ql.address AND lps.round_id = ?
 		SET
@@ -571,7 +623,7 @@ func (db *DB) UpdateAllLiquidityProvidersBalancesOptionSettle(
 	return nil
 }
 func (db *DB) GetVaultByAddress(address string) (*models.VaultState, error) {
-	query := `SELECT * FROM vault_states WHERE address = $1; LIMIT 1`
+	query := `SELECT * FROM "VaultStates" WHERE address = $1; LIMIT 1`
 	var vault models.VaultState
 
 	db.tx.QueryRow(context.Background(), query, address).Scan(&vault)
@@ -584,7 +636,7 @@ func (db *DB) GetVaultByAddress(address string) (*models.VaultState, error) {
 func (db *DB) GetVaultAddresses() ([]string, error) {
 	var addresses []string
 
-	query := `SELECT address FROM vault_states; `
+	query := `SELECT address FROM "VaultStates"; `
 	rows, err := db.tx.Query(context.Background(), query)
 	if err != nil {
 		return nil, err
@@ -613,7 +665,7 @@ func (db *DB) GetVaultAddresses() ([]string, error) {
 func (db *DB) GetRoundAddresses(vaultAddress string) (*[]string, error) {
 	var addresses []string
 
-	query := `SELECT address FROM option_rounds WHERE vault_address = $1;`
+	query := `SELECT address FROM "Option_Rounds" WHERE vault_address = $1;`
 	rows, err := db.tx.Query(context.Background(), query, vaultAddress)
 	if err != nil {
 		return nil, err
@@ -640,7 +692,7 @@ func (db *DB) GetRoundAddresses(vaultAddress string) (*[]string, error) {
 }
 func (db *DB) CreateOptionBuyer(buyer *models.OptionBuyer) error {
 	query := `
-		INSERT INTO option_buyers (
+		INSERT INTO "Option_Buyers" (
 			address,
 			round_address,
 			mintable_options,
@@ -674,7 +726,7 @@ func (db *DB) UpsertQueuedLiquidity(queuedLiquidity *models.QueuedLiquidity) err
 
 	// Perform upsert using GORM's Clauses with the transaction object
 	query := `
-		INSERT INTO queued_liquidity (
+		INSERT INTO "Queued_Liquidity" (
 			address,
 			round_address,
 			bps,
@@ -720,7 +772,7 @@ func (db *DB) UpsertLiquidityProviderState(lp *models.LiquidityProviderState, bl
 
 	// Perform upsert using GORM's Clauses with the transaction object
 	query := `
-		INSERT INTO liquidity_provider_states (
+		INSERT INTO "Liquidity_Providers" (
 			address,
 			vault_address,
 			unlocked_balance,
@@ -779,7 +831,7 @@ func (db *DB) UpdateOptionBuyerFields(
 
 	// Construct the query
 	query := fmt.Sprintf(`
-		UPDATE option_buyers
+		UPDATE "Option_Buyers"
 		SET %s
 		WHERE address = $%d AND round_address = $%d`,
 		strings.Join(setClause, ", "),
@@ -817,7 +869,7 @@ func (db *DB) UpdateAllOptionBuyerFields(roundAddress string, updates map[string
 
 	// Construct the query
 	query := fmt.Sprintf(`
-		UPDATE option_rounds
+		UPDATE "Option_Rounds"
 		SET %s
 		WHERE round_address = $%d`,
 		strings.Join(setClause, ", "),
@@ -863,7 +915,7 @@ func (db *DB) GetOptionRoundByAddress(address string) (*models.OptionRound, erro
 			premiums,
 			payout_per_option,
 			deployment_date
-		FROM option_rounds 
+		FROM "Option_Rounds" 
 		WHERE address = $1
 		LIMIT 1;`
 
@@ -917,7 +969,7 @@ func (db *DB) UpdateOptionRoundFields(address string, updates map[string]interfa
 
 	// Construct the query
 	query := fmt.Sprintf(`
-		UPDATE option_rounds
+		UPDATE "Option_Rounds"
 		SET %s
 		WHERE address = $%d`,
 		strings.Join(setClause, ", "),
@@ -952,7 +1004,7 @@ func (db *DB) UpdateVaultFields(address string, updates map[string]interface{})
 
 	// Construct the query
 	query := fmt.Sprintf(`
-		UPDATE vault_states
+		UPDATE "VaultStates"
 		SET %s
 		WHERE address = $%d`,
 		strings.Join(setClause, ", "),
@@ -987,7 +1039,7 @@ func (db *DB) UpdateLiquidityProviderFields(vaultAddress, address string, update
 
 	// Construct the query
 	query := fmt.Sprintf(`
-		UPDATE liquidity_provider_states
+		UPDATE "Liquidity_Providers"
 		SET %s
 		WHERE vault_address = $%d AND address = $%d`,
 		strings.Join(setClause, ", "),
@@ -1011,7 +1063,7 @@ func (db *DB) DeleteOptionRound(roundAddress string) error {
 	// }
 	// return nil
 
-	query := `DELETE FROM option_rounds WHERE address = $1`
+	query := `DELETE FROM "Option_Rounds" WHERE address = $1`
 
 	if _, err := db.tx.Exec(context.Background(), query, roundAddress); err != nil {
 		return fmt.Errorf("failed to delete option round: %w", err)
@@ -1029,7 +1081,7 @@ func (db *DB) CreateBid(bid *models.Bid) error {
 	// return nil
 
 	query := `
-		INSERT INTO bids (
+		INSERT INTO "Bids" (
 			buyer_address,
 			round_address,
 			bid_id,
@@ -1064,7 +1116,7 @@ func (db *DB) CreateOptionRound(round *models.OptionRound) error {
 	// return nil
 
 	query := `
-		INSERT INTO option_rounds (
+		INSERT INTO "Option_Rounds" (
 			address,
 			vault_address,
 			round_id,
@@ -1129,7 +1181,7 @@ func (db *DB) DeleteBid(bidID string, roundAddress string) error {
 	// }
 	// return nil
 
-	query := `DELETE FROM bids WHERE round_address = $1 AND bid_id = $2`
+	query := `DELETE FROM "Bids" WHERE round_address = $1 AND bid_id = $2`
 
 	if _, err := db.tx.Exec(context.Background(), query, roundAddress, bidID); err != nil {
 		return fmt.Errorf("failed to delete bid: %w", err)
@@ -1154,7 +1206,7 @@ func (db *DB) GetBidsForRound(roundAddress string) ([]models.Bid, error) {
 			tree_nonce,
 			amount,
 			price
-		FROM bids
+		FROM "Bids"
 		WHERE round_address = $1
 		ORDER BY price DESC, tree_nonce ASC;`
 
@@ -1212,7 +1264,7 @@ func (db *DB) GetBidsAboveClearingForRound(
 			tree_nonce,
 			amount,
 			price
-		FROM bids
+		FROM "Bids"
 		WHERE round_address = $1
 		AND (price > $2 OR (price = $3 AND tree_nonce <= $4))
 		ORDER BY price DESC, tree_nonce ASC;`
@@ -1277,7 +1329,7 @@ func (db *DB) GetBidsBelowClearingForRound(
 			tree_nonce,
 			amount,
 			price
-		FROM bids
+		FROM "Bids"
 		WHERE round_address = $1
 		AND (price < $2 OR (price = $3 AND tree_nonce > $4));`
 
@@ -1327,7 +1379,7 @@ func (db *DB) GetAllQueuedLiquidityForRound(roundAddress string) ([]models.Queue
 			round_address,
 			bps,
 			queued_liquidity
-		FROM queued_liquidity
+		FROM "Queued_Liquidity"
 		WHERE round_address=?`
 	rows, err := db.tx.Query(context.Background(), query, roundAddress)
 	if err != nil {
@@ -1388,8 +1440,8 @@ func (db *DB) RevertVaultState(address string, blockNumber uint64) error {
 	query := `
 		SELECT 
 			address 
-		FROM vault_states 
-		WHERE address = $1 AND last_block = $2 
+		FROM "VaultStates" 
+		WHERE address = $1 AND latest_block = $2 
 		LIMIT 1;`
 
 	var vaultAddress string
@@ -1403,7 +1455,7 @@ func (db *DB) RevertVaultState(address string, blockNumber uint64) error {
 
 	// Delete the historic record
 	deleteQuery := `
-		DELETE FROM vaults 
+		DELETE FROM "Vault_Historic"
 		WHERE address = $1 AND block_number = $2;`
 
 	if _, err := db.tx.Exec(context.Background(), deleteQuery, address, blockNumber); err != nil {
@@ -1417,7 +1469,7 @@ func (db *DB) RevertVaultState(address string, blockNumber uint64) error {
 			locked_balance,
 			stashed_balance,
 			block_number
-		FROM vaults 
+		FROM "Vault_Historic"
 		WHERE address = $1 
 		ORDER BY block_number DESC 
 		LIMIT 1;`
@@ -1440,7 +1492,7 @@ func (db *DB) RevertVaultState(address string, blockNumber uint64) error {
 
 	// Update the vault state
 	updateQuery := `
-		UPDATE vault_states 
+		UPDATE "VaultStates" 
 		SET 
 			unlocked_balance = $1,
 			locked_balance = $2,
@@ -1504,8 +1556,8 @@ func (db *DB) RevertAllLPState(vaultAddress string, blockNumber uint64) error {
 	query := `
 		SELECT 
 			address 
-		FROM liquidity_provider_states 
-		WHERE vault_address = $1 AND last_block = $2;`
+		FROM "Liquidity_Providers" 
+		WHERE vault_address = $1 AND latest_block = $2;`
 
 	rows, err := db.tx.Query(context.Background(), query, vaultAddress, blockNumber)
 	if err != nil {
@@ -1530,7 +1582,7 @@ func (db *DB) RevertAllLPState(vaultAddress string, blockNumber uint64) error {
 	for _, address := range lpAddresses {
 		// Delete the historic record
 		deleteQuery := `
-			DELETE FROM liquidity_providers 
+			DELETE FROM "Liquidity_Providers_Historic"
 			WHERE vault_address = $1 AND address = $2 AND block_number = $3;`
 
 		if _, err := db.tx.Exec(context.Background(), deleteQuery, vaultAddress, address, blockNumber); err != nil {
@@ -1544,7 +1596,7 @@ func (db *DB) RevertAllLPState(vaultAddress string, blockNumber uint64) error {
 				locked_balance,
 				stashed_balance,
 				block_number
-			FROM liquidity_providers 
+			FROM "Liquidity_Providers_Historic"
 			WHERE vault_address = $1 AND address = $2
 			ORDER BY block_number DESC 
 			LIMIT 1;`
@@ -1560,14 +1612,18 @@ func (db *DB) RevertAllLPState(vaultAddress string, blockNumber uint64) error {
 		)
 		if err != nil {
 			if err == pgx.ErrNoRows {
-				continue // Skip if no record found
+				// The liquidity provider was created in the reverted block
+				if err := db.deleteLPState(vaultAddress, address); err != nil {
+					return err
+				}
+				continue
 			}
 			return fmt.Errorf("failed to get latest LP: %w", err)
 		}
 
 		// Update the LP state
 		updateQuery := `
-			UPDATE liquidity_provider_states 
+			UPDATE "Liquidity_Providers" 
 			SET 
 				unlocked_balance = $1,
 				locked_balance = $2,
@@ -1631,8 +1687,8 @@ func (db *DB) RevertLPState(vaultAddress, address string, blockNumber uint64) er
 	query := `
 		SELECT 
 			address 
-		FROM liquidity_provider_states 
-		WHERE vault_address = $1 AND address = $2 AND last_block = $3 
+		FROM "Liquidity_Providers" 
+		WHERE vault_address = $1 AND address = $2 AND latest_block = $3 
 		LIMIT 1;`
 
 	var lpAddress string
@@ -1646,7 +1702,7 @@ func (db *DB) RevertLPState(vaultAddress, address string, blockNumber uint64) er
 
 	// Delete the historic record
 	deleteQuery := `
-		DELETE FROM liquidity_providers 
+		DELETE FROM "Liquidity_Providers_Historic"
 		WHERE vault_address = $1 AND address = $2 AND block_number = $3;`
 
 	if _, err := db.tx.Exec(context.Background(), deleteQuery, vaultAddress, address, blockNumber); err != nil {
@@ -1660,7 +1716,7 @@ func (db *DB) RevertLPState(vaultAddress, address string, blockNumber uint64) er
 			locked_balance,
 			stashed_balance,
 			block_number
-		FROM liquidity_providers 
+		FROM "Liquidity_Providers_Historic"
 		WHERE vault_address = $1 AND address = $2
 		ORDER BY block_number DESC 
 		LIMIT 1;`
@@ -1676,14 +1732,15 @@ func (db *DB) RevertLPState(vaultAddress, address string, blockNumber uint64) er
 	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil
+			// The liquidity provider was created in the reverted block
+			return db.deleteLPState(vaultAddress, address)
 		}
 		return fmt.Errorf("failed to get latest LP: %w", err)
 	}
 
 	// Update the LP state
 	updateQuery := `
-		UPDATE liquidity_provider_states 
+		UPDATE "Liquidity_Providers" 
 		SET 
 			unlocked_balance = $1,
 			locked_balance = $2,
@@ -1707,4 +1764,12 @@ func (db *DB) RevertLPState(vaultAddress, address string, blockNumber uint64) er
 	return nil
 }
 
+func (db *DB) deleteLPState(vaultAddress, address string) error {
+	query := `DELETE FROM "Liquidity_Providers" WHERE vault_address = $1 AND address = $2;`
+	if _, err := db.tx.Exec(context.Background(), query, vaultAddress, address); err != nil {
+		return fmt.Errorf("failed to delete LP state: %w", err)
+	}
+	return nil
+}
+
 // GetAllOptionBuyers retrieves all OptionBuyer records from the database
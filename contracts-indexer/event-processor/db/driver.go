@@ -1,7 +1,9 @@
 package db
 
 import (
+	"driverevents"
 	"event-processor/models"
+	"fmt"
 )
 
 func (db *DB) CatchupDriverEvents() error {
@@ -25,12 +27,22 @@ func (db *DB) CatchupDriverEvents() error {
 	return nil
 }
 
+// processDriverEvent applies the events a driver event points to and marks it
+// processed. An event that does not follow the driverevents contract is an
+// error and stays unprocessed, so that a type or version mismatch with the
+// event-logger stops processing instead of skipping blocks.
 func (db *DB) processDriverEvent(driverEventData models.DriverEvent) error {
+	if err := driverEventData.Validate(); err != nil {
+		db.logger.Printf("Rejecting driver event %d: %v", driverEventData.ID, err)
+		return err
+	}
+
 	db.BeginTx()
 	switch driverEventData.Type {
-	case "NewBlock":
+	case driverevents.TypeStartBlock:
 		events, err := db.GetEventsByBlockHash(driverEventData.BlockHash, "ASC")
 		if err != nil {
+			db.RollbackTx()
 			db.logger.Printf("Error getting events by block number: %v", err)
 			return err
 		}
@@ -43,9 +55,10 @@ func (db *DB) processDriverEvent(driverEventData models.DriverEvent) error {
 			}
 		}
 
-	case "RevertBlock":
+	case driverevents.TypeRevertBlock:
 		events, err := db.GetEventsByBlockHash(driverEventData.BlockHash, "DESC")
 		if err != nil {
+			db.RollbackTx()
 			db.logger.Printf("Error getting events by block number: %v", err)
 			return err
 		}
@@ -58,9 +71,10 @@ func (db *DB) processDriverEvent(driverEventData models.DriverEvent) error {
 			}
 		}
 
-	case "CatchupVault":
+	case driverevents.TypeCatchupVault:
 		events, err := db.GetEventsForVault(driverEventData.VaultAddress, driverEventData.StartBlockHash, driverEventData.EndBlockHash)
 		if err != nil {
+			db.RollbackTx()
 			db.logger.Printf("Error getting events by block number: %v", err)
 			return err
 		}
@@ -72,8 +86,17 @@ func (db *DB) processDriverEvent(driverEventData models.DriverEvent) error {
 				return err
 			}
 		}
+
+	default:
+		db.RollbackTx()
+		return fmt.Errorf("%w: %q (driver event %d)", driverevents.ErrUnknownType, driverEventData.Type, driverEventData.ID)
+	}
+	if err := db.MarkDriverEventAsProcessed(driverEventData.ID); err != nil {
+		db.RollbackTx()
+		return err
+	}
+	if err := db.CommitTx(); err != nil {
+		return fmt.Errorf("failed to commit driver event %d: %w", driverEventData.ID, err)
 	}
-	db.MarkDriverEventAsProcessed(driverEventData.ID)
-	db.CommitTx()
 	return nil
 }
@@ -0,0 +1,323 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"driverevents"
+	"event-processor/adaptors"
+	"event-processor/models"
+	"eventabi"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	testVault = "0x1"
+	testRound = "0x2"
+)
+
+// testDB resets the database at TEST_DB_URL to the migrations of the
+// event-logger and of this processor, and connects to it. It loads the
+// contract classes of eventabi/testdata to decode the events with.
+func testDB(t *testing.T) *DB {
+	t.Helper()
+	url := os.Getenv("TEST_DB_URL")
+	if url == "" {
+		t.Skip("TEST_DB_URL is not set")
+	}
+	ctx := context.Background()
+
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer pool.Close()
+	if _, err := pool.Exec(ctx, `DROP SCHEMA public CASCADE; CREATE SCHEMA public;`); err != nil {
+		t.Fatalf("Failed to reset the database: %v", err)
+	}
+	for _, dir := range []string{"../../event-logger/db/migrations", "migrations"} {
+		files, err := filepath.Glob(filepath.Join(dir, "*.up.sql"))
+		if err != nil || len(files) == 0 {
+			t.Fatalf("No migrations in %s: %v", dir, err)
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			migration, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := pool.Exec(ctx, string(migration)); err != nil {
+				t.Fatalf("Failed to apply %s: %v", file, err)
+			}
+		}
+	}
+
+	abis, err := filepath.Glob("../../eventabi/testdata/*.contract_class.json")
+	if err != nil || len(abis) == 0 {
+		t.Fatalf("No contract classes: %v", err)
+	}
+	if err := adaptors.LoadABIs(abis...); err != nil {
+		t.Fatalf("Failed to load ABIs: %v", err)
+	}
+
+	db := &DB{}
+	if err := db.Connect(url); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(db.Shutdown)
+	return db
+}
+
+func mustExec(t *testing.T, db *DB, query string, args ...any) {
+	t.Helper()
+	if _, err := db.Pool.Exec(context.Background(), query, args...); err != nil {
+		t.Fatalf("Failed to execute %q: %v", query, err)
+	}
+}
+
+// insertBlock stores a block and its events as the event-logger does
+func insertBlock(t *testing.T, db *DB, number uint64, hash string, events ...models.Event) {
+	t.Helper()
+	mustExec(t, db, `
+		INSERT INTO starknet_blocks (block_number, block_hash, parent_hash, timestamp, status)
+		VALUES ($1, $2, $3, $4, 'MINED')`,
+		number, hash, fmt.Sprintf("0xparent%d", number), 1000+number)
+	for _, event := range events {
+		mustExec(t, db, `
+			INSERT INTO events (event_nonce, block_number, block_hash, vault_address, event_name,
+				event_keys, event_data, transaction_hash, from_address, event_index)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 0)`,
+			event.EventNonce, number, hash, event.VaultAddress, event.EventName,
+			event.EventKeys, event.EventData, event.TransactionHash, event.From)
+	}
+}
+
+func insertDriverEvent(t *testing.T, db *DB, sequenceIndex int, eventType driverevents.Type, blockHash string) {
+	t.Helper()
+	mustExec(t, db, `
+		INSERT INTO driver_events (sequence_index, type, block_hash, version)
+		VALUES ($1, $2, $3, $4)`,
+		sequenceIndex, string(eventType), blockHash, driverevents.Version)
+}
+
+// u256 encodes a u256 as its low and high felts
+func u256(value int64) []string {
+	return []string{fmt.Sprintf("0x%x", value), "0x0"}
+}
+
+func depositEvent(nonce uint64, account string, accountUnlocked, vaultUnlocked int64) models.Event {
+	data := u256(accountUnlocked)
+	data = append(data, u256(accountUnlocked)...)
+	data = append(data, u256(vaultUnlocked)...)
+	return models.Event{
+		From:            testVault,
+		TransactionHash: fmt.Sprintf("0xt%d", nonce),
+		VaultAddress:    testVault,
+		EventNonce:      nonce,
+		EventName:       "Deposit",
+		EventKeys:       []string{eventabi.Selector("Deposit"), account},
+		EventData:       data,
+	}
+}
+
+func bidPlacedEvent(nonce uint64, account, bidID string, amount, price int64, treeNonceNow uint64) models.Event {
+	data := []string{bidID}
+	data = append(data, u256(amount)...)
+	data = append(data, u256(price)...)
+	data = append(data, fmt.Sprintf("0x%x", treeNonceNow))
+	return models.Event{
+		From:            testRound,
+		TransactionHash: fmt.Sprintf("0xt%d", nonce),
+		VaultAddress:    testVault,
+		EventNonce:      nonce,
+		EventName:       "BidPlaced",
+		EventKeys:       []string{eventabi.Selector("BidPlaced"), account},
+		EventData:       data,
+	}
+}
+
+func bidUpdatedEvent(nonce uint64, account, bidID string, priceIncrease int64, treeNonceBefore, treeNonceNow uint64) models.Event {
+	data := []string{bidID}
+	data = append(data, u256(priceIncrease)...)
+	data = append(data, fmt.Sprintf("0x%x", treeNonceBefore), fmt.Sprintf("0x%x", treeNonceNow))
+	return models.Event{
+		From:            testRound,
+		TransactionHash: fmt.Sprintf("0xt%d", nonce),
+		VaultAddress:    testVault,
+		EventNonce:      nonce,
+		EventName:       "BidUpdated",
+		EventKeys:       []string{eventabi.Selector("BidUpdated"), account},
+		EventData:       data,
+	}
+}
+
+func lpState(t *testing.T, db *DB, account string) (unlocked string, latestBlock uint64) {
+	t.Helper()
+	if err := db.Pool.QueryRow(context.Background(), `
+		SELECT unlocked_balance::text, latest_block
+		FROM "Liquidity_Providers"
+		WHERE vault_address = $1 AND address = $2`,
+		testVault, account,
+	).Scan(&unlocked, &latestBlock); err != nil {
+		t.Fatalf("Failed to get liquidity provider %s: %v", account, err)
+	}
+	return unlocked, latestBlock
+}
+
+func bidState(t *testing.T, db *DB, bidID string) (price, treeNonce string) {
+	t.Helper()
+	if err := db.Pool.QueryRow(context.Background(), `
+		SELECT price::text, tree_nonce::text
+		FROM "Bids"
+		WHERE round_address = $1 AND bid_id = $2`,
+		testRound, bidID,
+	).Scan(&price, &treeNonce); err != nil {
+		t.Fatalf("Failed to get bid %s: %v", bidID, err)
+	}
+	return price, treeNonce
+}
+
+func vaultState(t *testing.T, db *DB) (unlocked string, latestBlock uint64) {
+	t.Helper()
+	if err := db.Pool.QueryRow(context.Background(), `
+		SELECT unlocked_balance::text, latest_block
+		FROM "VaultStates"
+		WHERE address = $1`,
+		testVault,
+	).Scan(&unlocked, &latestBlock); err != nil {
+		t.Fatalf("Failed to get vault: %v", err)
+	}
+	return unlocked, latestBlock
+}
+
+func TestGetEventsByBlockHashRejectsOrder(t *testing.T) {
+	db := &DB{}
+	for _, order := range []string{"", "ASC;", "event_nonce", "DESC, 1"} {
+		if _, err := db.GetEventsByBlockHash("0xa", order); err == nil {
+			t.Errorf("Expected an error for order %q", order)
+		}
+	}
+}
+
+func TestStartBlockAppliesEventsInNonceOrder(t *testing.T) {
+	db := testDB(t)
+	mustExec(t, db, `
+		INSERT INTO "VaultStates" (address, unlocked_balance, locked_balance, stashed_balance, latest_block)
+		VALUES ($1, 0, 0, 0, 0)`, testVault)
+
+	// Stored out of order, the second deposit must be applied last
+	insertBlock(t, db, 10, "0xb10",
+		depositEvent(2, "0xdef", 3, 8),
+		depositEvent(1, "0xabc", 5, 5),
+	)
+	insertDriverEvent(t, db, 1, driverevents.TypeStartBlock, "0xb10")
+
+	if err := db.CatchupDriverEvents(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if unlocked, block := lpState(t, db, "0xabc"); unlocked != "5" || block != 10 {
+		t.Errorf("Expected 0xabc to have 5 unlocked at block 10, got %s at block %d", unlocked, block)
+	}
+	if unlocked, block := lpState(t, db, "0xdef"); unlocked != "3" || block != 10 {
+		t.Errorf("Expected 0xdef to have 3 unlocked at block 10, got %s at block %d", unlocked, block)
+	}
+	if unlocked, block := vaultState(t, db); unlocked != "8" || block != 10 {
+		t.Errorf("Expected the vault to have 8 unlocked at block 10, got %s at block %d", unlocked, block)
+	}
+
+	events, err := db.GetUnprocessedDriverEvents()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Expected the driver event to be processed, got %+v", events)
+	}
+}
+
+func TestRevertBlockUndoesEventsInReverseNonceOrder(t *testing.T) {
+	db := testDB(t)
+	mustExec(t, db, `
+		INSERT INTO "VaultStates" (address, unlocked_balance, locked_balance, stashed_balance, latest_block)
+		VALUES ($1, 0, 0, 0, 0)`, testVault)
+
+	insertBlock(t, db, 10, "0xb10",
+		depositEvent(1, "0xabc", 5, 5),
+		bidPlacedEvent(2, "0xb0b", "0xb1d1", 2, 3, 1),
+	)
+	insertDriverEvent(t, db, 1, driverevents.TypeStartBlock, "0xb10")
+	// 0xabc deposits again, 0xdef deposits for the first time, 0xb0b raises
+	// its bid and places another one
+	insertBlock(t, db, 11, "0xb11",
+		depositEvent(3, "0xabc", 7, 7),
+		depositEvent(4, "0xdef", 4, 11),
+		bidUpdatedEvent(5, "0xb0b", "0xb1d1", 4, 1, 2),
+		bidPlacedEvent(6, "0xb0b", "0xb1d2", 1, 5, 3),
+	)
+	insertDriverEvent(t, db, 2, driverevents.TypeStartBlock, "0xb11")
+	if err := db.CatchupDriverEvents(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if unlocked, block := vaultState(t, db); unlocked != "11" || block != 11 {
+		t.Fatalf("Expected the vault to have 11 unlocked at block 11, got %s at block %d", unlocked, block)
+	}
+	if price, treeNonce := bidState(t, db, "0xb1d1"); price != "7" || treeNonce != "1" {
+		t.Fatalf("Expected 0xb1d1 to have a price of 7 and tree nonce 1, got %s and %s", price, treeNonce)
+	}
+
+	db.BeginTx()
+	events, err := db.GetEventsByBlockHash("0xb11", "DESC")
+	db.RollbackTx()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("Expected the 4 events of the block, got %+v", events)
+	}
+	for i, event := range events {
+		if event.EventNonce != uint64(6-i) {
+			t.Fatalf("Expected the events of the block by descending nonce, got %+v", events)
+		}
+	}
+	if events[0].Timestamp != 1011 {
+		t.Errorf("Expected the timestamp of the block, got %d", events[0].Timestamp)
+	}
+
+	mustExec(t, db, `UPDATE events SET is_reverted = true WHERE block_hash = '0xb11'`)
+	insertDriverEvent(t, db, 3, driverevents.TypeRevertBlock, "0xb11")
+	if err := db.CatchupDriverEvents(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if unlocked, block := vaultState(t, db); unlocked != "5" || block != 10 {
+		t.Errorf("Expected the vault to have 5 unlocked at block 10, got %s at block %d", unlocked, block)
+	}
+	if unlocked, block := lpState(t, db, "0xabc"); unlocked != "5" || block != 10 {
+		t.Errorf("Expected 0xabc to have 5 unlocked at block 10, got %s at block %d", unlocked, block)
+	}
+	var count int
+	if err := db.Pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM "Liquidity_Providers" WHERE address = '0xdef'`,
+	).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("Expected 0xdef, created in the reverted block, to be deleted")
+	}
+	if price, treeNonce := bidState(t, db, "0xb1d1"); price != "3" || treeNonce != "0" {
+		t.Errorf("Expected 0xb1d1 to have a price of 3 and tree nonce 0, got %s and %s", price, treeNonce)
+	}
+	if err := db.Pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM "Bids" WHERE bid_id = '0xb1d2'`,
+	).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("Expected 0xb1d2, placed in the reverted block, to be deleted")
+	}
+}
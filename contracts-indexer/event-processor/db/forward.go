@@ -252,7 +252,7 @@ func (db *DB) BidUpdatedIndex(roundAddress, bidId string, price models.BigInt, t
 	// return nil
 
 	query := `
-		UPDATE bids
+		UPDATE "Bids"
 		SET 
 			price = price + $1,
 			tree_nonce = $2
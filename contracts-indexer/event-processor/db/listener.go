@@ -2,7 +2,7 @@ package db
 
 import (
 	"context"
-	"encoding/json"
+	"driverevents"
 	"event-processor/adaptors"
 	"event-processor/models"
 	"fmt"
@@ -10,8 +10,11 @@ import (
 	"math/big"
 )
 
-func (db *DB) Listener() {
-	_, err := db.Conn.Exec(db.ctx, "LISTEN driver_events")
+// Listener applies driver events as the event-logger emits them. It returns
+// on the first event that cannot be parsed or processed, leaving it
+// unprocessed for the next start.
+func (db *DB) Listener() error {
+	_, err := db.Conn.Exec(db.ctx, "LISTEN "+driverevents.Channel)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -25,19 +28,15 @@ func (db *DB) Listener() {
 			log.Fatal(err)
 		}
 
-		var driverEventData models.DriverEvent
-		err = json.Unmarshal([]byte(notification.Payload), &driverEventData)
+		driverEventData, err := driverevents.Parse([]byte(notification.Payload))
 		if err != nil {
-			log.Printf("Error parsing driver_events payload: %v", err)
-			return
+			return fmt.Errorf("invalid driver_events payload %s: %w", notification.Payload, err)
 		}
 		fmt.Println("Received an update on driver_events")
 		err = db.processDriverEvent(driverEventData)
 		if err != nil {
-			log.Printf("Error processing driver_events: %v", err)
-			return
+			return fmt.Errorf("error processing driver event %d: %w", driverEventData.ID, err)
 		}
-		//Process notification here
 	}
 }
 
@@ -45,16 +44,20 @@ func (db *DB) revertVaultEvent(
 	event models.Event,
 ) error {
 
-	junoEvent := adaptors.GetJunoEvent(event)
 	var err error
 	switch event.EventName {
 	case "ContractDeployed":
-	case "Deposit", "Withdraw":
-
-		lpAddress, _, _ := adaptors.DepositOrWithdraw(junoEvent)
+	case "Deposit", "Withdrawal":
+		lpAddress, _, _, decodeErr := adaptors.DepositOrWithdraw(event)
+		if decodeErr != nil {
+			return decodeErr
+		}
 		err = db.DepositOrWithdrawOrStashWithdrawRevert(event.VaultAddress, lpAddress, event.BlockNumber)
 	case "StashWithdrawn":
-		lpAddress, _, _ := adaptors.StashWithdrawn(junoEvent)
+		lpAddress, _, _, decodeErr := adaptors.StashWithdrawn(event)
+		if decodeErr != nil {
+			return decodeErr
+		}
 		err = db.DepositOrWithdrawOrStashWithdrawRevert(event.VaultAddress, lpAddress, event.BlockNumber)
 	case "WithdrawalQueued":
 		lpAddress,
@@ -62,7 +65,11 @@ func (db *DB) revertVaultEvent(
 			roundId,
 			accountQueuedBefore,
 			accountQueuedNow,
-			vaultQueuedNow := adaptors.WithdrawalQueued(junoEvent)
+			vaultQueuedNow,
+			decodeErr := adaptors.WithdrawalQueued(event)
+		if decodeErr != nil {
+			return decodeErr
+		}
 
 		err = db.WithdrawalQueuedRevertIndex(
 			lpAddress,
@@ -75,11 +82,17 @@ func (db *DB) revertVaultEvent(
 			event.BlockNumber,
 		)
 	case "OptionRoundDeployed":
-		roundAddress := adaptors.FeltToHexString(junoEvent.Data[2].Bytes())
-		err = db.DeleteOptionRound(roundAddress)
+		optionRound, decodeErr := adaptors.RoundDeployed(event)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		err = db.DeleteOptionRound(optionRound.Address)
 
 	case "AuctionStarted":
-		_, _, roundAddress := adaptors.AuctionStarted(junoEvent)
+		_, _, roundAddress, err := adaptors.AuctionStarted(event)
+		if err != nil {
+			return err
+		}
 		prevStateOptionRound, err := db.GetOptionRoundByAddress(roundAddress)
 		if err != nil {
 			return err
@@ -89,7 +102,10 @@ func (db *DB) revertVaultEvent(
 			return err
 		}
 	case "AuctionEnded":
-		_, _, _, _, _, roundAddress := adaptors.AuctionEnded(junoEvent)
+		_, _, _, _, _, roundAddress, err := adaptors.AuctionEnded(event)
+		if err != nil {
+			return err
+		}
 		prevStateOptionRound, err := db.GetOptionRoundByAddress(roundAddress)
 		if err != nil {
 			return err
@@ -99,7 +115,10 @@ func (db *DB) revertVaultEvent(
 			return err
 		}
 	case "OptionRoundSettled":
-		_, _, roundAddress := adaptors.OptionRoundSettled(junoEvent)
+		_, _, roundAddress, err := adaptors.OptionRoundSettled(event)
+		if err != nil {
+			return err
+		}
 		prevStateOptionRound, err := db.GetOptionRoundByAddress(roundAddress)
 		if err != nil {
 			return err
@@ -109,13 +128,22 @@ func (db *DB) revertVaultEvent(
 			return err
 		}
 	case "BidPlaced":
-		bid, _ := adaptors.BidPlaced(junoEvent)
+		bid, _, decodeErr := adaptors.BidPlaced(event)
+		if decodeErr != nil {
+			return decodeErr
+		}
 		err = db.BidPlacedRevert(bid.BidID, bid.RoundAddress)
 	case "BidUpdated":
-		bidId, amount, treeNonceOld, _, roundAddress := adaptors.BidUpdated(junoEvent)
-		err = db.BidUpdatedRevert(bidId, roundAddress, amount, treeNonceOld)
+		bidId, priceIncrease, treeNonceBefore, _, roundAddress, decodeErr := adaptors.BidUpdated(event)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		err = db.BidUpdatedRevert(bidId, roundAddress, priceIncrease, treeNonceBefore)
 	case "OptionsMinted":
-		buyerAddress, _, roundAddress := adaptors.OptionsMinted(junoEvent)
+		buyerAddress, _, roundAddress, decodeErr := adaptors.OptionsMinted(event)
+		if decodeErr != nil {
+			return decodeErr
+		}
 		err = db.UpdateOptionBuyerFields(
 			buyerAddress,
 			roundAddress,
@@ -123,7 +151,10 @@ func (db *DB) revertVaultEvent(
 				"has_minted": false,
 			})
 	case "OptionsExercised":
-		buyerAddress, _, mintableOptionsExercised, _, roundAddress := adaptors.OptionsExercised(junoEvent)
+		buyerAddress, _, mintableOptionsExercised, _, roundAddress, decodeErr := adaptors.OptionsExercised(event)
+		if decodeErr != nil {
+			return decodeErr
+		}
 
 		zero := models.BigInt{
 			Int: big.NewInt(0),
@@ -137,7 +168,10 @@ func (db *DB) revertVaultEvent(
 				})
 		}
 	case "UnusedBidsRefunded":
-		buyerAddress, _, roundAddress := adaptors.UnusedBidsRefunded(junoEvent)
+		buyerAddress, _, roundAddress, decodeErr := adaptors.UnusedBidsRefunded(event)
+		if decodeErr != nil {
+			return decodeErr
+		}
 		err = db.UpdateOptionBuyerFields(
 			buyerAddress,
 			roundAddress,
@@ -157,19 +191,26 @@ func (db *DB) processVaultEvent(
 ) error {
 
 	var err error
-	junoEvent := adaptors.GetJunoEvent(event)
 	switch event.EventName {
 	case "Deposit": //Add withdrawQueue and collect queue case based on event
 		lpAddress,
 			lpUnlocked,
-			vaultUnlocked := adaptors.DepositOrWithdraw(junoEvent)
+			vaultUnlocked,
+			decodeErr := adaptors.DepositOrWithdraw(event)
+		if decodeErr != nil {
+			return decodeErr
+		}
 
 		err = db.DepositIndex(event.VaultAddress, lpAddress, lpUnlocked, vaultUnlocked, event.BlockNumber)
 		//Map the other parameters as well
 	case "Withdrawal":
 		lpAddress,
 			lpUnlocked,
-			vaultUnlocked := adaptors.DepositOrWithdraw(junoEvent)
+			vaultUnlocked,
+			decodeErr := adaptors.DepositOrWithdraw(event)
+		if decodeErr != nil {
+			return decodeErr
+		}
 
 		err = db.WithdrawIndex(event.VaultAddress, lpAddress, lpUnlocked, vaultUnlocked, event.BlockNumber)
 	case "WithdrawalQueued":
@@ -178,7 +219,11 @@ func (db *DB) processVaultEvent(
 			roundId,
 			accountQueuedBefore,
 			accountQueuedNow,
-			vaultQueuedNow := adaptors.WithdrawalQueued(junoEvent)
+			vaultQueuedNow,
+			decodeErr := adaptors.WithdrawalQueued(event)
+		if decodeErr != nil {
+			return decodeErr
+		}
 
 		err = db.WithdrawalQueuedIndex(
 			lpAddress,
@@ -191,7 +236,10 @@ func (db *DB) processVaultEvent(
 		)
 
 	case "StashWithdrawn":
-		lpAddress, amount, vaultStashed := adaptors.StashWithdrawn(junoEvent)
+		lpAddress, amount, vaultStashed, decodeErr := adaptors.StashWithdrawn(event)
+		if decodeErr != nil {
+			return decodeErr
+		}
 		err = db.StashWithdrawnIndex(
 			event.VaultAddress,
 			lpAddress,
@@ -200,15 +248,23 @@ func (db *DB) processVaultEvent(
 			event.BlockNumber,
 		)
 	case "OptionRoundDeployed":
-
-		optionRound := adaptors.RoundDeployed(junoEvent)
+		optionRound, decodeErr := adaptors.RoundDeployed(event)
+		if decodeErr != nil {
+			return decodeErr
+		}
 		optionRound.DeploymentDate = event.Timestamp
 		err = db.RoundDeployedIndex(optionRound)
 	case "PricingDataSet":
-		strikePrice, capLevel, reservePrice, roundAddress := adaptors.PricingDataSet(junoEvent)
+		strikePrice, capLevel, reservePrice, roundAddress, decodeErr := adaptors.PricingDataSet(event)
+		if decodeErr != nil {
+			return decodeErr
+		}
 		err = db.PricingDataSetIndex(roundAddress, strikePrice, capLevel, reservePrice)
 	case "AuctionStarted":
-		availableOptions, startingLiquidity, roundAddress := adaptors.AuctionStarted(junoEvent)
+		availableOptions, startingLiquidity, roundAddress, decodeErr := adaptors.AuctionStarted(event)
+		if decodeErr != nil {
+			return decodeErr
+		}
 		err = db.AuctionStartedIndex(
 			event.VaultAddress,
 			roundAddress,
@@ -222,7 +278,11 @@ func (db *DB) processVaultEvent(
 			unsoldLiquidity,
 			clearingNonce,
 			premiums,
-			roundAddress := adaptors.AuctionEnded(junoEvent)
+			roundAddress,
+			err := adaptors.AuctionEnded(event)
+		if err != nil {
+			return err
+		}
 
 		prevStateOptionRound, err := db.GetOptionRoundByAddress(roundAddress)
 		if err != nil {
@@ -241,7 +301,10 @@ func (db *DB) processVaultEvent(
 			return err
 		}
 	case "OptionRoundSettled":
-		settlementPrice, payoutPerOption, roundAddress := adaptors.OptionRoundSettled(junoEvent)
+		settlementPrice, payoutPerOption, roundAddress, err := adaptors.OptionRoundSettled(event)
+		if err != nil {
+			return err
+		}
 		prevStateOptionRound, err := db.GetOptionRoundByAddress(roundAddress)
 		if err != nil {
 			return err
@@ -257,13 +320,22 @@ func (db *DB) processVaultEvent(
 			return err
 		}
 	case "BidPlaced":
-		bid, buyer := adaptors.BidPlaced(junoEvent)
+		bid, buyer, decodeErr := adaptors.BidPlaced(event)
+		if decodeErr != nil {
+			return decodeErr
+		}
 		err = db.BidPlacedIndex(bid, buyer)
 	case "BidUpdated":
-		bidId, price, _, treeNonceNew, roundAddress := adaptors.BidUpdated(junoEvent)
+		bidId, price, _, treeNonceNew, roundAddress, decodeErr := adaptors.BidUpdated(event)
+		if decodeErr != nil {
+			return decodeErr
+		}
 		err = db.BidUpdatedIndex(roundAddress, bidId, price, treeNonceNew)
 	case "OptionsMinted":
-		buyerAddress, _, roundAddress := adaptors.OptionsMinted(junoEvent)
+		buyerAddress, _, roundAddress, decodeErr := adaptors.OptionsMinted(event)
+		if decodeErr != nil {
+			return decodeErr
+		}
 
 		err = db.UpdateOptionBuyerFields(
 			buyerAddress,
@@ -272,7 +344,10 @@ func (db *DB) processVaultEvent(
 				"has_minted": true,
 			})
 	case "OptionsExercised":
-		buyerAddress, _, _, _, roundAddress := adaptors.OptionsExercised(junoEvent)
+		buyerAddress, _, _, _, roundAddress, decodeErr := adaptors.OptionsExercised(event)
+		if decodeErr != nil {
+			return decodeErr
+		}
 		err = db.UpdateOptionBuyerFields(
 			buyerAddress,
 			roundAddress,
@@ -280,7 +355,10 @@ func (db *DB) processVaultEvent(
 				"has_minted": true,
 			})
 	case "UnusedBidsRefunded":
-		buyerAddress, _, roundAddress := adaptors.UnusedBidsRefunded(junoEvent)
+		buyerAddress, _, roundAddress, decodeErr := adaptors.UnusedBidsRefunded(event)
+		if decodeErr != nil {
+			return decodeErr
+		}
 		err = db.UpdateOptionBuyerFields(
 			buyerAddress,
 			roundAddress,
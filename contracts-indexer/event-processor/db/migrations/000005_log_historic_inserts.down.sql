@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS lp_log_insert ON public."Liquidity_Providers";
+DROP TRIGGER IF EXISTS vault_log_insert ON public."VaultStates";
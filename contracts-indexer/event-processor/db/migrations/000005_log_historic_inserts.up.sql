@@ -0,0 +1,32 @@
+-- The historic tables hold every state of a liquidity provider and a vault,
+-- the first one included, so that reverting a block can tell a row created in
+-- it from one updated in it.
+INSERT INTO "Liquidity_Providers_Historic" (
+    address, vault_address, stashed_balance, locked_balance, unlocked_balance, block_number
+)
+SELECT address, vault_address, stashed_balance, locked_balance, unlocked_balance, latest_block
+FROM "Liquidity_Providers"
+WHERE latest_block IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+INSERT INTO "Vault_Historic" (
+    address, unlocked_balance, locked_balance, stashed_balance, block_number
+)
+SELECT address, unlocked_balance, locked_balance, stashed_balance, latest_block
+FROM "VaultStates"
+WHERE latest_block IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+CREATE TRIGGER lp_log_insert
+AFTER INSERT
+ON public."Liquidity_Providers"
+FOR EACH ROW
+WHEN (NEW.latest_block IS NOT NULL)
+EXECUTE FUNCTION public.log_lp_update();
+
+CREATE TRIGGER vault_log_insert
+AFTER INSERT
+ON public."VaultStates"
+FOR EACH ROW
+WHEN (NEW.latest_block IS NOT NULL)
+EXECUTE FUNCTION public.log_vault_update();
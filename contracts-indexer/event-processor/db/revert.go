@@ -1,7 +1,9 @@
 package db
 
 import (
+	"context"
 	"event-processor/models"
+	"fmt"
 )
 
 func (db *DB) DepositOrWithdrawOrStashWithdrawRevert(vaultAddress, lpAddress string, blockNumber uint64) error {
@@ -122,24 +124,25 @@ func (db *DB) BidPlacedRevert(bidId, roundAddress string) error {
 	return err
 }
 
-func (db *DB) BidUpdatedRevert(bidId, roundAddress string, amount models.BigInt, treeNonce uint64) error {
+// BidUpdatedRevert undoes BidUpdatedIndex: it takes the price increase off the
+// bid and restores the tree nonce it had before the update
+func (db *DB) BidUpdatedRevert(bidId, roundAddress string, priceIncrease models.BigInt, treeNonceBefore uint64) error {
 	query := `
-	UPDATE bids
-	amount = amount - $1,
-	tree_nonce = $2,
-	WHERE bid_id = $3 AND round_address = $4`
-
-	_, err := db.tx.Exec(db.ctx, query, amount, treeNonce, bidId, roundAddress)
-	if err != nil {
-		return err
+		UPDATE "Bids"
+		SET
+			price = price - $1,
+			tree_nonce = $2
+		WHERE bid_id = $3 AND round_address = $4`
+
+	if _, err := db.tx.Exec(
+		context.Background(),
+		query,
+		priceIncrease,
+		treeNonceBefore-1,
+		bidId,
+		roundAddress,
+	); err != nil {
+		return fmt.Errorf("failed to revert bid update: %w", err)
 	}
 	return nil
-	// if err := db.tx.Model(models.Bid{}).Where("bid_id = ?", bidId).Where("round_address = ?", roundAddress).Updates(map[string]interface{}{
-	// 	"amount":     gorm.Expr("amount - ?", amount),
-	// 	"tree_nonce": treeNonce,
-	// }); err != nil {
-	// 	return err.Error
-	// }
-	// return nil
-
 }
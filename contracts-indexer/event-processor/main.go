@@ -1,12 +1,21 @@
 package main
 
 import (
+	"event-processor/adaptors"
 	"event-processor/db"
 	"log"
+	"os"
 
 	"github.com/joho/godotenv"
 )
 
+// Contract classes built by scarb, used when VAULT_ABI_PATH or
+// OPTION_ROUND_ABI_PATH is not set
+const (
+	defaultVaultABIPath       = "../../contracts/target/dev/pitch_lake_Vault.contract_class.json"
+	defaultOptionRoundABIPath = "../../contracts/target/dev/pitch_lake_OptionRound.contract_class.json"
+)
+
 func main() {
 	log.SetFlags(0)
 
@@ -28,9 +37,26 @@ func main() {
 // OR Trigger:or_update
 func run() error {
 
+	if err := adaptors.LoadABIs(
+		envOr("VAULT_ABI_PATH", defaultVaultABIPath),
+		envOr("OPTION_ROUND_ABI_PATH", defaultOptionRoundABIPath),
+	); err != nil {
+		return err
+	}
+
 	db := &db.DB{}
-	db.Init()
-	db.CatchupDriverEvents()
-	db.Listener()
-	return nil
+	if err := db.Init(); err != nil {
+		return err
+	}
+	if err := db.CatchupDriverEvents(); err != nil {
+		return err
+	}
+	return db.Listener()
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
 }
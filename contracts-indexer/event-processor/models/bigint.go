@@ -39,6 +39,9 @@ func (b *BigInt) Scan(value interface{}) error {
 
 func (b *BigInt) scanString(s string) error {
 	s = strings.TrimSpace(s)
+	if _, ok := b.Int.SetString(s, 10); !ok {
+		return fmt.Errorf("invalid numeric value %q for type BigInt", s)
+	}
 	return b.validateUint256()
 }
 
@@ -0,0 +1,26 @@
+package models
+
+import "testing"
+
+func TestBigIntScan(t *testing.T) {
+	for _, value := range []any{"115792089237316195423570985008687907853269984665640564039457584007913129639935", []byte(" 42 "), int64(7)} {
+		var b BigInt
+		if err := b.Scan(value); err != nil {
+			t.Errorf("Unexpected error for %v: %v", value, err)
+		}
+		if b.Sign() <= 0 {
+			t.Errorf("Expected %v to be scanned, got %s", value, b)
+		}
+	}
+
+	var b BigInt
+	if err := b.Scan("12"); err != nil || b.Int64() != 12 {
+		t.Errorf("Expected 12, got %s (%v)", b, err)
+	}
+	for _, value := range []any{"abc", "-1", "115792089237316195423570985008687907853269984665640564039457584007913129639936"} {
+		var b BigInt
+		if err := b.Scan(value); err == nil {
+			t.Errorf("Expected an error for %v", value)
+		}
+	}
+}
@@ -1,22 +1,18 @@
 package models
 
-import (
-	"time"
-
-	"github.com/lib/pq"
-)
+import "driverevents"
 
 type Event struct {
-	From            string         `json:"from"`
-	TransactionHash string         `json:"transactionHash"`
-	BlockNumber     uint64         `json:"blockNumber"`
-	VaultAddress    string         `json:"vaultAddress"`
-	Timestamp       uint64         `json:"timestamp"`
-	EventNonce      uint64         `json:"eventNonce"`
-	BlockHash       string         `json:"blockHash"`
-	EventName       string         `json:"eventName"`
-	EventKeys       pq.StringArray `json:"eventKeys"`
-	EventData       pq.StringArray `json:"eventData"`
+	From            string   `json:"from"`
+	TransactionHash string   `json:"transactionHash"`
+	BlockNumber     uint64   `json:"blockNumber"`
+	VaultAddress    string   `json:"vaultAddress"`
+	Timestamp       uint64   `json:"timestamp"`
+	EventNonce      uint64   `json:"eventNonce"`
+	BlockHash       string   `json:"blockHash"`
+	EventName       string   `json:"eventName"`
+	EventKeys       []string `json:"eventKeys"`
+	EventData       []string `json:"eventData"`
 }
 
 type Vault struct {
@@ -120,14 +116,6 @@ type StarknetBlock struct {
 	Status      string `json:"status"`
 }
 
-type DriverEvent struct {
-	ID             int       `json:"id"`
-	SequenceIndex  int64     `json:"sequence_index"`
-	Type           string    `json:"type"`
-	Timestamp      time.Time `json:"timestamp"`
-	IsProcessed    bool      `json:"is_processed"`
-	BlockHash      string    `json:"block_hash"`
-	StartBlockHash string    `json:"start_block_hash"`
-	EndBlockHash   string    `json:"end_block_hash"`
-	VaultAddress   string    `json:"vault_address"`
-}
+// DriverEvent is a row of the driver_events table written by the
+// event-logger, as defined by the driverevents contract
+type DriverEvent = driverevents.Event
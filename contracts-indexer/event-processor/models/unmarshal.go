@@ -1,11 +1,6 @@
 package models
 
-import (
-	"encoding/json"
-	"time"
-
-	"github.com/lib/pq"
-)
+import "encoding/json"
 
 func (lps *LiquidityProviderState) UnmarshalJSON(data []byte) error {
 	// Auxiliary struct to map JSON keys
@@ -217,16 +212,16 @@ func (or *OptionRound) UnmarshalJSON(data []byte) error {
 func (e *Event) UnmarshalJSON(data []byte) error {
 	// Auxiliary struct to map JSON keys
 	aux := struct {
-		From            string         `json:"from"`
-		TransactionHash string         `json:"transaction_hash"`
-		BlockNumber     uint64         `json:"block_number"`
-		BlockHash       string         `json:"block_hash"`
-		EventNonce      uint64         `json:"event_nonce"`
-		VaultAddress    string         `json:"vault_address"`
-		Timestamp       uint64         `json:"timestamp"`
-		EventName       string         `json:"event_name"`
-		EventKeys       pq.StringArray `json:"event_keys"`
-		EventData       pq.StringArray `json:"event_data"`
+		From            string   `json:"from"`
+		TransactionHash string   `json:"transaction_hash"`
+		BlockNumber     uint64   `json:"block_number"`
+		BlockHash       string   `json:"block_hash"`
+		EventNonce      uint64   `json:"event_nonce"`
+		VaultAddress    string   `json:"vault_address"`
+		Timestamp       uint64   `json:"timestamp"`
+		EventName       string   `json:"event_name"`
+		EventKeys       []string `json:"event_keys"`
+		EventData       []string `json:"event_data"`
 	}{}
 
 	// Unmarshal into the auxiliary struct
@@ -272,36 +267,3 @@ func (sb *StarknetBlock) UnmarshalJSON(data []byte) error {
 
 	return nil
 }
-
-func (de *DriverEvent) UnmarshalJSON(data []byte) error {
-	// Auxiliary struct to map JSON keys
-	aux := struct {
-		ID             int       `json:"id"`
-		SequenceIndex  int64     `json:"sequence_index"`
-		Type           string    `json:"type"`
-		Timestamp      time.Time `json:"timestamp"`
-		IsProcessed    bool      `json:"is_processed"`
-		BlockHash      string    `json:"block_hash"`
-		StartBlockHash string    `json:"start_block_hash"`
-		EndBlockHash   string    `json:"end_block_hash"`
-		VaultAddress   string    `json:"vault_address"`
-	}{}
-
-	// Unmarshal into the auxiliary struct
-	if err := json.Unmarshal(data, &aux); err != nil {
-		return err
-	}
-
-	// Copy data from aux to the original struct
-	de.ID = aux.ID
-	de.SequenceIndex = aux.SequenceIndex
-	de.Type = aux.Type
-	de.Timestamp = aux.Timestamp
-	de.IsProcessed = aux.IsProcessed
-	de.BlockHash = aux.BlockHash
-	de.StartBlockHash = aux.StartBlockHash
-	de.EndBlockHash = aux.EndBlockHash
-	de.VaultAddress = aux.VaultAddress
-
-	return nil
-}
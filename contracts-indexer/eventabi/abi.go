@@ -0,0 +1,206 @@
+// Package eventabi decodes Starknet events with the ABI of the contract that
+// emitted them. It loads the Sierra contract classes built by the contracts
+// package, looks events up by their selector and decodes keys and data member
+// by member, so that a change to an event layout surfaces as an error instead
+// of a silently shifted felt index.
+package eventabi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+var (
+	// ErrUnknownEvent is returned for an event whose selector is not in the ABI
+	ErrUnknownEvent = errors.New("unknown event")
+	// ErrShortData is returned when the keys or data end before every member
+	// of the event was read
+	ErrShortData = errors.New("event data too short")
+	// ErrTrailingData is returned when felts are left after the last member
+	ErrTrailingData = errors.New("unexpected trailing event data")
+	// ErrOutOfRange is returned for a felt that does not fit its member type
+	ErrOutOfRange = errors.New("value out of range")
+	// ErrUnsupportedType is returned for a member type the decoder cannot read
+	ErrUnsupportedType = errors.New("unsupported type")
+	// ErrMismatch is returned when an event or a Go value does not match the ABI
+	ErrMismatch = errors.New("event does not match the ABI")
+)
+
+// Member is a field of an ABI struct or event. Kind is "key" or "data" for
+// event members and empty for struct members.
+type Member struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Kind string `json:"kind"`
+}
+
+// EventDef is an event struct of an ABI
+type EventDef struct {
+	// Name is the last segment of Path, e.g. "Deposit"
+	Name string
+	// Path is the full Cairo path of the event struct
+	Path string
+	// Selector is the hex sn_keccak of Name, the first key of the event
+	Selector string
+	Members  []Member
+}
+
+// abiEntry is an item of a Sierra ABI. Only the fields used to decode events
+// are read.
+type abiEntry struct {
+	Type     string   `json:"type"`
+	Name     string   `json:"name"`
+	Kind     string   `json:"kind"`
+	Members  []Member `json:"members"`
+	Variants []Member `json:"variants"`
+}
+
+// Registry holds the events, structs and enums of one or more ABIs
+type Registry struct {
+	events  map[string]*EventDef
+	byName  map[string]*EventDef
+	structs map[string][]Member
+	enums   map[string][]Member
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		events:  make(map[string]*EventDef),
+		byName:  make(map[string]*EventDef),
+		structs: make(map[string][]Member),
+		enums:   make(map[string][]Member),
+	}
+}
+
+// LoadFile loads the ABI of a contract class file, such as
+// target/dev/pitch_lake_Vault.contract_class.json
+func (r *Registry) LoadFile(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := r.Load(raw); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Load adds the events of an ABI to the registry. raw is either a contract
+// class, whose abi field is an array or a JSON encoded string, or a bare ABI
+// array. Events already loaded from another ABI must have the same members.
+func (r *Registry) Load(raw []byte) error {
+	entries, err := parseABI(raw)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		switch {
+		case entry.Type == "struct":
+			r.structs[entry.Name] = entry.Members
+		case entry.Type == "enum":
+			r.enums[entry.Name] = entry.Variants
+		case entry.Type == "event" && entry.Kind == "struct":
+			name := entry.Name
+			if i := strings.LastIndex(name, "::"); i >= 0 {
+				name = name[i+len("::"):]
+			}
+			def := &EventDef{
+				Name:     name,
+				Path:     entry.Name,
+				Selector: Selector(name),
+				Members:  entry.Members,
+			}
+			if known, ok := r.byName[name]; ok && !sameMembers(known.Members, def.Members) {
+				return fmt.Errorf("%w: %s and %s share the selector of %s", ErrMismatch, known.Path, def.Path, name)
+			}
+			r.events[def.Selector] = def
+			r.byName[name] = def
+		}
+	}
+	return nil
+}
+
+func parseABI(raw []byte) ([]abiEntry, error) {
+	raw = bytes.TrimSpace(raw)
+	var abi json.RawMessage = raw
+	if len(raw) > 0 && raw[0] == '{' {
+		var class struct {
+			ABI json.RawMessage `json:"abi"`
+		}
+		if err := json.Unmarshal(raw, &class); err != nil {
+			return nil, fmt.Errorf("invalid contract class: %w", err)
+		}
+		if len(class.ABI) == 0 {
+			return nil, errors.New("contract class has no abi")
+		}
+		abi = class.ABI
+		if abi[0] == '"' {
+			var encoded string
+			if err := json.Unmarshal(abi, &encoded); err != nil {
+				return nil, fmt.Errorf("invalid contract class abi: %w", err)
+			}
+			abi = json.RawMessage(encoded)
+		}
+	}
+
+	var entries []abiEntry
+	if err := json.Unmarshal(abi, &entries); err != nil {
+		return nil, fmt.Errorf("invalid abi: %w", err)
+	}
+	return entries, nil
+}
+
+func sameMembers(a, b []Member) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Event returns the definition of an event by name
+func (r *Registry) Event(name string) (*EventDef, bool) {
+	def, ok := r.byName[name]
+	return def, ok
+}
+
+// EventBySelector returns the definition of an event by its first key
+func (r *Registry) EventBySelector(selector string) (*EventDef, bool) {
+	def, ok := r.events[normalizeHex(selector)]
+	return def, ok
+}
+
+// Selector returns the Starknet selector of a name: the Keccak-256 of the
+// name masked to 250 bits, as a 0x prefixed hex string
+func Selector(name string) string {
+	hasher := sha3.NewLegacyKeccak256()
+	hasher.Write([]byte(name))
+	hash := new(big.Int).SetBytes(hasher.Sum(nil))
+
+	mask := new(big.Int).Lsh(big.NewInt(1), 250)
+	mask.Sub(mask, big.NewInt(1))
+	return "0x" + hash.And(hash, mask).Text(16)
+}
+
+// normalizeHex lowercases a hex string and strips its leading zeros
+func normalizeHex(s string) string {
+	s = strings.TrimPrefix(strings.ToLower(s), "0x")
+	s = strings.TrimLeft(s, "0")
+	if s == "" {
+		s = "0"
+	}
+	return "0x" + s
+}
@@ -0,0 +1,61 @@
+package eventabi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSelector(t *testing.T) {
+	want := "0x99cd8bde557814842a3121e8ddfd433a539b8c9f14bf31ebf108d12e6196e9"
+	if got := Selector("Transfer"); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestLoadFormats(t *testing.T) {
+	abi := `[{"type":"event","name":"pitch_lake::Test::Ping","kind":"struct","members":[{"name":"value","type":"core::felt252","kind":"data"}]}]`
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{name: "bare abi", raw: abi},
+		{name: "class with abi array", raw: `{"sierra_program":[],"abi":` + abi + `}`},
+		{name: "class with abi string", raw: `{"sierra_program":[],"abi":"[{\"type\":\"event\",\"name\":\"pitch_lake::Test::Ping\",\"kind\":\"struct\",\"members\":[{\"name\":\"value\",\"type\":\"core::felt252\",\"kind\":\"data\"}]}]"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			if err := r.Load([]byte(tt.raw)); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			def, ok := r.EventBySelector(Selector("Ping"))
+			if !ok || def.Path != "pitch_lake::Test::Ping" || len(def.Members) != 1 {
+				t.Errorf("Expected the Ping event, got %+v", def)
+			}
+		})
+	}
+
+	if err := NewRegistry().Load([]byte(`{"sierra_program":[]}`)); err == nil {
+		t.Error("Expected an error for a class without abi")
+	}
+}
+
+func TestLoadConflictingEvents(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Load([]byte(`[{"type":"event","name":"a::Ping","kind":"struct","members":[{"name":"value","type":"core::felt252","kind":"data"}]}]`)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err := r.Load([]byte(`[{"type":"event","name":"b::Ping","kind":"struct","members":[{"name":"value","type":"core::integer::u256","kind":"data"}]}]`))
+	if !errors.Is(err, ErrMismatch) {
+		t.Errorf("Expected ErrMismatch, got %v", err)
+	}
+}
+
+func TestLoadFileContracts(t *testing.T) {
+	r := testRegistry(t)
+	for _, name := range []string{"Deposit", "WithdrawalQueued", "OptionRoundDeployed", "BidPlaced", "AuctionEnded", "Transfer"} {
+		if _, ok := r.Event(name); !ok {
+			t.Errorf("Expected event %s to be loaded", name)
+		}
+	}
+}
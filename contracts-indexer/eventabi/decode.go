@@ -0,0 +1,395 @@
+package eventabi
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+)
+
+// feltTypes are the types stored as a single felt without a range check
+var feltTypes = map[string]bool{
+	"core::felt252": true,
+	"core::starknet::contract_address::ContractAddress": true,
+	"core::starknet::class_hash::ClassHash":             true,
+	"core::starknet::eth_address::EthAddress":           true,
+	"core::bytes_31::bytes31":                           true,
+}
+
+// uintBits maps the unsigned integer types stored in a single felt to their size
+var uintBits = map[string]int{
+	"core::integer::u8":    8,
+	"core::integer::u16":   16,
+	"core::integer::u32":   32,
+	"core::integer::u64":   64,
+	"core::integer::u128":  128,
+	"core::integer::usize": 32,
+}
+
+const (
+	typeU256 = "core::integer::u256"
+	typeBool = "core::bool"
+	typeUnit = "()"
+)
+
+// Enum is a decoded Cairo enum
+type Enum struct {
+	Variant string
+	Value   any
+}
+
+// Event is a decoded event. Values maps each member name to its value:
+// *big.Int for felts and integers, bool, map[string]any for structs, []any
+// for arrays and spans, and Enum for enums.
+type Event struct {
+	Def    *EventDef
+	Values map[string]any
+}
+
+// Decode decodes an event from its keys, the first of which is the selector,
+// and its data, both as hex strings
+func (r *Registry) Decode(keys, data []string) (*Event, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: event has no keys", ErrShortData)
+	}
+	def, ok := r.EventBySelector(keys[0])
+	if !ok {
+		return nil, fmt.Errorf("%w: selector %s", ErrUnknownEvent, keys[0])
+	}
+
+	keyFelts, err := parseFelts(keys[1:])
+	if err != nil {
+		return nil, fmt.Errorf("%s keys: %w", def.Name, err)
+	}
+	dataFelts, err := parseFelts(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s data: %w", def.Name, err)
+	}
+	keyCursor := &cursor{felts: keyFelts, what: "keys"}
+	dataCursor := &cursor{felts: dataFelts, what: "data"}
+
+	values := make(map[string]any, len(def.Members))
+	for _, member := range def.Members {
+		c := dataCursor
+		if member.Kind == "key" {
+			c = keyCursor
+		}
+		value, err := r.decodeType(member.Type, c, def.Name+"."+member.Name)
+		if err != nil {
+			return nil, err
+		}
+		values[member.Name] = value
+	}
+	for _, c := range []*cursor{keyCursor, dataCursor} {
+		if left := c.remaining(); left > 0 {
+			return nil, fmt.Errorf("%w: %d felts left in the %s of %s", ErrTrailingData, left, c.what, def.Name)
+		}
+	}
+	return &Event{Def: def, Values: values}, nil
+}
+
+// DecodeInto decodes an event that must be the named one into out, see
+// Event.Unmarshal
+func (r *Registry) DecodeInto(name string, keys, data []string, out any) error {
+	event, err := r.Decode(keys, data)
+	if err != nil {
+		return err
+	}
+	if event.Def.Name != name {
+		return fmt.Errorf("%w: expected %s, got %s", ErrMismatch, name, event.Def.Name)
+	}
+	return event.Unmarshal(out)
+}
+
+func parseFelts(values []string) ([]*big.Int, error) {
+	felts := make([]*big.Int, len(values))
+	for i, s := range values {
+		v, ok := new(big.Int).SetString(strings.TrimPrefix(strings.ToLower(s), "0x"), 16)
+		if !ok || v.Sign() < 0 {
+			return nil, fmt.Errorf("invalid felt %q", s)
+		}
+		felts[i] = v
+	}
+	return felts, nil
+}
+
+// cursor reads the felts of the keys or of the data in order
+type cursor struct {
+	felts []*big.Int
+	pos   int
+	what  string
+}
+
+func (c *cursor) next(path string) (*big.Int, error) {
+	if c.pos >= len(c.felts) {
+		return nil, fmt.Errorf("%w: %s needs %s felt %d, only %d present", ErrShortData, path, c.what, c.pos, len(c.felts))
+	}
+	v := c.felts[c.pos]
+	c.pos++
+	return v, nil
+}
+
+func (c *cursor) remaining() int {
+	return len(c.felts) - c.pos
+}
+
+func (r *Registry) decodeType(typ string, c *cursor, path string) (any, error) {
+	if bits, ok := uintBits[typ]; ok {
+		v, err := c.next(path)
+		if err != nil {
+			return nil, err
+		}
+		if v.BitLen() > bits {
+			return nil, fmt.Errorf("%w: %s is 0x%s, too large for %s", ErrOutOfRange, path, v.Text(16), typ)
+		}
+		return v, nil
+	}
+	if feltTypes[typ] {
+		return c.next(path)
+	}
+
+	switch typ {
+	case typeU256:
+		low, err := c.next(path + ".low")
+		if err != nil {
+			return nil, err
+		}
+		high, err := c.next(path + ".high")
+		if err != nil {
+			return nil, err
+		}
+		if low.BitLen() > 128 || high.BitLen() > 128 {
+			return nil, fmt.Errorf("%w: %s has a limb above 128 bits", ErrOutOfRange, path)
+		}
+		return new(big.Int).Or(new(big.Int).Lsh(high, 128), low), nil
+	case typeBool:
+		v, err := c.next(path)
+		if err != nil {
+			return nil, err
+		}
+		if !v.IsUint64() || v.Uint64() > 1 {
+			return nil, fmt.Errorf("%w: %s is 0x%s, not a bool", ErrOutOfRange, path, v.Text(16))
+		}
+		return v.Uint64() == 1, nil
+	case typeUnit:
+		return nil, nil
+	}
+
+	if elem, ok := arrayElem(typ); ok {
+		n, err := c.next(path + ".len")
+		if err != nil {
+			return nil, err
+		}
+		// Every element takes at least a felt, a longer length cannot be valid
+		if !n.IsInt64() || n.Int64() > int64(c.remaining()) {
+			return nil, fmt.Errorf("%w: %s has length %s, only %d felts left", ErrShortData, path, n, c.remaining())
+		}
+		values := make([]any, n.Int64())
+		for i := range values {
+			if values[i], err = r.decodeType(elem, c, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+	if members, ok := r.structs[typ]; ok {
+		values := make(map[string]any, len(members))
+		for _, member := range members {
+			value, err := r.decodeType(member.Type, c, path+"."+member.Name)
+			if err != nil {
+				return nil, err
+			}
+			values[member.Name] = value
+		}
+		return values, nil
+	}
+	if variants, ok := r.enums[typ]; ok {
+		index, err := c.next(path)
+		if err != nil {
+			return nil, err
+		}
+		if !index.IsInt64() || index.Int64() >= int64(len(variants)) {
+			return nil, fmt.Errorf("%w: %s has variant %s, %s has %d", ErrOutOfRange, path, index, typ, len(variants))
+		}
+		variant := variants[index.Int64()]
+		value, err := r.decodeType(variant.Type, c, path+"."+variant.Name)
+		if err != nil {
+			return nil, err
+		}
+		return Enum{Variant: variant.Name, Value: value}, nil
+	}
+	return nil, fmt.Errorf("%w: %s of %s", ErrUnsupportedType, typ, path)
+}
+
+// arrayElem returns the element type of an Array or Span type
+func arrayElem(typ string) (string, bool) {
+	for _, prefix := range []string{"core::array::Array::<", "core::array::Span::<"} {
+		if strings.HasPrefix(typ, prefix) && strings.HasSuffix(typ, ">") {
+			return typ[len(prefix) : len(typ)-1], true
+		}
+	}
+	return "", false
+}
+
+var bigIntType = reflect.TypeOf((*big.Int)(nil))
+
+// Unmarshal stores the members of the event in the fields of the struct
+// pointed to by out that have an abi tag naming the member, e.g.
+// `abi:"account"`. A field may be:
+//   - a string for felts, as 0x prefixed hex, and for enums, as the variant
+//   - a *big.Int for felts and integers of any size
+//   - an unsigned integer for integers that fit it
+//   - a bool for core::bool
+//   - a struct with abi tags for ABI structs, and a slice for arrays
+func (e *Event) Unmarshal(out any) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("eventabi: Unmarshal needs a pointer to a struct")
+	}
+	return assignStruct(rv.Elem(), e.Values, e.Def.Name)
+}
+
+func assignStruct(dst reflect.Value, values map[string]any, path string) error {
+	t := dst.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, ok := t.Field(i).Tag.Lookup("abi")
+		if !ok {
+			continue
+		}
+		value, ok := values[name]
+		if !ok {
+			return fmt.Errorf("%w: %s has no member %s", ErrMismatch, path, name)
+		}
+		if err := assign(dst.Field(i), value, path+"."+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func assign(dst reflect.Value, value any, path string) error {
+	switch v := value.(type) {
+	case *big.Int:
+		switch {
+		case dst.Type() == bigIntType:
+			dst.Set(reflect.ValueOf(new(big.Int).Set(v)))
+			return nil
+		case dst.Kind() == reflect.String:
+			dst.SetString("0x" + v.Text(16))
+			return nil
+		case isUint(dst.Kind()):
+			if !v.IsUint64() || dst.OverflowUint(v.Uint64()) {
+				return fmt.Errorf("%w: %s is 0x%s, too large for %s", ErrOutOfRange, path, v.Text(16), dst.Type())
+			}
+			dst.SetUint(v.Uint64())
+			return nil
+		}
+	case bool:
+		if dst.Kind() == reflect.Bool {
+			dst.SetBool(v)
+			return nil
+		}
+	case map[string]any:
+		if dst.Kind() == reflect.Struct && dst.Type() != bigIntType.Elem() {
+			return assignStruct(dst, v, path)
+		}
+	case []any:
+		if dst.Kind() == reflect.Slice {
+			slice := reflect.MakeSlice(dst.Type(), len(v), len(v))
+			for i, elem := range v {
+				if err := assign(slice.Index(i), elem, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+			dst.Set(slice)
+			return nil
+		}
+	case Enum:
+		if dst.Kind() == reflect.String {
+			dst.SetString(v.Variant)
+			return nil
+		}
+	case nil:
+		return nil
+	}
+	return fmt.Errorf("%w: %s cannot be stored in %s", ErrMismatch, path, dst.Type())
+}
+
+func isUint(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// Check verifies that the tagged fields of out, a struct or a pointer to one,
+// can hold the members of the named event. Running it at startup catches a
+// contract upgrade that renames, removes or retypes a member before the first
+// such event is processed.
+func (r *Registry) Check(name string, out any) error {
+	def, ok := r.Event(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownEvent, name)
+	}
+	t := reflect.TypeOf(out)
+	if t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return fmt.Errorf("eventabi: cannot check %s against %T", name, out)
+	}
+	return r.checkStruct(t, def.Members, name)
+}
+
+func (r *Registry) checkStruct(t reflect.Type, members []Member, path string) error {
+	types := make(map[string]string, len(members))
+	for _, member := range members {
+		types[member.Name] = member.Type
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := field.Tag.Lookup("abi")
+		if !ok {
+			continue
+		}
+		typ, ok := types[name]
+		if !ok {
+			return fmt.Errorf("%w: %s has no member %s", ErrMismatch, path, name)
+		}
+		if err := r.checkType(field.Type, typ, path+"."+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Registry) checkType(t reflect.Type, typ, path string) error {
+	ok := false
+	if bits, isInt := uintBits[typ]; isInt {
+		ok = t == bigIntType || t.Kind() == reflect.String || isUint(t.Kind()) && t.Bits() >= bits
+	} else if feltTypes[typ] {
+		ok = t == bigIntType || t.Kind() == reflect.String
+	} else if typ == typeU256 {
+		ok = t == bigIntType
+	} else if typ == typeBool {
+		ok = t.Kind() == reflect.Bool
+	} else if elem, isArray := arrayElem(typ); isArray {
+		if t.Kind() == reflect.Slice {
+			return r.checkType(t.Elem(), elem, path+"[]")
+		}
+	} else if members, isStruct := r.structs[typ]; isStruct {
+		if t.Kind() == reflect.Struct && t != bigIntType.Elem() {
+			return r.checkStruct(t, members, path)
+		}
+	} else if _, isEnum := r.enums[typ]; isEnum {
+		ok = t.Kind() == reflect.String
+	} else {
+		return fmt.Errorf("%w: %s of %s", ErrUnsupportedType, typ, path)
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s is %s, cannot be stored in %s", ErrMismatch, path, typ, t)
+	}
+	return nil
+}
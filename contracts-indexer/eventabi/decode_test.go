@@ -0,0 +1,205 @@
+package eventabi
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func testRegistry(t *testing.T) *Registry {
+	t.Helper()
+	r := NewRegistry()
+	for _, path := range []string{
+		"testdata/pitch_lake_Vault.contract_class.json",
+		"testdata/pitch_lake_OptionRound.contract_class.json",
+	} {
+		if err := r.LoadFile(path); err != nil {
+			t.Fatalf("Failed to load %s: %v", path, err)
+		}
+	}
+	return r
+}
+
+type deposit struct {
+	Account         string   `abi:"account"`
+	Amount          *big.Int `abi:"amount"`
+	AccountUnlocked *big.Int `abi:"account_unlocked_balance_now"`
+	VaultUnlocked   *big.Int `abi:"vault_unlocked_balance_now"`
+}
+
+type roundDeployed struct {
+	RoundID      uint64 `abi:"round_id"`
+	Address      string `abi:"address"`
+	AuctionStart uint64 `abi:"auction_start_date"`
+	PricingData  struct {
+		StrikePrice  *big.Int `abi:"strike_price"`
+		CapLevel     *big.Int `abi:"cap_level"`
+		ReservePrice *big.Int `abi:"reserve_price"`
+	} `abi:"pricing_data"`
+}
+
+func TestDecodeInto(t *testing.T) {
+	r := testRegistry(t)
+
+	var d deposit
+	keys := []string{Selector("Deposit"), "0x0abc"}
+	data := []string{"0x5", "0x0", "0x0", "0x1", "0x7", "0x0"}
+	if err := r.DecodeInto("Deposit", keys, data, &d); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	twoPow128 := new(big.Int).Lsh(big.NewInt(1), 128)
+	if d.Account != "0xabc" || d.Amount.Int64() != 5 || d.AccountUnlocked.Cmp(twoPow128) != 0 || d.VaultUnlocked.Int64() != 7 {
+		t.Errorf("Unexpected deposit %+v", d)
+	}
+
+	var round roundDeployed
+	keys = []string{Selector("OptionRoundDeployed")}
+	data = []string{"0x2", "0x7a", "0x64", "0xc8", "0x12c", "0xa", "0x0", "0x2710", "0xb", "0x0"}
+	if err := r.DecodeInto("OptionRoundDeployed", keys, data, &round); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if round.RoundID != 2 || round.Address != "0x7a" || round.AuctionStart != 100 ||
+		round.PricingData.StrikePrice.Int64() != 10 || round.PricingData.CapLevel.Int64() != 10000 || round.PricingData.ReservePrice.Int64() != 11 {
+		t.Errorf("Unexpected round %+v", round)
+	}
+
+	if err := r.DecodeInto("Withdrawal", []string{Selector("Deposit"), "0xabc"}, []string{"0x5", "0x0", "0x0", "0x1", "0x7", "0x0"}, &d); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Expected ErrMismatch for another event, got %v", err)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	r := testRegistry(t)
+	tests := []struct {
+		name string
+		keys []string
+		data []string
+		err  error
+	}{
+		{
+			name: "no keys",
+			err:  ErrShortData,
+		},
+		{
+			name: "unknown selector",
+			keys: []string{Selector("Unknown")},
+			err:  ErrUnknownEvent,
+		},
+		{
+			name: "missing key",
+			keys: []string{Selector("Deposit")},
+			data: []string{"0x5", "0x0", "0x0", "0x1", "0x7", "0x0"},
+			err:  ErrShortData,
+		},
+		{
+			name: "short data",
+			keys: []string{Selector("Deposit"), "0xabc"},
+			data: []string{"0x5", "0x0", "0x0"},
+			err:  ErrShortData,
+		},
+		{
+			name: "trailing data",
+			keys: []string{Selector("AuctionStarted")},
+			data: []string{"0x1", "0x0", "0x2", "0x0", "0x7a"},
+			err:  ErrTrailingData,
+		},
+		{
+			name: "u64 out of range",
+			keys: []string{Selector("WithdrawalQueued"), "0xabc"},
+			data: []string{"0x1", "0x10000000000000000", "0x0", "0x0", "0x0", "0x0", "0x0", "0x0"},
+			err:  ErrOutOfRange,
+		},
+		{
+			name: "u256 limb out of range",
+			keys: []string{Selector("AuctionStarted")},
+			data: []string{"0x100000000000000000000000000000000", "0x0", "0x2", "0x0"},
+			err:  ErrOutOfRange,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := r.Decode(tt.keys, tt.data); !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+
+	if _, err := r.Decode([]string{Selector("AuctionStarted")}, []string{"0xzz", "0x0", "0x2", "0x0"}); err == nil {
+		t.Error("Expected an error for an invalid felt")
+	}
+}
+
+func TestDecodeArraysAndEnums(t *testing.T) {
+	r := NewRegistry()
+	abi := `[
+		{"type":"enum","name":"test::Color","variants":[{"name":"Red","type":"()"},{"name":"Custom","type":"core::integer::u8"}]},
+		{"type":"event","name":"test::Painted","kind":"struct","members":[
+			{"name":"ids","type":"core::array::Span::<core::felt252>","kind":"data"},
+			{"name":"color","type":"test::Color","kind":"data"},
+			{"name":"done","type":"core::bool","kind":"data"}
+		]}
+	]`
+	if err := r.Load([]byte(abi)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var painted struct {
+		IDs   []string `abi:"ids"`
+		Color string   `abi:"color"`
+		Done  bool     `abi:"done"`
+	}
+	if err := r.Check("Painted", &painted); err != nil {
+		t.Fatalf("Unexpected check error: %v", err)
+	}
+	err := r.DecodeInto("Painted", []string{Selector("Painted")}, []string{"0x2", "0xa", "0xb", "0x1", "0xff", "0x1"}, &painted)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(painted.IDs, []string{"0xa", "0xb"}) || painted.Color != "Custom" || !painted.Done {
+		t.Errorf("Unexpected event %+v", painted)
+	}
+
+	tests := []struct {
+		name string
+		data []string
+		err  error
+	}{
+		{name: "array longer than data", data: []string{"0x64", "0xa"}, err: ErrShortData},
+		{name: "unknown variant", data: []string{"0x0", "0x2"}, err: ErrOutOfRange},
+		{name: "invalid bool", data: []string{"0x0", "0x0", "0x2"}, err: ErrOutOfRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := r.Decode([]string{Selector("Painted")}, tt.data); !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	r := testRegistry(t)
+	if err := r.Check("Deposit", deposit{}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := r.Check("OptionRoundDeployed", &roundDeployed{}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	var renamed struct {
+		Balance *big.Int `abi:"account_balance_now"`
+	}
+	if err := r.Check("Deposit", renamed); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Expected ErrMismatch for a missing member, got %v", err)
+	}
+	var narrowed struct {
+		Amount uint64 `abi:"amount"`
+	}
+	if err := r.Check("Deposit", narrowed); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Expected ErrMismatch for a u256 in a uint64, got %v", err)
+	}
+	if err := r.Check("Unknown", deposit{}); !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("Expected ErrUnknownEvent, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+// Command record records the blocks and events of a set of vaults from the
+// RPC node at RPC_URL into a replay fixture. The rounds deployed by the vaults
+// are decoded with the Vault contract class at VAULT_ABI_PATH, for instance
+//
+//	go run ./cmd/record -vaults 0x1,0x2 -from 100 -to 200 -out testdata/fixtures/name.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"junoplugin/network"
+	"junoplugin/utils"
+	"log"
+	"os"
+	"replay"
+	"strings"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// defaultVaultABIPath is the Vault contract class of the eventabi tests
+const defaultVaultABIPath = "../eventabi/testdata/pitch_lake_Vault.contract_class.json"
+
+func run() error {
+	vaultABIPath := os.Getenv("VAULT_ABI_PATH")
+	if vaultABIPath == "" {
+		vaultABIPath = defaultVaultABIPath
+	}
+	rpcURL := flag.String("rpc", os.Getenv("RPC_URL"), "Starknet JSON-RPC endpoint")
+	vaults := flag.String("vaults", "", "comma separated vault addresses")
+	from := flag.Uint64("from", 0, "first block")
+	to := flag.Uint64("to", 0, "last block")
+	out := flag.String("out", "", "fixture file to write")
+	vaultABI := flag.String("vault-abi", vaultABIPath, "Vault contract class")
+	flag.Parse()
+
+	if *rpcURL == "" || *vaults == "" || *out == "" {
+		flag.Usage()
+		return fmt.Errorf("-rpc, -vaults and -out are required")
+	}
+
+	if err := utils.LoadABIs(*vaultABI); err != nil {
+		return err
+	}
+	chain, err := network.NewNetwork(*rpcURL, network.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to initialize network: %w", err)
+	}
+	recording, err := replay.Record(chain, strings.Split(*vaults, ","), *from, *to)
+	if err != nil {
+		return err
+	}
+	if err := recording.Save(*out); err != nil {
+		return err
+	}
+	log.Printf("Recorded blocks %d to %d into %s", *from, *to, *out)
+	return nil
+}
@@ -0,0 +1,155 @@
+package replay
+
+import (
+	"context"
+	"event-processor/adaptors"
+	processordb "event-processor/db"
+	"fmt"
+	loggerdb "junoplugin/db"
+	"junoplugin/models"
+	"junoplugin/plugin/block"
+	"junoplugin/plugin/source"
+	"junoplugin/plugin/vault"
+	"junoplugin/utils"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Config locates the database and the files the harness needs
+type Config struct {
+	// DatabaseURL is a database the harness may wipe
+	DatabaseURL string
+	// MigrationDirs are applied in order, the *.up.sql files of each in name
+	// order: the event-logger migrations first, then the event-processor ones
+	MigrationDirs []string
+	// ABIPaths are the contract classes the event-logger and the
+	// event-processor decode with
+	ABIPaths []string
+}
+
+// Harness replays recordings through the event-logger and the event-processor
+// against a throwaway database
+type Harness struct {
+	config Config
+	pool   *pgxpool.Pool
+}
+
+// New connects to the database and loads the ABIs of the event-logger and
+// the event-processor
+func New(ctx context.Context, config Config) (*Harness, error) {
+	if err := utils.LoadABIs(config.ABIPaths...); err != nil {
+		return nil, err
+	}
+	if err := adaptors.LoadABIs(config.ABIPaths...); err != nil {
+		return nil, err
+	}
+	pool, err := pgxpool.New(ctx, config.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create connection pool: %w", err)
+	}
+	return &Harness{config: config, pool: pool}, nil
+}
+
+// Close closes the connections of the harness
+func (h *Harness) Close() {
+	h.pool.Close()
+}
+
+// Reset drops every table of the public schema and applies the migrations
+func (h *Harness) Reset(ctx context.Context) error {
+	if _, err := h.pool.Exec(ctx, `DROP SCHEMA public CASCADE; CREATE SCHEMA public;`); err != nil {
+		return fmt.Errorf("failed to reset the database: %w", err)
+	}
+	for _, dir := range h.config.MigrationDirs {
+		files, err := filepath.Glob(filepath.Join(dir, "*.up.sql"))
+		if err != nil {
+			return err
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			migration, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			if _, err := h.pool.Exec(ctx, string(migration)); err != nil {
+				return fmt.Errorf("failed to apply %s: %w", file, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Replay registers the vaults of the recording and hands its steps to the
+// event-logger block processor. After each step the event-processor applies
+// the driver events the step emitted, as it would on their notification.
+func (h *Harness) Replay(ctx context.Context, recording *Recording) error {
+	logger, err := loggerdb.Init(h.config.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer logger.Shutdown()
+
+	processor := &processordb.DB{}
+	if err := processor.Connect(h.config.DatabaseURL); err != nil {
+		return err
+	}
+	defer processor.Pool.Close()
+	defer processor.Conn.Close(ctx)
+
+	vaults, err := registerVaults(logger, recording.Vaults)
+	if err != nil {
+		return err
+	}
+	vaultManager := vault.NewManager(logger, nil, "", nil, nil)
+	vaultManager.TrackVaults(vaults)
+
+	handler := &pipeline{
+		logger:    block.NewProcessor(logger, nil, vaultManager, nil, 0),
+		processor: processor,
+	}
+	return recording.Steps.Run(ctx, handler)
+}
+
+func registerVaults(logger *loggerdb.DB, vaults []Vault) ([]*models.VaultRegistry, error) {
+	registry := make([]*models.VaultRegistry, 0, len(vaults))
+	err := logger.InTx(func(tx loggerdb.Tx) error {
+		for _, v := range vaults {
+			deployedAt := v.DeployedAt
+			entry := &models.VaultRegistry{Address: v.Address, DeployedAt: deployedAt, LastBlockIndexed: &deployedAt}
+			if err := tx.InsertVault(entry); err != nil {
+				return fmt.Errorf("failed to register vault %s: %w", v.Address, err)
+			}
+			registry = append(registry, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// pipeline is the source.Handler of a replay
+type pipeline struct {
+	logger    *block.Processor
+	processor *processordb.DB
+}
+
+var _ source.Handler = (*pipeline)(nil)
+
+func (p *pipeline) NewBlock(block *models.Block) error {
+	if err := p.logger.ProcessNewBlock(block); err != nil {
+		return err
+	}
+	return p.processor.CatchupDriverEvents()
+}
+
+func (p *pipeline) RevertBlock(from, to *models.Block) error {
+	if err := p.logger.RevertBlock(from, to); err != nil {
+		return err
+	}
+	return p.processor.CatchupDriverEvents()
+}
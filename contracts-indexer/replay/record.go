@@ -0,0 +1,78 @@
+package replay
+
+import (
+	"fmt"
+	"junoplugin/models"
+	"junoplugin/plugin/source"
+	"junoplugin/utils"
+	"log"
+
+	"github.com/NethermindEth/juno/core/felt"
+)
+
+// Chain is the part of the RPC client blocks are recorded from
+type Chain interface {
+	GetBlockWithEvents(number uint64) (*models.Block, error)
+}
+
+// Record reads the blocks fromBlock to toBlock and keeps the events emitted by
+// vaults and by the option rounds they deploy, dropping every other event and
+// the receipts left empty. Every block of the range is kept so that the
+// replayed chain has no gaps. The vaults are registered as deployed at the
+// parent of fromBlock. The ABIs of the event-logger must be loaded to follow
+// the rounds the vaults deploy.
+func Record(chain Chain, vaults []string, fromBlock, toBlock uint64) (*Recording, error) {
+	if fromBlock > toBlock {
+		return nil, fmt.Errorf("invalid block range %d-%d", fromBlock, toBlock)
+	}
+	tracked := make(map[string]struct{}, len(vaults))
+	addresses := make([]string, 0, len(vaults))
+	for _, address := range vaults {
+		vault, err := new(felt.Felt).SetString(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vault address %s: %w", address, err)
+		}
+		tracked[vault.String()] = struct{}{}
+		addresses = append(addresses, vault.String())
+	}
+
+	recording := &Recording{Steps: &source.Fixture{}}
+	for n := fromBlock; n <= toBlock; n++ {
+		block, err := chain.GetBlockWithEvents(n)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get block %d: %w", n, err)
+		}
+		if n == fromBlock {
+			for _, address := range addresses {
+				recording.Vaults = append(recording.Vaults, Vault{Address: address, DeployedAt: block.ParentHash.String()})
+			}
+		}
+
+		var receipts []*models.Receipt
+		for _, receipt := range block.Receipts {
+			var events []*models.BlockEvent
+			for _, event := range receipt.Events {
+				if _, ok := tracked[event.From.String()]; !ok {
+					continue
+				}
+				events = append(events, event)
+				round, ok, err := utils.DecodeOptionRoundDeployed(event.Keys, event.Data)
+				if err != nil {
+					return nil, fmt.Errorf("invalid event of tx %s: %w", receipt.TransactionHash, err)
+				}
+				if ok {
+					tracked[round.String()] = struct{}{}
+				}
+			}
+			if len(events) > 0 {
+				receipts = append(receipts, &models.Receipt{TransactionHash: receipt.TransactionHash, Events: events})
+			}
+		}
+		block.Receipts = receipts
+		recording.Steps.Steps = append(recording.Steps.Steps, source.Step{Block: block})
+		if len(receipts) > 0 {
+			log.Printf("Recorded %d transactions of block %d", len(receipts), n)
+		}
+	}
+	return recording, nil
+}
@@ -0,0 +1,55 @@
+// Package replay records the blocks and events of a set of vaults from an RPC
+// node into a fixture file and replays them through the whole indexing
+// pipeline: the event-logger stores the blocks, events and driver events, the
+// event-processor applies the driver events, and the resulting processor
+// tables are compared against golden snapshots.
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"junoplugin/plugin/source"
+	"os"
+	"path/filepath"
+)
+
+// Vault is a vault registered before the first step, deployed at DeployedAt
+type Vault struct {
+	Address    string `json:"address"`
+	DeployedAt string `json:"deployed_at"`
+}
+
+// Recording is a fixture of the whole pipeline: the vaults to track and the
+// blocks and reverts the event-logger receives
+type Recording struct {
+	Vaults []Vault         `json:"vaults"`
+	Steps  *source.Fixture `json:"steps"`
+}
+
+// LoadRecording reads a recording file
+func LoadRecording(path string) (*Recording, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var recording Recording
+	if err := json.Unmarshal(raw, &recording); err != nil {
+		return nil, fmt.Errorf("invalid recording %s: %w", path, err)
+	}
+	if recording.Steps == nil {
+		return nil, fmt.Errorf("invalid recording %s: no steps", path)
+	}
+	return &recording, nil
+}
+
+// Save writes the recording to path
+func (r *Recording) Save(path string) error {
+	raw, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(raw, '\n'), 0o644)
+}
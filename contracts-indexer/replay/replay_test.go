@@ -0,0 +1,224 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"junoplugin/models"
+	"junoplugin/utils"
+
+	"github.com/NethermindEth/juno/core/felt"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden snapshots of testdata/golden")
+
+// Contract classes kept by eventabi, used when VAULT_ABI_PATH or
+// OPTION_ROUND_ABI_PATH is not set
+const (
+	defaultVaultABIPath       = "../eventabi/testdata/pitch_lake_Vault.contract_class.json"
+	defaultOptionRoundABIPath = "../eventabi/testdata/pitch_lake_OptionRound.contract_class.json"
+)
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// TestReplayGolden replays every recording of testdata/fixtures against the
+// database at REPLAY_DB_URL, which is wiped, and compares the processor tables
+// with the snapshot of the same name in testdata/golden. Without a database it
+// is skipped, except in CI, where CI is set and the recordings must be checked.
+func TestReplayGolden(t *testing.T) {
+	fixtures, err := filepath.Glob("testdata/fixtures/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Skip("no recordings in testdata/fixtures")
+	}
+	dbURL := os.Getenv("REPLAY_DB_URL")
+	if dbURL == "" {
+		if os.Getenv("CI") != "" {
+			t.Fatalf("REPLAY_DB_URL is not set in CI, %d recordings cannot be replayed", len(fixtures))
+		}
+		t.Skip("REPLAY_DB_URL is not set")
+	}
+
+	ctx := context.Background()
+	h, err := New(ctx, Config{
+		DatabaseURL:   dbURL,
+		MigrationDirs: []string{"../event-logger/db/migrations", "../event-processor/db/migrations"},
+		ABIPaths: []string{
+			envOr("VAULT_ABI_PATH", defaultVaultABIPath),
+			envOr("OPTION_ROUND_ABI_PATH", defaultOptionRoundABIPath),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	for _, fixture := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(fixture), ".json")
+		t.Run(name, func(t *testing.T) {
+			recording, err := LoadRecording(fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := h.Reset(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if err := h.Replay(ctx, recording); err != nil {
+				t.Fatalf("replay failed: %v", err)
+			}
+			got, err := h.Snapshot(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", "golden", name+".json")
+			if *update {
+				if err := got.Save(golden); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := LoadSnapshot(golden)
+			if err != nil {
+				t.Fatalf("%v, run the test with -update to create it", err)
+			}
+			for _, diff := range got.Diff(want) {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+// fakeChain serves blocks by number
+type fakeChain map[uint64]*models.Block
+
+func (c fakeChain) GetBlockWithEvents(number uint64) (*models.Block, error) {
+	block, ok := c[number]
+	if !ok {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+	return block, nil
+}
+
+// roundDeployedData is the data of an OptionRoundDeployed event of the Vault
+// ABI: the round id and address, its dates and its pricing data
+func roundDeployedData(roundID uint64, round *felt.Felt) []*felt.Felt {
+	data := []*felt.Felt{new(felt.Felt).SetUint64(roundID), round}
+	for range 8 {
+		data = append(data, new(felt.Felt))
+	}
+	return data
+}
+
+func TestRecordKeepsVaultAndRoundEvents(t *testing.T) {
+	if err := utils.LoadABIs(defaultVaultABIPath); err != nil {
+		t.Fatal(err)
+	}
+	vault := new(felt.Felt).SetUint64(0x7a417)
+	round := new(felt.Felt).SetUint64(0x40d)
+	other := new(felt.Felt).SetUint64(0xbad)
+	deployed, _ := new(felt.Felt).SetString(utils.Keccak256("OptionRoundDeployed"))
+	key := []*felt.Felt{new(felt.Felt).SetUint64(1)}
+
+	chain := fakeChain{}
+	for n := uint64(10); n <= 12; n++ {
+		chain[n] = &models.Block{
+			Number:     n,
+			Hash:       new(felt.Felt).SetUint64(n),
+			ParentHash: new(felt.Felt).SetUint64(n - 1),
+		}
+	}
+	chain[10].Receipts = []*models.Receipt{{
+		TransactionHash: new(felt.Felt).SetUint64(0x10),
+		Events:          []*models.BlockEvent{{From: other, Keys: key}},
+	}}
+	chain[11].Receipts = []*models.Receipt{{
+		TransactionHash: new(felt.Felt).SetUint64(0x11),
+		Events: []*models.BlockEvent{
+			{From: vault, Keys: []*felt.Felt{deployed}, Data: roundDeployedData(1, round)},
+			{From: other, Keys: key},
+		},
+	}}
+	chain[12].Receipts = []*models.Receipt{{
+		TransactionHash: new(felt.Felt).SetUint64(0x12),
+		Events:          []*models.BlockEvent{{From: round, Keys: key}},
+	}}
+
+	recording, err := Record(chain, []string{"0x007A417"}, 10, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recording.Vaults) != 1 || recording.Vaults[0] != (Vault{Address: vault.String(), DeployedAt: "0x9"}) {
+		t.Errorf("vaults = %+v, want %s deployed at 0x9", recording.Vaults, vault)
+	}
+
+	steps := recording.Steps.Steps
+	if len(steps) != 3 {
+		t.Fatalf("recorded %d steps, want 3", len(steps))
+	}
+	// Block 10 only has the event of another contract, the round event of
+	// block 12 follows the deployment of block 11
+	want := []int{0, 1, 1}
+	for i, step := range steps {
+		events := 0
+		for _, receipt := range step.Block.Receipts {
+			events += len(receipt.Events)
+		}
+		if events != want[i] {
+			t.Errorf("block %d has %d events, want %d", step.Block.Number, events, want[i])
+		}
+	}
+}
+
+func TestRecordingRoundTrip(t *testing.T) {
+	chain := fakeChain{1: {Number: 1, Hash: new(felt.Felt).SetUint64(1), ParentHash: new(felt.Felt)}}
+	recording, err := Record(chain, []string{"0x1"}, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "fixtures", "recording.json")
+	if err := recording.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadRecording(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := json.Marshal(loaded)
+	want, _ := json.Marshal(recording)
+	if string(got) != string(want) {
+		t.Errorf("loaded %s, want %s", got, want)
+	}
+}
+
+func TestSnapshotDiff(t *testing.T) {
+	got := Snapshot{
+		"VaultStates":   {json.RawMessage(`{"address": "0x1", "unlocked_balance": 10}`)},
+		"Option_Rounds": {json.RawMessage(`{"address": "0x2"}`)},
+	}
+	want := Snapshot{
+		"VaultStates":   {json.RawMessage(`{"unlocked_balance":10,"address":"0x1"}`)},
+		"Option_Buyers": {json.RawMessage(`{"address":"0x3"}`)},
+	}
+	diff := got.Diff(want)
+	expected := []string{
+		`Option_Rounds: unexpected row {"address":"0x2"}`,
+		`Option_Buyers: missing row {"address":"0x3"}`,
+	}
+	if strings.Join(diff, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("diff = %q, want %q", diff, expected)
+	}
+}
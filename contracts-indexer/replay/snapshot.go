@@ -0,0 +1,112 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Tables are the event-processor tables a snapshot holds
+var Tables = []string{"VaultStates", "Liquidity_Providers", "Option_Rounds", "Option_Buyers"}
+
+// Snapshot holds the rows of each of Tables as JSON objects, ordered by their
+// text so that two replays of a recording compare equal
+type Snapshot map[string][]json.RawMessage
+
+// Snapshot reads the rows of Tables
+func (h *Harness) Snapshot(ctx context.Context) (Snapshot, error) {
+	snapshot := make(Snapshot, len(Tables))
+	for _, table := range Tables {
+		query := fmt.Sprintf(`SELECT row_to_json(t)::text FROM %s t ORDER BY 1`, pgx.Identifier{table}.Sanitize())
+		rows, err := h.pool.Query(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", table, err)
+		}
+		values, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", table, err)
+		}
+		snapshot[table] = make([]json.RawMessage, 0, len(values))
+		for _, value := range values {
+			snapshot[table] = append(snapshot[table], json.RawMessage(value))
+		}
+	}
+	return snapshot, nil
+}
+
+// LoadSnapshot reads a golden snapshot
+func LoadSnapshot(path string) (Snapshot, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(raw, &snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+	return snapshot, nil
+}
+
+// Save writes the snapshot to path
+func (s Snapshot) Save(path string) error {
+	raw, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(raw, '\n'), 0o644)
+}
+
+// Diff lists the rows of s missing from want and the rows of want missing
+// from s, table by table. An empty list means the snapshots are equal.
+func (s Snapshot) Diff(want Snapshot) []string {
+	var diff []string
+	for _, table := range Tables {
+		got, expected := rowSet(s[table]), rowSet(want[table])
+		for _, row := range s[table] {
+			key := compact(row)
+			if expected[key] > 0 {
+				expected[key]--
+				continue
+			}
+			diff = append(diff, fmt.Sprintf("%s: unexpected row %s", table, key))
+		}
+		for _, row := range want[table] {
+			key := compact(row)
+			if got[key] > 0 {
+				got[key]--
+				continue
+			}
+			diff = append(diff, fmt.Sprintf("%s: missing row %s", table, key))
+		}
+	}
+	return diff
+}
+
+func rowSet(rows []json.RawMessage) map[string]int {
+	set := make(map[string]int, len(rows))
+	for _, row := range rows {
+		set[compact(row)]++
+	}
+	return set
+}
+
+// compact re-encodes a row so that rows differing only in whitespace or key
+// order compare equal
+func compact(row json.RawMessage) string {
+	var value map[string]json.RawMessage
+	if err := json.Unmarshal(row, &value); err != nil {
+		return string(row)
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return string(row)
+	}
+	return string(raw)
+}